@@ -0,0 +1,175 @@
+// Package audit 记录开通、校验、重新同步、秘钥轮换以及使用恢复码等 OTP 操作的审计日志。
+//
+// 每条 Event 使用固定的 JSON 结构（见 SchemaVersion），Logger 为事件分配递增的序号，
+// 并将上一条事件的摘要写入 PrevHash，组成哈希链：修改、删除或者插入任意一条记录都会被 VerifyChain 发现。
+// 配置了 key 时摘要使用 HMAC-SHA256 计算，没有 key 的人无法重新计算整条链。
+//
+// 校验事件通过 otp.Hooks 产生，其它操作使用 Logger 上对应的包装方法：
+//
+//	sink, err := audit.OpenFile("/var/log/otp/audit.jsonl")
+//	logger := audit.NewLogger(sink, key)
+//	totp := otp.NewTOTP(secret, otp.WithIssuer("Example"), otp.WithAccountName("alice"), logger.Option())
+//	enrollment, err := logger.Enroll("alice", "Example", 10*time.Minute)
+//	i := logger.MatchRecoveryCode("alice", code, hashes)
+//
+// 账户锁定、重复使用的 token 等安全事件可以通过 Tee 同时写入 WebhookSink，发送到 SIEM/SOC 的事件管道。
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// SchemaVersion Event 的结构版本，只会在不兼容的修改时递增。
+const SchemaVersion = 1
+
+// EventType 审计事件的类型。
+type EventType string
+
+const (
+	// EventEnroll 生成开通信息。
+	EventEnroll EventType = "enroll"
+	// EventEnrollConfirm 用户提交 token 确认开通。
+	EventEnrollConfirm EventType = "enroll_confirm"
+	// EventVerify 校验 token，Result 为 otp.VerifyOutcome 的字符串形式。
+	EventVerify EventType = "verify"
+	// EventResync 使用前向窗口重新同步 HOTP 计数器。
+	EventResync EventType = "resync"
+	// EventRotate 开始轮换秘钥。
+	EventRotate EventType = "rotate"
+	// EventRotateComplete 结束轮换，旧秘钥不再被接受。
+	EventRotateComplete EventType = "rotate_complete"
+	// EventRecoveryCode 使用恢复码。
+	EventRecoveryCode EventType = "recovery_code"
+	// EventLockout 连续失败导致账户被 otp.Throttle 锁定，Details 中包含锁定的时长。
+	EventLockout EventType = "lockout"
+)
+
+const (
+	// ResultSuccess 操作成功。
+	ResultSuccess = "success"
+	// ResultFailure 操作失败，原因见 Event.Error。
+	ResultFailure = "failure"
+)
+
+// ErrTampered 审计日志的哈希链校验失败，记录被修改、删除或插入。
+var ErrTampered = errors.New("audit log tampered")
+
+// Event 一条审计记录，字段和 json 标签属于 SchemaVersion 的一部分，不包含 token、秘钥或恢复码。
+type Event struct {
+	// 结构版本，由 Logger 填充。
+	Version int `json:"version"`
+	// 从 1 开始递增的序号，由 Logger 填充。
+	Seq uint64 `json:"seq"`
+	// 事件发生的时间（UTC），为零值时 Logger 使用当前时间。
+	Time time.Time `json:"time"`
+	Type EventType `json:"type"`
+	// totp 或 hotp，与具体算法无关的事件为空。
+	OTPType string `json:"otp_type,omitempty"`
+	Issuer  string `json:"issuer,omitempty"`
+	Account string `json:"account,omitempty"`
+	// ResultSuccess、ResultFailure，校验事件为 otp.VerifyOutcome 的字符串形式。
+	Result string `json:"result"`
+	// 校验通过时使用的 skew，见 otp.VerifyEvent.Skew。
+	Skew *int `json:"skew,omitempty"`
+	// 失败原因
+	Error string `json:"error,omitempty"`
+	// 各类事件的附加信息，例如重新同步前后的计数器。
+	Details map[string]string `json:"details,omitempty"`
+	// 上一条记录的 Hash，第一条记录为空字符串。
+	PrevHash string `json:"prev_hash,omitempty"`
+	// 本条记录（Hash 为空字符串时）JSON 编码的 SHA-256 或 HMAC-SHA256，十六进制编码。
+	Hash string `json:"hash"`
+}
+
+// Sink 审计日志的存储位置，Write 按照 Logger 分配的序号依次调用，不会并发调用。
+type Sink interface {
+	Write(event Event) error
+}
+
+// Logger 为事件分配序号和摘要并写入 Sink，并发安全。
+type Logger struct {
+	// 包装方法以及 Hooks 写入失败时的回调，为 nil 时忽略错误。需要在写入失败时拒绝操作的调用方应该直接使用 Log。
+	OnError func(err error)
+
+	sink Sink
+	key  []byte
+	mu   sync.Mutex
+	seq  uint64
+	last string
+}
+
+// NewLogger 创建一个 Logger。
+//
+// Params:
+//
+//	sink: 事件的存储位置。sink 实现了 Last() (Event, bool) 时（例如 FileSink），从最后一条记录继续哈希链。
+//	key : HMAC 的密钥，为空时使用 SHA-256。VerifyChain 需要传入相同的 key。
+func NewLogger(sink Sink, key []byte) *Logger {
+	l := &Logger{sink: sink, key: append([]byte(nil), key...)}
+	if s, ok := sink.(interface{ Last() (Event, bool) }); ok {
+		if last, ok := s.Last(); ok {
+			l.seq, l.last = last.Seq, last.Hash
+		}
+	}
+	return l
+}
+
+// Log 填充 Version、Seq、PrevHash 和 Hash 之后写入 Sink，写入失败时不会推进哈希链。
+func (l *Logger) Log(event Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	event.Time = event.Time.UTC()
+	event.Version = SchemaVersion
+	event.Seq = l.seq + 1
+	event.PrevHash = l.last
+	hash, err := digest(event, l.key)
+	if err != nil {
+		return err
+	}
+	event.Hash = hash
+	if err := l.sink.Write(event); err != nil {
+		return err
+	}
+	l.seq, l.last = event.Seq, event.Hash
+	return nil
+}
+
+// log 写入事件，失败时调用 OnError。
+func (l *Logger) log(event Event) {
+	if err := l.Log(event); err != nil && l.OnError != nil {
+		l.OnError(err)
+	}
+}
+
+// digest 计算 event（忽略 Hash 字段）的摘要。
+func digest(event Event, key []byte) (string, error) {
+	event.Hash = ""
+	data, err := json.Marshal(event)
+	if err != nil {
+		return "", err
+	}
+	if len(key) == 0 {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:]), nil
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// result 将错误转换为 Result 和 Error 字段。
+func result(err error) (string, string) {
+	if err != nil {
+		return ResultFailure, err.Error()
+	}
+	return ResultSuccess, ""
+}
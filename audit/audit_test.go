@@ -0,0 +1,180 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/huk10/go-otp"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+const secret = "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6"
+
+// memorySink 将事件保存在内存中。
+type memorySink struct {
+	events []Event
+	err    error
+}
+
+func (s *memorySink) Write(event Event) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func TestLogger_Log(t *testing.T) {
+	sink := &memorySink{}
+	logger := NewLogger(sink, []byte("key"))
+	now := time.Date(2024, 1, 1, 8, 0, 0, 0, time.FixedZone("UTC+8", 8*3600))
+	assert.Nil(t, logger.Log(Event{Time: now, Type: EventVerify, Result: ResultSuccess}))
+	assert.Nil(t, logger.Log(Event{Type: EventVerify, Result: ResultFailure}))
+
+	assert.Len(t, sink.events, 2)
+	first, second := sink.events[0], sink.events[1]
+	assert.Equal(t, SchemaVersion, first.Version)
+	assert.Equal(t, uint64(1), first.Seq)
+	assert.Equal(t, time.UTC, first.Time.Location())
+	assert.True(t, now.Equal(first.Time))
+	assert.Equal(t, "", first.PrevHash)
+	assert.Len(t, first.Hash, 64)
+	assert.Equal(t, uint64(2), second.Seq)
+	assert.Equal(t, first.Hash, second.PrevHash)
+	assert.False(t, second.Time.IsZero())
+
+	// 写入失败时不推进哈希链
+	sink.err = errors.New("disk full")
+	assert.Equal(t, sink.err, logger.Log(Event{Type: EventVerify}))
+	sink.err = nil
+	assert.Nil(t, logger.Log(Event{Type: EventVerify}))
+	assert.Equal(t, uint64(3), sink.events[2].Seq)
+	assert.Equal(t, second.Hash, sink.events[2].PrevHash)
+}
+
+func TestEventSchema(t *testing.T) {
+	skew := 0
+	data, err := json.Marshal(Event{
+		Version: 1,
+		Seq:     7,
+		Time:    time.Unix(1704075000, 0).UTC(),
+		Type:    EventVerify,
+		OTPType: "totp",
+		Issuer:  "Example",
+		Account: "alice",
+		Result:  "success",
+		Skew:    &skew,
+		Hash:    "00",
+	})
+	assert.Nil(t, err)
+	expected := `{"version":1,"seq":7,"time":"2024-01-01T02:10:00Z","type":"verify","otp_type":"totp","issuer":"Example","account":"alice","result":"success","skew":0,"hash":"00"}`
+	assert.Equal(t, expected, string(data))
+}
+
+func TestLogger_Hooks(t *testing.T) {
+	sink := &memorySink{}
+	logger := NewLogger(sink, nil)
+	now := time.Unix(1704075000, 0)
+	totp := otp.NewTOTP(secret, otp.WithSkew(1), otp.WithIssuer("Example"), otp.WithAccountName("alice"), logger.Option())
+
+	assert.True(t, totp.Verify(totp.At(now.Add(30*time.Second)), now))
+	assert.False(t, totp.Verify("000000", now))
+	assert.Len(t, sink.events, 2)
+	assert.Equal(t, EventVerify, sink.events[0].Type)
+	assert.Equal(t, "totp", sink.events[0].OTPType)
+	assert.Equal(t, "Example", sink.events[0].Issuer)
+	assert.Equal(t, "alice", sink.events[0].Account)
+	assert.Equal(t, "success", sink.events[0].Result)
+	assert.Equal(t, 1, *sink.events[0].Skew)
+	assert.Equal(t, "mismatch", sink.events[1].Result)
+	assert.Nil(t, sink.events[1].Skew)
+
+	var errs []error
+	logger.OnError = func(err error) { errs = append(errs, err) }
+	sink.err = errors.New("disk full")
+	totp.Verify("000000", now)
+	assert.Equal(t, []error{sink.err}, errs)
+}
+
+func TestLogger_Operations(t *testing.T) {
+	sink := &memorySink{}
+	logger := NewLogger(sink, nil)
+
+	enrollment, err := logger.Enroll("alice", "Example Co", time.Hour, otp.WithSkew(1))
+	assert.Nil(t, err)
+	_, err = logger.Enroll("", "Example Co", time.Hour)
+	assert.NotNil(t, err)
+	now := time.Now()
+	assert.Equal(t, otp.ErrTokenInvalid, logger.Confirm(enrollment, "", now))
+	assert.Nil(t, logger.Confirm(enrollment, enrollment.TOTP.At(now), now))
+
+	hotp := otp.NewHOTP(secret, otp.WithIssuer("Example"))
+	counter, ok := logger.ValidateAndSync("alice", hotp, hotp.At(7), 5, 5)
+	assert.True(t, ok)
+	assert.Equal(t, int64(8), counter)
+
+	rotating := logger.Rotate("alice", enrollment.TOTP, now, time.Hour)
+	logger.CompleteRotation("alice", rotating)
+	assert.Nil(t, rotating.Previous)
+
+	hashed, err := otp.HashRecoveryCode("ABCD-EFGH")
+	assert.Nil(t, err)
+	hashes := []string{hashed}
+	assert.Equal(t, -1, logger.MatchRecoveryCode("alice", "0000-0000", hashes))
+	assert.Equal(t, 0, logger.MatchRecoveryCode("alice", "abcd efgh", hashes))
+
+	var types, results []string
+	for _, event := range sink.events {
+		types = append(types, string(event.Type))
+		results = append(results, event.Result)
+	}
+	assert.Equal(t, []string{"enroll", "enroll", "enroll_confirm", "enroll_confirm", "resync", "rotate", "rotate_complete", "recovery_code", "recovery_code"}, types)
+	assert.Equal(t, []string{"success", "failure", "failure", "success", "success", "success", "success", "failure", "success"}, results)
+	assert.Equal(t, "Example Co", sink.events[3].Issuer)
+	assert.Equal(t, "alice", sink.events[3].Account)
+	assert.Equal(t, otp.ErrTokenInvalid.Error(), sink.events[2].Error)
+	assert.Equal(t, map[string]string{"counter": "5", "new_counter": "8"}, sink.events[4].Details)
+	assert.Equal(t, "Example", sink.events[4].Issuer)
+	assert.Equal(t, map[string]string{"remaining": "1"}, sink.events[7].Details)
+	assert.Equal(t, map[string]string{"remaining": "0"}, sink.events[8].Details)
+}
+
+func TestVerifyChain(t *testing.T) {
+	var buf bytes.Buffer
+	key := []byte("key")
+	logger := NewLogger(NewWriterSink(&buf), key)
+	for _, account := range []string{"alice", "bob", "carol"} {
+		assert.Nil(t, logger.Log(Event{Type: EventVerify, Account: account, Result: ResultSuccess}))
+	}
+	log := buf.String()
+
+	n, err := VerifyChain(strings.NewReader(log), key)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+
+	// 错误的 key
+	_, err = VerifyChain(strings.NewReader(log), []byte("other"))
+	assert.ErrorIs(t, err, ErrTampered)
+
+	// 修改记录
+	n, err = VerifyChain(strings.NewReader(strings.Replace(log, "bob", "eve", 1)), key)
+	assert.ErrorIs(t, err, ErrTampered)
+	assert.Equal(t, 1, n)
+
+	// 删除记录
+	lines := strings.SplitAfter(log, "\n")
+	_, err = VerifyChain(strings.NewReader(lines[0]+lines[2]), key)
+	assert.ErrorIs(t, err, ErrTampered)
+	assert.Contains(t, err.Error(), "line 2")
+
+	// 从切割后的文件开始校验
+	n, err = VerifyChain(strings.NewReader(lines[1]+lines[2]), key)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, n)
+
+	_, err = VerifyChain(strings.NewReader("not json\n"), key)
+	assert.ErrorIs(t, err, ErrTampered)
+}
@@ -0,0 +1,151 @@
+package audit
+
+import (
+	"github.com/huk10/go-otp"
+	"strconv"
+	"time"
+)
+
+// Hooks 返回记录校验事件的 otp.Hooks，每次校验写入一条 EventVerify，账户和发行商为 WithAccountName、WithIssuer 配置的值。
+func (l *Logger) Hooks() otp.Hooks {
+	return otp.Hooks{
+		OnSuccess: l.verify,
+		OnFailure: l.verify,
+	}
+}
+
+// Option 返回 otp.WithHooks(l.Hooks())，可以直接传给 otp.NewTOTP、otp.NewHOTP。
+func (l *Logger) Option() otp.Option {
+	return otp.WithHooks(l.Hooks())
+}
+
+// verify 记录一次校验。
+func (l *Logger) verify(e otp.VerifyEvent) {
+	event := Event{
+		Time:    e.Time,
+		Type:    EventVerify,
+		OTPType: e.Type,
+		Issuer:  e.Issuer,
+		Account: e.AccountName,
+		Result:  e.Outcome.String(),
+	}
+	if e.Outcome == otp.VerifySuccess {
+		skew := e.Skew
+		event.Skew = &skew
+	}
+	if e.Err != nil {
+		event.Error = e.Err.Error()
+	}
+	l.log(event)
+}
+
+// Enroll 调用 otp.NewEnrollment 并记录 EventEnroll，参数和返回值与 otp.NewEnrollment 相同。
+func (l *Logger) Enroll(account, issuer string, ttl time.Duration, options ...otp.TOTPOption) (*otp.Enrollment, error) {
+	enrollment, err := otp.NewEnrollment(account, issuer, ttl, options...)
+	event := Event{Type: EventEnroll, OTPType: "totp", Issuer: issuer, Account: account}
+	event.Result, event.Error = result(err)
+	if err == nil {
+		event.Time = enrollment.CreatedAt
+		if !enrollment.ExpiresAt.IsZero() {
+			event.Details = map[string]string{"expires_at": enrollment.ExpiresAt.UTC().Format(time.RFC3339)}
+		}
+	}
+	l.log(event)
+	return enrollment, err
+}
+
+// Confirm 调用 enrollment.Confirm 并记录 EventEnrollConfirm，参数和返回值与 otp.Enrollment.Confirm 相同。
+func (l *Logger) Confirm(enrollment *otp.Enrollment, token string, t time.Time, options ...otp.TOTPOption) error {
+	err := enrollment.Confirm(token, t, options...)
+	event := Event{Time: t, Type: EventEnrollConfirm, OTPType: "totp", Account: enrollment.Account}
+	if enrollment.KeyURI != nil {
+		event.Issuer = enrollment.KeyURI.Issuer
+	}
+	event.Result, event.Error = result(err)
+	l.log(event)
+	return err
+}
+
+// ValidateAndSync 调用 hotp.ValidateAndSync 并记录 EventResync，Details 中包含同步前后的计数器。
+//
+// hotp 同时配置了 l.Option() 时还会产生一条 EventVerify。
+func (l *Logger) ValidateAndSync(account string, hotp *otp.HOTP, token string, counter int64, lookAhead int) (int64, bool) {
+	next, ok := hotp.ValidateAndSync(token, counter, lookAhead)
+	event := Event{
+		Type:    EventResync,
+		OTPType: "hotp",
+		Issuer:  hotp.Issuer,
+		Account: account,
+		Result:  ResultSuccess,
+		Details: map[string]string{
+			"counter":     strconv.FormatInt(counter, 10),
+			"new_counter": strconv.FormatInt(next, 10),
+		},
+	}
+	if !ok {
+		event.Result = ResultFailure
+	}
+	l.log(event)
+	return next, ok
+}
+
+// Rotate 调用 otp.RotateTOTP 并记录 EventRotate，Details 中包含宽限期结束的时间。
+func (l *Logger) Rotate(account string, previous *otp.TOTP, rotatedAt time.Time, gracePeriod time.Duration) *otp.RotatingTOTP {
+	rotating := otp.RotateTOTP(previous, rotatedAt, gracePeriod)
+	l.log(Event{
+		Time:    rotatedAt,
+		Type:    EventRotate,
+		OTPType: "totp",
+		Issuer:  previous.Issuer,
+		Account: account,
+		Result:  ResultSuccess,
+		Details: map[string]string{"grace_until": rotatedAt.Add(gracePeriod).UTC().Format(time.RFC3339)},
+	})
+	return rotating
+}
+
+// CompleteRotation 调用 rotating.Complete 并记录 EventRotateComplete。
+func (l *Logger) CompleteRotation(account string, rotating *otp.RotatingTOTP) {
+	rotating.Complete()
+	l.log(Event{
+		Type:    EventRotateComplete,
+		OTPType: "totp",
+		Issuer:  rotating.Current.Issuer,
+		Account: account,
+		Result:  ResultSuccess,
+	})
+}
+
+// MatchRecoveryCode 调用 otp.MatchRecoveryCode 并记录 EventRecoveryCode，Details 中包含剩余可用的恢复码数量。
+func (l *Logger) MatchRecoveryCode(account, code string, hashes []string) int {
+	i := otp.MatchRecoveryCode(code, hashes)
+	event := Event{Type: EventRecoveryCode, Account: account, Result: ResultSuccess}
+	remaining := len(hashes)
+	if i < 0 {
+		event.Result = ResultFailure
+	} else {
+		remaining--
+	}
+	event.Details = map[string]string{"remaining": strconv.Itoa(remaining)}
+	l.log(event)
+	return i
+}
+
+// Throttle 调用 throttle.VerifyWithResult，本次失败导致账户被锁定时记录 EventLockout，参数和返回值与 otp.Throttle.VerifyWithResult 相同。
+//
+// 账户已经被锁定时的尝试不会重复记录。
+func (l *Logger) Throttle(account string, throttle *otp.Throttle, verify func() bool) otp.VerifyResult {
+	result := throttle.VerifyWithResult(account, verify)
+	if !result.Ok && result.Err == nil && result.AttemptsRemaining == 0 {
+		l.log(Event{
+			Type:    EventLockout,
+			Account: account,
+			Result:  ResultFailure,
+			Details: map[string]string{
+				"max_failures": strconv.Itoa(throttle.MaxFailures),
+				"retry_after":  result.RetryAfter.String(),
+			},
+		})
+	}
+	return result
+}
@@ -0,0 +1,169 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// WriterSink 将事件以 JSON lines 的格式写入 io.Writer，每条事件一行。
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink 创建一个 WriterSink，例如写入 os.Stdout 交给日志采集系统。
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// Write 实现 Sink 接口，一次写入完整的一行。
+func (s *WriterSink) Write(event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(data, '\n'))
+	return err
+}
+
+// FileSink 以追加的方式写入 JSON lines 文件，每次写入之后调用 fsync。
+type FileSink struct {
+	WriterSink
+	file *os.File
+	last *Event
+}
+
+// OpenFile 以追加的方式打开（不存在时创建）审计日志文件，并读取最后一条记录，NewLogger 会从该记录继续哈希链。
+//
+// 最后一行不是合法的 Event 时返回错误，避免在损坏的文件之后继续写入。
+func OpenFile(path string) (*FileSink, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	sink := &FileSink{}
+	if line := lastLine(data); len(line) > 0 {
+		var last Event
+		if err := json.Unmarshal(line, &last); err != nil {
+			return nil, fmt.Errorf("%w: last line: %v", ErrTampered, err)
+		}
+		sink.last = &last
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	sink.file = file
+	sink.w = file
+	return sink, nil
+}
+
+// Last 返回打开文件时的最后一条记录，文件为空时返回 false。
+func (s *FileSink) Last() (Event, bool) {
+	if s.last == nil {
+		return Event{}, false
+	}
+	return *s.last, true
+}
+
+// Write 实现 Sink 接口。
+func (s *FileSink) Write(event Event) error {
+	if err := s.WriterSink.Write(event); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// Close 关闭文件。
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// teeSink 见 Tee。
+type teeSink []Sink
+
+// Tee 返回一个依次写入所有 sinks 的 Sink，任意一个写入失败时返回该错误，不再写入之后的 sink。
+//
+// 第一个实现了 Last() (Event, bool) 的 sink 用于 NewLogger 继续哈希链，因此 FileSink 等需要完整记录的存储应该放在前面，
+// WebhookSink 等只接收部分事件的 sink 放在后面。
+func Tee(sinks ...Sink) Sink {
+	return teeSink(sinks)
+}
+
+// Write 实现 Sink 接口。
+func (t teeSink) Write(event Event) error {
+	for _, sink := range t {
+		if err := sink.Write(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Last 返回第一个实现了 Last 的 sink 的最后一条记录。
+func (t teeSink) Last() (Event, bool) {
+	for _, sink := range t {
+		if s, ok := sink.(interface{ Last() (Event, bool) }); ok {
+			return s.Last()
+		}
+	}
+	return Event{}, false
+}
+
+// lastLine 返回 data 中最后一个非空行。
+func lastLine(data []byte) []byte {
+	data = bytes.TrimRight(data, "\r\n")
+	if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+		return data[i+1:]
+	}
+	return data
+}
+
+// VerifyChain 校验 JSON lines 格式的审计日志，返回校验通过的记录数。
+//
+// 第一条记录作为起点（日志文件可能被切割），之后每条记录的序号必须连续、PrevHash 必须等于上一条记录的 Hash，
+// 并且 Hash 与重新计算的摘要一致。任意一项不满足时返回包含行号的 ErrTampered。
+//
+// Example:
+//
+//	n, err := audit.VerifyChain(file, key)
+//	if errors.Is(err, audit.ErrTampered) {
+//		alert(err)
+//	}
+func VerifyChain(r io.Reader, key []byte) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var prev *Event
+	count, line := 0, 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var event Event
+		if err := json.Unmarshal(text, &event); err != nil {
+			return count, fmt.Errorf("%w: line %d: %v", ErrTampered, line, err)
+		}
+		if prev != nil && (event.Seq != prev.Seq+1 || event.PrevHash != prev.Hash) {
+			return count, fmt.Errorf("%w: line %d: broken chain at seq %d", ErrTampered, line, event.Seq)
+		}
+		hash, err := digest(event, key)
+		if err != nil {
+			return count, err
+		}
+		if hash != event.Hash {
+			return count, fmt.Errorf("%w: line %d: hash mismatch at seq %d", ErrTampered, line, event.Seq)
+		}
+		prev = &event
+		count++
+	}
+	return count, scanner.Err()
+}
@@ -0,0 +1,41 @@
+package audit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := OpenFile(path)
+	assert.Nil(t, err)
+	_, ok := sink.Last()
+	assert.False(t, ok)
+	logger := NewLogger(sink, nil)
+	assert.Nil(t, logger.Log(Event{Type: EventEnroll, Account: "alice", Result: ResultSuccess}))
+	assert.Nil(t, logger.Log(Event{Type: EventVerify, Account: "alice", Result: ResultSuccess}))
+	assert.Nil(t, sink.Close())
+
+	// 重新打开之后继续哈希链
+	sink, err = OpenFile(path)
+	assert.Nil(t, err)
+	last, ok := sink.Last()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(2), last.Seq)
+	assert.Nil(t, NewLogger(sink, nil).Log(Event{Type: EventVerify, Account: "alice", Result: ResultFailure}))
+	assert.Nil(t, sink.Close())
+
+	file, err := os.Open(path)
+	assert.Nil(t, err)
+	defer file.Close()
+	n, err := VerifyChain(file, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+
+	// 最后一行损坏
+	assert.Nil(t, os.WriteFile(path, []byte("{\"seq\":1}\ngarbage\n"), 0o600))
+	_, err = OpenFile(path)
+	assert.ErrorIs(t, err, ErrTampered)
+}
@@ -0,0 +1,283 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/huk10/go-otp"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebhookSignatureHeader WebhookSink 请求中携带签名的请求头，格式为 t=<unix 秒数>,v1=<十六进制 HMAC-SHA256>。
+const WebhookSignatureHeader = "X-OTP-Signature"
+
+// minWebhookKeyLength WebhookSink 签名秘钥的最小字节数，与 HMAC-SHA256 的输出长度相同。
+const minWebhookKeyLength = 32
+
+// ErrWebhookSignature 请求的签名缺失、格式错误、不匹配或者超出允许的时间偏差。
+var ErrWebhookSignature = errors.New("invalid webhook signature")
+
+// SecurityEvent WebhookConfig.Filter 的默认值，只保留需要安全团队关注的事件：
+// 账户锁定（EventLockout，即连续失败）、重复使用的 token、校验出错、计数器重新同步以及使用恢复码。
+func SecurityEvent(event Event) bool {
+	switch event.Type {
+	case EventLockout, EventResync, EventRecoveryCode:
+		return true
+	case EventVerify:
+		return event.Result == otp.VerifyReplay.String() || event.Result == otp.VerifyError.String()
+	default:
+		return false
+	}
+}
+
+// WebhookConfig WebhookSink 的配置，除 URL 和 Key 之外都可以使用零值。
+type WebhookConfig struct {
+	// 接收事件的地址，必传。
+	URL string
+	// 签名使用的秘钥，至少 32 字节，必传。接收方使用 VerifyWebhookSignature 校验。
+	Key []byte
+	// 发送请求使用的 client，为 nil 时使用超时为 10 秒的 http.Client。
+	Client *http.Client
+	// 需要发送的事件，为 nil 时使用 SecurityEvent。
+	Filter func(Event) bool
+	// 每个请求最多包含的事件数，默认为 50。
+	BatchSize int
+	// 事件不足 BatchSize 时等待的最长时间，默认为 5 秒。
+	FlushInterval time.Duration
+	// 请求失败（网络错误、408、429 或 5xx）时的最大重试次数，默认为 5，为负数时不重试。
+	MaxRetries int
+	// 第一次重试之前等待的时间，之后每次翻倍，默认为 1 秒。
+	RetryBackoff time.Duration
+	// 等待发送的事件的最大数量，超出时丢弃新的事件，默认为 1000。
+	QueueSize int
+	// 丢弃事件或者重试之后仍然发送失败时调用，为 nil 时忽略错误。
+	OnError func(err error)
+}
+
+// WebhookSink 将安全事件分批发送到 webhook，用于接入 SIEM/SOC 的事件管道。
+//
+// 每个请求的请求体为 {"events": [...]}，请求头 X-OTP-Signature 包含时间戳和请求体的 HMAC-SHA256 签名。
+// 事件在后台发送，Write 不会等待请求完成，也不会返回网络错误，写入 FileSink 等存储的哈希链不受 webhook 可用性的影响。
+// 通常与 FileSink 一起通过 Tee 使用：
+//
+//	webhook, err := audit.NewWebhookSink(audit.WebhookConfig{URL: url, Key: key})
+//	defer webhook.Close()
+//	logger := audit.NewLogger(audit.Tee(file, webhook), chainKey)
+type WebhookSink struct {
+	cfg    WebhookConfig
+	queue  chan Event
+	done   chan struct{}
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewWebhookSink 创建一个 WebhookSink 并启动后台发送，使用完毕后需要调用 Close。
+//
+// URL 为空时返回 otp.ErrInvalidOption，Key 短于 32 字节时返回 otp.ErrSecretTooShort。
+func NewWebhookSink(cfg WebhookConfig) (*WebhookSink, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("%w: webhook url is required", otp.ErrInvalidOption)
+	}
+	if len(cfg.Key) < minWebhookKeyLength {
+		return nil, fmt.Errorf("%w: webhook key has %d bytes, at least %d are required", otp.ErrSecretTooShort, len(cfg.Key), minWebhookKeyLength)
+	}
+	cfg.Key = append([]byte(nil), cfg.Key...)
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.Filter == nil {
+		cfg.Filter = SecurityEvent
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 50
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 5 * time.Second
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 5
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1000
+	}
+	s := &WebhookSink{cfg: cfg, queue: make(chan Event, cfg.QueueSize), done: make(chan struct{})}
+	go s.run()
+	return s, nil
+}
+
+// Write 实现 Sink 接口，Filter 接受的事件放入发送队列。
+//
+// 总是返回 nil：队列已满或者已经关闭时丢弃事件并调用 OnError，避免 webhook 不可用时影响 Logger 的哈希链。
+func (s *WebhookSink) Write(event Event) error {
+	if !s.cfg.Filter(event) {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		s.report(fmt.Errorf("audit: webhook sink closed, event %d dropped", event.Seq))
+		return nil
+	}
+	select {
+	case s.queue <- event:
+	default:
+		s.report(fmt.Errorf("audit: webhook queue full, event %d dropped", event.Seq))
+	}
+	return nil
+}
+
+// Close 发送队列中剩余的事件并停止后台发送，重复调用是安全的。
+func (s *WebhookSink) Close() error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+	<-s.done
+	return nil
+}
+
+// run 从队列中读取事件，达到 BatchSize 或者经过 FlushInterval 时发送。
+func (s *WebhookSink) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+	batch := make([]Event, 0, s.cfg.BatchSize)
+	for {
+		select {
+		case event, ok := <-s.queue:
+			if !ok {
+				s.send(batch)
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= s.cfg.BatchSize {
+				s.send(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			s.send(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// send 发送一批事件，失败时按照 RetryBackoff 重试，最终失败时调用 OnError。
+func (s *WebhookSink) send(batch []Event) {
+	if len(batch) == 0 {
+		return
+	}
+	body, err := json.Marshal(struct {
+		Events []Event `json:"events"`
+	}{batch})
+	if err != nil {
+		s.report(err)
+		return
+	}
+	backoff := s.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := s.post(body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt == s.cfg.MaxRetries {
+			s.report(fmt.Errorf("audit: webhook dropped %d events: %w", len(batch), err))
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// post 发送一次请求，返回是否可以重试。
+func (s *WebhookSink) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(s.cfg.Key, body, time.Now()))
+	resp, err := s.cfg.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook returned %s", resp.Status)
+	default:
+		return false, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+}
+
+// report 调用 OnError。
+func (s *WebhookSink) report(err error) {
+	if s.cfg.OnError != nil {
+		s.cfg.OnError(err)
+	}
+}
+
+// SignWebhook 返回 X-OTP-Signature 请求头的值，签名的内容为 "<unix 秒数>.<请求体>"。
+func SignWebhook(key, body []byte, t time.Time) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + webhookMAC(key, timestamp, body)
+}
+
+// VerifyWebhookSignature 校验 X-OTP-Signature 请求头，签名的时间与 now 相差超过 tolerance 时同样返回 ErrWebhookSignature，
+// 防止截获的请求被重放。
+//
+// Example:
+//
+//	body, _ := io.ReadAll(r.Body)
+//	if err := audit.VerifyWebhookSignature(key, r.Header.Get(audit.WebhookSignatureHeader), body, time.Now(), 5*time.Minute); err != nil {
+//		w.WriteHeader(http.StatusUnauthorized)
+//		return
+//	}
+func VerifyWebhookSignature(key []byte, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		name, value, _ := strings.Cut(part, "=")
+		switch name {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || signature == "" {
+		return ErrWebhookSignature
+	}
+	if !hmac.Equal([]byte(webhookMAC(key, timestamp, body)), []byte(signature)) {
+		return ErrWebhookSignature
+	}
+	if diff := now.Sub(time.Unix(unix, 0)); diff > tolerance || diff < -tolerance {
+		return ErrWebhookSignature
+	}
+	return nil
+}
+
+// webhookMAC 计算 "<timestamp>.<body>" 的 HMAC-SHA256，十六进制编码。
+func webhookMAC(key []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,175 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/huk10/go-otp"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+var webhookKey = []byte("0123456789abcdef0123456789abcdef")
+
+// webhookServer 记录收到的事件，前 failures 个请求返回 503。
+type webhookServer struct {
+	mu       sync.Mutex
+	failures int
+	requests int
+	events   []Event
+	errs     []error
+}
+
+func (s *webhookServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	body, _ := io.ReadAll(r.Body)
+	if err := VerifyWebhookSignature(webhookKey, r.Header.Get(WebhookSignatureHeader), body, time.Now(), time.Minute); err != nil {
+		s.errs = append(s.errs, err)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if s.failures > 0 {
+		s.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	var payload struct {
+		Events []Event `json:"events"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		s.errs = append(s.errs, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.events = append(s.events, payload.Events...)
+}
+
+func TestWebhookSink(t *testing.T) {
+	receiver := &webhookServer{failures: 2}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	var errs []error
+	webhook, err := NewWebhookSink(WebhookConfig{
+		URL:          server.URL,
+		Key:          webhookKey,
+		BatchSize:    2,
+		RetryBackoff: time.Millisecond,
+		OnError:      func(err error) { errs = append(errs, err) },
+	})
+	assert.Nil(t, err)
+	file := &memorySink{}
+	logger := NewLogger(Tee(file, webhook), nil)
+
+	// 重复使用的 token 和校验成功
+	totp := otp.NewTOTP(secret, otp.WithAccountName("alice"), otp.WithReplayGuard(otp.NewMemoryReplayGuard()), logger.Option())
+	now := time.Now()
+	assert.True(t, totp.Verify(totp.At(now), now))
+	assert.False(t, totp.Verify(totp.At(now), now))
+	// 连续失败导致锁定
+	throttle := otp.NewThrottle(2, time.Minute)
+	for i := 0; i < 3; i++ {
+		logger.Throttle("bob", throttle, func() bool { return false })
+	}
+	logger.MatchRecoveryCode("alice", "22222-22222", nil)
+	assert.Nil(t, webhook.Close())
+	assert.Nil(t, webhook.Close())
+
+	// 所有事件都写入 file，只有安全事件发送到 webhook
+	assert.Len(t, file.events, 4)
+	assert.Empty(t, receiver.errs)
+	assert.Empty(t, errs)
+	// 第一批失败两次之后成功，第二批在 Close 时发送
+	assert.Equal(t, 3+1, receiver.requests)
+	var types []EventType
+	for _, event := range receiver.events {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []EventType{EventVerify, EventLockout, EventRecoveryCode}, types)
+	assert.Equal(t, "replay", receiver.events[0].Result)
+	assert.Equal(t, "bob", receiver.events[1].Account)
+	assert.Equal(t, "2", receiver.events[1].Details["max_failures"])
+
+	// 关闭之后写入的事件被丢弃
+	assert.Nil(t, webhook.Write(Event{Type: EventLockout}))
+	assert.Len(t, errs, 1)
+}
+
+func TestWebhookSink_Errors(t *testing.T) {
+	_, err := NewWebhookSink(WebhookConfig{Key: webhookKey})
+	assert.ErrorIs(t, err, otp.ErrInvalidOption)
+	_, err = NewWebhookSink(WebhookConfig{URL: "http://localhost", Key: []byte("short")})
+	assert.ErrorIs(t, err, otp.ErrSecretTooShort)
+
+	// 4xx 不会重试
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+	var errs []error
+	webhook, err := NewWebhookSink(WebhookConfig{URL: server.URL, Key: webhookKey, Filter: func(Event) bool { return true },
+		OnError: func(err error) { errs = append(errs, err) }})
+	assert.Nil(t, err)
+	assert.Nil(t, webhook.Write(Event{Type: EventVerify}))
+	assert.Nil(t, webhook.Close())
+	assert.Equal(t, 1, requests)
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "400")
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	body := []byte(`{"events":[]}`)
+	header := SignWebhook(webhookKey, body, now)
+	assert.Regexp(t, `^t=1704075000,v1=[0-9a-f]{64}$`, header)
+	assert.Nil(t, VerifyWebhookSignature(webhookKey, header, body, now.Add(time.Minute), 5*time.Minute))
+
+	for _, c := range []struct {
+		key    []byte
+		header string
+		body   []byte
+		now    time.Time
+	}{
+		{webhookKey, header, []byte(`{"events":[{}]}`), now},
+		{bytes.Repeat([]byte{1}, 32), header, body, now},
+		{webhookKey, header, body, now.Add(6 * time.Minute)},
+		{webhookKey, SignWebhook(webhookKey, body, now.Add(6*time.Minute)), body, now},
+		{webhookKey, "", body, now},
+		{webhookKey, "t=1704075000", body, now},
+		{webhookKey, "v1=" + header[len("t=1704075000,v1="):], body, now},
+	} {
+		assert.Equal(t, ErrWebhookSignature, VerifyWebhookSignature(c.key, c.header, c.body, c.now, 5*time.Minute), c.header)
+	}
+}
+
+func TestTee(t *testing.T) {
+	first, second := &memorySink{}, &memorySink{}
+	assert.Nil(t, Tee(first, second).Write(Event{Seq: 1}))
+	assert.Len(t, first.events, 1)
+	assert.Len(t, second.events, 1)
+
+	first.err = io.ErrShortWrite
+	assert.Equal(t, io.ErrShortWrite, Tee(first, second).Write(Event{Seq: 2}))
+	assert.Len(t, second.events, 1)
+
+	_, ok := Tee(first, second).(interface{ Last() (Event, bool) }).Last()
+	assert.False(t, ok)
+
+	// NewLogger 从第一个 FileSink 继续哈希链
+	file, err := OpenFile(filepath.Join(t.TempDir(), "audit.jsonl"))
+	assert.Nil(t, err)
+	defer file.Close()
+	assert.Nil(t, NewLogger(file, nil).Log(Event{Type: EventVerify}))
+	file.last = &Event{Seq: 1}
+	second = &memorySink{}
+	assert.Nil(t, NewLogger(Tee(second, file), nil).Log(Event{Type: EventVerify}))
+	assert.Equal(t, uint64(2), second.events[0].Seq)
+}
@@ -0,0 +1,87 @@
+package otp
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+// VerifyRequest VerifyBatch 中的一个校验请求。
+type VerifyRequest struct {
+	// base32 编码的秘钥，TOTP 为 nil 时使用它和 VerifyBatch 的 options 创建 TOTP。
+	Secret string
+	// 可选，已经创建好的 TOTP，不为 nil 时忽略 Secret。
+	TOTP *TOTP
+	// 需要校验的 token。
+	Token string
+	// 校验的时间，零值表示当前时间。
+	Time time.Time
+}
+
+// VerifyResult 校验的结果，VerifyBatch 和 Throttle.VerifyWithResult 都会返回。
+type VerifyResult struct {
+	// token 是否有效。
+	Ok bool
+	// 校验通过的时间步，校验失败时为 0。Throttle 不知道匹配的时间步，总是为 0。
+	Step int64
+	// 创建 TOTP 失败（例如秘钥无法解码）时的错误，或者 Throttle 返回的 ErrThrottled 以及 Store 的错误，此时 Ok 为 false。
+	Err error
+	// 账户被锁定时距离可以再次尝试的时长，没有被锁定时为 0，仅由 Throttle 设置。
+	RetryAfter time.Duration
+	// 被锁定之前还可以失败的次数，仅由 Throttle 设置。
+	AttemptsRemaining int
+}
+
+// VerifyBatch 使用与 CPU 数量相同的 worker 并发校验多个请求，返回的结果与 requests 一一对应。
+//
+// 使用 Secret 创建的 TOTP 默认开启 WithReuseHMAC 以复用哈希对象，options 中可以覆盖。
+//
+// Example:
+//
+//	results := VerifyBatch([]VerifyRequest{
+//		{Secret: secretA, Token: tokenA},
+//		{Secret: secretB, Token: tokenB},
+//	}, WithSkew(1))
+func VerifyBatch(requests []VerifyRequest, options ...TOTPOption) []VerifyResult {
+	results := make([]VerifyResult, len(requests))
+	options = append([]TOTPOption{WithReuseHMAC(true)}, options...)
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(requests) {
+		workers = len(requests)
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = verifyRequest(requests[i], options)
+			}
+		}()
+	}
+	for i := range requests {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// verifyRequest 校验单个请求。
+func verifyRequest(req VerifyRequest, options []TOTPOption) VerifyResult {
+	totp := req.TOTP
+	if totp == nil {
+		var err error
+		if totp, err = NewTOTPWithError(req.Secret, options...); err != nil {
+			return VerifyResult{Err: err}
+		}
+	}
+	t := req.Time
+	if t.IsZero() {
+		t = totp.now()
+	}
+	step, ok := totp.VerifyWithMatch(req.Token, t)
+	return VerifyResult{Ok: ok, Step: step}
+}
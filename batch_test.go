@@ -0,0 +1,46 @@
+package otp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestVerifyBatch(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+	totp32 := NewTOTP(TestSecret32, WithDigits(DigitsEight))
+	results := VerifyBatch([]VerifyRequest{
+		{Secret: TestSecret20, Token: "076141", Time: now},
+		{Secret: TestSecret20, Token: "000000", Time: now},
+		{Secret: TestSecret20, Token: "076141", Time: now.Add(time.Second * 30)},
+		{TOTP: totp32, Token: totp32.At(now), Time: now},
+		{Secret: "!!!", Token: "076141", Time: now},
+		{Secret: TestSecret20, Token: NewTOTP(TestSecret20).Now()},
+	}, WithSkew(1))
+
+	assert.Len(t, results, 6)
+	assert.Equal(t, VerifyResult{Ok: true, Step: now.Unix() / 30}, results[0])
+	assert.Equal(t, VerifyResult{}, results[1])
+	assert.Equal(t, VerifyResult{Ok: true, Step: now.Unix() / 30}, results[2])
+	assert.True(t, results[3].Ok)
+	assert.False(t, results[4].Ok)
+	assert.ErrorIs(t, results[4].Err, ErrSecretDecode)
+	assert.True(t, results[5].Ok)
+
+	assert.Len(t, VerifyBatch(nil), 0)
+}
+
+func TestVerifyBatch_Large(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+	requests := make([]VerifyRequest, 1000)
+	for i := range requests {
+		secret := Base32Encode(RandomSecret(20))
+		requests[i] = VerifyRequest{Secret: secret, Token: NewTOTP(secret).At(now), Time: now}
+		if i%2 == 1 {
+			requests[i].Token = "x"
+		}
+	}
+	for i, result := range VerifyBatch(requests) {
+		assert.Equal(t, i%2 == 0, result.Ok)
+	}
+}
@@ -0,0 +1,76 @@
+package otp
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// BattleNetKey Battle.net Authenticator 的账户信息，由序列号和秘钥组成。
+//
+// Battle.net 使用标准的 TOTP 算法：HMAC-SHA1，8 位数字，30 秒有效期，秘钥为 20 字节。
+// 序列号的格式为地区代码加 12 位数字，例如：US-1234-5678-9012。
+type BattleNetKey struct {
+	// 规范化后的序列号，格式：US-1234-5678-9012
+	Serial string
+	// base32 encoded string
+	Secret string
+}
+
+// NewBattleNetTOTP 创建一个与 Battle.net Authenticator 兼容的 TOTP 结构体（8 位数字）。
+//
+// 传入的 options 会覆盖默认参数，Panic 的情况与 NewTOTP 一致。
+func NewBattleNetTOTP(secret string, options ...TOTPOption) *TOTP {
+	return NewTOTP(secret, append([]TOTPOption{WithDigits(DigitsEight)}, options...)...)
+}
+
+// NewBattleNetKey 通过序列号和十六进制编码的秘钥创建 BattleNetKey，这是 Battle.net 相关工具导出账户时常用的格式。
+//
+// Example:
+//
+//	key, err := NewBattleNetKey("US-1209-1071-1868", "88aaface48291e09dc1ece9c2aa44d839983a7ff")
+//	token := key.TOTP().Now()
+func NewBattleNetKey(serial, hexSecret string) (*BattleNetKey, error) {
+	normalized, err := NormalizeBattleNetSerial(serial)
+	if err != nil {
+		return nil, err
+	}
+	if hexSecret == "" {
+		return nil, ErrSecretCannotBeEmpty
+	}
+	secret, err := hex.DecodeString(hexSecret)
+	if err != nil {
+		return nil, ErrSecretDecode
+	}
+	return &BattleNetKey{Serial: normalized, Secret: Base32Encode(secret)}, nil
+}
+
+// TOTP 返回该账户对应的 TOTP 结构体。
+func (k *BattleNetKey) TOTP(options ...TOTPOption) *TOTP {
+	return NewBattleNetTOTP(k.Secret, options...)
+}
+
+// KeyURI 返回一个 KeyURI 结构体，issuer 为 Battle.net，账户名称为去掉连字符的序列号。
+//
+// 生成的二维码可以导入到支持 8 位数字的验证器应用中。
+func (k *BattleNetKey) KeyURI() *KeyURI {
+	return k.TOTP().KeyURI(strings.ReplaceAll(k.Serial, "-", ""), "Battle.net")
+}
+
+// NormalizeBattleNetSerial 校验并规范化 Battle.net 序列号，返回 US-1234-5678-9012 格式。
+//
+// 接受大小写以及是否包含连字符的各种写法，格式不正确时返回 ErrBattleNetSerial。
+func NormalizeBattleNetSerial(serial string) (string, error) {
+	s := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(serial), "-", ""))
+	if len(s) != 14 {
+		return "", ErrBattleNetSerial
+	}
+	for i, c := range s {
+		if i < 2 && (c < 'A' || c > 'Z') {
+			return "", ErrBattleNetSerial
+		}
+		if i >= 2 && (c < '0' || c > '9') {
+			return "", ErrBattleNetSerial
+		}
+	}
+	return s[:2] + "-" + s[2:6] + "-" + s[6:10] + "-" + s[10:], nil
+}
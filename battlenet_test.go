@@ -0,0 +1,47 @@
+package otp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewBattleNetTOTP(t *testing.T) {
+	totp := NewBattleNetTOTP(TestSecret20)
+	assert.Equal(t, DigitsEight, totp.Digits)
+	assert.Equal(t, 30, totp.Period)
+	assert.Equal(t, AlgorithmSHA1, totp.Algorithm)
+
+	now := time.Unix(1704075000000, 0)
+	token := totp.At(now)
+	assert.Equal(t, 8, len(token))
+	// 8 位的 token 与 6 位 token 的末尾一致
+	assert.Equal(t, "076141", token[2:])
+}
+
+func TestNewBattleNetKey(t *testing.T) {
+	key, err := NewBattleNetKey("us120910711868", "88aaface48291e09dc1ece9c2aa44d839983a7ff")
+	assert.Nil(t, err)
+	assert.Equal(t, "US-1209-1071-1868", key.Serial)
+	assert.Equal(t, "RCVPVTSIFEPATXA6Z2OCVJCNQOMYHJ77", key.Secret)
+	assert.Equal(t, "otpauth://totp/Battle.net:US120910711868?secret=RCVPVTSIFEPATXA6Z2OCVJCNQOMYHJ77&issuer=Battle.net&digits=8", key.KeyURI().URI().String())
+
+	_, err = NewBattleNetKey("US-1209-1071", "88aaface48291e09dc1ece9c2aa44d839983a7ff")
+	assert.Equal(t, ErrBattleNetSerial, err)
+	_, err = NewBattleNetKey("US-1209-1071-1868", "")
+	assert.Equal(t, ErrSecretCannotBeEmpty, err)
+	_, err = NewBattleNetKey("US-1209-1071-1868", "xyz")
+	assert.Equal(t, ErrSecretDecode, err)
+}
+
+func TestNormalizeBattleNetSerial(t *testing.T) {
+	for _, serial := range []string{"US-1209-1071-1868", "us-1209-1071-1868", "US120910711868", " US120910711868 "} {
+		normalized, err := NormalizeBattleNetSerial(serial)
+		assert.Nil(t, err)
+		assert.Equal(t, "US-1209-1071-1868", normalized)
+	}
+	for _, serial := range []string{"", "US-1209-1071-186", "1S-1209-1071-1868", "US-1209-1071-186X", "USA-1209-1071-1868"} {
+		_, err := NormalizeBattleNetSerial(serial)
+		assert.Equal(t, ErrBattleNetSerial, err)
+	}
+}
@@ -0,0 +1,160 @@
+package otp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GoogleCompatMode KeyURIBuilder 处理 Google Authenticator 会忽略的参数的方式。
+type GoogleCompatMode int
+
+const (
+	// GoogleCompatOff 不做任何处理，默认值。
+	GoogleCompatOff GoogleCompatMode = iota
+	// GoogleCompatReject Build 时返回 ErrGoogleIncompatible，错误信息中包含所有不兼容的参数。
+	GoogleCompatReject
+	// GoogleCompatStrip 将不兼容的参数恢复为默认值，服务端需要使用 Build 返回的 KeyURI 创建 TOTP、HOTP，否则 token 会不一致。
+	GoogleCompatStrip
+)
+
+// KeyURIBuilder 使用链式调用构造 KeyURI，参数在 Build 时统一校验。
+type KeyURIBuilder struct {
+	key     KeyURI
+	account string
+	issuer  string
+	compat  GoogleCompatMode
+}
+
+// NewKeyURI 创建一个 KeyURIBuilder，typ 为 totp 或 hotp，secret 为 base32 编码的秘钥。
+//
+// 其他参数的默认值与 NewTOTP、NewHOTP 一致：SHA1、6 位数字、30 秒有效期、计数器为 1。
+//
+// Example:
+//
+//	key, err := NewKeyURI("totp", secret).
+//		Account("alice@google.com").
+//		Issuer("Example").
+//		StrictGoogleAuthenticator().
+//		Build()
+func NewKeyURI(typ, secret string) *KeyURIBuilder {
+	return &KeyURIBuilder{key: KeyURI{
+		Type:      strings.ToLower(typ),
+		Algorithm: AlgorithmSHA1.String(),
+		Digits:    int(DigitsSix),
+		Period:    30,
+		Counter:   1,
+		Secret:    secret,
+	}}
+}
+
+// Account 设置账户名称，不需要编码。
+func (b *KeyURIBuilder) Account(account string) *KeyURIBuilder {
+	b.account = account
+	return b
+}
+
+// Issuer 设置发行商，不需要编码，同时会作为 label 的前缀。
+func (b *KeyURIBuilder) Issuer(issuer string) *KeyURIBuilder {
+	b.issuer = issuer
+	return b
+}
+
+// Algorithm 设置哈希算法。
+func (b *KeyURIBuilder) Algorithm(algorithm Algorithms) *KeyURIBuilder {
+	b.key.Algorithm = algorithm.String()
+	return b
+}
+
+// Digits 设置 token 的长度。
+func (b *KeyURIBuilder) Digits(digits Digits) *KeyURIBuilder {
+	b.key.Digits = int(digits)
+	return b
+}
+
+// Period 设置 TOTP 的有效期（秒），HOTP 会忽略此参数。
+func (b *KeyURIBuilder) Period(period int) *KeyURIBuilder {
+	b.key.Period = period
+	return b
+}
+
+// Counter 设置 HOTP 的初始计数器，TOTP 会忽略此参数。
+func (b *KeyURIBuilder) Counter(counter int64) *KeyURIBuilder {
+	b.key.Counter = counter
+	return b
+}
+
+// Encoder 设置 token 的编码方式。
+func (b *KeyURIBuilder) Encoder(encoder Encoder) *KeyURIBuilder {
+	b.key.Encoder = encoder.String()
+	return b
+}
+
+// StrictGoogleAuthenticator 开启 Google Authenticator 兼容检查，Build 时如果存在 Google Authenticator 会忽略的参数
+// （非 SHA1 算法、digits 不为 6、period 不为 30、非标准的 encoder、epoch）将返回 ErrGoogleIncompatible。
+//
+// 这些参数会被 Google Authenticator 静默忽略，生成的 token 与服务端不一致，用户只能看到"验证码错误"。
+func (b *KeyURIBuilder) StrictGoogleAuthenticator() *KeyURIBuilder {
+	b.compat = GoogleCompatReject
+	return b
+}
+
+// GoogleCompat 设置处理 Google Authenticator 不兼容参数的方式，参考 GoogleCompatMode。
+func (b *KeyURIBuilder) GoogleCompat(mode GoogleCompatMode) *KeyURIBuilder {
+	b.compat = mode
+	return b
+}
+
+// Build 校验参数并返回 KeyURI，校验规则与 Validate、ValidateStrict 一致。
+func (b *KeyURIBuilder) Build() (*KeyURI, error) {
+	key := b.key
+	key.Label = formatLabel(b.issuer, b.account)
+	key.AccountName = b.account
+	key.Issuer = b.issuer
+	if key.Type == "hotp" {
+		key.Period = 0
+	} else {
+		key.Counter = 0
+	}
+	if issues := googleIncompatible(key); len(issues) > 0 {
+		switch b.compat {
+		case GoogleCompatReject:
+			return nil, fmt.Errorf("%w: %s", ErrGoogleIncompatible, strings.Join(issues, ", "))
+		case GoogleCompatStrip:
+			key.Algorithm = AlgorithmSHA1.String()
+			key.Digits = int(DigitsSix)
+			key.Encoder = ""
+			key.Epoch = 0
+			if key.Type == "totp" {
+				key.Period = 30
+			}
+		}
+	}
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+	if err := key.ValidateStrict(); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// googleIncompatible 返回 Google Authenticator 会忽略的参数。
+func googleIncompatible(key KeyURI) []string {
+	var issues []string
+	if key.Algorithm != AlgorithmSHA1.String() {
+		issues = append(issues, "algorithm="+key.Algorithm)
+	}
+	if key.Digits != int(DigitsSix) {
+		issues = append(issues, fmt.Sprintf("digits=%d", key.Digits))
+	}
+	if key.Type == "totp" && key.Period != 30 {
+		issues = append(issues, fmt.Sprintf("period=%d", key.Period))
+	}
+	if key.Encoder != "" {
+		issues = append(issues, "encoder="+key.Encoder)
+	}
+	if key.Epoch != 0 {
+		issues = append(issues, fmt.Sprintf("epoch=%d", key.Epoch))
+	}
+	return issues
+}
@@ -0,0 +1,46 @@
+package otp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewKeyURI(t *testing.T) {
+	key, err := NewKeyURI("totp", TestSecret20).Account("alice@google.com").Issuer("Example Co").Build()
+	assert.Nil(t, err)
+	assert.Equal(t, NewTOTP(TestSecret20).KeyURI("alice@google.com", "Example Co"), key)
+
+	key, err = NewKeyURI("HOTP", TestSecret20).Account("alice").Issuer("Example").
+		Algorithm(AlgorithmSHA256).Digits(DigitsEight).Counter(5).Period(60).Build()
+	assert.Nil(t, err)
+	assert.Equal(t, "otpauth://hotp/Example:alice?secret="+TestSecret20+"&issuer=Example&algorithm=SHA256&digits=8&counter=5", key.URI().String())
+
+	_, err = NewKeyURI("totp", TestSecret20).Build()
+	assert.ErrorIs(t, err, ErrURIFormat)
+	_, err = NewKeyURI("totp", "").Account("alice").Build()
+	assert.ErrorIs(t, err, ErrMissingSecret)
+	_, err = NewKeyURI("totp", TestSecret20).Account(" alice").Build()
+	assert.ErrorIs(t, err, ErrLabelWhitespace)
+}
+
+func TestKeyURIBuilder_StrictGoogleAuthenticator(t *testing.T) {
+	_, err := NewKeyURI("totp", TestSecret20).Account("alice").Issuer("Example").
+		Algorithm(AlgorithmSHA256).Digits(DigitsEight).Period(60).StrictGoogleAuthenticator().Build()
+	assert.ErrorIs(t, err, ErrGoogleIncompatible)
+	assert.ErrorIs(t, err, ErrURIFormat)
+	assert.Contains(t, err.Error(), "algorithm=SHA256, digits=8, period=60")
+
+	_, err = NewKeyURI("hotp", TestSecret20).Account("alice").Period(60).StrictGoogleAuthenticator().Build()
+	assert.Nil(t, err)
+	_, err = NewKeyURI("totp", TestSecret20).Account("alice").Encoder(EncoderSteam).StrictGoogleAuthenticator().Build()
+	assert.ErrorIs(t, err, ErrGoogleIncompatible)
+
+	key, err := NewKeyURI("totp", TestSecret20).Account("alice").Issuer("Example").
+		Algorithm(AlgorithmSHA512).Digits(DigitsEight).Period(60).GoogleCompat(GoogleCompatStrip).Build()
+	assert.Nil(t, err)
+	assert.Equal(t, NewTOTP(TestSecret20).KeyURI("alice", "Example"), key)
+
+	key, err = NewKeyURI("totp", TestSecret20).Account("alice").Digits(DigitsEight).Build()
+	assert.Nil(t, err)
+	assert.Equal(t, 8, key.Digits)
+}
@@ -0,0 +1,75 @@
+package otp
+
+import (
+	"strings"
+)
+
+// FormatSecretWithChecksum 将 base32 秘钥转换为便于手动输入的格式：每 4 个字符一组，末尾追加一个 Luhn mod 32 校验字符。
+//
+// 校验字符可以发现所有的单字符输入错误以及绝大多数相邻字符的位置颠倒，配合 ParseSecretWithChecksum 使用，
+// 在用户完成绑定之前就能提示输入有误。
+//
+// Example:
+//
+//	FormatSecretWithChecksum("J3W2XPZP5HDYXYRB") // "J3W2 XPZP 5HDY XYRB 4"
+func FormatSecretWithChecksum(secret string) (string, error) {
+	if secret == "" {
+		return "", ErrSecretCannotBeEmpty
+	}
+	upper := strings.ToUpper(secret)
+	if _, err := Base32Decode(upper); err != nil {
+		return "", err
+	}
+	var groups []string
+	for i := 0; i < len(upper); i += 4 {
+		end := i + 4
+		if end > len(upper) {
+			end = len(upper)
+		}
+		groups = append(groups, upper[i:end])
+	}
+	groups = append(groups, string(base32Alphabet[luhnMod32Check(upper)]))
+	return strings.Join(groups, " "), nil
+}
+
+// ParseSecretWithChecksum 解析 FormatSecretWithChecksum 生成的字符串，返回不带校验字符的 base32 秘钥。
+//
+// 忽略大小写、空格和连字符。包含非法字符时返回 *SecretDecodeError（位置为去掉空格和连字符之后的位置），
+// 校验失败时返回 ErrSecretChecksum。
+func ParseSecretWithChecksum(str string) (string, error) {
+	normalized := strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(str))
+	if len(normalized) < 2 {
+		return "", ErrSecretCannotBeEmpty
+	}
+	if _, err := Base32Decode(normalized); err != nil {
+		return "", err
+	}
+	if !luhnMod32Valid(normalized) {
+		return "", ErrSecretChecksum
+	}
+	return normalized[:len(normalized)-1], nil
+}
+
+// luhnMod32Check 计算 Luhn mod 32 校验字符在字母表中的下标，str 只能包含大写的 base32 字符。
+func luhnMod32Check(str string) int {
+	n := len(base32Alphabet)
+	factor, sum := 2, 0
+	for i := len(str) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(base32Alphabet, str[i])
+		factor = 3 - factor
+		sum += addend/n + addend%n
+	}
+	return (n - sum%n) % n
+}
+
+// luhnMod32Valid 校验末尾带有校验字符的字符串。
+func luhnMod32Valid(str string) bool {
+	n := len(base32Alphabet)
+	factor, sum := 1, 0
+	for i := len(str) - 1; i >= 0; i-- {
+		addend := factor * strings.IndexByte(base32Alphabet, str[i])
+		factor = 3 - factor
+		sum += addend/n + addend%n
+	}
+	return sum%n == 0
+}
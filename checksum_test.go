@@ -0,0 +1,80 @@
+package otp
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestFormatSecretWithChecksum(t *testing.T) {
+	formatted, err := FormatSecretWithChecksum(TestSecret20)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(formatted, "J3W2 XPZP 5HDY XYRB 4HS6 ZLU6 M6VB O6C6 "))
+	assert.Equal(t, 41, len(formatted))
+
+	lower, err := FormatSecretWithChecksum(strings.ToLower(TestSecret20))
+	assert.Nil(t, err)
+	assert.Equal(t, formatted, lower)
+
+	_, err = FormatSecretWithChecksum("")
+	assert.Equal(t, ErrSecretCannotBeEmpty, err)
+	_, err = FormatSecretWithChecksum("111111")
+	assert.True(t, errors.Is(err, ErrSecretDecode))
+}
+
+func TestParseSecretWithChecksum(t *testing.T) {
+	for _, secret := range []string{TestSecret20, TestSecret32, TestSecret64, "JBSWY3DPEHPK3PXP"} {
+		formatted, err := FormatSecretWithChecksum(secret)
+		assert.Nil(t, err)
+		parsed, err := ParseSecretWithChecksum(formatted)
+		assert.Nil(t, err)
+		assert.Equal(t, secret, parsed)
+
+		// 忽略大小写和分隔符
+		parsed, err = ParseSecretWithChecksum(strings.ReplaceAll(strings.ToLower(formatted), " ", "-"))
+		assert.Nil(t, err)
+		assert.Equal(t, secret, parsed)
+	}
+
+	formatted, _ := FormatSecretWithChecksum(TestSecret20)
+	compact := strings.ReplaceAll(formatted, " ", "")
+
+	t.Run("detect every single character typo", func(t *testing.T) {
+		for i := 0; i < len(compact); i++ {
+			for _, c := range base32Alphabet {
+				if byte(c) == compact[i] {
+					continue
+				}
+				typo := compact[:i] + string(c) + compact[i+1:]
+				_, err := ParseSecretWithChecksum(typo)
+				assert.Equal(t, ErrSecretChecksum, err, typo)
+			}
+		}
+	})
+
+	t.Run("detect adjacent transpositions", func(t *testing.T) {
+		detected, total := 0, 0
+		for i := 0; i+1 < len(compact); i++ {
+			if compact[i] == compact[i+1] {
+				continue
+			}
+			total++
+			swapped := compact[:i] + string(compact[i+1]) + string(compact[i]) + compact[i+2:]
+			if _, err := ParseSecretWithChecksum(swapped); err == ErrSecretChecksum {
+				detected++
+			}
+		}
+		assert.Equal(t, total, detected)
+	})
+
+	t.Run("illegal characters", func(t *testing.T) {
+		_, err := ParseSecretWithChecksum("J3W2 XPZ0 A")
+		var decodeErr *SecretDecodeError
+		assert.True(t, errors.As(err, &decodeErr))
+		assert.Equal(t, 7, decodeErr.Position)
+
+		_, err = ParseSecretWithChecksum("A")
+		assert.Equal(t, ErrSecretCannotBeEmpty, err)
+	})
+}
@@ -0,0 +1,313 @@
+// Command otp 基于 github.com/huk10/go-otp 的命令行工具，用于生成秘钥、计算和校验一次性密码以及处理 otpauth URI。
+//
+// Usage:
+//
+//	otp secret [-size 20]
+//	otp code   [flags] <secret|uri>          计算当前的 token，-watch 持续输出并显示剩余时间
+//	otp verify [flags] <secret|uri> <token>  校验 token，有效时退出码为 0，无效时为 1
+//	otp uri    [flags] <secret>              生成 otpauth URI
+//	otp parse  <uri>                         解析 otpauth URI
+//	otp qr     [flags] <secret|uri>          在终端中打印二维码
+//
+// secret 为 base32 编码的秘钥，以 otpauth:// 开头时按 URI 解析，此时忽略 -digits 等参数。
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/huk10/go-otp"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const usage = `usage: otp <command> [flags] [args]
+
+commands:
+  secret   generate a random base32 secret
+  code     print the current token
+  verify   verify a token (exit status 1 when invalid)
+  uri      build an otpauth uri
+  parse    parse an otpauth uri
+  qr       render an otpauth uri as a terminal qr code
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run 执行命令并返回退出码，0 表示成功，1 表示校验失败，2 表示参数错误。
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	var cmd func(args []string, stdout io.Writer) error
+	switch args[0] {
+	case "secret":
+		cmd = secretCommand
+	case "code":
+		cmd = codeCommand
+	case "verify":
+		cmd = verifyCommand
+	case "uri":
+		cmd = uriCommand
+	case "parse":
+		cmd = parseCommand
+	case "qr":
+		cmd = qrCommand
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "otp: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+	err := cmd(args[1:], stdout)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errInvalidToken):
+		fmt.Fprintln(stderr, "otp:", err)
+		return 1
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	default:
+		fmt.Fprintln(stderr, "otp:", err)
+		return 2
+	}
+}
+
+var errInvalidToken = errors.New("invalid token")
+
+// keyFlags 计算 token 相关的公共参数。
+type keyFlags struct {
+	typ       string
+	digits    int
+	period    int
+	algorithm string
+	counter   int64
+	account   string
+	issuer    string
+}
+
+func newFlagSet(name string, stdout io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	return fs
+}
+
+func (k *keyFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&k.typ, "type", "totp", "otp type: totp or hotp")
+	fs.IntVar(&k.digits, "digits", 6, "token length")
+	fs.IntVar(&k.period, "period", 30, "totp period in seconds")
+	fs.StringVar(&k.algorithm, "algorithm", "SHA1", "hmac algorithm")
+	fs.Int64Var(&k.counter, "counter", 0, "hotp counter")
+	fs.StringVar(&k.account, "account", "", "account name used in the uri label")
+	fs.StringVar(&k.issuer, "issuer", "", "issuer used in the uri")
+}
+
+// key 将 secret 或 otpauth URI 转换为 KeyURI。
+func (k *keyFlags) key(arg string) (*otp.KeyURI, error) {
+	if strings.HasPrefix(arg, "otpauth://") {
+		return otp.FromURI(arg)
+	}
+	var algorithm otp.Algorithms
+	if err := algorithm.UnmarshalText([]byte(k.algorithm)); err != nil {
+		return nil, err
+	}
+	var digits otp.Digits
+	if err := digits.UnmarshalText([]byte(fmt.Sprint(k.digits))); err != nil {
+		return nil, err
+	}
+	options := []otp.Option{
+		otp.WithDigits(digits),
+		otp.WithAlgorithm(algorithm),
+	}
+	switch k.typ {
+	case "totp":
+		totp, err := otp.NewTOTPWithError(arg, append(otp.AsTOTPOptions(options...), otp.WithPeriod(k.period))...)
+		if err != nil {
+			return nil, err
+		}
+		return totp.KeyURI(k.account, k.issuer), nil
+	case "hotp":
+		hotp, err := otp.NewHOTPWithError(arg, append(otp.AsHOTPOptions(options...), otp.WithCounter(k.counter))...)
+		if err != nil {
+			return nil, err
+		}
+		return hotp.KeyURI(k.account, k.issuer), nil
+	default:
+		return nil, fmt.Errorf("unknown type %q", k.typ)
+	}
+}
+
+func secretCommand(args []string, stdout io.Writer) error {
+	fs := newFlagSet("secret", stdout)
+	size := fs.Int("size", 20, "secret size in bytes")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *size <= 0 {
+		return errors.New("size must be positive")
+	}
+	fmt.Fprintln(stdout, otp.Base32Encode(otp.RandomSecret(*size)))
+	return nil
+}
+
+func codeCommand(args []string, stdout io.Writer) error {
+	fs := newFlagSet("code", stdout)
+	var k keyFlags
+	k.register(fs)
+	at := fs.Int64("time", 0, "unix time used instead of the current time")
+	watch := fs.Bool("watch", false, "keep printing tokens with the remaining seconds")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("code requires exactly one secret or uri")
+	}
+	key, err := k.key(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if key.Type == "hotp" {
+		hotp, err := key.HOTP()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout, hotp.At(key.Counter))
+		return nil
+	}
+	totp, err := key.TOTP()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if *at != 0 {
+		now = time.Unix(*at, 0)
+	}
+	if !*watch {
+		fmt.Fprintln(stdout, totp.At(now))
+		return nil
+	}
+	for {
+		now := time.Now()
+		token, validUntil := totp.WithExpiration(now)
+		fmt.Fprintf(stdout, "\r%s %2ds", token, int(validUntil.Sub(now).Round(time.Second)/time.Second))
+		time.Sleep(time.Second)
+	}
+}
+
+func verifyCommand(args []string, stdout io.Writer) error {
+	fs := newFlagSet("verify", stdout)
+	var k keyFlags
+	k.register(fs)
+	skew := fs.Int("skew", 1, "number of adjacent windows (or counters) to accept")
+	at := fs.Int64("time", 0, "unix time used instead of the current time")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return errors.New("verify requires a secret or uri and a token")
+	}
+	key, err := k.key(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	token := fs.Arg(1)
+	var ok bool
+	if key.Type == "hotp" {
+		hotp, err := key.HOTP(otp.WithSkew(*skew))
+		if err != nil {
+			return err
+		}
+		ok = hotp.Verify(token, key.Counter)
+	} else {
+		totp, err := key.TOTP(otp.WithSkew(*skew))
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if *at != 0 {
+			now = time.Unix(*at, 0)
+		}
+		ok = totp.Verify(token, now)
+	}
+	if !ok {
+		return errInvalidToken
+	}
+	fmt.Fprintln(stdout, "valid")
+	return nil
+}
+
+func uriCommand(args []string, stdout io.Writer) error {
+	fs := newFlagSet("uri", stdout)
+	var k keyFlags
+	k.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("uri requires exactly one secret")
+	}
+	key, err := k.key(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, key.URI().String())
+	return nil
+}
+
+func parseCommand(args []string, stdout io.Writer) error {
+	fs := newFlagSet("parse", stdout)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("parse requires exactly one uri")
+	}
+	key, err := otp.FromURI(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "type:      %s\n", key.Type)
+	fmt.Fprintf(stdout, "label:     %s\n", key.Label)
+	fmt.Fprintf(stdout, "issuer:    %s\n", key.Issuer)
+	fmt.Fprintf(stdout, "secret:    %s\n", key.Secret)
+	fmt.Fprintf(stdout, "algorithm: %s\n", key.Algorithm)
+	fmt.Fprintf(stdout, "digits:    %d\n", key.Digits)
+	if key.Type == "hotp" {
+		fmt.Fprintf(stdout, "counter:   %d\n", key.Counter)
+	} else {
+		fmt.Fprintf(stdout, "period:    %d\n", key.Period)
+	}
+	if key.Encoder != "" {
+		fmt.Fprintf(stdout, "encoder:   %s\n", key.Encoder)
+	}
+	return nil
+}
+
+func qrCommand(args []string, stdout io.Writer) error {
+	fs := newFlagSet("qr", stdout)
+	var k keyFlags
+	k.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("qr requires exactly one secret or uri")
+	}
+	key, err := k.key(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	qr := key.QRCodeTerminal()
+	if qr == "" {
+		return errors.New("uri is too long for a qr code")
+	}
+	fmt.Fprint(stdout, qr)
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build !noqrcode
+
+package main
+
+import (
+	"github.com/huk10/go-otp"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRun_QR(t *testing.T) {
+	key := otp.NewTOTP(testSecret).KeyURI("alice@google.com", "Example")
+	code, stdout, _ := runCommand("qr", key.URI().String())
+	assert.Equal(t, 0, code)
+	assert.Equal(t, key.QRCodeTerminal(), stdout)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"github.com/huk10/go-otp"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+const testSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func runCommand(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun_Usage(t *testing.T) {
+	code, _, stderr := runCommand()
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "usage: otp")
+
+	code, _, stderr = runCommand("unknown")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `unknown command "unknown"`)
+
+	code, stdout, _ := runCommand("help")
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "commands:")
+}
+
+func TestRun_Secret(t *testing.T) {
+	code, stdout, _ := runCommand("secret", "-size", "32")
+	assert.Equal(t, 0, code)
+	decoded, err := otp.Base32Decode(strings.TrimSpace(stdout))
+	assert.Nil(t, err)
+	assert.Len(t, decoded, 32)
+
+	code, _, _ = runCommand("secret", "-size", "0")
+	assert.Equal(t, 2, code)
+}
+
+func TestRun_Code(t *testing.T) {
+	// RFC-6238 附录 B 的测试向量
+	code, stdout, _ := runCommand("code", "-digits", "8", "-time", "59", testSecret)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "94287082\n", stdout)
+
+	code, stdout, _ = runCommand("code", "-time", "59", "otpauth://totp/Example:alice?secret="+testSecret+"&digits=8")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "94287082\n", stdout)
+
+	// RFC-4226 附录 D 的测试向量
+	code, stdout, _ = runCommand("code", "-type", "hotp", "-counter", "1", testSecret)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "287082\n", stdout)
+
+	code, _, stderr := runCommand("code", "-algorithm", "MD5", testSecret)
+	assert.Equal(t, 2, code)
+	assert.NotEmpty(t, stderr)
+}
+
+func TestRun_Verify(t *testing.T) {
+	code, stdout, _ := runCommand("verify", "-digits", "8", "-time", "59", testSecret, "94287082")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "valid\n", stdout)
+
+	code, _, stderr := runCommand("verify", "-digits", "8", "-time", "59", "-skew", "0", testSecret, "00000000")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "invalid token")
+
+	code, _, _ = runCommand("verify", testSecret)
+	assert.Equal(t, 2, code)
+}
+
+func TestRun_URI(t *testing.T) {
+	code, stdout, _ := runCommand("uri", "-account", "alice@google.com", "-issuer", "Example", testSecret)
+	assert.Equal(t, 0, code)
+	uri := strings.TrimSpace(stdout)
+	key, err := otp.FromURI(uri)
+	assert.Nil(t, err)
+	assert.Equal(t, testSecret, key.Secret)
+
+	code, stdout, _ = runCommand("parse", uri)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout, "type:      totp\n")
+	assert.Contains(t, stdout, "issuer:    Example\n")
+	assert.Contains(t, stdout, "period:    30\n")
+
+	code, _, _ = runCommand("parse", "https://example.com")
+	assert.Equal(t, 2, code)
+}
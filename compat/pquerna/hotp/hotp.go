@@ -0,0 +1,122 @@
+// Package hotp 提供与 github.com/pquerna/otp/hotp 相同的 API，内部基于 github.com/huk10/go-otp 实现。
+package hotp
+
+import (
+	"crypto/rand"
+	"github.com/huk10/go-otp"
+	"github.com/huk10/go-otp/compat/pquerna/internal"
+	pquerna "github.com/huk10/go-otp/compat/pquerna/otp"
+	"io"
+	"strings"
+)
+
+// ValidateOpts 生成和校验一次性密码时使用的参数。
+type ValidateOpts struct {
+	// 一次性密码的长度。
+	Digits pquerna.Digits
+	// 哈希算法。
+	Algorithm pquerna.Algorithm
+}
+
+// GenerateOpts 生成新秘钥时使用的参数。
+type GenerateOpts struct {
+	// 发行商，必填。
+	Issuer string
+	// 账户名称，必填。
+	AccountName string
+	// 随机秘钥的字节数，默认 10。
+	SecretSize uint
+	// 指定秘钥，不为空时不再随机生成。
+	Secret []byte
+	// 一次性密码的长度，默认 6。
+	Digits pquerna.Digits
+	// 哈希算法，默认 SHA1。
+	Algorithm pquerna.Algorithm
+	// 随机数来源，默认 crypto/rand。
+	Rand io.Reader
+}
+
+// Validate 使用默认参数（6 位、SHA1）校验指定计数器的一次性密码。
+func Validate(passcode string, counter uint64, secret string) bool {
+	ok, _ := ValidateCustom(passcode, counter, secret, ValidateOpts{
+		Digits:    pquerna.DigitsSix,
+		Algorithm: pquerna.AlgorithmSHA1,
+	})
+	return ok
+}
+
+// GenerateCode 使用默认参数生成指定计数器的一次性密码。
+func GenerateCode(secret string, counter uint64) (string, error) {
+	return GenerateCodeCustom(secret, counter, ValidateOpts{
+		Digits:    pquerna.DigitsSix,
+		Algorithm: pquerna.AlgorithmSHA1,
+	})
+}
+
+// GenerateCodeCustom 使用自定义参数生成指定计数器的一次性密码。
+func GenerateCodeCustom(secret string, counter uint64, opts ValidateOpts) (string, error) {
+	if opts.Digits == 0 {
+		opts.Digits = pquerna.DigitsSix
+	}
+	hotp, err := newHOTP(secret, opts)
+	if err != nil {
+		return "", err
+	}
+	return hotp.At(int64(counter)), nil
+}
+
+// ValidateCustom 使用自定义参数校验指定计数器的一次性密码。
+func ValidateCustom(passcode string, counter uint64, secret string, opts ValidateOpts) (bool, error) {
+	passcode = strings.TrimSpace(passcode)
+	if len(passcode) != opts.Digits.Length() {
+		return false, pquerna.ErrValidateInputInvalidLength
+	}
+	hotp, err := newHOTP(secret, opts)
+	if err != nil {
+		return false, err
+	}
+	return hotp.Verify(passcode, int64(counter)), nil
+}
+
+// Generate 生成一个新的 HOTP 秘钥。
+func Generate(opts GenerateOpts) (*pquerna.Key, error) {
+	if opts.Issuer == "" {
+		return nil, pquerna.ErrGenerateMissingIssuer
+	}
+	if opts.AccountName == "" {
+		return nil, pquerna.ErrGenerateMissingAccountName
+	}
+	if opts.SecretSize == 0 {
+		opts.SecretSize = 10
+	}
+	if opts.Digits == 0 {
+		opts.Digits = pquerna.DigitsSix
+	}
+	if opts.Rand == nil {
+		opts.Rand = rand.Reader
+	}
+	secret, err := internal.RandomSecret(opts.Rand, opts.SecretSize, opts.Secret)
+	if err != nil {
+		return nil, err
+	}
+	hotp, err := newHOTP(secret, ValidateOpts{Digits: opts.Digits, Algorithm: opts.Algorithm})
+	if err != nil {
+		return nil, err
+	}
+	return pquerna.NewKeyFromURL(hotp.KeyURI(opts.AccountName, opts.Issuer).URI().String())
+}
+
+func newHOTP(secret string, opts ValidateOpts) (*otp.HOTP, error) {
+	secret, err := internal.Secret(secret)
+	if err != nil {
+		return nil, err
+	}
+	algorithm, err := internal.Algorithm(opts.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return otp.NewHOTP(secret,
+		otp.WithDigits(otp.Digits(opts.Digits)),
+		otp.WithAlgorithm(algorithm),
+	), nil
+}
@@ -0,0 +1,37 @@
+package hotp
+
+import (
+	pquerna "github.com/huk10/go-otp/compat/pquerna/otp"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// base32 encoded "12345678901234567890", RFC 4226 Appendix D
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateCode(t *testing.T) {
+	expected := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, code := range expected {
+		actual, err := GenerateCode(rfcSecret, uint64(counter))
+		assert.Nil(t, err)
+		assert.Equal(t, code, actual)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert.Equal(t, true, Validate("755224", 0, rfcSecret))
+	assert.Equal(t, false, Validate("755224", 1, rfcSecret))
+	assert.Equal(t, false, Validate("755224", 0, "1"))
+
+	_, err := ValidateCustom("75522", 0, rfcSecret, ValidateOpts{Digits: pquerna.DigitsSix})
+	assert.Equal(t, pquerna.ErrValidateInputInvalidLength, err)
+}
+
+func TestGenerate(t *testing.T) {
+	key, err := Generate(GenerateOpts{Issuer: "Example", AccountName: "alice@google.com", Secret: []byte("12345678901234567890")})
+	assert.Nil(t, err)
+	assert.Equal(t, "hotp", key.Type())
+	assert.Equal(t, "Example", key.Issuer())
+	assert.Equal(t, "alice@google.com", key.AccountName())
+	assert.Equal(t, rfcSecret, key.Secret())
+}
@@ -0,0 +1,47 @@
+// Package internal 包含 pquerna 兼容层内部共用的转换方法。
+package internal
+
+import (
+	"github.com/huk10/go-otp"
+	pquerna "github.com/huk10/go-otp/compat/pquerna/otp"
+	"io"
+	"strings"
+)
+
+// Algorithm 将 pquerna 的算法枚举转换为本库的算法枚举。
+func Algorithm(a pquerna.Algorithm) (otp.Algorithms, error) {
+	switch a {
+	case pquerna.AlgorithmSHA1:
+		return otp.AlgorithmSHA1, nil
+	case pquerna.AlgorithmSHA256:
+		return otp.AlgorithmSHA256, nil
+	case pquerna.AlgorithmSHA512:
+		return otp.AlgorithmSHA512, nil
+	default:
+		return 0, pquerna.ErrUnsupportedAlgorithm
+	}
+}
+
+// Secret 规范化秘钥（去除首尾空白以及 base32 填充），并校验其能否被解码。
+func Secret(secret string) (string, error) {
+	secret = strings.TrimRight(strings.ToUpper(strings.TrimSpace(secret)), "=")
+	if secret == "" {
+		return "", pquerna.ErrValidateSecretInvalidBase32
+	}
+	if _, err := otp.Base32Decode(secret); err != nil {
+		return "", pquerna.ErrValidateSecretInvalidBase32
+	}
+	return secret, nil
+}
+
+// RandomSecret 从 r 中读取 size 字节并进行 base32 编码，如果 secret 不为空则直接使用 secret。
+func RandomSecret(r io.Reader, size uint, secret []byte) (string, error) {
+	if len(secret) != 0 {
+		return otp.Base32Encode(secret), nil
+	}
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return otp.Base32Encode(buf), nil
+}
@@ -0,0 +1,204 @@
+// Package otp 提供与 github.com/pquerna/otp 相同的 API，内部基于 github.com/huk10/go-otp 实现。
+//
+// 迁移时只需要将 import 路径从 github.com/pquerna/otp 替换为 github.com/huk10/go-otp/compat/pquerna/otp，
+// totp 和 hotp 子包同理。
+//
+// 与原库的差异：
+//   - 不支持 AlgorithmMD5，使用时将会返回错误。
+//   - Key.Image 仅支持宽高相等的正方形二维码。
+package otp
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"github.com/skip2/go-qrcode"
+	"hash"
+	"image"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrValidateSecretInvalidBase32 秘钥无法进行 base32 解码。
+	ErrValidateSecretInvalidBase32 = errors.New("Decoding of secret as base32 failed.")
+	// ErrValidateInputInvalidLength 用户输入的一次性密码长度不符合预期。
+	ErrValidateInputInvalidLength = errors.New("Input length unexpected")
+	// ErrGenerateMissingIssuer 生成 Key 时必须设置 Issuer。
+	ErrGenerateMissingIssuer = errors.New("Issuer must be set")
+	// ErrGenerateMissingAccountName 生成 Key 时必须设置 AccountName。
+	ErrGenerateMissingAccountName = errors.New("AccountName must be set")
+	// ErrUnsupportedAlgorithm 底层库不支持的哈希算法（MD5）。
+	ErrUnsupportedAlgorithm = errors.New("algorithm is not supported")
+)
+
+// Key 表示一个 TOTP 或 HOTP 秘钥，由 otpauth URI 构成。
+type Key struct {
+	orig string
+	url  *url.URL
+}
+
+// NewKeyFromURL 通过 otpauth URI 创建一个 Key。
+//
+// See https://github.com/google/google-authenticator/wiki/Key-Uri-Format
+func NewKeyFromURL(orig string) (*Key, error) {
+	s := strings.TrimSpace(orig)
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{orig: s, url: u}, nil
+}
+
+// String 返回原始的 URI 字符串。
+func (k *Key) String() string {
+	return k.orig
+}
+
+// Image 返回一个指定尺寸的二维码图片，可供 Google Authenticator 扫码导入。
+//
+// 仅支持宽高相等的尺寸。
+func (k *Key) Image(width int, height int) (image.Image, error) {
+	if width != height {
+		return nil, errors.New("width and height must be equal")
+	}
+	code, err := qrcode.New(k.orig, qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+	return code.Image(width), nil
+}
+
+// Type 返回 "hotp" 或 "totp"。
+func (k *Key) Type() string {
+	return k.url.Host
+}
+
+// Issuer 返回发行商，优先使用 issuer 参数，其次使用 label 中的前缀。
+func (k *Key) Issuer() string {
+	issuer := k.url.Query().Get("issuer")
+	if issuer != "" {
+		return issuer
+	}
+	p := strings.TrimPrefix(k.url.Path, "/")
+	i := strings.Index(p, ":")
+	if i == -1 {
+		return ""
+	}
+	return p[:i]
+}
+
+// AccountName 返回账户名称。
+func (k *Key) AccountName() string {
+	p := strings.TrimPrefix(k.url.Path, "/")
+	i := strings.Index(p, ":")
+	if i == -1 {
+		return p
+	}
+	return p[i+1:]
+}
+
+// Secret 返回 base32 编码的秘钥。
+func (k *Key) Secret() string {
+	return k.url.Query().Get("secret")
+}
+
+// Period 返回时间窗口的长度（秒），默认 30。
+func (k *Key) Period() uint64 {
+	if u, err := strconv.ParseUint(k.url.Query().Get("period"), 10, 64); err == nil {
+		return u
+	}
+	return 30
+}
+
+// Digits 返回一次性密码的长度，默认 6。
+func (k *Key) Digits() Digits {
+	if u, err := strconv.ParseUint(k.url.Query().Get("digits"), 10, 64); err == nil && u > 0 {
+		return Digits(u)
+	}
+	return DigitsSix
+}
+
+// Algorithm 返回哈希算法，默认 SHA1。
+func (k *Key) Algorithm() Algorithm {
+	switch strings.ToLower(k.url.Query().Get("algorithm")) {
+	case "md5":
+		return AlgorithmMD5
+	case "sha256":
+		return AlgorithmSHA256
+	case "sha512":
+		return AlgorithmSHA512
+	default:
+		return AlgorithmSHA1
+	}
+}
+
+// URL 返回 URI 字符串。
+func (k *Key) URL() string {
+	return k.url.String()
+}
+
+// Algorithm HMAC 使用的哈希算法。
+type Algorithm int
+
+const (
+	AlgorithmSHA1 Algorithm = iota
+	AlgorithmSHA256
+	AlgorithmSHA512
+	AlgorithmMD5
+)
+
+func (a Algorithm) String() string {
+	switch a {
+	case AlgorithmSHA1:
+		return "SHA1"
+	case AlgorithmSHA256:
+		return "SHA256"
+	case AlgorithmSHA512:
+		return "SHA512"
+	case AlgorithmMD5:
+		return "MD5"
+	}
+	panic("unreachable")
+}
+
+// Hash 返回对应的哈希函数。
+func (a Algorithm) Hash() hash.Hash {
+	switch a {
+	case AlgorithmSHA1:
+		return sha1.New()
+	case AlgorithmSHA256:
+		return sha256.New()
+	case AlgorithmSHA512:
+		return sha512.New()
+	case AlgorithmMD5:
+		return md5.New()
+	}
+	panic("unreachable")
+}
+
+// Digits 一次性密码的长度。
+type Digits int
+
+const (
+	DigitsSix   Digits = 6
+	DigitsEight Digits = 8
+)
+
+// Format 将数字格式化为指定长度的字符串，不足位数前面补 0。
+func (d Digits) Format(in int32) string {
+	return fmt.Sprintf(fmt.Sprintf("%%0%dd", d), in)
+}
+
+// Length 返回一次性密码的字符数。
+func (d Digits) Length() int {
+	return int(d)
+}
+
+func (d Digits) String() string {
+	return fmt.Sprintf("%d", d)
+}
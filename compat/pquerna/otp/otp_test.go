@@ -0,0 +1,36 @@
+package otp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewKeyFromURL(t *testing.T) {
+	key, err := NewKeyFromURL("otpauth://totp/Example:alice@google.com?secret=JBSWY3DPEHPK3PXP&issuer=Example&algorithm=SHA256&digits=8&period=60")
+	assert.Nil(t, err)
+	assert.Equal(t, "totp", key.Type())
+	assert.Equal(t, "Example", key.Issuer())
+	assert.Equal(t, "alice@google.com", key.AccountName())
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", key.Secret())
+	assert.Equal(t, uint64(60), key.Period())
+	assert.Equal(t, DigitsEight, key.Digits())
+	assert.Equal(t, AlgorithmSHA256, key.Algorithm())
+
+	key2, err := NewKeyFromURL("otpauth://totp/alice@google.com?secret=JBSWY3DPEHPK3PXP")
+	assert.Nil(t, err)
+	assert.Equal(t, "", key2.Issuer())
+	assert.Equal(t, uint64(30), key2.Period())
+	assert.Equal(t, DigitsSix, key2.Digits())
+	assert.Equal(t, AlgorithmSHA1, key2.Algorithm())
+
+	img, err := key.Image(200, 200)
+	assert.Nil(t, err)
+	assert.Equal(t, 200, img.Bounds().Dx())
+	_, err = key.Image(200, 100)
+	assert.Error(t, err)
+}
+
+func TestDigits_Format(t *testing.T) {
+	assert.Equal(t, "000123", DigitsSix.Format(123))
+	assert.Equal(t, "00000123", DigitsEight.Format(123))
+}
@@ -0,0 +1,138 @@
+// Package totp 提供与 github.com/pquerna/otp/totp 相同的 API，内部基于 github.com/huk10/go-otp 实现。
+package totp
+
+import (
+	"crypto/rand"
+	"github.com/huk10/go-otp"
+	"github.com/huk10/go-otp/compat/pquerna/internal"
+	pquerna "github.com/huk10/go-otp/compat/pquerna/otp"
+	"io"
+	"strings"
+	"time"
+)
+
+// ValidateOpts 生成和校验一次性密码时使用的参数。
+type ValidateOpts struct {
+	// 时间窗口的长度（秒），默认 30。
+	Period uint
+	// 同时校验的相邻窗口数。
+	Skew uint
+	// 一次性密码的长度。
+	Digits pquerna.Digits
+	// 哈希算法。
+	Algorithm pquerna.Algorithm
+}
+
+// GenerateOpts 生成新秘钥时使用的参数。
+type GenerateOpts struct {
+	// 发行商，必填。
+	Issuer string
+	// 账户名称，必填。
+	AccountName string
+	// 时间窗口的长度（秒），默认 30。
+	Period uint
+	// 随机秘钥的字节数，默认 20。
+	SecretSize uint
+	// 指定秘钥，不为空时不再随机生成。
+	Secret []byte
+	// 一次性密码的长度，默认 6。
+	Digits pquerna.Digits
+	// 哈希算法，默认 SHA1。
+	Algorithm pquerna.Algorithm
+	// 随机数来源，默认 crypto/rand。
+	Rand io.Reader
+}
+
+// Validate 使用默认参数（30 秒、6 位、SHA1、skew 为 1）校验当前时间的一次性密码。
+func Validate(passcode string, secret string) bool {
+	ok, _ := ValidateCustom(passcode, secret, time.Now().UTC(), ValidateOpts{
+		Period:    30,
+		Skew:      1,
+		Digits:    pquerna.DigitsSix,
+		Algorithm: pquerna.AlgorithmSHA1,
+	})
+	return ok
+}
+
+// GenerateCode 使用默认参数生成指定时间的一次性密码。
+func GenerateCode(secret string, t time.Time) (string, error) {
+	return GenerateCodeCustom(secret, t, ValidateOpts{
+		Period:    30,
+		Skew:      1,
+		Digits:    pquerna.DigitsSix,
+		Algorithm: pquerna.AlgorithmSHA1,
+	})
+}
+
+// GenerateCodeCustom 使用自定义参数生成指定时间的一次性密码。
+func GenerateCodeCustom(secret string, t time.Time, opts ValidateOpts) (string, error) {
+	if opts.Digits == 0 {
+		opts.Digits = pquerna.DigitsSix
+	}
+	totp, err := newTOTP(secret, opts)
+	if err != nil {
+		return "", err
+	}
+	return totp.At(t), nil
+}
+
+// ValidateCustom 使用自定义参数校验指定时间的一次性密码。
+func ValidateCustom(passcode string, secret string, t time.Time, opts ValidateOpts) (bool, error) {
+	passcode = strings.TrimSpace(passcode)
+	if len(passcode) != opts.Digits.Length() {
+		return false, pquerna.ErrValidateInputInvalidLength
+	}
+	totp, err := newTOTP(secret, opts)
+	if err != nil {
+		return false, err
+	}
+	return totp.Verify(passcode, t), nil
+}
+
+// Generate 生成一个新的 TOTP 秘钥。
+func Generate(opts GenerateOpts) (*pquerna.Key, error) {
+	if opts.Issuer == "" {
+		return nil, pquerna.ErrGenerateMissingIssuer
+	}
+	if opts.AccountName == "" {
+		return nil, pquerna.ErrGenerateMissingAccountName
+	}
+	if opts.SecretSize == 0 {
+		opts.SecretSize = 20
+	}
+	if opts.Digits == 0 {
+		opts.Digits = pquerna.DigitsSix
+	}
+	if opts.Rand == nil {
+		opts.Rand = rand.Reader
+	}
+	secret, err := internal.RandomSecret(opts.Rand, opts.SecretSize, opts.Secret)
+	if err != nil {
+		return nil, err
+	}
+	totp, err := newTOTP(secret, ValidateOpts{Period: opts.Period, Digits: opts.Digits, Algorithm: opts.Algorithm})
+	if err != nil {
+		return nil, err
+	}
+	return pquerna.NewKeyFromURL(totp.KeyURI(opts.AccountName, opts.Issuer).URI().String())
+}
+
+func newTOTP(secret string, opts ValidateOpts) (*otp.TOTP, error) {
+	if opts.Period == 0 {
+		opts.Period = 30
+	}
+	secret, err := internal.Secret(secret)
+	if err != nil {
+		return nil, err
+	}
+	algorithm, err := internal.Algorithm(opts.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	return otp.NewTOTP(secret,
+		otp.WithPeriod(int(opts.Period)),
+		otp.WithSkew(int(opts.Skew)),
+		otp.WithDigits(otp.Digits(opts.Digits)),
+		otp.WithAlgorithm(algorithm),
+	), nil
+}
@@ -0,0 +1,63 @@
+package totp
+
+import (
+	pquerna "github.com/huk10/go-otp/compat/pquerna/otp"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// base32 encoded "12345678901234567890", RFC 6238 Appendix B
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestGenerateCodeCustom(t *testing.T) {
+	cases := map[int64]string{
+		59:         "94287082",
+		1111111109: "07081804",
+		1111111111: "14050471",
+		1234567890: "89005924",
+		2000000000: "69279037",
+	}
+	for sec, expected := range cases {
+		code, err := GenerateCodeCustom(rfcSecret, time.Unix(sec, 0), ValidateOpts{Digits: pquerna.DigitsEight})
+		assert.Nil(t, err)
+		assert.Equal(t, expected, code)
+	}
+
+	_, err := GenerateCodeCustom("1", time.Unix(59, 0), ValidateOpts{})
+	assert.Equal(t, pquerna.ErrValidateSecretInvalidBase32, err)
+
+	_, err = GenerateCodeCustom(rfcSecret, time.Unix(59, 0), ValidateOpts{Algorithm: pquerna.AlgorithmMD5})
+	assert.Equal(t, pquerna.ErrUnsupportedAlgorithm, err)
+}
+
+func TestValidate(t *testing.T) {
+	code, err := GenerateCode(rfcSecret, time.Now())
+	assert.Nil(t, err)
+	assert.Equal(t, true, Validate(code, rfcSecret))
+	assert.Equal(t, true, Validate(code, "gezdgnbvgy3tqojqgezdgnbvgy3tqojq"))
+
+	ok, err := ValidateCustom("287082", rfcSecret, time.Unix(59, 0), ValidateOpts{Digits: pquerna.DigitsSix, Skew: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, true, ok)
+
+	_, err = ValidateCustom("1234", rfcSecret, time.Unix(59, 0), ValidateOpts{Digits: pquerna.DigitsSix})
+	assert.Equal(t, pquerna.ErrValidateInputInvalidLength, err)
+}
+
+func TestGenerate(t *testing.T) {
+	key, err := Generate(GenerateOpts{Issuer: "Example", AccountName: "alice@google.com", Period: 60, Digits: pquerna.DigitsEight})
+	assert.Nil(t, err)
+	assert.Equal(t, "totp", key.Type())
+	assert.Equal(t, "Example", key.Issuer())
+	assert.Equal(t, "alice@google.com", key.AccountName())
+	assert.Equal(t, uint64(60), key.Period())
+	assert.Equal(t, pquerna.DigitsEight, key.Digits())
+	assert.Equal(t, pquerna.AlgorithmSHA1, key.Algorithm())
+	assert.Equal(t, 32, len(key.Secret()))
+
+	_, err = Generate(GenerateOpts{AccountName: "alice@google.com"})
+	assert.Equal(t, pquerna.ErrGenerateMissingIssuer, err)
+	_, err = Generate(GenerateOpts{Issuer: "Example"})
+	assert.Equal(t, pquerna.ErrGenerateMissingAccountName, err)
+}
@@ -0,0 +1,111 @@
+// Package pyotp 提供一套仿照 Python pyotp 库的 API，内部基于 github.com/huk10/go-otp 实现。
+//
+// 方便从 Python 服务迁移时逐行对照，避免在 skew 和窗口的语义上出现偏差：
+//
+//	pyotp.TOTP(s).now()                      -> pyotp.NewTOTP(s).Now()
+//	pyotp.TOTP(s).verify(code, valid_window=1) -> pyotp.NewTOTP(s).Verify(code, time.Time{}, 1)
+//	pyotp.TOTP(s).provisioning_uri(name=..., issuer_name=...) -> pyotp.NewTOTP(s).ProvisioningURI(name, issuer)
+package pyotp
+
+import (
+	"crypto/subtle"
+	"github.com/huk10/go-otp"
+	"time"
+)
+
+// RandomBase32 对应 pyotp.random_base32，返回一个随机的 base32 字符串。
+//
+// length 为字符数，默认使用 32（即 20 字节）。length 应为 8 的倍数，否则会向下取整到字节。
+func RandomBase32(length int) string {
+	return otp.Base32Encode(otp.RandomSecret(length * 5 / 8))
+}
+
+// TOTP 对应 pyotp.TOTP。
+type TOTP struct {
+	totp *otp.TOTP
+}
+
+// NewTOTP 对应 pyotp.TOTP(s, digits=6, digest=sha1, interval=30)，参数通过 otp.TOTPOption 传递。
+//
+// 与 otp.NewTOTP 一样，secret 为空或无法解码时会 panic。
+func NewTOTP(s string, options ...otp.TOTPOption) *TOTP {
+	return &TOTP{totp: otp.NewTOTP(s, options...)}
+}
+
+// Now 对应 TOTP.now()，返回当前时间的 token。
+func (t *TOTP) Now() string {
+	return t.totp.Now()
+}
+
+// At 对应 TOTP.at(for_time, counter_offset=0)，返回指定时间偏移 counterOffset 个窗口后的 token。
+func (t *TOTP) At(forTime time.Time, counterOffset int) string {
+	return t.totp.At(forTime.Add(time.Duration(counterOffset*t.totp.Period) * time.Second))
+}
+
+// Timecode 对应 TOTP.timecode(for_time)，返回指定时间所在的窗口序号。
+func (t *TOTP) Timecode(forTime time.Time) int64 {
+	return t.totp.CurrentStep(forTime)
+}
+
+// Verify 对应 TOTP.verify(otp, for_time=None, valid_window=0)。
+//
+// forTime 为零值时使用当前时间；validWindow 为 1 时会同时校验前后各一个窗口，与 pyotp 的语义一致。
+func (t *TOTP) Verify(code string, forTime time.Time, validWindow int) bool {
+	if code == "" {
+		return false
+	}
+	if forTime.IsZero() {
+		forTime = time.Now()
+	}
+	if validWindow < 0 {
+		validWindow = 0
+	}
+	for i := -validWindow; i <= validWindow; i++ {
+		if stringsEqual(code, t.At(forTime, i)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ProvisioningURI 对应 TOTP.provisioning_uri(name, issuer_name)。
+func (t *TOTP) ProvisioningURI(name, issuerName string) string {
+	return t.totp.KeyURI(name, issuerName).URI().String()
+}
+
+// HOTP 对应 pyotp.HOTP。
+type HOTP struct {
+	hotp *otp.HOTP
+}
+
+// NewHOTP 对应 pyotp.HOTP(s, digits=6, digest=sha1)，参数通过 otp.HOTPOption 传递，initial_count 在 ProvisioningURI 中指定。
+//
+// 与 otp.NewHOTP 一样，secret 为空或无法解码时会 panic。
+func NewHOTP(s string, options ...otp.HOTPOption) *HOTP {
+	return &HOTP{hotp: otp.NewHOTP(s, options...)}
+}
+
+// At 对应 HOTP.at(count)。
+func (h *HOTP) At(count int64) string {
+	return h.hotp.At(count)
+}
+
+// Verify 对应 HOTP.verify(otp, counter)，只校验指定的计数器。
+func (h *HOTP) Verify(code string, counter int64) bool {
+	if code == "" {
+		return false
+	}
+	return stringsEqual(code, h.hotp.At(counter))
+}
+
+// ProvisioningURI 对应 HOTP.provisioning_uri(name, initial_count, issuer_name)。
+func (h *HOTP) ProvisioningURI(name string, initialCount int64, issuerName string) string {
+	uri := h.hotp.KeyURI(name, issuerName)
+	uri.Counter = initialCount
+	return uri.URI().String()
+}
+
+// stringsEqual 对应 pyotp.utils.strings_equal，使用常量时间比较。
+func stringsEqual(s1, s2 string) bool {
+	return subtle.ConstantTimeCompare([]byte(s1), []byte(s2)) == 1
+}
@@ -0,0 +1,55 @@
+package pyotp
+
+import (
+	"fmt"
+	"github.com/huk10/go-otp"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+const testSecret = "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6"
+
+func TestRandomBase32(t *testing.T) {
+	s := RandomBase32(32)
+	assert.Equal(t, 32, len(s))
+	_, err := otp.Base32Decode(s)
+	assert.Nil(t, err)
+}
+
+func TestTOTP(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+	totp := NewTOTP(testSecret)
+	assert.Equal(t, "076141", totp.At(now, 0))
+	assert.Equal(t, totp.At(now.Add(30*time.Second), 0), totp.At(now, 1))
+	assert.Equal(t, now.Unix()/30, totp.Timecode(now))
+
+	t.Run("verify with valid window", func(t *testing.T) {
+		assert.Equal(t, true, totp.Verify("076141", now, 0))
+		assert.Equal(t, false, totp.Verify("076141", now.Add(30*time.Second), 0))
+		assert.Equal(t, true, totp.Verify("076141", now.Add(30*time.Second), 1))
+		assert.Equal(t, true, totp.Verify("076141", now.Add(-30*time.Second), 1))
+		assert.Equal(t, false, totp.Verify("076141", now.Add(60*time.Second), 1))
+		assert.Equal(t, false, totp.Verify("", now, 1))
+	})
+
+	t.Run("verify now", func(t *testing.T) {
+		assert.Equal(t, true, totp.Verify(totp.Now(), time.Time{}, 0))
+	})
+
+	t.Run("provisioning uri", func(t *testing.T) {
+		expected := fmt.Sprintf("otpauth://totp/Example:alice@google.com?secret=%s&issuer=Example", testSecret)
+		assert.Equal(t, expected, totp.ProvisioningURI("alice@google.com", "Example"))
+	})
+}
+
+func TestHOTP(t *testing.T) {
+	hotp := NewHOTP(testSecret)
+	assert.Equal(t, "347255", hotp.At(1))
+	assert.Equal(t, true, hotp.Verify("347255", 1))
+	assert.Equal(t, false, hotp.Verify("347255", 2))
+	assert.Equal(t, false, hotp.Verify("", 1))
+
+	expected := fmt.Sprintf("otpauth://hotp/Example:alice@google.com?secret=%s&issuer=Example&counter=5", testSecret)
+	assert.Equal(t, expected, hotp.ProvisioningURI("alice@google.com", 5, "Example"))
+}
@@ -0,0 +1,244 @@
+package otp
+
+import (
+	"context"
+	"time"
+)
+
+// SecretStoreContext 可选的 SecretStore 扩展接口，VerifyContext 会优先使用 GetContext 读取秘钥。
+//
+// 基于数据库或远程服务实现 SecretStore 时建议同时实现此接口，以便请求取消或超时时及时返回。
+type SecretStoreContext interface {
+	GetContext(ctx context.Context, id string) ([]byte, error)
+}
+
+// ReplayGuardContext 可选的 ReplayGuard 扩展接口，VerifyContext 会优先使用 UseContext 记录使用的 step。
+//
+// 与 Use 不同，UseContext 可以返回错误，以便区分 token 已经被使用过和存储不可用。
+type ReplayGuardContext interface {
+	UseContext(ctx context.Context, key string, step int64) (bool, error)
+}
+
+// VerifyContext 与 Verify 相同，但是会将 ctx 传递给 SecretStoreContext、ReplayGuardContext 等外部依赖，
+// 并在读取秘钥、Signer 签名或防重放检查出错时返回错误，而不是简单地返回 false。
+//
+// 返回 false 和 nil 表示 token 无效；返回错误时调用方不应该认为 token 无效，而应该提示稍后重试。
+//
+// Example:
+//
+//	ok, err := totp.VerifyContext(ctx, token, time.Now())
+//	if err != nil {
+//		return status.Error(codes.Unavailable, err.Error())
+//	}
+func (o *TOTP) VerifyContext(ctx context.Context, token string, t time.Time) (bool, error) {
+	_, ok, err := o.VerifyWithMatchContext(ctx, token, t)
+	return ok, err
+}
+
+// VerifyWithMatchContext 与 VerifyContext 相同，额外返回校验通过的时间步，见 VerifyWithMatch。
+func (o *TOTP) VerifyWithMatchContext(ctx context.Context, token string, t time.Time) (int64, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, false, err
+	}
+	current := o.step(t)
+	event := o.beginVerify("totp", current, t)
+	matched, outcome, err := o.matchContext(ctx, token, t, current)
+	o.endVerify(event, matched, outcome, err)
+	if outcome != VerifySuccess {
+		return 0, false, err
+	}
+	return matched, true, nil
+}
+
+// matchContext 在 current 附近的窗口内查找与 token 匹配的时间步，秘钥只读取一次（Signer 不需要读取），ctx 会传递给外部依赖。
+func (o *TOTP) matchContext(ctx context.Context, token string, t time.Time, current int64) (int64, VerifyOutcome, error) {
+	if token == "" {
+		return 0, VerifyMismatch, nil
+	}
+	if !o.validAt(t) {
+		return 0, VerifyNotValid, nil
+	}
+	generate, release, err := o.generator(ctx)
+	if err != nil {
+		return 0, VerifyError, err
+	}
+	defer release()
+	backward, forward := o.window()
+	from, to := counterRange(current, backward, forward)
+	for step := from; step <= to; step++ {
+		generated, err := generate(step)
+		if err != nil {
+			return 0, VerifyError, err
+		}
+		if generated == token {
+			return useReplayGuardOutcome(ctx, o.replayGuard, o.replayKey, step)
+		}
+		if step == to {
+			break
+		}
+	}
+	return 0, VerifyMismatch, nil
+}
+
+// VerifyContext 与 Verify 相同，但是会将 ctx 传递给 SecretStoreContext、ReplayGuardContext 等外部依赖，
+// 并在读取秘钥、Signer 签名或防重放检查出错时返回错误，而不是简单地返回 false。
+func (h *HOTP) VerifyContext(ctx context.Context, token string, counter int64) (bool, error) {
+	backward, forward := h.window()
+	from, to := counterRange(counter, backward, forward)
+	_, outcome, err := h.verifyRangeContext(ctx, token, counter, from, to)
+	return outcome == VerifySuccess, err
+}
+
+// ValidateAndSyncContext 与 ValidateAndSync 相同，但是会将 ctx 传递给外部依赖，并在出错时返回错误。
+//
+// 返回错误时新计数器为 currentCounter，调用方不应该更新保存的计数器。
+func (h *HOTP) ValidateAndSyncContext(ctx context.Context, token string, currentCounter int64, lookAhead int) (int64, bool, error) {
+	if lookAhead < 0 {
+		lookAhead = 0
+	}
+	from, to := counterRange(currentCounter, 0, lookAhead)
+	matched, outcome, err := h.verifyRangeContext(ctx, token, currentCounter, from, to)
+	if outcome != VerifySuccess {
+		return currentCounter, false, err
+	}
+	return matched + 1, true, nil
+}
+
+// verifyRangeContext 与 verifyRange 相同，但是会将 ctx 传递给外部依赖。
+func (h *HOTP) verifyRangeContext(ctx context.Context, token string, expected, from, to int64) (int64, VerifyOutcome, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, VerifyError, err
+	}
+	now := h.now()
+	event := h.beginVerify("hotp", expected, now)
+	matched, outcome, err := h.matchContext(ctx, token, now, from, to)
+	h.endVerify(event, matched, outcome, err)
+	return matched, outcome, err
+}
+
+// matchContext 在 [from, to] 之间查找与 token 匹配的计数器，秘钥只读取一次（Signer 不需要读取），ctx 会传递给外部依赖。
+func (h *HOTP) matchContext(ctx context.Context, token string, now time.Time, from, to int64) (int64, VerifyOutcome, error) {
+	if token == "" {
+		return 0, VerifyMismatch, nil
+	}
+	if !h.validAt(now) {
+		return 0, VerifyNotValid, nil
+	}
+	generate, release, err := h.generator(ctx)
+	if err != nil {
+		return 0, VerifyError, err
+	}
+	defer release()
+	for i := from; i <= to; i++ {
+		generated, err := generate(i)
+		if err != nil {
+			return 0, VerifyError, err
+		}
+		if generated == token {
+			return useReplayGuardOutcome(ctx, h.replayGuard, h.replayKey, i)
+		}
+		if i == to {
+			break
+		}
+	}
+	return 0, VerifyMismatch, nil
+}
+
+// generator 返回计算计数器（时间步）对应 token 的方法，以及使用完毕后清理秘钥的方法。
+func (o *TOTP) generator(ctx context.Context) (func(counter int64) (string, error), func(), error) {
+	if o.signer != nil {
+		return signerGenerator(o.Otp, o.signer), func() {}, nil
+	}
+	secret, err := o.secretContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return secretGenerator(o.Otp, secret), releaseSecret(o.store, secret), nil
+}
+
+// generator 返回计算计数器对应 token 的方法，以及使用完毕后清理秘钥的方法。
+func (h *HOTP) generator(ctx context.Context) (func(counter int64) (string, error), func(), error) {
+	if h.signer != nil {
+		return signerGenerator(h.Otp, h.signer), func() {}, nil
+	}
+	secret, err := h.secretContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return secretGenerator(h.Otp, secret), releaseSecret(h.store, secret), nil
+}
+
+// secretGenerator 使用解码后的秘钥计算 token。
+func secretGenerator(o Otp, secret []byte) func(counter int64) (string, error) {
+	return func(counter int64) (string, error) {
+		return o.generate(secret, counter), nil
+	}
+}
+
+// releaseSecret 从 store 中读取的秘钥在使用之后清零，其他情况不做处理。
+func releaseSecret(store SecretStore, secret []byte) func() {
+	if store == nil {
+		return func() {}
+	}
+	return func() { zero(secret) }
+}
+
+// secretContext 返回解码后的秘钥，使用 NewTOTPFromStore 创建时从 store 中读取。
+func (o *TOTP) secretContext(ctx context.Context) ([]byte, error) {
+	if o.store != nil {
+		return getSecretContext(ctx, o.store, o.storeID)
+	}
+	if len(o.decodedSecret) == 0 {
+		return nil, ErrSecretCannotBeEmpty
+	}
+	return o.decodedSecret, nil
+}
+
+// secretContext 返回解码后的秘钥，使用 NewHOTPFromStore 创建时从 store 中读取。
+func (h *HOTP) secretContext(ctx context.Context) ([]byte, error) {
+	if h.store != nil {
+		return getSecretContext(ctx, h.store, h.storeID)
+	}
+	if len(h.decodedSecret) == 0 {
+		return nil, ErrSecretCannotBeEmpty
+	}
+	return h.decodedSecret, nil
+}
+
+// getSecretContext store 实现了 SecretStoreContext 时使用 GetContext 读取秘钥，否则使用 Get。
+func getSecretContext(ctx context.Context, store SecretStore, id string) ([]byte, error) {
+	if s, ok := store.(SecretStoreContext); ok {
+		return s.GetContext(ctx, id)
+	}
+	return store.Get(id)
+}
+
+// useReplayGuard guard 为 nil 时直接返回 true，实现了 ReplayGuardContext 时使用 UseContext，否则使用 Use。
+//
+// key 只在 guard 不为 nil 时调用，计算 key 出错时返回对应的错误。
+func useReplayGuard(ctx context.Context, guard ReplayGuard, key func() (string, error), step int64) (bool, error) {
+	if guard == nil {
+		return true, nil
+	}
+	k, err := key()
+	if err != nil {
+		return false, err
+	}
+	if g, ok := guard.(ReplayGuardContext); ok {
+		return g.UseContext(ctx, k, step)
+	}
+	return guard.Use(k, step), nil
+}
+
+// useReplayGuardOutcome 调用 useReplayGuard 并将结果转换为 VerifyOutcome，step 为匹配的时间步或计数器。
+func useReplayGuardOutcome(ctx context.Context, guard ReplayGuard, key func() (string, error), step int64) (int64, VerifyOutcome, error) {
+	ok, err := useReplayGuard(ctx, guard, key, step)
+	switch {
+	case err != nil:
+		return step, VerifyError, err
+	case !ok:
+		return step, VerifyReplay, nil
+	default:
+		return step, VerifySuccess, nil
+	}
+}
@@ -0,0 +1,224 @@
+package otp
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var errBackend = errors.New("backend unavailable")
+
+// contextSecretStore 实现了 SecretStoreContext 的 SecretStore。
+type contextSecretStore struct {
+	*MemorySecretStore
+	err error
+	ctx context.Context
+}
+
+func (s *contextSecretStore) GetContext(ctx context.Context, id string) ([]byte, error) {
+	s.ctx = ctx
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.Get(id)
+}
+
+// contextReplayGuard 实现了 ReplayGuardContext 的 ReplayGuard。
+type contextReplayGuard struct {
+	*MemoryReplayGuard
+	err error
+}
+
+func (g *contextReplayGuard) UseContext(_ context.Context, key string, step int64) (bool, error) {
+	if g.err != nil {
+		return false, g.err
+	}
+	return g.Use(key, step), nil
+}
+
+func TestTOTP_VerifyContext(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1704075000000, 0)
+
+	totp := NewTOTP(TestSecret20, WithSkew(1))
+	ok, err := totp.VerifyContext(ctx, "076141", now)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	ok, err = totp.VerifyContext(ctx, "076141", now.Add(time.Second*30))
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	ok, err = totp.VerifyContext(ctx, "000000", now)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+	ok, err = totp.VerifyContext(ctx, "", now)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	ok, err = totp.VerifyContext(canceled, "076141", now)
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	t.Run("secret store", func(t *testing.T) {
+		store := &contextSecretStore{MemorySecretStore: NewMemorySecretStore()}
+		secret, _ := Base32Decode(TestSecret20)
+		_ = store.Put("alice", secret)
+		totp, err := NewTOTPFromStore(store, "alice")
+		assert.Nil(t, err)
+		type key struct{}
+		ctx := context.WithValue(ctx, key{}, "value")
+		ok, err := totp.VerifyContext(ctx, "076141", now)
+		assert.True(t, ok)
+		assert.Nil(t, err)
+		assert.Equal(t, "value", store.ctx.Value(key{}))
+
+		store.err = errBackend
+		ok, err = totp.VerifyContext(ctx, "076141", now)
+		assert.False(t, ok)
+		assert.ErrorIs(t, err, errBackend)
+	})
+
+	t.Run("replay guard", func(t *testing.T) {
+		guard := &contextReplayGuard{MemoryReplayGuard: NewMemoryReplayGuard()}
+		totp := NewTOTP(TestSecret20, WithReplayGuard(guard))
+		ok, err := totp.VerifyContext(ctx, "076141", now)
+		assert.True(t, ok)
+		assert.Nil(t, err)
+		ok, err = totp.VerifyContext(ctx, "076141", now)
+		assert.False(t, ok)
+		assert.Nil(t, err)
+
+		guard.err = errBackend
+		ok, err = NewTOTP(TestSecret32, WithReplayGuard(guard)).VerifyContext(ctx, NewTOTP(TestSecret32).At(now), now)
+		assert.False(t, ok)
+		assert.ErrorIs(t, err, errBackend)
+	})
+}
+
+func TestHOTP_VerifyContext(t *testing.T) {
+	ctx := context.Background()
+	hotp := NewHOTP(TestSecret20, WithSkew(1), WithReplayGuard(NewMemoryReplayGuard()))
+	ok, err := hotp.VerifyContext(ctx, hotp.At(3), 2)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	ok, err = hotp.VerifyContext(ctx, hotp.At(3), 2)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+	ok, err = hotp.VerifyContext(ctx, hotp.At(5), 2)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+
+	store := NewMemorySecretStore()
+	_ = store.Put("bob", []byte("12345678901234567890"))
+	s := &contextSecretStore{MemorySecretStore: store, err: errBackend}
+	hotp = &HOTP{Otp: newOtp(), store: s, storeID: "bob"}
+	ok, err = hotp.VerifyContext(ctx, "755224", 0)
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, errBackend)
+	s.err = nil
+	ok, err = hotp.VerifyContext(ctx, "755224", 0)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+}
+
+func TestTOTP_VerifyWithMatchContext(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1704075000000, 0)
+	totp := NewTOTP(TestSecret20, WithSkew(1))
+	step, ok, err := totp.VerifyWithMatchContext(ctx, "076141", now.Add(time.Second*30))
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, now.Unix()/30, step)
+	step, ok, err = totp.VerifyWithMatchContext(ctx, "000000", now)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), step)
+}
+
+func TestHOTP_ValidateAndSyncContext(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemorySecretStore()
+	_ = store.Put("bob", []byte("12345678901234567890"))
+	s := &contextSecretStore{MemorySecretStore: store}
+	hotp := &HOTP{Otp: newOtp(), store: s, storeID: "bob"}
+
+	// RFC 4226 附录 D 中计数器 3 的 token
+	next, ok, err := hotp.ValidateAndSyncContext(ctx, "969429", 1, 5)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), next)
+	next, ok, err = hotp.ValidateAndSyncContext(ctx, "969429", 4, 5)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), next)
+
+	s.err = errBackend
+	next, ok, err = hotp.ValidateAndSyncContext(ctx, "969429", 1, 5)
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, errBackend)
+	assert.Equal(t, int64(1), next)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, ok, err = hotp.ValidateAndSyncContext(canceled, "969429", 1, 5)
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestThrottle_VerifyContext(t *testing.T) {
+	ctx := context.Background()
+	throttle := NewThrottle(2, time.Minute)
+	// 尝试在校验之前计入失败次数，后端错误同样算作一次失败
+	ok, err := throttle.VerifyContext(ctx, "alice", func(context.Context) (bool, error) { return false, errBackend })
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, errBackend)
+	allowed, _ := throttle.Allow("alice")
+	assert.True(t, allowed)
+
+	ok, err = throttle.VerifyContext(ctx, "alice", func(context.Context) (bool, error) { return false, nil })
+	assert.False(t, ok)
+	assert.Nil(t, err)
+	_, err = throttle.VerifyContext(ctx, "alice", func(context.Context) (bool, error) { return true, nil })
+	assert.ErrorIs(t, err, ErrThrottled)
+}
+
+func TestThrottle_VerifyContextBackendErrors(t *testing.T) {
+	ctx := context.Background()
+	throttle := NewThrottle(2, time.Minute)
+	unavailable := func(context.Context) (bool, error) { return false, errBackend }
+
+	// 后端错误计入失败次数，持续不可用时账户会被锁定
+	result := throttle.VerifyContextWithResult(ctx, "alice", unavailable)
+	assert.ErrorIs(t, result.Err, errBackend)
+	assert.Equal(t, 1, result.AttemptsRemaining)
+	result = throttle.VerifyContextWithResult(ctx, "alice", unavailable)
+	assert.ErrorIs(t, result.Err, errBackend)
+	assert.Equal(t, 0, result.AttemptsRemaining)
+	assert.Greater(t, result.RetryAfter, time.Duration(0))
+	ok, err := throttle.VerifyContext(ctx, "alice", func(context.Context) (bool, error) { return true, nil })
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, ErrThrottled)
+
+	// 后端恢复之后通过 Reset 解锁
+	assert.Nil(t, throttle.Reset("alice"))
+	ok, err = throttle.VerifyContext(ctx, "alice", func(context.Context) (bool, error) { return true, nil })
+	assert.True(t, ok)
+	assert.Nil(t, err)
+}
+
+func TestEncryptedSecretStore_GetContext(t *testing.T) {
+	backend := &contextSecretStore{MemorySecretStore: NewMemorySecretStore()}
+	store, _ := NewEncryptedSecretStore(make([]byte, 32), backend)
+	assert.Nil(t, store.Put("alice", []byte("secret")))
+	secret, err := store.GetContext(context.Background(), "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("secret"), secret)
+	assert.NotNil(t, backend.ctx)
+
+	backend.err = errBackend
+	_, err = store.GetContext(context.Background(), "alice")
+	assert.ErrorIs(t, err, errBackend)
+}
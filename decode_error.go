@@ -0,0 +1,79 @@
+package otp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DecodeErrorReason base32 解码失败的原因。
+type DecodeErrorReason int
+
+const (
+	// DecodeErrorIllegalChar 包含 base32 字母表以外的字符。
+	DecodeErrorIllegalChar DecodeErrorReason = iota + 1
+	// DecodeErrorPadding 包含填充字符 "="，秘钥不应该填充。
+	DecodeErrorPadding
+)
+
+// String 枚举值转换为字符串形式。
+func (r DecodeErrorReason) String() string {
+	switch r {
+	case DecodeErrorIllegalChar:
+		return "illegal character"
+	case DecodeErrorPadding:
+		return "unexpected padding"
+	default:
+		panic("unreachable")
+	}
+}
+
+// SecretDecodeError base32 秘钥解码失败的详细信息，可以用于在界面上标出用户输入错误的位置。
+//
+// 使用 errors.Is(err, ErrSecretDecode) 判断是否是解码错误。
+type SecretDecodeError struct {
+	// 失败的原因
+	Reason DecodeErrorReason
+	// 出错字符的位置（按字符计算，从 0 开始）。
+	Position int
+	// 出错的字符。
+	Char rune
+	// 容易混淆的字符给出的建议，例如 '0' 建议为 'O'，没有建议时为 0。
+	Hint rune
+}
+
+func (e *SecretDecodeError) Error() string {
+	if e.Hint != 0 {
+		return fmt.Sprintf("%s: %s %q at position %d, did you mean %q", ErrSecretDecode, e.Reason, e.Char, e.Position, e.Hint)
+	}
+	return fmt.Sprintf("%s: %s %q at position %d", ErrSecretDecode, e.Reason, e.Char, e.Position)
+}
+
+// Is 使 errors.Is(err, ErrSecretDecode) 返回 true。
+func (e *SecretDecodeError) Is(target error) bool {
+	return target == ErrSecretDecode
+}
+
+// base32Hints 用户手动输入时容易混淆的字符。
+var base32Hints = map[rune]rune{
+	'0': 'O',
+	'1': 'I',
+	'8': 'B',
+	'9': 'G',
+}
+
+// newSecretDecodeError 找出 str 中第一个不属于 alphabet 的字符，str 应该已经转换为大写，hints 为容易混淆的字符的建议。
+//
+// base32 解码器会跳过换行符，因此换行符不会被当作错误。
+func newSecretDecodeError(str, alphabet string, hints map[rune]rune) *SecretDecodeError {
+	position := 0
+	for _, r := range str {
+		switch {
+		case r == '=':
+			return &SecretDecodeError{Reason: DecodeErrorPadding, Position: position, Char: r}
+		case r != '\r' && r != '\n' && !strings.ContainsRune(alphabet, r):
+			return &SecretDecodeError{Reason: DecodeErrorIllegalChar, Position: position, Char: r, Hint: hints[r]}
+		}
+		position++
+	}
+	return &SecretDecodeError{Reason: DecodeErrorIllegalChar, Position: position}
+}
@@ -0,0 +1,27 @@
+package otp
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBase32Decode_Error(t *testing.T) {
+	var cases = []struct {
+		secret   string
+		expected *SecretDecodeError
+		message  string
+	}{
+		{"JBSW1DPE", &SecretDecodeError{Reason: DecodeErrorIllegalChar, Position: 4, Char: '1', Hint: 'I'}, `secret base32 decode error: illegal character '1' at position 4, did you mean 'I'`},
+		{"jbsw0dpe", &SecretDecodeError{Reason: DecodeErrorIllegalChar, Position: 4, Char: '0', Hint: 'O'}, `secret base32 decode error: illegal character '0' at position 4, did you mean 'O'`},
+		{"JBSW!DPE", &SecretDecodeError{Reason: DecodeErrorIllegalChar, Position: 4, Char: '!'}, `secret base32 decode error: illegal character '!' at position 4`},
+		{"密钥JBSW", &SecretDecodeError{Reason: DecodeErrorIllegalChar, Position: 0, Char: '密'}, `secret base32 decode error: illegal character '密' at position 0`},
+		{"JBSWY3DPEA======", &SecretDecodeError{Reason: DecodeErrorPadding, Position: 10, Char: '='}, `secret base32 decode error: unexpected padding '=' at position 10`},
+	}
+	for _, c := range cases {
+		_, err := Base32Decode(c.secret)
+		assert.Equal(t, c.expected, err)
+		assert.Equal(t, c.message, err.Error())
+		assert.True(t, errors.Is(err, ErrSecretDecode))
+	}
+}
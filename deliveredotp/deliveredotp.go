@@ -0,0 +1,218 @@
+// Package deliveredotp 生成并校验通过邮件、短信下发的一次性验证码。
+//
+// 与 TOTP、HOTP 不同，验证码是随机生成的数字，不依赖共享的秘钥。服务端只保存验证码的 HMAC，
+// 在有效期内最多允许尝试 MaxAttempts 次，校验成功之后立即删除，同一个验证码不能使用两次。
+// 校验的返回值与 otp.Throttle 相同，可以与 TOTP 使用同一套提示逻辑。
+//
+// Example:
+//
+//	codes := deliveredotp.New(deliveredotp.NewMemoryStore(), serverKey)
+//	code, err := codes.Generate("login:" + userID)
+//	if err != nil {
+//		return err
+//	}
+//	mail.Send(user.Email, "您的验证码为 "+code)
+//
+//	// 用户提交后
+//	result := codes.VerifyWithResult("login:"+userID, input)
+//	switch {
+//	case result.Ok:
+//		// 校验通过
+//	case errors.Is(result.Err, deliveredotp.ErrNotFound):
+//		// 验证码已过期或者已经使用，重新发送
+//	case errors.Is(result.Err, deliveredotp.ErrTooManyAttempts):
+//		// 失败次数过多，重新发送
+//	default:
+//		fmt.Printf("验证码错误，还可以尝试 %d 次", result.AttemptsRemaining)
+//	}
+package deliveredotp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/huk10/go-otp"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound key 没有未过期的验证码，可能从未发送、已经过期或者已经校验成功。
+	ErrNotFound = errors.New("delivered code not found or expired")
+	// ErrTooManyAttempts 验证码的失败次数达到 MaxAttempts，在过期之前不再进行校验，需要重新发送。
+	ErrTooManyAttempts = errors.New("too many delivered code attempts")
+)
+
+const (
+	// DefaultDigits 默认的验证码位数。
+	DefaultDigits = 6
+	// DefaultTTL 默认的验证码有效期。
+	DefaultTTL = 10 * time.Minute
+	// DefaultMaxAttempts 默认每个验证码允许的最大尝试次数。
+	DefaultMaxAttempts = 5
+)
+
+// Codes 生成和校验下发的验证码，Digits、TTL、MaxAttempts 为零值时使用默认值。
+type Codes struct {
+	// 保存验证码记录的存储。
+	Store Store
+	// 验证码的位数，取值范围为 [4, 10]，默认为 DefaultDigits。
+	Digits int
+	// 验证码的有效期，从 Generate 开始计算，默认为 DefaultTTL。
+	TTL time.Duration
+	// 每个验证码允许的最大尝试次数，包括成功的一次，默认为 DefaultMaxAttempts。
+	MaxAttempts int
+	// 计算验证码 HMAC 的秘钥，仅服务端持有，建议至少 32 字节。
+	key []byte
+	// 获取当前时间的方法，为 nil 时使用 time.Now。
+	clock func() time.Time
+	// 生成验证码的随机数来源，为 nil 时使用 crypto/rand。
+	rand io.Reader
+}
+
+// New 创建一个使用默认参数的 Codes。
+//
+// Params:
+//
+//	store: 保存验证码记录的存储，单实例部署可以使用 NewMemoryStore。
+//	key  : 计算 HMAC 的秘钥。验证码的取值空间很小，存储泄露时只保存 SHA-256 的话可以被穷举，因此需要一个不与存储放在一起的秘钥。
+func New(store Store, key []byte) *Codes {
+	return &Codes{
+		Store:       store,
+		Digits:      DefaultDigits,
+		TTL:         DefaultTTL,
+		MaxAttempts: DefaultMaxAttempts,
+		key:         append([]byte(nil), key...),
+	}
+}
+
+// now 返回当前时间。
+func (c *Codes) now() time.Time {
+	if c.clock != nil {
+		return c.clock()
+	}
+	return time.Now()
+}
+
+// digits 返回验证码的位数，未配置时使用 DefaultDigits。
+func (c *Codes) digits() int {
+	if c.Digits == 0 {
+		return DefaultDigits
+	}
+	return c.Digits
+}
+
+// ttl 返回验证码的有效期，未配置时使用 DefaultTTL。
+func (c *Codes) ttl() time.Duration {
+	if c.TTL <= 0 {
+		return DefaultTTL
+	}
+	return c.TTL
+}
+
+// maxAttempts 返回每个验证码允许的最大尝试次数，未配置时使用 DefaultMaxAttempts。
+func (c *Codes) maxAttempts() int {
+	if c.MaxAttempts <= 0 {
+		return DefaultMaxAttempts
+	}
+	return c.MaxAttempts
+}
+
+// Generate 为 key 生成一个新的验证码并保存，返回的验证码由调用方下发给用户。
+//
+// 同一个 key 之前的验证码以及失败次数会被覆盖，调用方需要自行限制发送的频率，例如使用 otp.Throttle。
+func (c *Codes) Generate(key string) (string, error) {
+	digits := c.digits()
+	if digits < 4 || digits > 10 {
+		return "", fmt.Errorf("%w: digits %d out of range [4, 10]", otp.ErrInvalidOption, digits)
+	}
+	code, err := c.random(digits)
+	if err != nil {
+		return "", err
+	}
+	record := Record{
+		Hash:    c.hash(key, code),
+		Expires: c.now().Add(c.ttl()),
+	}
+	if err := c.Store.Put(key, record); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// random 生成 digits 位均匀分布的随机数字。
+func (c *Codes) random(digits int) (string, error) {
+	reader := c.rand
+	if reader == nil {
+		reader = rand.Reader
+	}
+	code := make([]byte, 0, digits)
+	buf := make([]byte, digits)
+	for len(code) < digits {
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return "", fmt.Errorf("generate delivered code: %w", err)
+		}
+		for _, b := range buf {
+			// 丢弃 250 及以上的值，避免取模导致的偏差
+			if b < 250 && len(code) < digits {
+				code = append(code, '0'+b%10)
+			}
+		}
+	}
+	return string(code), nil
+}
+
+// hash 计算验证码的 HMAC，key 参与计算，一个账户的记录不能用于校验另一个账户。
+func (c *Codes) hash(key, code string) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(key))
+	mac.Write([]byte{0})
+	mac.Write([]byte(code))
+	return mac.Sum(nil)
+}
+
+// Verify 校验 key 的验证码，校验成功之后删除记录。
+//
+// 验证码错误时返回 (false, nil)，其他情况见 VerifyWithResult。
+func (c *Codes) Verify(key, code string) (bool, error) {
+	result := c.VerifyWithResult(key, code)
+	return result.Ok, result.Err
+}
+
+// VerifyWithResult 与 Verify 相同，额外返回剩余的尝试次数，字段的含义与 otp.Throttle.VerifyWithResult 相同。
+//
+//   - 没有未过期的验证码时 Err 为 ErrNotFound，并发提交的正确验证码只有一个会成功，其余的同样返回 ErrNotFound。
+//   - 尝试次数超过 MaxAttempts 时 Err 为 ErrTooManyAttempts，RetryAfter 为验证码剩余的有效期，此时不会进行校验。
+//   - 验证码错误时 Err 为 nil，AttemptsRemaining 为剩余的尝试次数，为 0 时 RetryAfter 为验证码剩余的有效期。
+//
+// 每次尝试在比较之前先通过 Store.AddAttempt 原子地计数，校验成功时通过 Store.Consume 原子地删除记录。
+// 提交的验证码会去掉首尾的空白字符。
+func (c *Codes) VerifyWithResult(key, code string) otp.VerifyResult {
+	now := c.now()
+	record, err := c.Store.AddAttempt(key, now)
+	if err != nil {
+		return otp.VerifyResult{Err: err}
+	}
+	maxAttempts := c.maxAttempts()
+	if record.Attempts > maxAttempts {
+		return otp.VerifyResult{Err: ErrTooManyAttempts, RetryAfter: record.Expires.Sub(now)}
+	}
+	if hmac.Equal(record.Hash, c.hash(key, strings.TrimSpace(code))) {
+		consumed, err := c.Store.Consume(key, record.Hash)
+		if err != nil {
+			return otp.VerifyResult{Err: err}
+		}
+		if !consumed {
+			return otp.VerifyResult{Err: ErrNotFound}
+		}
+		return otp.VerifyResult{Ok: true}
+	}
+	result := otp.VerifyResult{AttemptsRemaining: maxAttempts - record.Attempts}
+	if result.AttemptsRemaining == 0 {
+		result.RetryAfter = record.Expires.Sub(now)
+	}
+	return result
+}
@@ -0,0 +1,193 @@
+package deliveredotp
+
+import (
+	"bytes"
+	"errors"
+	"github.com/huk10/go-otp"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func newTestCodes(now *time.Time) *Codes {
+	codes := New(NewMemoryStore(), []byte("server key"))
+	codes.clock = func() time.Time { return *now }
+	return codes
+}
+
+func TestCodes_Generate(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	codes := newTestCodes(&now)
+	// 250 及以上的字节会被丢弃
+	codes.rand = bytes.NewReader([]byte{250, 1, 255, 12, 23, 34, 45, 56, 249, 0, 0, 0})
+	code, err := codes.Generate("alice")
+	assert.Nil(t, err)
+	assert.Equal(t, "123456", code)
+
+	record := codes.Store.(*MemoryStore).records["alice"]
+	assert.Equal(t, now.Add(DefaultTTL), record.Expires)
+	assert.Equal(t, 0, record.Attempts)
+	assert.NotContains(t, string(record.Hash), code)
+
+	codes.rand = nil
+	codes.Digits = 8
+	code, err = codes.Generate("alice")
+	assert.Nil(t, err)
+	assert.Len(t, code, 8)
+
+	codes.Digits = 3
+	_, err = codes.Generate("alice")
+	assert.ErrorIs(t, err, otp.ErrInvalidOption)
+
+	readErr := errors.New("entropy unavailable")
+	codes.Digits = 6
+	codes.rand = iotest.ErrReader(readErr)
+	_, err = codes.Generate("alice")
+	assert.ErrorIs(t, err, readErr)
+}
+
+func TestCodes_Verify(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	codes := newTestCodes(&now)
+	code, err := codes.Generate("alice")
+	assert.Nil(t, err)
+
+	// 其他账户的验证码不能通过
+	_, err = codes.Generate("bob")
+	assert.Nil(t, err)
+	ok, err := codes.Verify("bob", code+"0")
+	assert.False(t, ok)
+	assert.Nil(t, err)
+
+	result := codes.VerifyWithResult("alice", "000000x")
+	assert.Equal(t, otp.VerifyResult{AttemptsRemaining: 4}, result)
+	ok, err = codes.Verify("alice", " "+code+"\n")
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	// 校验成功之后删除
+	ok, err = codes.Verify("alice", code)
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// 过期
+	code, _ = codes.Generate("alice")
+	now = now.Add(DefaultTTL)
+	_, err = codes.Verify("alice", code)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestCodes_VerifyMaxAttempts(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	codes := newTestCodes(&now)
+	codes.MaxAttempts = 2
+	code, _ := codes.Generate("alice")
+	now = now.Add(time.Minute)
+
+	result := codes.VerifyWithResult("alice", "x")
+	assert.Equal(t, otp.VerifyResult{AttemptsRemaining: 1}, result)
+	result = codes.VerifyWithResult("alice", "x")
+	assert.Equal(t, otp.VerifyResult{RetryAfter: 9 * time.Minute}, result)
+	// 达到上限之后正确的验证码也会被拒绝
+	result = codes.VerifyWithResult("alice", code)
+	assert.Equal(t, otp.VerifyResult{Err: ErrTooManyAttempts, RetryAfter: 9 * time.Minute}, result)
+
+	// 重新发送之后恢复
+	code, _ = codes.Generate("alice")
+	ok, err := codes.Verify("alice", code)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+}
+
+func TestCodes_ZeroValue(t *testing.T) {
+	codes := &Codes{Store: NewMemoryStore()}
+	code, err := codes.Generate("alice")
+	assert.Nil(t, err)
+	assert.Len(t, code, DefaultDigits)
+	result := codes.VerifyWithResult("alice", "x")
+	assert.Equal(t, otp.VerifyResult{AttemptsRemaining: DefaultMaxAttempts - 1}, result)
+	ok, err := codes.Verify("alice", code)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+}
+
+func TestCodes_VerifyConcurrent(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	codes := newTestCodes(&now)
+	codes.MaxAttempts = 3
+	code, _ := codes.Generate("alice")
+
+	// 并发的错误猜测最多只有 MaxAttempts 次进行比较
+	results := make([]otp.VerifyResult, 100)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = codes.VerifyWithResult("alice", "x")
+		}(i)
+	}
+	wg.Wait()
+	compared := 0
+	for _, result := range results {
+		if result.Err == nil {
+			compared++
+		} else {
+			assert.ErrorIs(t, result.Err, ErrTooManyAttempts)
+		}
+	}
+	assert.Equal(t, 3, compared)
+
+	// 并发提交的正确验证码只有一个成功
+	codes.MaxAttempts = 100
+	code, _ = codes.Generate("alice")
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = codes.VerifyWithResult("alice", code)
+		}(i)
+	}
+	wg.Wait()
+	succeeded := 0
+	for _, result := range results {
+		if result.Ok {
+			succeeded++
+		} else {
+			assert.ErrorIs(t, result.Err, ErrNotFound)
+		}
+	}
+	assert.Equal(t, 1, succeeded)
+}
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	store := NewMemoryStore()
+	hash := []byte{1, 2, 3}
+	assert.Nil(t, store.Put("a", Record{Hash: hash, Expires: now.Add(time.Minute)}))
+	hash[0] = 9
+	record, err := store.AddAttempt("a", now)
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, record.Hash)
+	assert.Equal(t, 1, record.Attempts)
+	record, _ = store.AddAttempt("a", now)
+	assert.Equal(t, 2, record.Attempts)
+	_, err = store.AddAttempt("b", now)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// 只有 hash 相同时才会删除
+	consumed, err := store.Consume("a", []byte{9, 2, 3})
+	assert.Nil(t, err)
+	assert.False(t, consumed)
+	consumed, err = store.Consume("a", []byte{1, 2, 3})
+	assert.Nil(t, err)
+	assert.True(t, consumed)
+	consumed, _ = store.Consume("a", []byte{1, 2, 3})
+	assert.False(t, consumed)
+
+	assert.Nil(t, store.Put("a", Record{Hash: hash, Expires: now.Add(time.Minute)}))
+	_, err = store.AddAttempt("a", now.Add(time.Minute))
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Empty(t, store.records)
+}
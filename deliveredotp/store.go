@@ -0,0 +1,82 @@
+package deliveredotp
+
+import (
+	"bytes"
+	"sync"
+	"time"
+)
+
+// Record 一个已发送的验证码的记录。
+type Record struct {
+	// 验证码的 HMAC-SHA256。
+	Hash []byte
+	// 过期时间，过期之后 AddAttempt 返回 ErrNotFound，存储可以据此设置 TTL。
+	Expires time.Time
+	// 尝试的次数，包括成功的一次。
+	Attempts int
+}
+
+// Store 保存验证码记录的存储，多实例部署时需要使用共享的存储，例如 Redis 或数据库。
+//
+// AddAttempt 和 Consume 都必须是原子操作（例如 Redis 的 Lua 脚本、数据库的条件 UPDATE/DELETE），
+// 否则并发的猜测可以绕过 MaxAttempts，同一个验证码也可能被使用两次。
+type Store interface {
+	// Put 保存 key 的记录，覆盖之前的记录。
+	Put(key string, record Record) error
+	// AddAttempt 原子地增加 key 的尝试次数，返回增加之后的记录，不存在或者在 now 已经过期时返回 ErrNotFound。
+	AddAttempt(key string, now time.Time) (Record, error)
+	// Consume 原子地删除 key 的记录，仅当记录的 Hash 与 hash 相同时删除，返回是否删除。
+	Consume(key string, hash []byte) (bool, error)
+}
+
+// MemoryStore 基于内存的 Store 实现，仅适用于单实例部署。
+//
+// 过期的记录在 AddAttempt 时删除。
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+// NewMemoryStore 创建一个 MemoryStore。
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]Record)}
+}
+
+// Put 实现 Store 接口。
+func (s *MemoryStore) Put(key string, record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record.Hash = append([]byte(nil), record.Hash...)
+	s.records[key] = record
+	return nil
+}
+
+// AddAttempt 实现 Store 接口。
+func (s *MemoryStore) AddAttempt(key string, now time.Time) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[key]
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+	if !now.Before(record.Expires) {
+		delete(s.records, key)
+		return Record{}, ErrNotFound
+	}
+	record.Attempts++
+	s.records[key] = record
+	record.Hash = append([]byte(nil), record.Hash...)
+	return record, nil
+}
+
+// Consume 实现 Store 接口。
+func (s *MemoryStore) Consume(key string, hash []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[key]
+	if !ok || !bytes.Equal(record.Hash, hash) {
+		return false, nil
+	}
+	delete(s.records, key)
+	return true, nil
+}
@@ -0,0 +1,86 @@
+package otp
+
+import (
+	"encoding/base64"
+	"fmt"
+	"golang.org/x/crypto/argon2"
+	"strings"
+)
+
+// Argon2Params Argon2id 秘钥派生参数。
+//
+// 可以通过 String 方法编码成带版本号的字符串与账户一起存储，之后使用 ParseArgon2Params 还原，
+// 这样在调整参数之后仍然可以派生出旧账户的秘钥。
+type Argon2Params struct {
+	// 内存开销，单位 KiB。
+	Memory uint32
+	// 迭代次数。
+	Time uint32
+	// 并行度。
+	Threads uint8
+	// 派生出的秘钥字节数，建议与 hmac 算法匹配：SHA1 20 字节、SHA256 32 字节、SHA512 64 字节。
+	KeyLen uint32
+	// 盐值，建议至少 16 字节。
+	Salt []byte
+}
+
+// DefaultArgon2Params 返回 RFC 9106 推荐的参数（64 MiB 内存，3 次迭代，4 并行度），
+// 派生 20 字节的秘钥并使用 16 字节的随机盐值。
+//
+// See https://datatracker.ietf.org/doc/html/rfc9106#section-4
+func DefaultArgon2Params() Argon2Params {
+	return Argon2Params{
+		Memory:  64 * 1024,
+		Time:    3,
+		Threads: 4,
+		KeyLen:  20,
+		Salt:    RandomSecret(16),
+	}
+}
+
+// String 将参数编码为 PHC 字符串格式（不包含派生结果）。
+//
+// Example:
+//
+//	$argon2id$v=19$m=65536,t=3,p=4,l=20$c29tZXNhbHQ
+func (p Argon2Params) String() string {
+	salt := base64.RawStdEncoding.EncodeToString(p.Salt)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d,l=%d$%s", argon2.Version, p.Memory, p.Time, p.Threads, p.KeyLen, salt)
+}
+
+// ParseArgon2Params 解析 String 方法编码的参数，不支持的版本或格式错误将会返回 ErrArgon2Params。
+func ParseArgon2Params(str string) (Argon2Params, error) {
+	var p Argon2Params
+	parts := strings.Split(str, "$")
+	if len(parts) != 5 || parts[0] != "" || parts[1] != "argon2id" {
+		return p, ErrArgon2Params
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, ErrArgon2Params
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d,l=%d", &p.Memory, &p.Time, &p.Threads, &p.KeyLen); err != nil {
+		return p, ErrArgon2Params
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, ErrArgon2Params
+	}
+	p.Salt = salt
+	if p.Memory == 0 || p.Time == 0 || p.Threads == 0 || p.KeyLen == 0 {
+		return p, ErrArgon2Params
+	}
+	return p, nil
+}
+
+// DeriveSecretArgon2id 使用 Argon2id 从口令派生出一个秘钥，相同的口令和参数总是得到相同的秘钥。
+//
+// Example:
+//
+//	params := DefaultArgon2Params()
+//	secret := Base32Encode(DeriveSecretArgon2id([]byte("passphrase"), params))
+//	totp   := NewTOTP(secret)
+//	// 保存 params.String() 以便之后重新派生
+func DeriveSecretArgon2id(passphrase []byte, params Argon2Params) []byte {
+	return argon2.IDKey(passphrase, params.Salt, params.Time, params.Memory, params.Threads, params.KeyLen)
+}
@@ -0,0 +1,52 @@
+package otp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDeriveSecretArgon2id(t *testing.T) {
+	params := Argon2Params{Memory: 1024, Time: 1, Threads: 1, KeyLen: 20, Salt: []byte("somesaltsomesalt")}
+	secret1 := DeriveSecretArgon2id([]byte("passphrase"), params)
+	secret2 := DeriveSecretArgon2id([]byte("passphrase"), params)
+	assert.Equal(t, 20, len(secret1))
+	assert.Equal(t, secret1, secret2)
+
+	params.Salt = []byte("othersaltothersalt")
+	assert.NotEqual(t, secret1, DeriveSecretArgon2id([]byte("passphrase"), params))
+
+	// 派生出来的秘钥可以直接用于 TOTP
+	totp := NewTOTP(Base32Encode(secret1))
+	assert.Equal(t, true, totp.Verify(totp.Now(), time.Now()))
+}
+
+func TestDefaultArgon2Params(t *testing.T) {
+	params := DefaultArgon2Params()
+	assert.Equal(t, uint32(64*1024), params.Memory)
+	assert.Equal(t, 16, len(params.Salt))
+	assert.NotEqual(t, params.Salt, DefaultArgon2Params().Salt)
+}
+
+func TestArgon2Params_String(t *testing.T) {
+	params := Argon2Params{Memory: 65536, Time: 3, Threads: 4, KeyLen: 20, Salt: []byte("somesalt")}
+	encoded := params.String()
+	assert.Equal(t, "$argon2id$v=19$m=65536,t=3,p=4,l=20$c29tZXNhbHQ", encoded)
+
+	parsed, err := ParseArgon2Params(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, params, parsed)
+
+	var errorParams = []string{
+		"",
+		"$argon2i$v=19$m=65536,t=3,p=4,l=20$c29tZXNhbHQ",
+		"$argon2id$v=16$m=65536,t=3,p=4,l=20$c29tZXNhbHQ",
+		"$argon2id$v=19$m=65536,t=3$c29tZXNhbHQ",
+		"$argon2id$v=19$m=0,t=3,p=4,l=20$c29tZXNhbHQ",
+		"$argon2id$v=19$m=65536,t=3,p=4,l=20$!!!",
+	}
+	for _, str := range errorParams {
+		_, err := ParseArgon2Params(str)
+		assert.Equal(t, ErrArgon2Params, err)
+	}
+}
@@ -0,0 +1,79 @@
+package otp
+
+import "time"
+
+// Candidate 一组 TOTP 参数组合，用于探测来源不明的秘钥实际使用的参数。
+type Candidate struct {
+	Algorithm Algorithms
+	Digits    Digits
+	Period    int
+}
+
+// Options 将参数组合转换为 TOTPOption，可以直接传递给 NewTOTP。
+//
+// Example:
+//
+//	c, ok := TryVerify(secret, token, time.Now())
+//	if ok {
+//		totp := NewTOTP(secret, c.Options()...)
+//	}
+func (c Candidate) Options() []TOTPOption {
+	return []TOTPOption{WithAlgorithm(c.Algorithm), WithDigits(c.Digits), WithPeriod(c.Period)}
+}
+
+// DefaultCandidates 返回常见的参数组合：SHA1/SHA256/SHA512 × 6/8 位 × 30/60 秒。
+//
+// 按照常见程度排序，第一个是 Google Authenticator 的默认参数。
+func DefaultCandidates() []Candidate {
+	var candidates []Candidate
+	for _, period := range []int{30, 60} {
+		for _, digits := range []Digits{DigitsSix, DigitsEight} {
+			for _, algorithm := range []Algorithms{AlgorithmSHA1, AlgorithmSHA256, AlgorithmSHA512} {
+				candidates = append(candidates, Candidate{Algorithm: algorithm, Digits: digits, Period: period})
+			}
+		}
+	}
+	return candidates
+}
+
+// TryVerify 使用多组参数组合依次校验 token，返回第一个校验通过的参数组合。
+//
+// 适用于导入来源不明的秘钥时，通过用户输入的一个 token 自动识别正确的参数。
+// 不传 candidates 时使用 DefaultCandidates，period 小于 10 的组合会被跳过。
+//
+// Params:
+//
+//	secret    : base32 编码后的秘钥，如果为空或无法解码将会返回 false。
+//	token     : 用户输入的 token。
+//	t         : 校验的时间点。
+//	candidates: 需要尝试的参数组合，按顺序尝试。
+func TryVerify(secret, token string, t time.Time, candidates ...Candidate) (Candidate, bool) {
+	if secret == "" || token == "" {
+		return Candidate{}, false
+	}
+	decodedSecret, err := Base32Decode(secret)
+	if err != nil {
+		return Candidate{}, false
+	}
+	if len(candidates) == 0 {
+		candidates = DefaultCandidates()
+	}
+	for _, c := range candidates {
+		if c.Period < minPeriodNumber || len(token) != int(c.Digits) {
+			continue
+		}
+		totp := &TOTP{
+			Otp: Otp{
+				Period:    c.Period,
+				Digits:    c.Digits,
+				Algorithm: c.Algorithm,
+			},
+			Secret:        secret,
+			decodedSecret: decodedSecret,
+		}
+		if totp.Verify(token, t) {
+			return c, true
+		}
+	}
+	return Candidate{}, false
+}
@@ -0,0 +1,47 @@
+package otp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDefaultCandidates(t *testing.T) {
+	candidates := DefaultCandidates()
+	assert.Equal(t, 12, len(candidates))
+	assert.Equal(t, Candidate{Algorithm: AlgorithmSHA1, Digits: DigitsSix, Period: 30}, candidates[0])
+}
+
+func TestTryVerify(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+
+	t.Run("detect default parameters", func(t *testing.T) {
+		c, ok := TryVerify(TestSecret20, "076141", now)
+		assert.Equal(t, true, ok)
+		assert.Equal(t, Candidate{Algorithm: AlgorithmSHA1, Digits: DigitsSix, Period: 30}, c)
+	})
+
+	t.Run("detect custom parameters", func(t *testing.T) {
+		expected := Candidate{Algorithm: AlgorithmSHA512, Digits: DigitsEight, Period: 60}
+		token := NewTOTP(TestSecret32, expected.Options()...).At(now)
+		c, ok := TryVerify(TestSecret32, token, now)
+		assert.Equal(t, true, ok)
+		assert.Equal(t, expected, c)
+	})
+
+	t.Run("custom candidates", func(t *testing.T) {
+		_, ok := TryVerify(TestSecret20, "076141", now, Candidate{Algorithm: AlgorithmSHA256, Digits: DigitsSix, Period: 30})
+		assert.Equal(t, false, ok)
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		_, ok := TryVerify("", "076141", now)
+		assert.Equal(t, false, ok)
+		_, ok = TryVerify("111111", "076141", now)
+		assert.Equal(t, false, ok)
+		_, ok = TryVerify(TestSecret20, "", now)
+		assert.Equal(t, false, ok)
+		_, ok = TryVerify(TestSecret20, "076141", now, Candidate{Algorithm: AlgorithmSHA1, Digits: DigitsSix, Period: 0})
+		assert.Equal(t, false, ok)
+	})
+}
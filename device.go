@@ -0,0 +1,207 @@
+package otp
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TrustedDevice 一个被用户标记为"记住此设备"的设备。
+//
+// 只保存令牌秘密部分和设备指纹的 SHA256 摘要，存储泄露时无法还原出可用的令牌。
+type TrustedDevice struct {
+	// 设备 id，令牌的第一部分
+	ID string
+	// 所属账户
+	Account string
+	// 令牌秘密部分的 SHA256 摘要
+	SecretHash []byte
+	// 设备指纹的 SHA256 摘要
+	FingerprintHash []byte
+	CreatedAt       time.Time
+	ExpiresAt       time.Time
+}
+
+// DeviceStore 保存受信任的设备，找不到设备时 Get 应该返回 ErrDeviceNotTrusted。
+type DeviceStore interface {
+	Put(device TrustedDevice) error
+	Get(id string) (TrustedDevice, error)
+	Delete(id string) error
+	// DeleteAccount 删除账户的所有设备，例如用户修改密码或重新绑定验证器之后。
+	DeleteAccount(account string) error
+	// Prune 删除在 now 之前已经过期的设备，返回删除的数量。
+	Prune(now time.Time) (int, error)
+}
+
+// DeviceTrust 在 OTP 校验通过后为设备签发长期有效、可撤销的令牌，持有令牌的设备在有效期内可以跳过 OTP 校验。
+//
+// 令牌与账户和设备指纹绑定，指纹由调用方提供，例如 User-Agent 加上客户端生成的随机 id。
+type DeviceTrust struct {
+	Store DeviceStore
+	// 令牌的有效期
+	TTL   time.Duration
+	clock func() time.Time
+}
+
+// NewDeviceTrust 创建一个 DeviceTrust。
+//
+// Example:
+//
+//	trust := NewDeviceTrust(NewMemoryDeviceStore(), 30*24*time.Hour)
+//	if totp.Verify(token, time.Now()) && rememberDevice {
+//		deviceToken, err := trust.Trust(userID, fingerprint)
+//		setCookie("device", deviceToken)
+//	}
+//	// 之后登录时
+//	if trust.Verify(cookie, userID, fingerprint) == nil {
+//		// 跳过 OTP 校验
+//	}
+func NewDeviceTrust(store DeviceStore, ttl time.Duration) *DeviceTrust {
+	return &DeviceTrust{Store: store, TTL: ttl}
+}
+
+// now 返回当前时间。
+func (d *DeviceTrust) now() time.Time {
+	if d.clock != nil {
+		return d.clock()
+	}
+	return time.Now()
+}
+
+// Trust 为 account 的设备签发令牌，令牌格式为 id.secret，需要由调用方保存在设备上（例如 cookie）。
+func (d *DeviceTrust) Trust(account, fingerprint string) (string, error) {
+	random, err := randomSecret(48)
+	if err != nil {
+		return "", err
+	}
+	id := base64.RawURLEncoding.EncodeToString(random[:16])
+	secret := base64.RawURLEncoding.EncodeToString(random[16:])
+	now := d.now()
+	device := TrustedDevice{
+		ID:              id,
+		Account:         account,
+		SecretHash:      sha256Sum(secret),
+		FingerprintHash: sha256Sum(fingerprint),
+		CreatedAt:       now,
+		ExpiresAt:       now.Add(d.TTL),
+	}
+	if err := d.Store.Put(device); err != nil {
+		return "", err
+	}
+	return id + "." + secret, nil
+}
+
+// Verify 校验令牌是否属于 account 且由相同指纹的设备持有。
+//
+// 令牌无效、账户或指纹不匹配时返回 ErrDeviceNotTrusted，已过期时返回 ErrDeviceExpired，存储出错时返回对应的错误。
+func (d *DeviceTrust) Verify(token, account, fingerprint string) error {
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok || id == "" || secret == "" {
+		return ErrDeviceNotTrusted
+	}
+	device, err := d.Store.Get(id)
+	if err != nil {
+		return err
+	}
+	secretOK := subtle.ConstantTimeCompare(device.SecretHash, sha256Sum(secret)) == 1
+	fingerprintOK := subtle.ConstantTimeCompare(device.FingerprintHash, sha256Sum(fingerprint)) == 1
+	if !secretOK || !fingerprintOK || device.Account != account {
+		return ErrDeviceNotTrusted
+	}
+	if !d.now().Before(device.ExpiresAt) {
+		return ErrDeviceExpired
+	}
+	return nil
+}
+
+// Revoke 撤销令牌对应的设备，令牌格式错误时不做任何处理。
+func (d *DeviceTrust) Revoke(token string) error {
+	id, _, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil
+	}
+	return d.Store.Delete(id)
+}
+
+// RevokeAll 撤销账户的所有设备。
+func (d *DeviceTrust) RevokeAll(account string) error {
+	return d.Store.DeleteAccount(account)
+}
+
+// Prune 删除已经过期的设备，建议定期调用。
+func (d *DeviceTrust) Prune() (int, error) {
+	return d.Store.Prune(d.now())
+}
+
+// sha256Sum 返回字符串的 SHA256 摘要。
+func sha256Sum(str string) []byte {
+	sum := sha256.Sum256([]byte(str))
+	return sum[:]
+}
+
+// MemoryDeviceStore 基于内存的 DeviceStore 实现，并发安全。
+type MemoryDeviceStore struct {
+	mu      sync.RWMutex
+	devices map[string]TrustedDevice
+}
+
+// NewMemoryDeviceStore 创建一个 MemoryDeviceStore。
+func NewMemoryDeviceStore() *MemoryDeviceStore {
+	return &MemoryDeviceStore{devices: map[string]TrustedDevice{}}
+}
+
+// Put 实现 DeviceStore 接口。
+func (s *MemoryDeviceStore) Put(device TrustedDevice) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.devices[device.ID] = device
+	return nil
+}
+
+// Get 实现 DeviceStore 接口。
+func (s *MemoryDeviceStore) Get(id string) (TrustedDevice, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	device, ok := s.devices[id]
+	if !ok {
+		return TrustedDevice{}, ErrDeviceNotTrusted
+	}
+	return device, nil
+}
+
+// Delete 实现 DeviceStore 接口。
+func (s *MemoryDeviceStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.devices, id)
+	return nil
+}
+
+// DeleteAccount 实现 DeviceStore 接口。
+func (s *MemoryDeviceStore) DeleteAccount(account string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, device := range s.devices {
+		if device.Account == account {
+			delete(s.devices, id)
+		}
+	}
+	return nil
+}
+
+// Prune 实现 DeviceStore 接口。
+func (s *MemoryDeviceStore) Prune(now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for id, device := range s.devices {
+		if !now.Before(device.ExpiresAt) {
+			delete(s.devices, id)
+			n++
+		}
+	}
+	return n, nil
+}
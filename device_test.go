@@ -0,0 +1,71 @@
+package otp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeviceTrust(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	store := NewMemoryDeviceStore()
+	trust := NewDeviceTrust(store, 30*24*time.Hour)
+	trust.clock = func() time.Time { return now }
+
+	token, err := trust.Trust("alice", "firefox/linux")
+	assert.Nil(t, err)
+	assert.Nil(t, trust.Verify(token, "alice", "firefox/linux"))
+
+	// 存储中不保存明文令牌和指纹
+	id, secret, _ := strings.Cut(token, ".")
+	device, err := store.Get(id)
+	assert.Nil(t, err)
+	assert.Equal(t, sha256Sum(secret), device.SecretHash)
+	assert.Equal(t, sha256Sum("firefox/linux"), device.FingerprintHash)
+
+	assert.ErrorIs(t, trust.Verify(token, "bob", "firefox/linux"), ErrDeviceNotTrusted)
+	assert.ErrorIs(t, trust.Verify(token, "alice", "chrome/linux"), ErrDeviceNotTrusted)
+	assert.ErrorIs(t, trust.Verify(id+".forged", "alice", "firefox/linux"), ErrDeviceNotTrusted)
+	assert.ErrorIs(t, trust.Verify("unknown."+secret, "alice", "firefox/linux"), ErrDeviceNotTrusted)
+	assert.ErrorIs(t, trust.Verify("", "alice", "firefox/linux"), ErrDeviceNotTrusted)
+	assert.ErrorIs(t, trust.Verify(id, "alice", "firefox/linux"), ErrDeviceNotTrusted)
+
+	now = now.Add(30 * 24 * time.Hour)
+	assert.ErrorIs(t, trust.Verify(token, "alice", "firefox/linux"), ErrDeviceExpired)
+}
+
+func TestDeviceTrust_Revoke(t *testing.T) {
+	trust := NewDeviceTrust(NewMemoryDeviceStore(), time.Hour)
+	laptop, _ := trust.Trust("alice", "laptop")
+	phone, _ := trust.Trust("alice", "phone")
+	other, _ := trust.Trust("bob", "laptop")
+
+	assert.Nil(t, trust.Revoke(laptop))
+	assert.ErrorIs(t, trust.Verify(laptop, "alice", "laptop"), ErrDeviceNotTrusted)
+	assert.Nil(t, trust.Verify(phone, "alice", "phone"))
+
+	assert.Nil(t, trust.RevokeAll("alice"))
+	assert.ErrorIs(t, trust.Verify(phone, "alice", "phone"), ErrDeviceNotTrusted)
+	assert.Nil(t, trust.Verify(other, "bob", "laptop"))
+
+	assert.Nil(t, trust.Revoke("malformed"))
+}
+
+func TestDeviceTrust_Prune(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	store := NewMemoryDeviceStore()
+	trust := NewDeviceTrust(store, time.Hour)
+	trust.clock = func() time.Time { return now }
+
+	old, _ := trust.Trust("alice", "laptop")
+	now = now.Add(30 * time.Minute)
+	recent, _ := trust.Trust("alice", "phone")
+
+	now = now.Add(45 * time.Minute)
+	n, err := trust.Prune()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.ErrorIs(t, trust.Verify(old, "alice", "laptop"), ErrDeviceNotTrusted)
+	assert.Nil(t, trust.Verify(recent, "alice", "phone"))
+}
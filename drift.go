@@ -0,0 +1,93 @@
+package otp
+
+import (
+	"sync"
+	"time"
+)
+
+// VerifyWithDrift 与 Verify 相同，额外返回匹配的时间步相对于 t 所在时间步的偏移量。
+//
+// 偏移量为负数表示客户端的时钟慢于服务端，为正数表示快于服务端，校验失败时返回 0。
+// 偏移量的范围受 WithSkew、WithSkewWindow 配置的窗口限制。
+//
+// Example:
+//
+//	totp := NewTOTP(secret, WithSkew(1))
+//	ok, offset := totp.VerifyWithDrift(token, time.Now()) // offset 为 -1、0 或 1
+func (o *TOTP) VerifyWithDrift(token string, t time.Time) (bool, int) {
+	step, ok := o.VerifyWithMatch(token, t)
+	if !ok {
+		return false, 0
+	}
+	return true, int(step - o.step(t))
+}
+
+// DriftTracker 为每个用户记录最后一次观察到的时钟偏移（以时间步为单位），并在之后的校验中以该偏移为中心校验，并发安全。
+//
+// 客户端设备的时钟通常是缓慢漂移的，记录偏移之后只需要很小的 skew 窗口就可以持续接受这些客户端，
+// 而不需要扩大所有用户的校验窗口。
+//
+// 只适用于单实例部署，偏移量只保存在内存中。
+type DriftTracker struct {
+	// 允许记录的最大偏移量（时间步数），超出的部分会被截断。
+	MaxOffset int
+	mu        sync.Mutex
+	offsets   map[string]int
+}
+
+// NewDriftTracker 创建一个 DriftTracker，maxOffset 为允许记录的最大偏移量（时间步数）。
+//
+// Example:
+//
+//	tracker := NewDriftTracker(10) // 最多接受 5 分钟的时钟偏移
+//	totp    := NewTOTP(secret, WithSkew(1))
+//	tracker.Verify(userID, totp, token, time.Now())
+func NewDriftTracker(maxOffset int) *DriftTracker {
+	if maxOffset < 0 {
+		maxOffset = 0
+	}
+	return &DriftTracker{MaxOffset: maxOffset, offsets: map[string]int{}}
+}
+
+// Offset 返回 user 当前记录的偏移量，没有记录时返回 0。
+func (d *DriftTracker) Offset(user string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.offsets[user]
+}
+
+// Verify 使用 user 记录的偏移量调整 t 之后校验 token，校验通过时更新记录的偏移量。
+func (d *DriftTracker) Verify(user string, totp *TOTP, token string, t time.Time) bool {
+	offset := d.Offset(user)
+	shifted := t.Add(time.Duration(offset*totp.Period) * time.Second)
+	ok, drift := totp.VerifyWithDrift(token, shifted)
+	if !ok {
+		return false
+	}
+	d.record(user, offset+drift)
+	return true
+}
+
+// Reset 清除 user 记录的偏移量，例如用户重新绑定设备之后。
+func (d *DriftTracker) Reset(user string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.offsets, user)
+}
+
+// record 记录 user 的偏移量，超出 MaxOffset 的部分会被截断。
+func (d *DriftTracker) record(user string, offset int) {
+	if offset > d.MaxOffset {
+		offset = d.MaxOffset
+	}
+	if offset < -d.MaxOffset {
+		offset = -d.MaxOffset
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if offset == 0 {
+		delete(d.offsets, user)
+		return
+	}
+	d.offsets[user] = offset
+}
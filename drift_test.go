@@ -0,0 +1,55 @@
+package otp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestTOTP_VerifyWithDrift(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+	totp := NewTOTP(TestSecret20, WithSkew(2))
+	for _, offset := range []int{-2, -1, 0, 1, 2} {
+		token := totp.At(now.Add(time.Duration(offset*30) * time.Second))
+		ok, drift := totp.VerifyWithDrift(token, now)
+		assert.True(t, ok)
+		assert.Equal(t, offset, drift)
+	}
+	ok, drift := totp.VerifyWithDrift(totp.At(now.Add(time.Second*90)), now)
+	assert.False(t, ok)
+	assert.Equal(t, 0, drift)
+	ok, _ = totp.VerifyWithDrift("", now)
+	assert.False(t, ok)
+}
+
+func TestDriftTracker(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+	tracker := NewDriftTracker(3)
+	totp := NewTOTP(TestSecret20, WithSkew(1))
+	// 客户端的时钟每次都再慢一个时间步
+	client := func(steps int) string {
+		return totp.At(now.Add(time.Duration(-steps*30) * time.Second))
+	}
+
+	assert.True(t, tracker.Verify("alice", totp, client(1), now))
+	assert.Equal(t, -1, tracker.Offset("alice"))
+	assert.True(t, tracker.Verify("alice", totp, client(2), now))
+	assert.Equal(t, -2, tracker.Offset("alice"))
+	assert.True(t, tracker.Verify("alice", totp, client(3), now))
+	assert.Equal(t, -3, tracker.Offset("alice"))
+	// 超出 MaxOffset 的部分被截断
+	assert.True(t, tracker.Verify("alice", totp, client(4), now))
+	assert.Equal(t, -3, tracker.Offset("alice"))
+	assert.False(t, tracker.Verify("alice", totp, client(5), now))
+
+	// 没有记录偏移的用户只接受 skew 窗口内的 token
+	assert.False(t, tracker.Verify("bob", totp, client(2), now))
+	assert.Equal(t, 0, tracker.Offset("bob"))
+
+	// 失败的校验不会改变记录的偏移量
+	assert.False(t, tracker.Verify("alice", totp, "000000", now))
+	assert.Equal(t, -3, tracker.Offset("alice"))
+	tracker.Reset("alice")
+	assert.Equal(t, 0, tracker.Offset("alice"))
+	assert.Equal(t, 0, NewDriftTracker(-1).MaxOffset)
+}
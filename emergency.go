@@ -0,0 +1,68 @@
+package otp
+
+import (
+	"context"
+	"crypto/hmac"
+)
+
+// DefaultEmergencyPeriod Emergency 派生的紧急 token 默认的时间窗口长度（秒），即 30 分钟。
+const DefaultEmergencyPeriod = 30 * 60
+
+// emergencyDomain 派生紧急 token 秘钥时使用的域分隔字符串，长度与 8 字节的计数器不同，不会与正常的 token 计算冲突。
+const emergencyDomain = "go-otp emergency totp v1"
+
+// Emergency 使用同一个秘钥派生一个时间窗口更长的 TOTP，用于通过短信、邮件等备用渠道下发的紧急验证码。
+//
+// 派生的秘钥为 HMAC(秘钥, "go-otp emergency totp v1")，与主 token 流相互独立：知道紧急验证码无法推算出认证器应用中的 token，
+// 反之亦然，用户只需要开通一个秘钥。返回的 TOTP 继承当前的参数（包括 WithReplayGuard、WithHooks），
+// 时间窗口默认为 DefaultEmergencyPeriod，可以通过 options 覆盖，例如 WithPeriod、WithDigits。
+//
+// 使用 NewTOTPWithSigner 创建时 signer 需要能够对任意长度的数据签名，派生的秘钥保存在返回的 TOTP 中；
+// 使用 NewTOTPFromStore 创建时会读取一次秘钥。派生的秘钥不应该通过 KeyURI 下发给认证器应用。
+//
+// options 配置了超出范围的参数时返回 ErrInvalidOption，读取秘钥失败时返回对应的错误。
+//
+// Example:
+//
+//	emergency, err := totp.Emergency(WithDigits(DigitsEight))
+//	if err != nil {
+//		return err
+//	}
+//	sms.Send(phone, emergency.Now())
+//	// 用户提交后
+//	ok := emergency.Verify(token, time.Now())
+func (o *TOTP) Emergency(options ...TOTPOption) (*TOTP, error) {
+	otp := o.Otp
+	otp.Period = DefaultEmergencyPeriod
+	otp.optionErr = nil
+	for _, opt := range options {
+		opt.applyTOTP(&otp)
+	}
+	if err := otp.Validate(); err != nil {
+		return nil, err
+	}
+	secret, err := o.emergencySecret()
+	if err != nil {
+		return nil, err
+	}
+	return &TOTP{
+		Otp:           otp,
+		Secret:        Base32Encode(secret),
+		decodedSecret: secret,
+	}, nil
+}
+
+// emergencySecret 计算紧急 token 使用的派生秘钥。
+func (o *TOTP) emergencySecret() ([]byte, error) {
+	if o.signer != nil {
+		return o.signer.Sign([]byte(emergencyDomain))
+	}
+	secret, err := o.secretContext(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer releaseSecret(o.store, secret)()
+	mac := hmac.New(hasher(o.Algorithm), secret)
+	mac.Write([]byte(emergencyDomain))
+	return mac.Sum(nil), nil
+}
@@ -0,0 +1,64 @@
+package otp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestTOTP_Emergency(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	totp := NewTOTP(TestSecret20)
+	emergency, err := totp.Emergency()
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultEmergencyPeriod, emergency.Period)
+	assert.NotEqual(t, totp.Secret, emergency.Secret)
+	token := emergency.At(now)
+	assert.Equal(t, "942853", token)
+	assert.NotEqual(t, totp.At(now), token)
+	// 在整个 30 分钟的窗口内有效
+	start := emergency.stepStart(emergency.StepFor(now))
+	assert.True(t, emergency.Verify(token, start))
+	assert.True(t, emergency.Verify(token, start.Add(29*time.Minute)))
+	assert.False(t, emergency.Verify(token, start.Add(30*time.Minute)))
+	assert.False(t, totp.Verify(token, now))
+
+	// 使用 Signer、SecretStore 创建时派生相同的秘钥
+	secret, _ := Base32Decode(TestSecret20)
+	signed, err := NewTOTPWithSigner(NewHMACSigner(AlgorithmSHA1, secret)).Emergency()
+	assert.Nil(t, err)
+	assert.Equal(t, emergency.Secret, signed.Secret)
+	store := NewMemorySecretStore()
+	assert.Nil(t, store.Put("alice", secret))
+	stored, _ := NewTOTPFromStore(store, "alice")
+	fromStore, err := stored.Emergency()
+	assert.Nil(t, err)
+	assert.Equal(t, emergency.Secret, fromStore.Secret)
+	assert.Nil(t, store.Delete("alice"))
+	_, err = stored.Emergency()
+	assert.ErrorIs(t, err, ErrSecretNotFound)
+
+	// 派生的秘钥与算法相关，options 覆盖继承的参数
+	sha256 := NewTOTP(TestSecret20, WithAlgorithm(AlgorithmSHA256), WithSkew(1))
+	custom, err := sha256.Emergency(WithPeriod(3600), WithDigits(DigitsEight))
+	assert.Nil(t, err)
+	assert.NotEqual(t, emergency.Secret, custom.Secret)
+	assert.Equal(t, 3600, custom.Period)
+	assert.Equal(t, 1, custom.Skew)
+	assert.Len(t, custom.At(now), 8)
+
+	_, err = totp.Emergency(WithPeriod(0))
+	assert.ErrorIs(t, err, ErrInvalidOption)
+}
+
+func TestTOTP_Emergency_ReplayGuard(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	totp := NewTOTP(TestSecret20, WithReplayGuard(NewMemoryReplayGuard()))
+	emergency, err := totp.Emergency()
+	assert.Nil(t, err)
+	// 主 token 流与紧急 token 流的使用记录互不影响
+	assert.True(t, totp.Verify(totp.At(now), now))
+	token := emergency.At(now)
+	assert.True(t, emergency.Verify(token, now))
+	assert.False(t, emergency.Verify(token, now))
+}
@@ -0,0 +1,141 @@
+package otp
+
+import (
+	"encoding/base32"
+	"strings"
+	"unicode"
+)
+
+const (
+	// base32HexAlphabet RFC 4648 第 7 节定义的 base32hex 字母表
+	base32HexAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUV"
+	// crockfordAlphabet Crockford Base32 字母表，不包含 I、L、O、U
+	crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+var (
+	base32HexEncoding = base32.HexEncoding.WithPadding(base32.NoPadding)
+	crockfordEncoding = base32.NewEncoding(crockfordAlphabet).WithPadding(base32.NoPadding)
+)
+
+// SecretEncoding 秘钥字符串使用的编码方式。
+//
+// 默认值：SecretEncodingBase32，即 RFC 4648 的标准 base32，Key Uri Format 中的 secret 参数总是使用这种编码。
+// 部分令牌厂商和内部系统使用其他字母表分发种子，可以通过 WithSecretEncoding 或 DecodeSecret 解码。
+type SecretEncoding int
+
+const (
+	SecretEncodingBase32 SecretEncoding = iota
+	// SecretEncodingBase32Hex RFC 4648 第 7 节定义的 base32hex，字母表为 0-9A-V。
+	SecretEncodingBase32Hex
+	// SecretEncodingCrockford Crockford Base32，解码时 I、L 按 1 处理，O 按 0 处理。
+	SecretEncodingCrockford
+)
+
+// String 枚举值转换为字符串形式。
+func (e SecretEncoding) String() string {
+	switch e {
+	case SecretEncodingBase32:
+		return "base32"
+	case SecretEncodingBase32Hex:
+		return "base32hex"
+	case SecretEncodingCrockford:
+		return "crockford"
+	default:
+		panic("unreachable")
+	}
+}
+
+// Base32HexEncode 使用 RFC 4648 的 base32hex 字母表编码，不填充。
+func Base32HexEncode(str []byte) string {
+	return base32HexEncoding.EncodeToString(str)
+}
+
+// Base32HexDecode 对一个 base32hex 字符串进行解码，忽略大小写。
+//
+// 解码失败时返回 *SecretDecodeError，其中包含出错的字符和位置。
+func Base32HexDecode(str string) ([]byte, error) {
+	upper := strings.Map(upperASCII, str)
+	decoded, err := base32HexEncoding.DecodeString(upper)
+	if err != nil {
+		return nil, newSecretDecodeError(upper, base32HexAlphabet, nil)
+	}
+	return decoded, nil
+}
+
+// CrockfordEncode 使用 Crockford Base32 字母表编码，不填充也不添加校验字符。
+func CrockfordEncode(str []byte) string {
+	return crockfordEncoding.EncodeToString(str)
+}
+
+// CrockfordDecode 对一个 Crockford Base32 字符串进行解码，忽略大小写和连字符，I、L 按 1 处理，O 按 0 处理。
+//
+// 解码失败时返回 *SecretDecodeError，其中包含出错的字符和位置（按去掉连字符之后的字符计算）。
+func CrockfordDecode(str string) ([]byte, error) {
+	normalized := strings.Map(func(r rune) rune {
+		switch r = upperASCII(r); r {
+		case '-':
+			return -1
+		case 'I', 'L':
+			return '1'
+		case 'O':
+			return '0'
+		default:
+			return r
+		}
+	}, str)
+	decoded, err := crockfordEncoding.DecodeString(normalized)
+	if err != nil {
+		return nil, newSecretDecodeError(normalized, crockfordAlphabet, nil)
+	}
+	return decoded, nil
+}
+
+// DecodeSecret 使用指定的编码解码秘钥字符串，解码之前与 NormalizeSecret 一样去掉空白字符、连字符以及末尾的 = 填充。
+//
+// 为空时返回 ErrSecretCannotBeEmpty，无法解码时返回 *SecretDecodeError。
+//
+// Example:
+//
+//	secret, err := DecodeSecret("9RMQNFPFT73ONOH1S7IUPBKUCUL1EU2U", SecretEncodingBase32Hex)
+//	totp := NewTOTPFromBytes(secret)
+func DecodeSecret(secret string, encoding SecretEncoding) ([]byte, error) {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || unicode.IsSpace(r) {
+			return -1
+		}
+		return upperASCII(r)
+	}, secret)
+	normalized = strings.TrimRight(normalized, "=")
+	if normalized == "" {
+		return nil, ErrSecretCannotBeEmpty
+	}
+	switch encoding {
+	case SecretEncodingBase32Hex:
+		return Base32HexDecode(normalized)
+	case SecretEncodingCrockford:
+		return CrockfordDecode(normalized)
+	default:
+		return Base32Decode(normalized)
+	}
+}
+
+// decodeSecret 按照 WithSecretEncoding 配置的编码解码秘钥，返回标准 base32 编码的秘钥和解码后的秘钥。
+func (o Otp) decodeSecret(secret string) (string, []byte, error) {
+	if o.secretEncoding == SecretEncodingBase32 {
+		secret, err := NormalizeSecret(secret)
+		if err != nil {
+			return "", nil, err
+		}
+		decoded, err := Base32Decode(secret)
+		if err != nil {
+			return "", nil, err
+		}
+		return secret, decoded, nil
+	}
+	decoded, err := DecodeSecret(secret, o.secretEncoding)
+	if err != nil {
+		return "", nil, err
+	}
+	return Base32Encode(decoded), decoded, nil
+}
@@ -0,0 +1,72 @@
+package otp
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// TestSecret20 使用 base32hex 和 Crockford Base32 编码后的结果
+const (
+	testSecret20Hex       = "9RMQNFPFT73ONOH1S7IUPBKUCUL1EU2U"
+	testSecret20Crockford = "9VPTQFSFX73RQRH1W7JYSBMYCYN1EY2Y"
+)
+
+func TestSecretEncoding(t *testing.T) {
+	expected, _ := Base32Decode(TestSecret20)
+	assert.Equal(t, testSecret20Hex, Base32HexEncode(expected))
+	assert.Equal(t, testSecret20Crockford, CrockfordEncode(expected))
+
+	for _, tt := range []struct {
+		secret   string
+		encoding SecretEncoding
+	}{
+		{TestSecret20, SecretEncodingBase32},
+		{"j3w2 xpzp 5hdy xyrb 4hs6 zlu6 m6vb o6c6", SecretEncodingBase32},
+		{testSecret20Hex, SecretEncodingBase32Hex},
+		{"9rmq-nfpf-t73o-noh1-s7iu-pbku-cul1-eu2u", SecretEncodingBase32Hex},
+		{testSecret20Crockford, SecretEncodingCrockford},
+		// I、L 按 1 处理，O 按 0 处理
+		{"9VPTQFSFX73RQRHIW7JYSBMYCYNLEY2Y", SecretEncodingCrockford},
+	} {
+		actual, err := DecodeSecret(tt.secret, tt.encoding)
+		assert.Nil(t, err, tt.secret)
+		assert.Equal(t, expected, actual, tt.secret)
+	}
+
+	_, err := DecodeSecret(" - ", SecretEncodingCrockford)
+	assert.Equal(t, ErrSecretCannotBeEmpty, err)
+
+	_, err = Base32HexDecode("9RMQNFPFT73ONOH1S7IUPBKUCUL1EU2W")
+	var decodeErr *SecretDecodeError
+	assert.True(t, errors.As(err, &decodeErr))
+	assert.Equal(t, 31, decodeErr.Position)
+	assert.Equal(t, 'W', decodeErr.Char)
+
+	_, err = CrockfordDecode("9VPTQFSFX73RQRH1W7JUSBMYCYN1EY2Y")
+	assert.ErrorIs(t, err, ErrSecretDecode)
+}
+
+func TestWithSecretEncoding(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	expected := NewTOTP(TestSecret20)
+	for secret, encoding := range map[string]SecretEncoding{
+		testSecret20Hex:       SecretEncodingBase32Hex,
+		testSecret20Crockford: SecretEncodingCrockford,
+	} {
+		totp := NewTOTP(secret, WithSecretEncoding(encoding))
+		// Secret 字段以及 KeyURI 使用标准 base32
+		assert.Equal(t, TestSecret20, totp.Secret)
+		assert.Equal(t, expected.At(now), totp.At(now))
+
+		hotp, err := NewHOTPWithError(secret, WithSecretEncoding(encoding))
+		assert.Nil(t, err)
+		assert.Equal(t, TestSecret20, hotp.KeyURI("alice", "Example").Secret)
+	}
+
+	// 标准 base32 的秘钥无法使用 base32hex 解码
+	_, err := NewTOTPWithError(TestSecret20, WithSecretEncoding(SecretEncodingBase32Hex))
+	assert.ErrorIs(t, err, ErrSecretDecode)
+	assert.ErrorIs(t, ValidateTOTPOptions(WithSecretEncoding(SecretEncoding(9))), ErrInvalidOption)
+}
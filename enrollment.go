@@ -0,0 +1,75 @@
+package otp
+
+import "time"
+
+// EnrollmentState 开通的状态。
+type EnrollmentState int
+
+const (
+	// EnrollmentPending 已经生成秘钥和二维码，等待用户提交第一个 token 确认。
+	EnrollmentPending EnrollmentState = iota
+	// EnrollmentConfirmed 用户已经提交过有效的 token，秘钥可以用于登录。
+	EnrollmentConfirmed
+)
+
+// NewEnrollment 为单个账户生成一个待确认的开通信息，ttl 为等待确认的有效期，为 0 时不会过期。
+//
+// 标准的开通流程：生成秘钥并展示二维码 -> 用户扫码后提交一个 token -> Confirm 校验通过后才启用秘钥。
+// 在确认之前不应该要求用户使用此秘钥登录，避免用户没有正确保存秘钥而被锁在账户之外。
+//
+// Example:
+//
+//	enrollment, err := NewEnrollment("alice@google.com", "Example", 10*time.Minute)
+//	save(enrollment.Secret, enrollment.State, enrollment.ExpiresAt)
+//	show(enrollment.QRCode)
+//	// 用户提交 token 之后
+//	if err := enrollment.Confirm(token, time.Now()); err == nil {
+//		activate(enrollment.Secret)
+//	}
+func NewEnrollment(account, issuer string, ttl time.Duration, options ...TOTPOption) (*Enrollment, error) {
+	p := NewProvisioner(issuer, options...)
+	p.TTL = ttl
+	enrollments, err := p.Provision(account)
+	if err != nil {
+		return nil, err
+	}
+	return &enrollments[0], nil
+}
+
+// Expired 判断待确认的开通信息在 t 时是否已经过期，已经确认的开通信息不会过期。
+func (e *Enrollment) Expired(t time.Time) bool {
+	return e.State == EnrollmentPending && !e.ExpiresAt.IsZero() && !t.Before(e.ExpiresAt)
+}
+
+// Confirm 使用用户提交的 token 确认开通，校验通过后 State 变为 EnrollmentConfirmed。
+//
+// 已经确认过时返回 ErrEnrollmentConfirmed，过期时返回 ErrEnrollmentExpired，token 无效时返回 ErrTokenInvalid。
+//
+// 从持久化的数据中恢复时可以只设置 Secret、State 和 ExpiresAt，TOTP 为 nil 时会使用 Secret 和 options 创建，
+// options 需要与 NewEnrollment 时传入的一致（例如 WithAlgorithm、WithDigits、WithPeriod），否则无法确认。
+// TOTP 不为 nil 时 options 将被忽略。
+//
+// Example:
+//
+//	restored := &Enrollment{Secret: secret, State: EnrollmentPending, ExpiresAt: expiresAt}
+//	err := restored.Confirm(token, time.Now(), WithAlgorithm(AlgorithmSHA256), WithDigits(DigitsEight))
+func (e *Enrollment) Confirm(token string, t time.Time, options ...TOTPOption) error {
+	if e.State == EnrollmentConfirmed {
+		return ErrEnrollmentConfirmed
+	}
+	if e.Expired(t) {
+		return ErrEnrollmentExpired
+	}
+	if e.TOTP == nil {
+		totp, err := NewTOTPWithError(e.Secret, options...)
+		if err != nil {
+			return err
+		}
+		e.TOTP = totp
+	}
+	if !e.TOTP.Verify(token, t) {
+		return ErrTokenInvalid
+	}
+	e.State = EnrollmentConfirmed
+	return nil
+}
@@ -0,0 +1,70 @@
+package otp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewEnrollment(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+	clock := WithClock(func() time.Time { return now })
+	enrollment, err := NewEnrollment("alice@google.com", "Example", 10*time.Minute, clock, WithSkew(1))
+	assert.Nil(t, err)
+	assert.Equal(t, EnrollmentPending, enrollment.State)
+	assert.Equal(t, now, enrollment.CreatedAt)
+	assert.Equal(t, now.Add(10*time.Minute), enrollment.ExpiresAt)
+	assert.False(t, enrollment.Expired(now))
+	assert.True(t, enrollment.Expired(now.Add(10*time.Minute)))
+
+	_, err = NewEnrollment("", "Example", time.Minute)
+	assert.ErrorIs(t, err, ErrLabelEmpty)
+
+	forever, _ := NewEnrollment("bob", "Example", 0)
+	assert.True(t, forever.ExpiresAt.IsZero())
+	assert.False(t, forever.Expired(now.Add(time.Hour*24*365*100)))
+}
+
+func TestEnrollment_Confirm(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+	enrollment, _ := NewEnrollment("alice@google.com", "Example", 10*time.Minute, WithClock(func() time.Time { return now }))
+	token := enrollment.TOTP.At(now.Add(time.Minute))
+
+	assert.ErrorIs(t, enrollment.Confirm("000000", now.Add(time.Minute)), ErrTokenInvalid)
+	assert.Equal(t, EnrollmentPending, enrollment.State)
+	assert.Nil(t, enrollment.Confirm(token, now.Add(time.Minute)))
+	assert.Equal(t, EnrollmentConfirmed, enrollment.State)
+	assert.ErrorIs(t, enrollment.Confirm(token, now.Add(time.Minute)), ErrEnrollmentConfirmed)
+	// 已经确认的开通信息不会过期
+	assert.False(t, enrollment.Expired(now.Add(time.Hour)))
+
+	expired, _ := NewEnrollment("bob", "Example", time.Minute, WithClock(func() time.Time { return now }))
+	later := now.Add(time.Minute)
+	assert.ErrorIs(t, expired.Confirm(expired.TOTP.At(later), later), ErrEnrollmentExpired)
+}
+
+func TestEnrollment_ConfirmRestored(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+	restored := &Enrollment{Secret: TestSecret20, State: EnrollmentPending, ExpiresAt: now.Add(time.Minute)}
+	assert.Nil(t, restored.Confirm("076141", now))
+	assert.Equal(t, EnrollmentConfirmed, restored.State)
+
+	broken := &Enrollment{Secret: "!!!"}
+	assert.ErrorIs(t, broken.Confirm("076141", now), ErrSecretDecode)
+}
+
+func TestEnrollment_ConfirmRestoredOptions(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+	options := []TOTPOption{WithAlgorithm(AlgorithmSHA256), WithDigits(DigitsEight), WithPeriod(60), WithEpoch(time.Unix(30, 0))}
+	enrollment, err := NewEnrollment("alice@google.com", "Example", 10*time.Minute, append(options, WithClock(func() time.Time { return now }))...)
+	assert.Nil(t, err)
+	token := enrollment.TOTP.At(now)
+
+	// 只保存了 Secret、State 和 ExpiresAt，重新加载之后使用相同的 options 确认
+	defaults := &Enrollment{Secret: enrollment.Secret, State: enrollment.State, ExpiresAt: enrollment.ExpiresAt}
+	assert.ErrorIs(t, defaults.Confirm(token, now), ErrTokenInvalid)
+	restored := &Enrollment{Secret: enrollment.Secret, State: enrollment.State, ExpiresAt: enrollment.ExpiresAt}
+	assert.Nil(t, restored.Confirm(token, now, options...))
+	assert.Equal(t, EnrollmentConfirmed, restored.State)
+	assert.Equal(t, AlgorithmSHA256, restored.TOTP.Algorithm)
+}
@@ -2,24 +2,83 @@ package otp
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 )
 
 var (
-	ErrURIFormat           = errors.New("uri format error")
-	ErrSecretDecode        = errors.New("secret base32 decode error")
-	ErrSecretCannotBeEmpty = errors.New("secret cannot be empty")
+	ErrURIFormat            = errors.New("uri format error")
+	ErrSecretDecode         = errors.New("secret base32 decode error")
+	ErrSecretCannotBeEmpty  = errors.New("secret cannot be empty")
+	ErrArgon2Params         = errors.New("argon2 params format error")
+	ErrLabelEmpty           = errors.New("label cannot be empty")
+	ErrLabelTooLong         = errors.New("label too long")
+	ErrLabelWhitespace      = errors.New("label has leading or trailing whitespace")
+	ErrLabelNotPrintable    = errors.New("label contains non-printable characters")
+	ErrLabelColon           = errors.New("label contains extra colon")
+	ErrBattleNetSerial      = errors.New("battle.net serial format error")
+	ErrSignatureInvalid     = errors.New("provisioning uri signature invalid")
+	ErrProvisioningExpired  = errors.New("provisioning uri expired")
+	ErrSplitLength          = errors.New("split suffix length out of range")
+	ErrSplitChecksum        = errors.New("split suffix checksum mismatch")
+	ErrSecretChecksum       = errors.New("secret checksum mismatch")
+	ErrMigrationFormat      = errors.New("otpauth-migration uri format error")
+	ErrMigrationUnsupported = errors.New("key cannot be represented in otpauth-migration payload")
+	ErrOCRASuite            = errors.New("ocra suite format error")
+	ErrOCRAInput            = errors.New("ocra input does not match suite")
+	ErrSecretNotFound       = errors.New("secret not found")
+	ErrSecretDecrypt        = errors.New("secret decrypt error")
+	ErrDuplicateAccount     = errors.New("duplicate account name")
+	ErrThrottled            = errors.New("too many failed attempts")
+	ErrTokenInvalid         = errors.New("token invalid")
+	ErrEnrollmentExpired    = errors.New("enrollment expired")
+	ErrEnrollmentConfirmed  = errors.New("enrollment already confirmed")
+	ErrMFATokenExpired      = errors.New("mfa token expired")
+	ErrDeviceNotTrusted     = errors.New("device not trusted")
+	ErrDeviceExpired        = errors.New("device trust expired")
+	ErrYubiKeyName          = errors.New("yubikey credential name format error")
+	ErrYubiKeyUnsupported   = errors.New("key cannot be stored on yubikey")
+	ErrInvalidOption        = errors.New("invalid option")
+	ErrSecretTooShort       = errors.New("secret too short")
+	ErrQRCodeUnavailable    = errors.New("qr code generation is not available in this build")
+	ErrSignerOutput         = errors.New("signer output is shorter than the digest size")
+)
+
+// KeyURI 参数错误，都可以使用 errors.Is(err, ErrURIFormat) 判断。
+var (
+	ErrInvalidScheme        = fmt.Errorf("%w: scheme must be otpauth", ErrURIFormat)
+	ErrInvalidType          = fmt.Errorf("%w: type must be totp or hotp", ErrURIFormat)
+	ErrMissingSecret        = fmt.Errorf("%w: missing secret", ErrURIFormat)
+	ErrUnsupportedAlgorithm = fmt.Errorf("%w: unsupported algorithm", ErrURIFormat)
+	ErrInvalidDigits        = fmt.Errorf("%w: invalid digits", ErrURIFormat)
+	ErrInvalidPeriod        = fmt.Errorf("%w: invalid period", ErrURIFormat)
+	ErrInvalidCounter       = fmt.Errorf("%w: invalid counter", ErrURIFormat)
+	ErrUnsupportedEncoder   = fmt.Errorf("%w: unsupported encoder", ErrURIFormat)
+	ErrIssuerMismatch       = fmt.Errorf("%w: issuer parameter does not match label prefix", ErrURIFormat)
+	ErrInvalidEpoch         = fmt.Errorf("%w: invalid epoch", ErrURIFormat)
+	ErrGoogleIncompatible   = fmt.Errorf("%w: parameter ignored by google authenticator", ErrURIFormat)
 )
 
 var (
 	minSkewNumber   = 0
 	minPeriodNumber = 10
+	// 超过一年的 period 没有实际意义，通常是解析错误或者恶意构造的 URI。
+	maxPeriodNumber = 365 * 24 * 3600
+	// RFC-4226 要求至少 6 位，这里放宽到 4 位以兼容部分硬件令牌；超过 10 位时截断后的 31 位整数无法提供更多的信息。
+	minDigitsNumber = 4
+	maxDigitsNumber = 10
+	// 部分验证器应用会截断过长的标签，这里取一个绝大多数应用都能完整显示的长度。
+	maxLabelLength = 64
 )
 
 // Algorithms 支持的 HMAC 类型。
 //
 // 默认值：HMAC_SHA1，与 Google Authenticator 兼容。
 //
+// SHA3 系列并非 Key Uri Format 中定义的取值，在 URI 上使用 SHA3-256、SHA3-512 表示，通常只适用于内部系统。
+//
+// SHA224、SHA384 同样不是 Key Uri Format 中定义的取值，用于对接使用这些摘要算法的硬件令牌和 HSM。
+//
 // See https://github.com/google/google-authenticator/wiki/Key-Uri-Format
 type Algorithms int
 
@@ -27,6 +86,10 @@ const (
 	AlgorithmSHA1 Algorithms = iota + 1
 	AlgorithmSHA256
 	AlgorithmSHA512
+	AlgorithmSHA3_256
+	AlgorithmSHA3_512
+	AlgorithmSHA224
+	AlgorithmSHA384
 )
 
 // String 枚举值转换为字符串形式 - 该值可以放置在 uri 上。
@@ -38,6 +101,14 @@ func (h Algorithms) String() string {
 		return "SHA256"
 	case AlgorithmSHA512:
 		return "SHA512"
+	case AlgorithmSHA3_256:
+		return "SHA3-256"
+	case AlgorithmSHA3_512:
+		return "SHA3-512"
+	case AlgorithmSHA224:
+		return "SHA224"
+	case AlgorithmSHA384:
+		return "SHA384"
 	default:
 		panic("unreachable")
 	}
@@ -54,12 +125,22 @@ func (h Algorithms) from(str string) (Algorithms, error) {
 		return AlgorithmSHA256, nil
 	case "SHA512":
 		return AlgorithmSHA512, nil
+	case "SHA3-256", "SHA3_256":
+		return AlgorithmSHA3_256, nil
+	case "SHA3-512", "SHA3_512":
+		return AlgorithmSHA3_512, nil
+	case "SHA224":
+		return AlgorithmSHA224, nil
+	case "SHA384":
+		return AlgorithmSHA384, nil
 	default:
 		return 0, errors.New("unknown 'algorithm' string")
 	}
 }
 
-// Digits 生成出来的一次性密码的长度。6 和 8 是最常见的值。
+// Digits 生成出来的一次性密码的长度。6 和 8 是最常见的值，支持 4 到 10 之间的任意长度。
+//
+// Google Authenticator 可能仅支持 6 位。
 type Digits int
 
 const (
@@ -69,12 +150,43 @@ const (
 
 // from 从 int 类型转换至 Digits 枚举
 func (d Digits) from(i int) (Digits, error) {
-	switch i {
-	case 6:
-		return DigitsSix, nil
-	case 8:
-		return DigitsEight, nil
-	default:
+	if i < minDigitsNumber || i > maxDigitsNumber {
 		return 0, errors.New("unknown 'digits' number")
 	}
+	return Digits(i), nil
+}
+
+// Encoder 一次性密码的编码方式，决定 HMAC 截断后的结果如何转换为用户看到的 token。
+//
+// 默认值：EncoderDefault，使用 RFC-4226 定义的十进制数字。
+type Encoder int
+
+const (
+	EncoderDefault Encoder = iota
+	// EncoderSteam Steam Guard 使用的编码方式，输出 5 个字符的字母数字组合，Digits 参数将被忽略。
+	EncoderSteam
+)
+
+// String 枚举值转换为字符串形式 - 该值可以放置在 uri 的 encoder 参数上，默认值为空字符串。
+func (e Encoder) String() string {
+	switch e {
+	case EncoderDefault:
+		return ""
+	case EncoderSteam:
+		return "steam"
+	default:
+		panic("unreachable")
+	}
+}
+
+// from 从字符串转换至 Encoder 枚举
+func (e Encoder) from(str string) (Encoder, error) {
+	switch strings.ToLower(str) {
+	case "":
+		return EncoderDefault, nil
+	case "steam":
+		return EncoderSteam, nil
+	default:
+		return 0, errors.New("unknown 'encoder' string")
+	}
 }
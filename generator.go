@@ -0,0 +1,44 @@
+package otp
+
+import "time"
+
+// Generator 根据移动因子生成 token 的类型，F 为移动因子的类型。
+//
+// TOTP（包括 NewSteamTOTP 创建的实例）、YandexTOTP 的移动因子为 time.Time，HOTP 为计数器 int64，OCRA 为 OCRAInput。
+// 中间件、存储和测试可以面向接口编写，而不依赖具体的结构体。
+//
+// Example:
+//
+//	func send(gen Generator[time.Time], t time.Time) {
+//		sms(gen.At(t))
+//	}
+type Generator[F any] interface {
+	At(factor F) string
+}
+
+// Verifier 根据移动因子校验 token 的类型，F 的取值与 Generator 相同。
+//
+// 除上述类型外，RotatingTOTP 也实现了 Verifier[time.Time]。
+type Verifier[F any] interface {
+	Verify(token string, factor F) bool
+}
+
+// NowGenerator 基于时间的 Generator，额外支持使用当前时间生成 token。
+//
+// TOTP 和 YandexTOTP 实现了该接口，基于计数器的 HOTP 和 OCRA 没有当前值的概念，因此不实现该接口。
+type NowGenerator interface {
+	Generator[time.Time]
+	Now() string
+}
+
+var (
+	_ NowGenerator         = (*TOTP)(nil)
+	_ NowGenerator         = (*YandexTOTP)(nil)
+	_ Generator[int64]     = (*HOTP)(nil)
+	_ Generator[OCRAInput] = (*OCRA)(nil)
+	_ Verifier[time.Time]  = (*TOTP)(nil)
+	_ Verifier[time.Time]  = (*YandexTOTP)(nil)
+	_ Verifier[time.Time]  = (*RotatingTOTP)(nil)
+	_ Verifier[int64]      = (*HOTP)(nil)
+	_ Verifier[OCRAInput]  = (*OCRA)(nil)
+)
@@ -0,0 +1,36 @@
+package otp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// roundTrip 面向接口生成并校验 token。
+func roundTrip[F any](t *testing.T, gen Generator[F], verifier Verifier[F], factor F) {
+	token := gen.At(factor)
+	assert.NotEmpty(t, token)
+	assert.True(t, verifier.Verify(token, factor))
+	assert.False(t, verifier.Verify("", factor))
+}
+
+func TestGenerator(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	totp := NewTOTP(TestSecret20, WithClock(func() time.Time { return now }))
+	roundTrip[time.Time](t, totp, totp, now)
+
+	steam := NewSteamTOTP(TestSecret20)
+	roundTrip[time.Time](t, steam, steam, now)
+
+	hotp := NewHOTP("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+	roundTrip[int64](t, hotp, hotp, 1)
+	assert.Equal(t, "287082", Generator[int64](hotp).At(1))
+
+	ocra := NewOCRA("OCRA-1:HOTP-SHA1-6:QN08", ocraKey20)
+	roundTrip[OCRAInput](t, ocra, ocra, OCRAInput{Question: "00000000"})
+	assert.Equal(t, "237653", ocra.At(OCRAInput{Question: "00000000"}))
+	assert.Equal(t, "", ocra.At(OCRAInput{}))
+
+	var gen NowGenerator = totp
+	assert.Equal(t, totp.At(now), gen.Now())
+}
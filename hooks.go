@@ -0,0 +1,123 @@
+package otp
+
+import "time"
+
+// VerifyOutcome 一次校验的结果。
+type VerifyOutcome int
+
+const (
+	// VerifySuccess 校验通过。
+	VerifySuccess VerifyOutcome = iota + 1
+	// VerifyMismatch token 为空或者与窗口内的所有 token 都不匹配。
+	VerifyMismatch
+	// VerifyNotValid 校验时间不在 WithNotBefore、WithNotAfter 配置的有效期内。
+	VerifyNotValid
+	// VerifyReplay token 匹配，但是已经被使用过，被 ReplayGuard 拒绝。
+	VerifyReplay
+	// VerifyError 读取秘钥、Signer 签名、防重放检查等外部依赖出错，VerifyContext 会同时返回对应的错误。
+	VerifyError
+)
+
+// String 枚举值转换为字符串形式，可以用作指标的标签。
+func (o VerifyOutcome) String() string {
+	switch o {
+	case VerifySuccess:
+		return "success"
+	case VerifyMismatch:
+		return "mismatch"
+	case VerifyNotValid:
+		return "not_valid"
+	case VerifyReplay:
+		return "replay"
+	case VerifyError:
+		return "error"
+	default:
+		panic("unreachable")
+	}
+}
+
+// VerifyEvent 传递给 Hooks 回调的校验信息，不包含 token 和秘钥。
+type VerifyEvent struct {
+	// totp 或 hotp
+	Type string
+	// WithIssuer、WithAccountName 配置的值，未配置时为空字符串。
+	Issuer      string
+	AccountName string
+	// 期望的时间步（TOTP）或计数器（HOTP）
+	Expected int64
+	// 匹配的时间步或计数器，仅在 VerifySuccess 和 VerifyReplay 时有意义。
+	Matched int64
+	// 匹配值与期望值的距离，即使用的 skew，负数表示客户端落后。仅在 VerifySuccess 和 VerifyReplay 时有意义。
+	Skew int
+	// 校验的结果，OnAttempt 中为 0。
+	Outcome VerifyOutcome
+	// Outcome 为 VerifyError 时的错误。
+	Err error
+	// 校验使用的时间，TOTP 为传入的时间，HOTP 为当前时间。
+	Time time.Time
+}
+
+// Hooks 校验过程中的回调，用于对接 Prometheus 等指标系统或者结构化日志，不需要包装每一个调用点。
+//
+// 所有字段都可以为 nil，回调在校验的 goroutine 中同步执行，不应该阻塞。
+// 每次校验都会先调用 OnAttempt，之后根据结果调用 OnSuccess 或 OnFailure；被 ReplayGuard 拒绝时会先调用 OnReplay 再调用 OnFailure。
+//
+// Example:
+//
+//	totp := NewTOTP(secret, WithHooks(Hooks{
+//		OnFailure: func(e VerifyEvent) {
+//			failures.WithLabelValues(e.Type, e.Outcome.String()).Inc()
+//		},
+//		OnSuccess: func(e VerifyEvent) {
+//			skew.Observe(float64(e.Skew))
+//		},
+//	}))
+type Hooks struct {
+	OnAttempt func(event VerifyEvent)
+	OnSuccess func(event VerifyEvent)
+	OnFailure func(event VerifyEvent)
+	OnReplay  func(event VerifyEvent)
+}
+
+// WithHooks 配置校验过程中的回调，见 Hooks。
+func WithHooks(hooks Hooks) Option {
+	return func(opt *Otp) {
+		opt.hooks = &hooks
+	}
+}
+
+// beginVerify 创建校验事件并调用 OnAttempt。
+func (o Otp) beginVerify(typ string, expected int64, t time.Time) VerifyEvent {
+	event := VerifyEvent{Type: typ, Issuer: o.Issuer, AccountName: o.AccountName, Expected: expected, Time: t}
+	if o.hooks != nil && o.hooks.OnAttempt != nil {
+		o.hooks.OnAttempt(event)
+	}
+	return event
+}
+
+// endVerify 记录校验的结果并调用对应的回调。
+func (o Otp) endVerify(event VerifyEvent, matched int64, outcome VerifyOutcome, err error) {
+	if o.hooks == nil {
+		return
+	}
+	event.Outcome = outcome
+	event.Err = err
+	if outcome == VerifySuccess || outcome == VerifyReplay {
+		event.Matched = matched
+		event.Skew = int(matched - event.Expected)
+	}
+	switch outcome {
+	case VerifySuccess:
+		if o.hooks.OnSuccess != nil {
+			o.hooks.OnSuccess(event)
+		}
+		return
+	case VerifyReplay:
+		if o.hooks.OnReplay != nil {
+			o.hooks.OnReplay(event)
+		}
+	}
+	if o.hooks.OnFailure != nil {
+		o.hooks.OnFailure(event)
+	}
+}
@@ -0,0 +1,174 @@
+package otp
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// recorder 记录 Hooks 的调用顺序。
+type recorder struct {
+	calls  []string
+	events []VerifyEvent
+}
+
+func (r *recorder) hooks() Hooks {
+	record := func(name string) func(VerifyEvent) {
+		return func(e VerifyEvent) {
+			r.calls = append(r.calls, name)
+			r.events = append(r.events, e)
+		}
+	}
+	return Hooks{
+		OnAttempt: record("attempt"),
+		OnSuccess: record("success"),
+		OnFailure: record("failure"),
+		OnReplay:  record("replay"),
+	}
+}
+
+func (r *recorder) last() VerifyEvent {
+	return r.events[len(r.events)-1]
+}
+
+func (r *recorder) reset() {
+	r.calls, r.events = nil, nil
+}
+
+func TestWithHooks_TOTP(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	r := &recorder{}
+	totp := NewTOTP(TestSecret20, WithSkew(1), WithHooks(r.hooks()), WithAccountName("alice"), WithIssuer("Example"),
+		WithReplayGuard(NewMemoryReplayGuard()))
+
+	assert.True(t, totp.Verify(totp.At(now.Add(-30*time.Second)), now))
+	assert.Equal(t, []string{"attempt", "success"}, r.calls)
+	event := r.last()
+	assert.Equal(t, "totp", event.Type)
+	assert.Equal(t, "alice", event.AccountName)
+	assert.Equal(t, "Example", event.Issuer)
+	assert.Equal(t, totp.StepFor(now), event.Expected)
+	assert.Equal(t, totp.StepFor(now)-1, event.Matched)
+	assert.Equal(t, -1, event.Skew)
+	assert.Equal(t, VerifySuccess, event.Outcome)
+	assert.Equal(t, now, event.Time)
+
+	// 重复使用
+	r.reset()
+	assert.False(t, totp.Verify(totp.At(now.Add(-30*time.Second)), now))
+	assert.Equal(t, []string{"attempt", "replay", "failure"}, r.calls)
+	assert.Equal(t, VerifyReplay, r.last().Outcome)
+	assert.Equal(t, -1, r.last().Skew)
+
+	r.reset()
+	assert.False(t, totp.Verify("000000", now))
+	assert.Equal(t, []string{"attempt", "failure"}, r.calls)
+	assert.Equal(t, VerifyMismatch, r.last().Outcome)
+	assert.Equal(t, 0, r.last().Skew)
+
+	r.reset()
+	expired := NewTOTP(TestSecret20, WithNotAfter(now.Add(-time.Hour)), WithHooks(r.hooks()))
+	assert.False(t, expired.Verify(expired.At(now), now))
+	assert.Equal(t, VerifyNotValid, r.last().Outcome)
+}
+
+func TestWithHooks_HOTP(t *testing.T) {
+	r := &recorder{}
+	hotp := NewHOTP(TestSecret20, WithSkew(1), WithHooks(r.hooks()))
+
+	_, ok := hotp.VerifyWithMatch(hotp.At(6), 5)
+	assert.True(t, ok)
+	assert.Equal(t, "hotp", r.last().Type)
+	assert.Equal(t, int64(5), r.last().Expected)
+	assert.Equal(t, 1, r.last().Skew)
+
+	r.reset()
+	counter, ok := hotp.ValidateAndSync(hotp.At(8), 5, 10)
+	assert.True(t, ok)
+	assert.Equal(t, int64(9), counter)
+	assert.Equal(t, []string{"attempt", "success"}, r.calls)
+	assert.Equal(t, 3, r.last().Skew)
+
+	r.reset()
+	_, ok = hotp.ValidateAndSync("", 5, 10)
+	assert.False(t, ok)
+	assert.Equal(t, []string{"attempt", "failure"}, r.calls)
+}
+
+func TestWithHooks_Context(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	r := &recorder{}
+	totp := NewTOTP(TestSecret20, WithHooks(r.hooks()))
+	ok, err := totp.VerifyContext(context.Background(), totp.At(now), now)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"attempt", "success"}, r.calls)
+
+	r.reset()
+	store := NewMemorySecretStore()
+	assert.Nil(t, store.Put("alice", []byte("12345678901234567890")))
+	hotp, err := NewHOTPFromStore(store, "alice", WithHooks(r.hooks()))
+	assert.Nil(t, err)
+	// 秘钥在创建之后被删除
+	assert.Nil(t, store.Delete("alice"))
+	ok, err = hotp.VerifyContext(context.Background(), "123456", 1)
+	assert.False(t, ok)
+	assert.True(t, errors.Is(err, ErrSecretNotFound))
+	assert.Equal(t, []string{"attempt", "failure"}, r.calls)
+	assert.Equal(t, VerifyError, r.last().Outcome)
+	assert.Equal(t, err, r.last().Err)
+}
+
+// countingStore 记录 Get 的调用次数。
+type countingStore struct {
+	*MemorySecretStore
+	gets int
+}
+
+func (s *countingStore) Get(id string) ([]byte, error) {
+	s.gets++
+	return s.MemorySecretStore.Get(id)
+}
+
+func TestWithHooks_Errors(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	r := &recorder{}
+	store := &countingStore{MemorySecretStore: NewMemorySecretStore()}
+	assert.Nil(t, store.Put("alice", []byte("12345678901234567890")))
+	totp, err := NewTOTPFromStore(store, "alice", WithSkew(2), WithHooks(r.hooks()))
+	assert.Nil(t, err)
+	hotp, err := NewHOTPFromStore(store, "alice", WithSkew(2), WithHooks(r.hooks()))
+	assert.Nil(t, err)
+
+	// 每次校验只读取一次秘钥，而不是窗口内的每个时间步读取一次
+	store.gets = 0
+	assert.False(t, totp.Verify("000000", now))
+	assert.False(t, hotp.Verify("000000", 10))
+	_, ok := hotp.ValidateAndSync("000000", 10, 20)
+	assert.False(t, ok)
+	assert.Equal(t, 3, store.gets)
+
+	// 读取秘钥出错时报告 VerifyError，而不是 VerifyMismatch
+	assert.Nil(t, store.Delete("alice"))
+	r.reset()
+	assert.False(t, totp.Verify("000000", now))
+	assert.False(t, hotp.Verify("000000", 10))
+	_, ok = hotp.ValidateAndSync("000000", 10, 20)
+	assert.False(t, ok)
+	assert.Equal(t, []string{"attempt", "failure", "attempt", "failure", "attempt", "failure"}, r.calls)
+	for i := 1; i < len(r.events); i += 2 {
+		assert.Equal(t, VerifyError, r.events[i].Outcome)
+		assert.ErrorIs(t, r.events[i].Err, ErrSecretNotFound)
+	}
+
+	// Signer 出错时只调用一次
+	r.reset()
+	secret, _ := Base32Decode(TestSecret20)
+	signer := &countingSigner{HMACSigner: NewHMACSigner(AlgorithmSHA1, secret), err: errBackend}
+	assert.False(t, NewTOTPWithSigner(signer, WithSkew(2), WithHooks(r.hooks())).Verify("000000", now))
+	assert.Equal(t, 1, signer.calls)
+	assert.Equal(t, VerifyError, r.last().Outcome)
+	assert.ErrorIs(t, r.last().Err, errBackend)
+}
@@ -1,9 +1,8 @@
 package otp
 
 import (
-	"crypto/hmac"
-	"fmt"
-	"net/url"
+	"context"
+	"math"
 )
 
 // HOTP 基于 RFC-4266 的 HOTP 算法
@@ -13,20 +12,26 @@ type HOTP struct {
 	Secret string
 	// base32 decoded string
 	decodedSecret []byte
+	// 使用 NewHOTPFromStore 创建时，每次计算 token 都从 store 中读取秘钥
+	store   SecretStore
+	storeID string
+	// 使用 NewHOTPWithSigner 创建时，由 signer 计算 HMAC，内存中不保存秘钥
+	signer Signer
 }
 
 // NewHOTP 创建一个 HOTP 结构体，可以使用 option 的模式传递参数。
 //
 // Params:
 //
-//	secret       : 必传，一个 base32 编码后的字符串，建议使用 RandomSecret 方法生成的。
+//	secret       : 必传，一个 base32 编码后的字符串，建议使用 RandomSecret 方法生成的。允许小写、空格分组以及填充，会使用 NormalizeSecret 规范化。
 //	WithCounter  : 设置初始计数器，该值仅用于 KeyURI 方法。
 //	WithSkew     : 是否校验相邻的窗口。
 //	WithAlgorithm: 设置 hmac 算法类型。
 //
 // Panic:
-//   - secret base32 decode error
+//   - secret base32 decode error（*SecretDecodeError）
 //   - secret is an empty string
+//   - secret too short（*SecretStrengthError），仅在配置了 WithStrictSecretLength 时
 //
 // 注意: Google Authenticator 可能仅支持 Counter 这一个参数
 //
@@ -36,29 +41,72 @@ type HOTP struct {
 //
 //	secret := Base32Encode(RandomSecret(20))
 //	hotp   := NewHOTP(secret, WithCounter(2))
-func NewHOTP(secret string, options ...Option) *HOTP {
-	if secret == "" {
+func NewHOTP(secret string, options ...HOTPOption) *HOTP {
+	hotp, err := newHOTP(secret, false, options...)
+	if err != nil {
+		panic(err)
+	}
+	return hotp
+}
+
+// NewHOTPFromBytes 使用未编码的秘钥创建 HOTP 结构体，其余参数与 NewHOTP 一致。
+//
+// 秘钥会被复制，Secret 字段为秘钥的 base32 编码。十六进制的秘钥可以先使用 HexDecode 解码。
+//
+// Panic:
+//   - secret is empty
+//   - secret too short（*SecretStrengthError），仅在配置了 WithStrictSecretLength 时
+func NewHOTPFromBytes(secret []byte, options ...HOTPOption) *HOTP {
+	if len(secret) == 0 {
 		panic(ErrSecretCannotBeEmpty)
 	}
-	decodedSecret, err := Base32Decode(secret)
+	otp := newHOTPOtp(options)
+	if err := otp.checkSecretStrength(secret); err != nil {
+		panic(err)
+	}
+	return &HOTP{
+		Otp:           otp,
+		Secret:        Base32Encode(secret),
+		decodedSecret: append([]byte(nil), secret...),
+	}
+}
+
+// NewHOTPWithError 与 NewHOTP 相同，但是在 secret 为空或无法解码时返回错误而不是 panic。
+//
+// 与 NewHOTP 不同，option 配置了超出范围的参数时（例如 WithPeriod(0)）会返回 ErrInvalidOption 而不是静默修正，见 Otp.Validate。
+//
+// 适用于 secret 来自外部输入的场景，例如服务端根据外部数据批量开通账号。
+//
+// Example:
+//
+//	hotp, err := NewHOTPWithError(secret)
+//	if errors.Is(err, ErrSecretDecode) {
+//		// secret 格式错误
+//	}
+func NewHOTPWithError(secret string, options ...HOTPOption) (*HOTP, error) {
+	return newHOTP(secret, true, options...)
+}
+
+// newHOTP 创建 HOTP 结构体，validate 为 true 时校验 option 配置的参数。
+func newHOTP(secret string, validate bool, options ...HOTPOption) (*HOTP, error) {
+	otp := newHOTPOtp(options)
+	secret, decodedSecret, err := otp.decodeSecret(secret)
 	if err != nil {
-		panic(ErrSecretDecode)
+		return nil, err
 	}
-	otp := Otp{
-		Skew:      0,
-		Counter:   1,
-		Period:    30,
-		Algorithm: AlgorithmSHA1,
-		Digits:    DigitsSix,
+	if validate {
+		if err := otp.Validate(); err != nil {
+			return nil, err
+		}
 	}
-	for _, opt := range options {
-		opt(&otp)
+	if err := otp.checkSecretStrength(decodedSecret); err != nil {
+		return nil, err
 	}
 	return &HOTP{
 		Otp:           otp,
 		Secret:        secret,
 		decodedSecret: decodedSecret,
-	}
+	}, nil
 }
 
 // At 通过指定的 Counter 生成一个 token。
@@ -68,13 +116,19 @@ func NewHOTP(secret string, options ...Option) *HOTP {
 //	hotp  := NewHOTP(Base32Encode(RandomSecret(20)))
 //	token := hotp.At(1)  	       // 使用的 1 作为counter 生成 token
 //	bool  := hotp.Verify(token, 1) // 校验 token 是否有效
+//
+// 使用 NewHOTPFromStore 创建时，如果从 store 中读取秘钥失败将会返回空字符串。
 func (h *HOTP) At(counter int64) string {
-	s := intToByte(counter)
-	hashFunc := hasher(h.Algorithm)
-	mac := hmac.New(hashFunc, h.decodedSecret)
-	mac.Write(s)
-	hex := mac.Sum(nil)
-	return truncate(hex, int(h.Digits))
+	generate, release, err := h.generator(context.Background())
+	if err != nil {
+		return ""
+	}
+	defer release()
+	token, err := generate(counter)
+	if err != nil {
+		return ""
+	}
+	return token
 }
 
 // Verify 校验token是否有效，窗口内的所有结果都认为有效。
@@ -89,29 +143,139 @@ func (h *HOTP) At(counter int64) string {
 //	hotp  := NewHOTP(Base32Encode(RandomSecret(20)), WithSkew(1))
 //	token := hotp.At(2)  		   // 使用的 2 作为counter 生成 token
 //	bool  := hotp.Verify(token, 2) // 通过 WithSkew 方法指定 skew 参数为1，那么这里将会校验 counter 为 1、2、3 的token
+//
+// 如果配置了 WithNotBefore 或 WithNotAfter，当前时间不在秘钥有效期内时将会返回 false。
+// 如果配置了 WithReplayGuard，已经使用过的 token 将会返回 false。
 func (h *HOTP) Verify(token string, counter int64) bool {
-	if token == "" {
-		return false
+	_, ok := h.VerifyWithMatch(token, counter)
+	return ok
+}
+
+// VerifyWithMatch 与 Verify 相同，额外返回校验通过的计数器。
+//
+// 配置了 WithSkew 时匹配的计数器可能与传入的 counter 不同，调用方应该将服务端保存的计数器更新为匹配值加一，
+// 防止同一个 token 被重复使用。
+//
+// Example:
+//
+//	matched, ok := hotp.VerifyWithMatch(token, counter)
+//	if ok {
+//		counter = matched + 1
+//	}
+func (h *HOTP) VerifyWithMatch(token string, counter int64) (int64, bool) {
+	backward, forward := h.window()
+	from, to := counterRange(counter, backward, forward)
+	matched, outcome := h.verifyRange(token, counter, from, to)
+	if outcome != VerifySuccess {
+		return 0, false
 	}
-	c := counter
-	for i := c - int64(h.Skew); i <= c+int64(h.Skew); i++ {
-		if h.At(i) == token {
-			return true
-		}
+	return matched, true
+}
+
+// ValidateAndSync 基于 RFC-4226 第 7.4 节的前向窗口校验 token，并返回服务端需要保存的新计数器。
+//
+// 只向前检查 currentCounter 到 currentCounter+lookAhead 之间的计数器，不会向后检查，Skew 参数将被忽略。
+// 校验通过时返回匹配的计数器加一，校验失败时返回 currentCounter。检查的计数器不会超过 math.MaxInt64-1，
+// 超出的部分不会进行校验。
+//
+// Params:
+//
+//	token         : 需要进行校验的参数，如果为空将会返回 false。
+//	currentCounter: 服务端当前保存的计数器，即下一个期望的计数器。
+//	lookAhead     : 前向窗口的大小，小于 0 时按 0 处理。
+//
+// Example:
+//
+//	newCounter, ok := hotp.ValidateAndSync(token, counter, 10)
+//	if ok {
+//		counter = newCounter // 持久化
+//	}
+func (h *HOTP) ValidateAndSync(token string, currentCounter int64, lookAhead int) (int64, bool) {
+	if lookAhead < 0 {
+		lookAhead = 0
+	}
+	from, to := counterRange(currentCounter, 0, lookAhead)
+	matched, outcome := h.verifyRange(token, currentCounter, from, to)
+	if outcome != VerifySuccess {
+		return currentCounter, false
 	}
-	return false
+	return matched + 1, true
+}
+
+// verifyRange 在 [from, to] 之间校验 token，返回匹配的计数器和校验的结果，expected 为期望的计数器。
+func (h *HOTP) verifyRange(token string, expected, from, to int64) (int64, VerifyOutcome) {
+	now := h.now()
+	event := h.beginVerify("hotp", expected, now)
+	matched, outcome, err := h.matchContext(context.Background(), token, now, from, to)
+	h.endVerify(event, matched, outcome, err)
+	return matched, outcome
+}
+
+// maxCounter 校验时允许的最大计数器，匹配的计数器加一之后不会溢出。
+const maxCounter = math.MaxInt64 - 1
+
+// counterRange 返回 counter 向后 backward、向前 forward 的计数器范围，计算时不会溢出。
+//
+// 范围的上限截断到 maxCounter，下限截断到 math.MinInt64；counter 本身超过 maxCounter 时 from 大于 to，不会校验任何计数器。
+func counterRange(counter int64, backward, forward int) (from, to int64) {
+	from, to = math.MinInt64, maxCounter
+	if counter >= math.MinInt64+int64(backward) {
+		from = counter - int64(backward)
+	}
+	if counter <= maxCounter-int64(forward) {
+		to = counter + int64(forward)
+	}
+	return from, to
 }
 
 // KeyURI 返回一个 KeyURI 结构体，其包含转换至 URI 和生成二维码的方法。
-func (h *HOTP) KeyURI(account, issuer string) *KeyURI {
+//
+// 不传参数时使用 WithAccountName 和 WithIssuer 配置的值；按顺序传入 account、issuer 时覆盖配置的值，
+// 只传入 account 时 issuer 仍使用配置的值。多余的参数将被忽略。
+//
+// Example:
+//
+//	hotp.KeyURI()                                // 使用配置的账户名称和发行商
+//	hotp.KeyURI("alice@google.com", "Example")   // 覆盖配置的值
+func (h *HOTP) KeyURI(override ...string) *KeyURI {
+	account, issuer := h.labelParts(override)
 	ret := &KeyURI{
-		Type:      "hotp",
-		Label:     url.PathEscape(fmt.Sprintf("%s:%s", issuer, account)),
-		Counter:   h.Counter,
-		Digits:    int(h.Digits),
-		Algorithm: h.Algorithm.String(),
-		Issuer:    url.QueryEscape(issuer),
-		Secret:    h.Secret,
+		Type:        "hotp",
+		Label:       formatLabel(issuer, account),
+		AccountName: account,
+		Counter:     h.Counter,
+		Digits:      int(h.Digits),
+		Algorithm:   h.Algorithm.String(),
+		Issuer:      issuer,
+		Secret:      h.secretString(),
+		Encoder:     h.Encoder.String(),
 	}
 	return ret
 }
+
+// secretString 返回 base32 编码的秘钥，读取失败时返回空字符串。
+func (h *HOTP) secretString() string {
+	if h.signer != nil {
+		return ""
+	}
+	if h.store == nil {
+		return h.Secret
+	}
+	secret, err := h.secretContext(context.Background())
+	if err != nil {
+		return ""
+	}
+	defer zero(secret)
+	return Base32Encode(secret)
+}
+
+// replayKey 返回 ReplayGuard 中标识账户的 key，使用 Signer 签名失败时返回错误。
+func (h *HOTP) replayKey() (string, error) {
+	if h.signer != nil {
+		return signerReplayKey(h.signer, h.Algorithm)
+	}
+	if h.store != nil {
+		return "store:" + h.storeID, nil
+	}
+	return replayKey(h.decodedSecret), nil
+}
@@ -1,9 +1,12 @@
 package otp
 
 import (
+	"context"
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"math"
 	"testing"
+	"time"
 )
 
 const TestSecret20 = "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6"
@@ -38,11 +41,26 @@ func TestNewHOTP(t *testing.T) {
 	assert.PanicsWithError(t, ErrSecretCannotBeEmpty.Error(), func() {
 		NewHOTP("")
 	})
-	assert.PanicsWithError(t, ErrSecretDecode.Error(), func() {
+	assert.PanicsWithError(t, `secret base32 decode error: illegal character '1' at position 0, did you mean 'I'`, func() {
 		NewHOTP("111111")
 	})
 }
 
+func TestNewHOTPWithError(t *testing.T) {
+	hotp, err := NewHOTPWithError(TestSecret20, WithDigits(DigitsEight))
+	assert.Nil(t, err)
+	assert.Equal(t, NewTOTP(TestSecret20, WithDigits(DigitsEight)).Otp, hotp.Otp)
+	assert.Equal(t, TestSecret20, hotp.Secret)
+
+	hotp, err = NewHOTPWithError("")
+	assert.Nil(t, hotp)
+	assert.Equal(t, ErrSecretCannotBeEmpty, err)
+
+	hotp, err = NewHOTPWithError("111111")
+	assert.Nil(t, hotp)
+	assert.ErrorIs(t, err, ErrSecretDecode)
+}
+
 func TestHOTP_At(t *testing.T) {
 	var cases = map[int64]string{
 		1: "347255",
@@ -129,13 +147,14 @@ func TestHOTP_KeyURI(t *testing.T) {
 		uri := hotp.KeyURI("alice@google.com", "Example")
 		expected := fmt.Sprintf("otpauth://hotp/Example:alice@google.com?secret=%s&issuer=Example&counter=1", TestSecret20)
 		expectedKeyUri := &KeyURI{
-			Digits:    6,
-			Counter:   1,
-			Type:      "hotp",
-			Algorithm: "SHA1",
-			Issuer:    "Example",
-			Label:     "Example:alice@google.com",
-			Secret:    TestSecret20,
+			Digits:      6,
+			Counter:     1,
+			Type:        "hotp",
+			Algorithm:   "SHA1",
+			Issuer:      "Example",
+			Label:       "Example:alice@google.com",
+			AccountName: "alice@google.com",
+			Secret:      TestSecret20,
 		}
 		assert.Equal(t, expected, uri.URI().String())
 		assert.Equal(t, expectedKeyUri, uri)
@@ -146,15 +165,142 @@ func TestHOTP_KeyURI(t *testing.T) {
 		uri2 := hotp2.KeyURI("alice@google.com", "Example")
 		expected2 := fmt.Sprintf("otpauth://hotp/Example:alice@google.com?secret=%s&issuer=Example&algorithm=SHA256&digits=8&counter=2", TestSecret32)
 		expectedKeyUri2 := &KeyURI{
-			Digits:    8,
-			Counter:   2,
-			Type:      "hotp",
-			Algorithm: "SHA256",
-			Issuer:    "Example",
-			Label:     "Example:alice@google.com",
-			Secret:    TestSecret32,
+			Digits:      8,
+			Counter:     2,
+			Type:        "hotp",
+			Algorithm:   "SHA256",
+			Issuer:      "Example",
+			Label:       "Example:alice@google.com",
+			AccountName: "alice@google.com",
+			Secret:      TestSecret32,
 		}
 		assert.Equal(t, expected2, uri2.URI().String())
 		assert.Equal(t, expectedKeyUri2, uri2)
 	})
 }
+
+func TestHOTP_KeyURIConfigured(t *testing.T) {
+	otp := NewHOTP(TestSecret20, WithIssuer("Example Co"), WithAccountName("alice@google.com"))
+	key := otp.KeyURI()
+	assert.Equal(t, "Example Co:alice@google.com", key.Label)
+	assert.Equal(t, "Example Co", key.Issuer)
+	assert.Equal(t, "alice@google.com", key.AccountName)
+
+	// 显式传入的参数覆盖配置的值
+	assert.Equal(t, "Example Co:bob", otp.KeyURI("bob").Label)
+	assert.Equal(t, "Other:bob", otp.KeyURI("bob", "Other").Label)
+	assert.Equal(t, "bob", otp.KeyURI("bob", "").Label)
+	assert.Equal(t, otp.KeyURI("alice@google.com", "Example Co"), key)
+}
+
+func TestHOTP_VerifyValidityWindow(t *testing.T) {
+	hotp := NewHOTP(TestSecret20, WithNotAfter(time.Now().Add(-time.Hour)))
+	assert.Equal(t, false, hotp.Verify("347255", 1))
+
+	hotp2 := NewHOTP(TestSecret20, WithNotBefore(time.Now().Add(time.Hour)))
+	assert.Equal(t, false, hotp2.Verify("347255", 1))
+
+	hotp3 := NewHOTP(TestSecret20, WithNotBefore(time.Now().Add(-time.Hour)), WithNotAfter(time.Now().Add(time.Hour)))
+	assert.Equal(t, true, hotp3.Verify("347255", 1))
+}
+
+func TestHOTP_WithClock(t *testing.T) {
+	notAfter := time.Unix(1704075000000, 0)
+	now := notAfter.Add(-time.Second)
+	hotp := NewHOTP(TestSecret20, WithNotAfter(notAfter), WithClock(func() time.Time { return now }))
+	assert.Equal(t, true, hotp.Verify(hotp.At(1), 1))
+
+	now = notAfter.Add(time.Second)
+	assert.Equal(t, false, hotp.Verify(hotp.At(1), 1))
+}
+
+func TestHOTP_VerifyWithMatch(t *testing.T) {
+	hotp := NewHOTP(TestSecret20, WithSkew(2))
+	counter, ok := hotp.VerifyWithMatch(hotp.At(12), 10)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, int64(12), counter)
+
+	_, ok = hotp.VerifyWithMatch(hotp.At(13), 10)
+	assert.Equal(t, false, ok)
+	_, ok = hotp.VerifyWithMatch("", 10)
+	assert.Equal(t, false, ok)
+}
+
+func TestHOTP_ValidateAndSync(t *testing.T) {
+	hotp := NewHOTP(TestSecret20, WithSkew(5))
+
+	counter, ok := hotp.ValidateAndSync(hotp.At(10), 10, 0)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, int64(11), counter)
+
+	// 客户端多生成了几次 token
+	counter, ok = hotp.ValidateAndSync(hotp.At(15), 11, 5)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, int64(16), counter)
+
+	// 超出前向窗口
+	counter, ok = hotp.ValidateAndSync(hotp.At(22), 16, 5)
+	assert.Equal(t, false, ok)
+	assert.Equal(t, int64(16), counter)
+
+	// 不会向后检查，忽略 Skew
+	counter, ok = hotp.ValidateAndSync(hotp.At(15), 16, 5)
+	assert.Equal(t, false, ok)
+	assert.Equal(t, int64(16), counter)
+
+	counter, ok = hotp.ValidateAndSync(hotp.At(16), 16, -1)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, int64(17), counter)
+
+	_, ok = hotp.ValidateAndSync("", 16, 5)
+	assert.Equal(t, false, ok)
+}
+
+func TestHOTP_CounterOverflow(t *testing.T) {
+	hotp := NewHOTP(TestSecret20, WithSkew(5))
+	wrong := hotp.At(0)
+
+	// 窗口的上限截断到 math.MaxInt64-1，计数器不会回绕到负数
+	counter, ok := hotp.ValidateAndSync(wrong, math.MaxInt64-5, 5)
+	assert.Equal(t, false, ok)
+	assert.Equal(t, int64(math.MaxInt64-5), counter)
+	counter, ok = hotp.ValidateAndSync(hotp.At(math.MaxInt64-1), math.MaxInt64-5, 10)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, int64(math.MaxInt64), counter)
+	_, ok = hotp.ValidateAndSync(hotp.At(math.MaxInt64), math.MaxInt64-5, 10)
+	assert.Equal(t, false, ok)
+
+	_, ok = hotp.VerifyWithMatch(wrong, math.MaxInt64)
+	assert.Equal(t, false, ok)
+	matched, ok := hotp.VerifyWithMatch(hotp.At(math.MaxInt64-3), math.MaxInt64)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, int64(math.MaxInt64-3), matched)
+	assert.Equal(t, true, hotp.Verify(hotp.At(math.MinInt64), math.MinInt64+2))
+
+	ok, err := hotp.VerifyContext(context.Background(), wrong, math.MaxInt64)
+	assert.Nil(t, err)
+	assert.Equal(t, false, ok)
+	ok, err = hotp.VerifyContext(context.Background(), hotp.At(math.MinInt64), math.MinInt64+2)
+	assert.Nil(t, err)
+	assert.Equal(t, true, ok)
+}
+
+func TestHOTP_WithSkewWindow(t *testing.T) {
+	hotp := NewHOTP(TestSecret20, WithSkewWindow(0, 2))
+	assert.Equal(t, true, hotp.Verify(hotp.At(12), 10))
+	assert.Equal(t, false, hotp.Verify(hotp.At(9), 10))
+}
+
+func TestNewHOTPFromBytes(t *testing.T) {
+	// RFC-4226 附录 D
+	secret, _ := HexDecode("3132333435363738393031323334353637383930")
+	hotp := NewHOTPFromBytes(secret)
+	for counter, expected := range []string{"755224", "287082", "359152", "969429", "338314"} {
+		assert.Equal(t, expected, hotp.At(int64(counter)))
+	}
+	assert.Equal(t, NewHOTP(hotp.Secret).Otp, hotp.Otp)
+
+	assert.PanicsWithError(t, ErrSecretCannotBeEmpty.Error(), func() {
+		NewHOTPFromBytes([]byte{})
+	})
+}
@@ -2,10 +2,10 @@ package otp
 
 import (
 	"fmt"
-	"github.com/skip2/go-qrcode"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // KeyURI TOTP 或 HOTP 的 URI 包含的参数。
@@ -19,8 +19,12 @@ type KeyURI struct {
 	// 标签，用于识别密钥与哪个帐户关联。它包含一个帐户名称，该名称是一个 URI 编码的字符串，可以选择以标识管理该帐户的提供商或服务的发行者字符串为前缀。
 	// 发行者前缀和帐户名称应使用文字或 URL 编码的冒号分隔，并且帐户名称之前可以有可选空格。发行人或账户名称本身都不能包含冒号。
 	// 根据 Google Authenticator 的建议，应该拼接发行商字符串为前缀。
-	// 需要已被 url.QueryEscape 方法处理过。
+	// 保存未编码的值，例如 "Example:alice@google.com"，只在 URI 方法中编码一次。
+	// 为空时使用 Issuer 和 AccountName 生成。
 	Label string
+	// 未编码的账户名称，即 label 中 issuer 前缀之后的部分，FromURI 以及 TOTP.KeyURI、HOTP.KeyURI 都会设置。
+	// 需要重新组合 label 时应该使用 Issuer 和 AccountName，而不是拼接 Label，避免出现重复的 issuer 前缀。
+	AccountName string
 	// hotp 或 totp 采用的哈希算法类型
 	// Google Authenticator 可能会忽略此参数，而采用默认值：HMAC-SHA1。
 	Algorithm string
@@ -32,122 +36,179 @@ type KeyURI struct {
 	// 仅当 type 为 totp 时可选，该 period 参数定义 TOTP 密码的有效期限（以秒为单位）。默认值为 30。
 	// Google Authenticator 可能会忽略此参数，而采用默认值 30。
 	Period int
-	// 发行商，使用 URL 编码进行编码的字符串
-	// 需要已被 url.QueryEscape 方法处理过。
+	// 发行商，保存未编码的值，例如 "C++ Corp"，只在 URI 方法中编码一次。
 	Issuer string
 	// base32 编码的任意字符，不应该填充。
 	Secret string
+	// token 的编码方式，为空时表示默认的十进制数字，目前仅支持 steam。
+	// 这不是 Key Uri Format 中定义的参数，仅部分验证器应用支持。
+	Encoder string
+	// RFC-6238 中的 T0（unix 秒数），仅当 type 为 totp 时可选，默认值为 0。
+	// 这不是 Key Uri Format 中定义的参数，只有使用 WithEpochParameter 时 TOTP.KeyURI 才会设置。
+	Epoch int64
 }
 
 // URI 生成 otpauth 的 URI 形式，可以将其作为二维码的内容供 Google Authenticator 扫码导入。
-// params 顺序：secret、issuer、algorithm、digits、period、counter
+// params 顺序：secret、issuer、algorithm、digits、period、counter、encoder、epoch
+//
+// Label 和 Issuer 是未编码的值，在这里编码且只编码一次：label 使用路径编码，参数使用查询编码，空格均编码为 %20，
+// 因此包含 &、+、%、空格以及非 ASCII 字符的 issuer 都可以通过 FromURI 原样还原。
 func (p KeyURI) URI() *url.URL {
 	u := url.URL{}
 	u.Scheme = "otpauth"
 	u.Host = p.Type
-	u.Path = p.Label
-	params := "secret=" + p.Secret
-	params += "&issuer=" + p.Issuer
-
+	u.Path = "/" + p.label()
+	params := []string{"secret", p.Secret, "issuer", p.Issuer}
 	if p.Algorithm != "SHA1" {
-		params += "&algorithm=" + p.Algorithm
+		params = append(params, "algorithm", p.Algorithm)
 	}
 	if p.Digits != 6 {
-		params += "&digits=" + strconv.Itoa(p.Digits)
+		params = append(params, "digits", strconv.Itoa(p.Digits))
 	}
 	if p.Type == "totp" {
 		if p.Period != 30 {
-			params += "&period=" + strconv.Itoa(p.Period)
+			params = append(params, "period", strconv.Itoa(p.Period))
 		}
 	} else {
-		params += "&counter=" + strconv.FormatInt(p.Counter, 10)
+		params = append(params, "counter", strconv.FormatInt(p.Counter, 10))
+	}
+	if p.Encoder != "" {
+		params = append(params, "encoder", p.Encoder)
 	}
-	u.RawQuery = params
+	if p.Type == "totp" && p.Epoch != 0 {
+		params = append(params, "epoch", strconv.FormatInt(p.Epoch, 10))
+	}
+	u.RawQuery = encodeQuery(params)
 	return &u
 }
 
-// QRCode 将此 URI 信息生成一个二维码，可供 Google Authenticator 扫码导入。
-func (p KeyURI) QRCode() ([]byte, error) {
-	uri := p.URI().String()
-	code, err := qrcode.New(uri, qrcode.Highest)
-	if err != nil {
-		return nil, err
-	}
-	png, err := code.PNG(256)
-	if err != nil {
-		return nil, err
+// encodeQuery 与 url.Values.Encode 相同的方式编码参数，但是保持参数的顺序，并将空格编码为 %20 而不是 +，
+// 部分验证器应用会将 + 原样显示。
+func encodeQuery(pairs []string) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte('&')
+		}
+		b.WriteString(url.QueryEscape(pairs[i]))
+		b.WriteByte('=')
+		b.WriteString(strings.ReplaceAll(url.QueryEscape(pairs[i+1]), "+", "%20"))
 	}
-	return png, nil
+	return b.String()
 }
 
 // FromURI 解析 URI 创建一个 KeyURI 结构体。
+//
+// 返回的错误都可以使用 errors.Is(err, ErrURIFormat) 判断，也可以使用 ErrInvalidScheme、ErrMissingSecret、
+// ErrUnsupportedAlgorithm、ErrInvalidDigits、ErrInvalidPeriod 等判断具体原因，错误信息中包含不合法的参数值。
+//
+// label 按照 ParseLabel 的规则解析，label 中的 issuer 前缀与 issuer 参数不一致时返回 ErrIssuerMismatch。
+// 返回的 Label 统一为 issuer:account 的形式（去掉冒号后的空格），与 TOTP.KeyURI 一致，
+// Label 和 Issuer 都是解码后的值。
+//
+// 非法的百分号编码、无效的 UTF-8、控制字符以及包含冒号的 issuer 都会返回 ErrURIFormat，period 不能超过一年。
+// 需要导入不完全符合规范的 URI 时使用 ParseLenient。
 func FromURI(uri string) (*KeyURI, error) {
-	u, err := url.Parse(uri)
-	if err != nil {
-		return nil, ErrURIFormat
+	return (&uriParser{}).parse(uri)
+}
+
+// ParseLabel 按照 Key Uri Format 的规则解析未编码的 label，返回 issuer 前缀和账户名称。
+//
+// 支持的格式：
+//   - account
+//   - issuer:account
+//   - issuer: account，冒号后可以有任意个空格
+//
+// URI 中编码为 %3A 的冒号在解析 URI 时已经被解码，与未编码的冒号相同处理。
+// 旧版本在 issuer 为空时会生成 :account 形式的 label，此时返回的 issuer 为空字符串。
+// issuer 或账户名称包含多余的冒号时返回 ErrLabelColon，账户名称为空时返回 ErrLabelEmpty。
+//
+// Example:
+//
+//	issuer, account, err := ParseLabel("Example: alice@google.com") // "Example", "alice@google.com"
+func ParseLabel(label string) (issuer, account string, err error) {
+	account = label
+	if i := strings.Index(label, ":"); i != -1 {
+		issuer, account = label[:i], strings.TrimLeft(label[i+1:], " ")
+	}
+	if strings.Contains(account, ":") {
+		return "", "", fmt.Errorf("%w: %q", ErrLabelColon, label)
 	}
-	if u.Scheme != "otpauth" {
-		return nil, ErrURIFormat
+	if account == "" {
+		return "", "", fmt.Errorf("%w: account is empty", ErrLabelEmpty)
 	}
-	if u.Host != "hotp" && u.Host != "totp" {
-		return nil, ErrURIFormat
+	return issuer, account, nil
+}
+
+// formatLabel 使用 issuer 和账户名称生成未编码的 label，issuer 为空时只包含账户名称。
+func formatLabel(issuer, account string) string {
+	if issuer == "" {
+		return account
 	}
-	query := u.Query()
-	issuer := query.Get("issuer")
-	secret := query.Get("secret")
-	if secret == "" {
-		return nil, ErrURIFormat
+	return issuer + ":" + account
+}
+
+// TOTP 使用 KeyURI 中的参数创建 TOTP 结构体，可以将 FromURI 解析的结果直接用于生成和校验 token。
+//
+// type 不是 totp 或者参数不合法时返回错误，错误类型与 Validate 一致。
+//
+// Example:
+//
+//	key, err := FromURI(uri)
+//	totp, err := key.TOTP(WithSkew(1))
+func (p KeyURI) TOTP(options ...TOTPOption) (*TOTP, error) {
+	if p.Type != "totp" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidType, p.Type)
 	}
-	digits, err := atoi(query.Get("digits"), 6)
+	shared, err := p.options()
 	if err != nil {
-		return nil, ErrURIFormat
+		return nil, err
 	}
-	digitsEnum, err := Digits.from(DigitsSix, digits)
-	if err != nil {
-		return nil, ErrURIFormat
+	if p.Period < minPeriodNumber {
+		return nil, fmt.Errorf("%w: %d is less than %d", ErrInvalidPeriod, p.Period, minPeriodNumber)
 	}
-	period, err := atoi(query.Get("period"), 30)
-	if err != nil || period < minPeriodNumber {
-		return nil, ErrURIFormat
+	opts := append(AsTOTPOptions(shared...), WithPeriod(p.Period))
+	if p.Epoch != 0 {
+		opts = append(opts, WithEpoch(time.Unix(p.Epoch, 0)), WithEpochParameter(true))
 	}
-	counter, err := parseInt(query.Get("counter"), 1, 10, 64)
-	if err != nil {
-		return nil, ErrURIFormat
+	return NewTOTPWithError(p.Secret, append(opts, options...)...)
+}
+
+// HOTP 使用 KeyURI 中的参数创建 HOTP 结构体，Counter 对应 WithCounter。
+//
+// type 不是 hotp 或者参数不合法时返回错误，错误类型与 Validate 一致。
+func (p KeyURI) HOTP(options ...HOTPOption) (*HOTP, error) {
+	if p.Type != "hotp" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidType, p.Type)
 	}
-	algorithm, err := Algorithms.from(AlgorithmSHA1, query.Get("algorithm"))
+	shared, err := p.options()
 	if err != nil {
-		return nil, ErrURIFormat
+		return nil, err
 	}
+	opts := append(AsHOTPOptions(shared...), WithCounter(p.Counter))
+	return NewHOTPWithError(p.Secret, append(opts, options...)...)
+}
 
-	if u.Host == "hotp" {
-		period = 0
-	} else {
-		counter = 0
+// options 将 algorithm、digits、encoder 以及 issuer、账户名称转换为 Option，
+// 创建的 TOTP、HOTP 调用 KeyURI() 时不需要再传入账户名称和发行商。
+func (p KeyURI) options() ([]Option, error) {
+	if p.Secret == "" {
+		return nil, ErrMissingSecret
 	}
-
-	// 按照规则 issuer 和 account 都不能包含 ":"
-	path := strings.Split(u.Path, ":")
-	// 如果 label 存在 issuer 但是 params 中不存在 issuer
-	if issuer == "" {
-		if len(path) > 1 {
-			issuer = path[0][1:]
-		}
+	algorithm, err := Algorithms.from(AlgorithmSHA1, p.Algorithm)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, p.Algorithm)
 	}
-	var label = u.Path[1:]
-	// 如果 label 不存在 issuer 但是 params 中存在 issuer
-	if len(path) == 1 && issuer != "" {
-		label = fmt.Sprintf("%s:%s", issuer, u.Path[1:])
+	digits, err := Digits.from(DigitsSix, p.Digits)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidDigits, p.Digits)
 	}
-
-	key := &KeyURI{
-		Type:      u.Host,
-		Label:     label,
-		Algorithm: algorithm.String(),
-		Digits:    int(digitsEnum),
-		Counter:   counter,
-		Period:    period,
-		Issuer:    issuer,
-		Secret:    secret,
-	}
-	return key, nil
+	encoder, err := Encoder.from(EncoderDefault, p.Encoder)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedEncoder, p.Encoder)
+	}
+	return []Option{
+		WithAlgorithm(algorithm), WithDigits(digits), WithEncoder(encoder),
+		WithIssuer(p.Issuer), WithAccountName(p.AccountName),
+	}, nil
 }
@@ -1,11 +1,9 @@
 package otp
 
 import (
-	"bytes"
-	"github.com/makiuchi-d/gozxing"
-	"github.com/makiuchi-d/gozxing/qrcode"
+	"errors"
 	"github.com/stretchr/testify/assert"
-	"image"
+	"strings"
 	"testing"
 )
 
@@ -17,13 +15,14 @@ func TestFromURI(t *testing.T) {
 		uri, err := FromURI(expected)
 		assert.Nil(t, err)
 		assert.Equal(t, &KeyURI{
-			Digits:    6,
-			Counter:   1,
-			Type:      "hotp",
-			Algorithm: "SHA1",
-			Issuer:    "Example",
-			Label:     "Example:alice@google.com",
-			Secret:    "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6",
+			Digits:      6,
+			Counter:     1,
+			Type:        "hotp",
+			Algorithm:   "SHA1",
+			Issuer:      "Example",
+			Label:       "Example:alice@google.com",
+			AccountName: "alice@google.com",
+			Secret:      "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6",
 		}, uri)
 
 		// totp
@@ -31,13 +30,14 @@ func TestFromURI(t *testing.T) {
 		uri2, err := FromURI(expected2)
 		assert.Nil(t, err)
 		assert.Equal(t, &KeyURI{
-			Digits:    6,
-			Period:    30,
-			Type:      "totp",
-			Algorithm: "SHA1",
-			Issuer:    "Example",
-			Label:     "Example:alice@google.com",
-			Secret:    "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6",
+			Digits:      6,
+			Period:      30,
+			Type:        "totp",
+			Algorithm:   "SHA1",
+			Issuer:      "Example",
+			Label:       "Example:alice@google.com",
+			AccountName: "alice@google.com",
+			Secret:      "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6",
 		}, uri2)
 	})
 
@@ -46,13 +46,14 @@ func TestFromURI(t *testing.T) {
 		uri, err := FromURI(expected)
 		assert.Nil(t, err)
 		assert.Equal(t, &KeyURI{
-			Digits:    8,
-			Period:    60,
-			Type:      "totp",
-			Algorithm: "SHA256",
-			Issuer:    "Example",
-			Label:     "Example:alice@google.com",
-			Secret:    "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6",
+			Digits:      8,
+			Period:      60,
+			Type:        "totp",
+			Algorithm:   "SHA256",
+			Issuer:      "Example",
+			Label:       "Example:alice@google.com",
+			AccountName: "alice@google.com",
+			Secret:      "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6",
 		}, uri)
 	})
 
@@ -61,62 +62,92 @@ func TestFromURI(t *testing.T) {
 		uri, err := FromURI(expected)
 		assert.Nil(t, err)
 		assert.Equal(t, &KeyURI{
-			Digits:    6,
-			Counter:   1,
-			Type:      "hotp",
-			Algorithm: "SHA1",
-			Issuer:    "Example",
-			Label:     "Example:alice@google.com",
-			Secret:    "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6",
+			Digits:      6,
+			Counter:     1,
+			Type:        "hotp",
+			Algorithm:   "SHA1",
+			Issuer:      "Example",
+			Label:       "Example:alice@google.com",
+			AccountName: "alice@google.com",
+			Secret:      "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6",
 		}, uri)
 
 		expected2 := "otpauth://totp/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&issuer=Example&algorithm=SHA512"
 		uri2, err := FromURI(expected2)
 		assert.Nil(t, err)
 		assert.Equal(t, &KeyURI{
-			Digits:    6,
-			Period:    30,
-			Type:      "totp",
-			Algorithm: "SHA512",
-			Issuer:    "Example",
-			Label:     "Example:alice@google.com",
-			Secret:    "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6",
+			Digits:      6,
+			Period:      30,
+			Type:        "totp",
+			Algorithm:   "SHA512",
+			Issuer:      "Example",
+			Label:       "Example:alice@google.com",
+			AccountName: "alice@google.com",
+			Secret:      "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6",
 		}, uri2)
 	})
 
+	t.Run("case3.1: sha3 algorithm parameter values", func(t *testing.T) {
+		uri, err := FromURI("otpauth://totp/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&issuer=Example&algorithm=SHA3-256")
+		assert.Nil(t, err)
+		assert.Equal(t, "SHA3-256", uri.Algorithm)
+
+		uri2, err := FromURI("otpauth://totp/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&issuer=Example&algorithm=sha3_512")
+		assert.Nil(t, err)
+		assert.Equal(t, "SHA3-512", uri2.Algorithm)
+
+		key := NewTOTP(TestSecret20, WithAlgorithm(AlgorithmSHA3_512)).KeyURI("alice@google.com", "Example")
+		assert.Equal(t, "otpauth://totp/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&issuer=Example&algorithm=SHA3-512", key.URI().String())
+	})
+
 	t.Run("case4: bad uris and uris that don t support parameters", func(t *testing.T) {
-		var errorUris = []string{
+		var errorUris = []struct {
+			uri string
+			err error
+		}{
 			// 缺参数，无法识别等。
-			"otpauth1://to1tp/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&issuer=Example",
-			"otpauth://totp1/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&issuer=Example",
-			"otpauth://xxxx/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&issuer=Example",
-			"otpauth://totp/Example:alice@google.com?counter=1&issuer=Example",
+			{"otpauth1://to1tp/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&issuer=Example", ErrInvalidScheme},
+			{"otpauth://totp1/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&issuer=Example", ErrInvalidType},
+			{"otpauth://xxxx/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&issuer=Example", ErrInvalidType},
+			{"otpauth://totp/Example:alice@google.com?counter=1&issuer=Example", ErrMissingSecret},
+			{"otpauth://totp/%zz?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6", ErrURIFormat},
 			// 不支持的参数
 			// algorithm 不支持 md5
-			"otpauth://totp/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&counter=1&issuer=Example&algorithm=md5",
-			// Digits 只支持 6 和 8
-			"otpauth://totp/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&counter=1&issuer=Example&digits=4",
+			{"otpauth://totp/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&counter=1&issuer=Example&algorithm=md5", ErrUnsupportedAlgorithm},
+			// Digits 只支持 4 到 10
+			{"otpauth://totp/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&counter=1&issuer=Example&digits=3", ErrInvalidDigits},
+			{"otpauth://totp/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&counter=1&issuer=Example&digits=11", ErrInvalidDigits},
+			{"otpauth://totp/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&counter=1&issuer=Example&digits=six", ErrInvalidDigits},
 			// period 不能小于 minPeriodNumber
-			"otpauth://totp/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&counter=1&issuer=Example&period=4",
+			{"otpauth://totp/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&counter=1&issuer=Example&period=4", ErrInvalidPeriod},
+			{"otpauth://hotp/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&counter=x&issuer=Example", ErrInvalidCounter},
+			{"otpauth://totp/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&encoder=x", ErrUnsupportedEncoder},
 		}
-		for _, uri := range errorUris {
-			_, err := FromURI(uri)
-			assert.Error(t, ErrURIFormat, err)
+		for _, c := range errorUris {
+			_, err := FromURI(c.uri)
+			assert.True(t, errors.Is(err, c.err), c.uri)
+			assert.True(t, errors.Is(err, ErrURIFormat), c.uri)
 		}
 	})
 
+	t.Run("error message contains the invalid value", func(t *testing.T) {
+		_, err := FromURI("otpauth://totp/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&digits=11")
+		assert.EqualError(t, err, "uri format error: invalid digits: 11")
+	})
+
 	t.Run("case5: label 存在 issuer 值，URI 不存在 issuer 参数", func(t *testing.T) {
 		expected := "otpauth://hotp/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&counter=1"
 		uri, err := FromURI(expected)
 		assert.Nil(t, err)
 		assert.Equal(t, &KeyURI{
-			Digits:    6,
-			Counter:   1,
-			Type:      "hotp",
-			Algorithm: "SHA1",
-			Issuer:    "Example",
-			Label:     "Example:alice@google.com",
-			Secret:    "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6",
+			Digits:      6,
+			Counter:     1,
+			Type:        "hotp",
+			Algorithm:   "SHA1",
+			Issuer:      "Example",
+			Label:       "Example:alice@google.com",
+			AccountName: "alice@google.com",
+			Secret:      "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6",
 		}, uri)
 	})
 
@@ -125,13 +156,14 @@ func TestFromURI(t *testing.T) {
 		uri, err := FromURI(expected)
 		assert.Nil(t, err)
 		assert.Equal(t, &KeyURI{
-			Digits:    6,
-			Counter:   1,
-			Type:      "hotp",
-			Algorithm: "SHA1",
-			Issuer:    "Example",
-			Label:     "Example:alice@google.com",
-			Secret:    "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6",
+			Digits:      6,
+			Counter:     1,
+			Type:        "hotp",
+			Algorithm:   "SHA1",
+			Issuer:      "Example",
+			Label:       "Example:alice@google.com",
+			AccountName: "alice@google.com",
+			Secret:      "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6",
 		}, uri)
 	})
 
@@ -140,17 +172,81 @@ func TestFromURI(t *testing.T) {
 		uri, err := FromURI(expected)
 		assert.Nil(t, err)
 		assert.Equal(t, &KeyURI{
-			Digits:    6,
-			Counter:   1,
-			Type:      "hotp",
-			Algorithm: "SHA1",
-			Issuer:    "",
-			Label:     "alice@google.com",
-			Secret:    "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6",
+			Digits:      6,
+			Counter:     1,
+			Type:        "hotp",
+			Algorithm:   "SHA1",
+			Issuer:      "",
+			Label:       "alice@google.com",
+			AccountName: "alice@google.com",
+			Secret:      "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6",
 		}, uri)
 	})
 }
 
+func TestParseLabel(t *testing.T) {
+	cases := []struct {
+		label, issuer, account string
+	}{
+		{"alice@google.com", "", "alice@google.com"},
+		{"Example:alice@google.com", "Example", "alice@google.com"},
+		{"Example: alice@google.com", "Example", "alice@google.com"},
+		{"Example:   alice", "Example", "alice"},
+		{"Example Co:alice smith", "Example Co", "alice smith"},
+		{":alice", "", "alice"},
+	}
+	for _, c := range cases {
+		issuer, account, err := ParseLabel(c.label)
+		assert.Nil(t, err, c.label)
+		assert.Equal(t, c.issuer, issuer, c.label)
+		assert.Equal(t, c.account, account, c.label)
+	}
+
+	_, _, err := ParseLabel("Example:alice:bob")
+	assert.ErrorIs(t, err, ErrLabelColon)
+	_, _, err = ParseLabel("Example: ")
+	assert.ErrorIs(t, err, ErrLabelEmpty)
+	_, _, err = ParseLabel("")
+	assert.ErrorIs(t, err, ErrLabelEmpty)
+}
+
+func TestFromURI_Label(t *testing.T) {
+	uris := []string{
+		"otpauth://totp/Example:alice@google.com?secret=" + TestSecret20 + "&issuer=Example",
+		"otpauth://totp/Example:%20alice@google.com?secret=" + TestSecret20 + "&issuer=Example",
+		"otpauth://totp/Example%3Aalice@google.com?secret=" + TestSecret20 + "&issuer=Example",
+		"otpauth://totp/Example%3A%20%20alice@google.com?secret=" + TestSecret20,
+		"otpauth://totp/alice@google.com?secret=" + TestSecret20 + "&issuer=Example",
+	}
+	expected := NewTOTP(TestSecret20).KeyURI("alice@google.com", "Example")
+	for _, uri := range uris {
+		key, err := FromURI(uri)
+		assert.Nil(t, err, uri)
+		assert.Equal(t, expected, key, uri)
+	}
+
+	// issuer 为空时 label 不包含冒号
+	key, err := FromURI("otpauth://totp/:alice?secret=" + TestSecret20)
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", key.Label)
+	assert.Equal(t, "otpauth://totp/alice?secret="+TestSecret20+"&issuer=", NewTOTP(TestSecret20).KeyURI("alice", "").URI().String())
+
+	invalid := []struct {
+		uri string
+		err error
+	}{
+		{"otpauth://totp/Example:alice?secret=" + TestSecret20 + "&issuer=Other", ErrIssuerMismatch},
+		{"otpauth://totp/Example:alice:bob?secret=" + TestSecret20, ErrURIFormat},
+		{"otpauth://totp/?secret=" + TestSecret20, ErrURIFormat},
+		{"otpauth://totp?secret=" + TestSecret20, ErrURIFormat},
+	}
+	for _, c := range invalid {
+		_, err := FromURI(c.uri)
+		assert.ErrorIs(t, err, c.err, c.uri)
+		assert.ErrorIs(t, err, ErrURIFormat, c.uri)
+	}
+}
+
 func TestKeyURI_URI(t *testing.T) {
 	t.Run("uri for default parameters", func(t *testing.T) {
 		expected := "otpauth://hotp/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&issuer=Example&counter=1"
@@ -179,25 +275,129 @@ func TestKeyURI_URI(t *testing.T) {
 	})
 }
 
-func TestKeyURI_QRCode(t *testing.T) {
-	expected := "otpauth://hotp/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&issuer=Example&counter=1"
-	key := KeyURI{
-		Digits:    6,
-		Counter:   1,
-		Type:      "hotp",
-		Algorithm: "SHA1",
-		Issuer:    "Example",
-		Label:     "Example:alice@google.com",
-		Secret:    "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6",
+func TestKeyURI_AccountName(t *testing.T) {
+	key := NewTOTP(TestSecret20).KeyURI("alice smith@google.com", "Example Co")
+	assert.Equal(t, "alice smith@google.com", key.AccountName)
+
+	parsed, err := FromURI(key.URI().String())
+	assert.Nil(t, err)
+	assert.Equal(t, "alice smith@google.com", parsed.AccountName)
+
+	// Label 为空时使用 Issuer 和 AccountName，修改 issuer 不会出现重复的前缀
+	parsed.Label = ""
+	parsed.Issuer = "Other"
+	assert.Equal(t, "otpauth://totp/Other:alice%20smith@google.com?secret="+TestSecret20+"&issuer=Other", parsed.URI().String())
+	assert.Nil(t, parsed.Validate())
+
+	key = &KeyURI{Type: "totp", Label: "Example:alice", Issuer: "Example", Secret: TestSecret20}
+	key.Normalize()
+	assert.Equal(t, "alice", key.AccountName)
+}
+
+func TestKeyURI_URIEncoding(t *testing.T) {
+	issuers := []string{"Example Co", "A&B", "C++ Shop", "100% Secure", "例子", "Ünïcødé = ok?", "a/b#c"}
+	for _, issuer := range issuers {
+		expected := NewTOTP(TestSecret20).KeyURI("alice smith@google.com", issuer)
+		uri := expected.URI().String()
+		assert.NotContains(t, uri, " ", issuer)
+
+		key, err := FromURI(uri)
+		assert.Nil(t, err, issuer)
+		// FromURI 与 TOTP.KeyURI 得到相同的结构
+		assert.Equal(t, expected, key, issuer)
+		assert.Equal(t, issuer, key.Issuer)
+		assert.Equal(t, issuer+":alice smith@google.com", key.Label)
+		assert.Equal(t, uri, key.URI().String(), issuer)
+		assert.Nil(t, key.Validate(), issuer)
+	}
+
+	uri := NewTOTP(TestSecret20).KeyURI("alice", "A&B Co").URI().String()
+	assert.Equal(t, "otpauth://totp/A&B%20Co:alice?secret="+TestSecret20+"&issuer=A%26B%20Co", uri)
+}
+
+func TestKeyURI_URIRoundTrip(t *testing.T) {
+	// 手动构造的 KeyURI 中的 Label 和 Issuer 是未编码的值，只在 URI 中编码一次
+	for _, issuer := range []string{"C++ Corp", "100%", "50%25 off", "a+b%2Bc"} {
+		key := KeyURI{Type: "totp", Label: issuer + ":alice", Issuer: issuer, Algorithm: "SHA1", Digits: 6, Period: 30, Secret: TestSecret20}
+		parsed, err := FromURI(key.URI().String())
+		if assert.Nil(t, err, issuer) {
+			assert.Equal(t, issuer, parsed.Issuer)
+			assert.Equal(t, issuer+":alice", parsed.Label)
+			assert.Equal(t, key.URI().String(), parsed.URI().String())
+		}
 	}
-	png, err := key.QRCode()
+	uri := KeyURI{Type: "totp", Label: "C++ Corp:alice", Issuer: "C++ Corp", Algorithm: "SHA1", Digits: 6, Period: 30, Secret: TestSecret20}.URI()
+	assert.Equal(t, "C%2B%2B%20Corp", strings.Split(uri.RawQuery, "issuer=")[1])
+
+	// 冒号在 issuer 参数中编码为 %3A 并原样解码，issuer 不能包含冒号，FromURI 返回错误而不是错误地解码
+	key := KeyURI{Type: "totp", AccountName: "alice", Issuer: "a:b+c%", Algorithm: "SHA1", Digits: 6, Period: 30, Secret: TestSecret20}
+	u := key.URI()
+	assert.Equal(t, "a:b+c%", u.Query().Get("issuer"))
+	assert.Equal(t, "a:b+c%:alice", u.Path[1:])
+	_, err := FromURI(u.String())
+	assert.ErrorIs(t, err, ErrURIFormat)
+}
+
+func TestKeyURI_TOTP(t *testing.T) {
+	expected := NewTOTP(TestSecret32, WithAlgorithm(AlgorithmSHA256), WithDigits(DigitsEight), WithPeriod(60),
+		WithAccountName("alice@google.com"), WithIssuer("Example"))
+	uri := expected.KeyURI().URI().String()
+	key, err := FromURI(uri)
+	assert.Nil(t, err)
+
+	totp, err := key.TOTP()
 	assert.Nil(t, err)
-	img, _, err := image.Decode(bytes.NewReader(png))
+	assert.Equal(t, expected.Otp, totp.Otp)
+	assert.Equal(t, expected.Secret, totp.Secret)
+	// 账户名称和发行商保留下来，可以直接重新生成相同的 URI
+	assert.Equal(t, uri, totp.KeyURI().URI().String())
+
+	// 额外的 option 在 uri 参数之后应用
+	totp, err = key.TOTP(WithSkew(1))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, totp.Skew)
+
+	steam, err := NewSteamTOTP(TestSecret20).KeyURI("alice", "Steam").TOTP()
 	assert.Nil(t, err)
-	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	assert.Equal(t, EncoderSteam, steam.Encoder)
+
+	_, err = key.HOTP()
+	assert.ErrorIs(t, err, ErrInvalidType)
+
+	invalid := *key
+	invalid.Period = 0
+	_, err = invalid.TOTP()
+	assert.ErrorIs(t, err, ErrInvalidPeriod)
+	invalid = *key
+	invalid.Secret = "111111"
+	_, err = invalid.TOTP()
+	assert.ErrorIs(t, err, ErrSecretDecode)
+	invalid.Secret = ""
+	_, err = invalid.TOTP()
+	assert.ErrorIs(t, err, ErrMissingSecret)
+	invalid = *key
+	invalid.Algorithm = "MD5"
+	_, err = invalid.TOTP()
+	assert.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+	invalid = *key
+	invalid.Digits = 11
+	_, err = invalid.TOTP()
+	assert.ErrorIs(t, err, ErrInvalidDigits)
+}
+
+func TestKeyURI_HOTP(t *testing.T) {
+	expected := NewHOTP(TestSecret20, WithCounter(10), WithAlgorithm(AlgorithmSHA512),
+		WithAccountName("alice@google.com"), WithIssuer("Example"))
+	uri := expected.KeyURI().URI().String()
+	key, err := FromURI(uri)
 	assert.Nil(t, err)
-	qrReader := qrcode.NewQRCodeReader()
-	result, err := qrReader.Decode(bmp, nil)
+
+	hotp, err := key.HOTP()
 	assert.Nil(t, err)
-	assert.Equal(t, expected, result.String())
+	assert.Equal(t, expected.Otp, hotp.Otp)
+	assert.Equal(t, expected.At(10), hotp.At(10))
+	assert.Equal(t, uri, hotp.KeyURI().URI().String())
+
+	_, err = key.TOTP()
+	assert.ErrorIs(t, err, ErrInvalidType)
 }
@@ -0,0 +1,62 @@
+package otp
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ValidateStrict 严格模式校验 label 和 issuer，在生成二维码之前发现部分验证器应用不能正确处理的标签。
+//
+// 校验规则：
+//   - label 不能为空，不能超过 64 个字符。
+//   - issuer 和账户名称不能有首尾空白（冒号后的空格是规范允许的，不算在内）。
+//   - 只能包含可打印字符。
+//   - issuer 和账户名称都不能包含冒号。
+//
+// 返回的错误可以使用 errors.Is 判断类型，错误信息中包含具体的字段和原因，可以直接展示给用户。
+func (p KeyURI) ValidateStrict() error {
+	label, issuer := p.label(), p.Issuer
+	if label == "" {
+		return ErrLabelEmpty
+	}
+	if n := utf8.RuneCountInString(label); n > maxLabelLength {
+		return fmt.Errorf("%w: label has %d characters, at most %d are allowed", ErrLabelTooLong, n, maxLabelLength)
+	}
+	account := label
+	if i := strings.Index(label, ":"); i != -1 {
+		if err := validateLabelPart("issuer", label[:i]); err != nil {
+			return err
+		}
+		account = strings.TrimLeft(label[i+1:], " ")
+	}
+	if err := validateLabelPart("account", account); err != nil {
+		return err
+	}
+	if issuer != "" {
+		if err := validateLabelPart("issuer", issuer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateLabelPart 校验 issuer 或账户名称
+func validateLabelPart(field, value string) error {
+	if value == "" {
+		return fmt.Errorf("%w: %s is empty", ErrLabelEmpty, field)
+	}
+	if strings.TrimSpace(value) != value {
+		return fmt.Errorf("%w: %s %q", ErrLabelWhitespace, field, value)
+	}
+	for i, r := range value {
+		if r == ':' {
+			return fmt.Errorf("%w: %s %q has a colon at position %d", ErrLabelColon, field, value, i)
+		}
+		if !unicode.IsPrint(r) {
+			return fmt.Errorf("%w: %s %q has %U at position %d", ErrLabelNotPrintable, field, value, r, i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package otp
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestKeyURI_ValidateStrict(t *testing.T) {
+	t.Run("valid labels", func(t *testing.T) {
+		assert.Nil(t, NewTOTP(TestSecret20).KeyURI("alice@google.com", "Example").ValidateStrict())
+		assert.Nil(t, NewTOTP(TestSecret20).KeyURI("alice smith", "Example Co").ValidateStrict())
+		assert.Nil(t, NewTOTP(TestSecret20).KeyURI("张三", "示例").ValidateStrict())
+		assert.Nil(t, KeyURI{Label: "Example: alice@google.com", Issuer: "Example"}.ValidateStrict())
+		assert.Nil(t, KeyURI{Label: "alice@google.com"}.ValidateStrict())
+	})
+
+	var cases = []struct {
+		key KeyURI
+		err error
+	}{
+		{KeyURI{Label: ""}, ErrLabelEmpty},
+		{KeyURI{Label: ":alice"}, ErrLabelEmpty},
+		{KeyURI{Label: "Example:" + strings.Repeat("a", 60)}, ErrLabelTooLong},
+		{KeyURI{Label: " Example:alice"}, ErrLabelWhitespace},
+		{KeyURI{Label: "Example:alice "}, ErrLabelWhitespace},
+		{KeyURI{Label: "Example:alice", Issuer: "Example "}, ErrLabelWhitespace},
+		{KeyURI{Label: "Example:al\tice"}, ErrLabelNotPrintable},
+		{KeyURI{Label: "Example:al​ice"}, ErrLabelNotPrintable},
+		{KeyURI{Label: "Example:alice:bob"}, ErrLabelColon},
+	}
+	for _, c := range cases {
+		err := c.key.ValidateStrict()
+		assert.True(t, errors.Is(err, c.err), "label %q: %v", c.key.Label, err)
+	}
+}
@@ -0,0 +1,142 @@
+package otp
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+// MarshalText 实现 encoding.TextMarshaler 接口，输出与 uri 上的 algorithm 参数一致，例如 SHA1。
+func (h Algorithms) MarshalText() ([]byte, error) {
+	switch h {
+	case AlgorithmSHA1, AlgorithmSHA224, AlgorithmSHA256, AlgorithmSHA384, AlgorithmSHA512, AlgorithmSHA3_256, AlgorithmSHA3_512:
+		return []byte(h.String()), nil
+	default:
+		return nil, errors.New("unknown algorithm " + strconv.Itoa(int(h)))
+	}
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler 接口，忽略大小写，空字符串解析为 AlgorithmSHA1。
+func (h *Algorithms) UnmarshalText(text []byte) error {
+	algorithm, err := Algorithms.from(AlgorithmSHA1, string(text))
+	if err != nil {
+		return err
+	}
+	*h = algorithm
+	return nil
+}
+
+// MarshalText 实现 encoding.TextMarshaler 接口，输出十进制数字。
+func (d Digits) MarshalText() ([]byte, error) {
+	if _, err := Digits.from(DigitsSix, int(d)); err != nil {
+		return nil, err
+	}
+	return []byte(strconv.Itoa(int(d))), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler 接口，只接受合法的长度。
+func (d *Digits) UnmarshalText(text []byte) error {
+	i, err := strconv.Atoi(string(text))
+	if err != nil {
+		return errors.New("unknown 'digits' number")
+	}
+	digits, err := Digits.from(DigitsSix, i)
+	if err != nil {
+		return err
+	}
+	*d = digits
+	return nil
+}
+
+// MarshalJSON 实现 json.Marshaler 接口，在 JSON 中使用数字而不是字符串。
+func (d Digits) MarshalJSON() ([]byte, error) {
+	return d.MarshalText()
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler 接口，同时接受数字和字符串形式。
+func (d *Digits) UnmarshalJSON(data []byte) error {
+	if len(data) > 1 && data[0] == '"' {
+		var str string
+		if err := json.Unmarshal(data, &str); err != nil {
+			return err
+		}
+		data = []byte(str)
+	}
+	return d.UnmarshalText(data)
+}
+
+// MarshalText 实现 encoding.TextMarshaler 接口，输出与 uri 上的 encoder 参数一致，默认值为空字符串。
+func (e Encoder) MarshalText() ([]byte, error) {
+	switch e {
+	case EncoderDefault, EncoderSteam:
+		return []byte(e.String()), nil
+	default:
+		return nil, errors.New("unknown encoder " + strconv.Itoa(int(e)))
+	}
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler 接口。
+func (e *Encoder) UnmarshalText(text []byte) error {
+	encoder, err := Encoder.from(EncoderDefault, string(text))
+	if err != nil {
+		return err
+	}
+	*e = encoder
+	return nil
+}
+
+// MarshalText 实现 encoding.TextMarshaler 接口，输出 otpauth uri。
+func (p KeyURI) MarshalText() ([]byte, error) {
+	return []byte(p.URI().String()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler 接口，使用 FromURI 解析 otpauth uri。
+func (p *KeyURI) UnmarshalText(text []byte) error {
+	key, err := FromURI(string(text))
+	if err != nil {
+		return err
+	}
+	*p = *key
+	return nil
+}
+
+// keyURIJSON KeyURI 在 JSON 中的对象形式。
+type keyURIJSON struct {
+	Type        string `json:"type"`
+	Label       string `json:"label"`
+	AccountName string `json:"accountName,omitempty"`
+	Algorithm   string `json:"algorithm"`
+	Digits      int    `json:"digits"`
+	Counter     int64  `json:"counter,omitempty"`
+	Period      int    `json:"period,omitempty"`
+	Issuer      string `json:"issuer"`
+	Secret      string `json:"secret"`
+	Encoder     string `json:"encoder,omitempty"`
+	Epoch       int64  `json:"epoch,omitempty"`
+}
+
+// MarshalJSON 实现 json.Marshaler 接口，输出便于在配置文件中阅读和编辑的 JSON 对象，字段名为小写。
+//
+// Example:
+//
+//	{"type":"totp","label":"Example:alice@google.com","accountName":"alice@google.com","algorithm":"SHA1","digits":6,"period":30,"issuer":"Example","secret":"..."}
+func (p KeyURI) MarshalJSON() ([]byte, error) {
+	return json.Marshal(keyURIJSON(p))
+}
+
+// UnmarshalJSON 实现 json.Unmarshaler 接口，同时接受 MarshalJSON 输出的对象以及 otpauth uri 字符串。
+func (p *KeyURI) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var uri string
+		if err := json.Unmarshal(data, &uri); err != nil {
+			return err
+		}
+		return p.UnmarshalText([]byte(uri))
+	}
+	var v keyURIJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*p = KeyURI(v)
+	return nil
+}
@@ -0,0 +1,94 @@
+package otp
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAlgorithms_MarshalText(t *testing.T) {
+	data, err := json.Marshal(map[string]Algorithms{"a": AlgorithmSHA256, "b": AlgorithmSHA3_512})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"a":"SHA256","b":"SHA3-512"}`, string(data))
+
+	var v map[string]Algorithms
+	assert.Nil(t, json.Unmarshal([]byte(`{"a":"sha512","b":""}`), &v))
+	assert.Equal(t, map[string]Algorithms{"a": AlgorithmSHA512, "b": AlgorithmSHA1}, v)
+
+	assert.NotNil(t, json.Unmarshal([]byte(`{"a":"MD5"}`), &v))
+	_, err = json.Marshal(Algorithms(0))
+	assert.NotNil(t, err)
+}
+
+func TestDigits_MarshalJSON(t *testing.T) {
+	data, err := json.Marshal([]Digits{DigitsSix, DigitsEight})
+	assert.Nil(t, err)
+	assert.Equal(t, `[6,8]`, string(data))
+
+	var v []Digits
+	assert.Nil(t, json.Unmarshal([]byte(`[8,"6"]`), &v))
+	assert.Equal(t, []Digits{DigitsEight, DigitsSix}, v)
+
+	assert.Nil(t, json.Unmarshal([]byte(`[7]`), &v))
+	assert.Equal(t, []Digits{7}, v)
+	assert.NotNil(t, json.Unmarshal([]byte(`[11]`), &v))
+	assert.NotNil(t, json.Unmarshal([]byte(`["x"]`), &v))
+	_, err = json.Marshal(Digits(3))
+	assert.NotNil(t, err)
+
+	text, err := DigitsEight.MarshalText()
+	assert.Nil(t, err)
+	assert.Equal(t, "8", string(text))
+}
+
+func TestEncoder_MarshalText(t *testing.T) {
+	data, err := json.Marshal([]Encoder{EncoderDefault, EncoderSteam})
+	assert.Nil(t, err)
+	assert.Equal(t, `["","steam"]`, string(data))
+
+	var v []Encoder
+	assert.Nil(t, json.Unmarshal([]byte(`["steam",""]`), &v))
+	assert.Equal(t, []Encoder{EncoderSteam, EncoderDefault}, v)
+	assert.NotNil(t, json.Unmarshal([]byte(`["unknown"]`), &v))
+}
+
+func TestOtp_MarshalJSON(t *testing.T) {
+	otp := NewTOTP(TestSecret20, WithAlgorithm(AlgorithmSHA256), WithDigits(DigitsEight)).Otp
+	data, err := json.Marshal(otp)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"Digits":8`)
+	assert.Contains(t, string(data), `"Algorithm":"SHA256"`)
+
+	var v Otp
+	assert.Nil(t, json.Unmarshal(data, &v))
+	assert.Equal(t, otp, v)
+}
+
+func TestKeyURI_MarshalJSON(t *testing.T) {
+	key := NewTOTP(TestSecret20, WithPeriod(60)).KeyURI("alice@google.com", "Example")
+	data, err := json.Marshal(key)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"type":"totp","label":"Example:alice@google.com","accountName":"alice@google.com","algorithm":"SHA1","digits":6,"period":60,"issuer":"Example","secret":"`+TestSecret20+`"}`, string(data))
+
+	var v KeyURI
+	assert.Nil(t, json.Unmarshal(data, &v))
+	assert.Equal(t, *key, v)
+
+	t.Run("uri string", func(t *testing.T) {
+		var v KeyURI
+		assert.Nil(t, json.Unmarshal([]byte(`"`+key.URI().String()+`"`), &v))
+		assert.Equal(t, *key, v)
+
+		assert.ErrorIs(t, json.Unmarshal([]byte(`"otpauth://unknown"`), &v), ErrURIFormat)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		text, err := key.MarshalText()
+		assert.Nil(t, err)
+		assert.Equal(t, key.URI().String(), string(text))
+
+		var v KeyURI
+		assert.Nil(t, v.UnmarshalText(text))
+		assert.Equal(t, *key, v)
+	})
+}
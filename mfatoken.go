@@ -0,0 +1,101 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minMFATokenKeyLength MFATokenSigner 秘钥的最小字节数，与 HMAC-SHA256 的输出长度相同。
+const minMFATokenKeyLength = 32
+
+// MFATokenSigner 签发和校验表示"已完成 OTP 校验"的会话令牌。
+//
+// Verify 通过后签发一个带有过期时间的令牌保存在 cookie 或会话中，之后的请求只需要校验令牌，
+// 不需要每次都让用户输入 token。令牌格式为 base64url(account).expires.signature，不包含敏感信息，但也没有加密。
+type MFATokenSigner struct {
+	key   []byte
+	clock func() time.Time
+}
+
+// NewMFATokenSigner 创建一个 MFATokenSigner。
+//
+// Params:
+//
+//	key: 签名使用的秘钥，仅服务端持有，至少 32 字节，不要与 ProvisioningSigner 共用。秘钥会被复制，之后修改 key 不影响签名。
+//
+// key 短于 32 字节时返回 ErrSecretTooShort。
+//
+// Example:
+//
+//	signer, err := NewMFATokenSigner(serverKey)
+//	if err != nil {
+//		return err
+//	}
+//	if totp.Verify(token, time.Now()) {
+//		setCookie("mfa", signer.IssueMFAToken(userID, 12*time.Hour))
+//	}
+//	// 之后的请求
+//	account, err := signer.VerifyMFAToken(cookie)
+func NewMFATokenSigner(key []byte) (*MFATokenSigner, error) {
+	if len(key) < minMFATokenKeyLength {
+		return nil, fmt.Errorf("%w: mfa token key has %d bytes, at least %d are required", ErrSecretTooShort, len(key), minMFATokenKeyLength)
+	}
+	return &MFATokenSigner{key: append([]byte(nil), key...)}, nil
+}
+
+// now 返回当前时间。
+func (s *MFATokenSigner) now() time.Time {
+	if s.clock != nil {
+		return s.clock()
+	}
+	return time.Now()
+}
+
+// IssueMFAToken 为 account 签发一个有效期为 ttl 的令牌。
+func (s *MFATokenSigner) IssueMFAToken(account string, ttl time.Duration) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(account)) + "." + strconv.FormatInt(s.now().Add(ttl).Unix(), 10)
+	return payload + "." + s.sign(payload)
+}
+
+// VerifyMFAToken 校验令牌并返回签发时的 account。
+//
+// 签名错误或格式错误返回 ErrSignatureInvalid，已过期返回 ErrMFATokenExpired。
+// 调用方需要确认返回的 account 与当前会话的用户一致。
+func (s *MFATokenSigner) VerifyMFAToken(token string) (string, error) {
+	i := strings.LastIndexByte(token, '.')
+	if i == -1 {
+		return "", ErrSignatureInvalid
+	}
+	payload, signature := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(s.sign(payload)), []byte(signature)) {
+		return "", ErrSignatureInvalid
+	}
+	parts := strings.Split(payload, ".")
+	if len(parts) != 2 {
+		return "", ErrSignatureInvalid
+	}
+	account, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", ErrSignatureInvalid
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", ErrSignatureInvalid
+	}
+	if !s.now().Before(time.Unix(expires, 0)) {
+		return "", ErrMFATokenExpired
+	}
+	return string(account), nil
+}
+
+// sign 使用 HMAC-SHA256 对 payload 签名，添加前缀以区分其他用途的签名。
+func (s *MFATokenSigner) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte("mfa:" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,66 @@
+package otp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMFATokenSigner(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	signer, err := NewMFATokenSigner([]byte("0123456789abcdef0123456789abcdef"))
+	assert.Nil(t, err)
+	signer.clock = func() time.Time { return now }
+
+	token := signer.IssueMFAToken("alice@google.com", time.Hour)
+	assert.Equal(t, 3, len(strings.Split(token, ".")))
+	account, err := signer.VerifyMFAToken(token)
+	assert.Nil(t, err)
+	assert.Equal(t, "alice@google.com", account)
+
+	now = now.Add(time.Hour)
+	_, err = signer.VerifyMFAToken(token)
+	assert.ErrorIs(t, err, ErrMFATokenExpired)
+}
+
+func TestMFATokenSigner_Invalid(t *testing.T) {
+	signer, _ := NewMFATokenSigner([]byte("0123456789abcdef0123456789abcdef"))
+	token := signer.IssueMFAToken("alice", time.Hour)
+	parts := strings.Split(token, ".")
+
+	tampered := []string{
+		"",
+		"abc",
+		// 替换账户
+		signer.IssueMFAToken("bob", time.Hour)[:len(parts[0])] + token[len(parts[0]):],
+		// 延长过期时间
+		parts[0] + ".9999999999." + parts[2],
+		token + "x",
+	}
+	for _, str := range tampered {
+		_, err := signer.VerifyMFAToken(str)
+		assert.ErrorIs(t, err, ErrSignatureInvalid, str)
+	}
+	// 其他秘钥签发的令牌
+	other, _ := NewMFATokenSigner([]byte("fedcba9876543210fedcba9876543210"))
+	_, err := other.VerifyMFAToken(token)
+	assert.ErrorIs(t, err, ErrSignatureInvalid)
+}
+
+func TestNewMFATokenSigner_Key(t *testing.T) {
+	_, err := NewMFATokenSigner(nil)
+	assert.ErrorIs(t, err, ErrSecretTooShort)
+	_, err = NewMFATokenSigner([]byte("0123456789abcdef0123456789abcde"))
+	assert.ErrorIs(t, err, ErrSecretTooShort)
+
+	// 秘钥被复制，调用方修改或清零原切片不影响签名
+	key := []byte("0123456789abcdef0123456789abcdef")
+	signer, err := NewMFATokenSigner(key)
+	assert.Nil(t, err)
+	token := signer.IssueMFAToken("alice", time.Hour)
+	zero(key)
+	account, err := signer.VerifyMFAToken(token)
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", account)
+}
@@ -0,0 +1,319 @@
+package otp
+
+import (
+	"encoding/base64"
+	"net/url"
+	"strings"
+)
+
+// Google Authenticator 导出数据（MigrationPayload）中使用的 protobuf 字段编号与枚举值。
+//
+// See https://github.com/google/google-authenticator-android/issues/118
+const (
+	migrationFieldOtpParameters = 1
+	migrationFieldVersion       = 2
+	migrationFieldBatchSize     = 3
+	migrationFieldBatchIndex    = 4
+
+	migrationFieldSecret    = 1
+	migrationFieldName      = 2
+	migrationFieldIssuer    = 3
+	migrationFieldAlgorithm = 4
+	migrationFieldDigits    = 5
+	migrationFieldType      = 6
+	migrationFieldCounter   = 7
+
+	migrationAlgorithmSHA1   = 1
+	migrationAlgorithmSHA256 = 2
+	migrationAlgorithmSHA512 = 3
+
+	migrationDigitsSix   = 1
+	migrationDigitsEight = 2
+
+	migrationTypeHOTP = 1
+	migrationTypeTOTP = 2
+)
+
+// ParseMigrationURI 解析 Google Authenticator 导出的 otpauth-migration://offline?data=... URI，返回其中包含的所有账号。
+//
+// Google Authenticator 在账号较多时会将导出数据拆分为多个二维码，每个二维码需要分别调用该方法解析。
+// 导出数据中没有 period 参数，TOTP 的 period 固定为 30。
+//
+// Example:
+//
+//	keys, err := ParseMigrationURI(uri)
+//	for _, key := range keys {
+//		fmt.Println(key.URI().String())
+//	}
+func ParseMigrationURI(uri string) ([]*KeyURI, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "otpauth-migration" || u.Host != "offline" {
+		return nil, ErrMigrationFormat
+	}
+	// 部分二维码识别工具不会对 data 进行 URL 编码，"+" 会被解析为空格
+	data := strings.ReplaceAll(u.Query().Get("data"), " ", "+")
+	payload, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		payload, err = base64.RawStdEncoding.DecodeString(data)
+	}
+	if err != nil || len(payload) == 0 {
+		return nil, ErrMigrationFormat
+	}
+	var keys []*KeyURI
+	err = walkProtobuf(payload, func(field int, value uint64, data []byte) error {
+		if field != migrationFieldOtpParameters || data == nil {
+			return nil
+		}
+		key, err := parseMigrationEntry(data)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// parseMigrationEntry 解析单个 OtpParameters 消息。
+func parseMigrationEntry(data []byte) (*KeyURI, error) {
+	var secret []byte
+	var name, issuer string
+	var algorithm, digits, typ uint64
+	var counter int64
+	err := walkProtobuf(data, func(field int, value uint64, data []byte) error {
+		switch field {
+		case migrationFieldSecret:
+			secret = data
+		case migrationFieldName:
+			name = string(data)
+		case migrationFieldIssuer:
+			issuer = string(data)
+		case migrationFieldAlgorithm:
+			algorithm = value
+		case migrationFieldDigits:
+			digits = value
+		case migrationFieldType:
+			typ = value
+		case migrationFieldCounter:
+			counter = int64(value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(secret) == 0 {
+		return nil, ErrMigrationFormat
+	}
+
+	key := &KeyURI{Secret: Base32Encode(secret), Counter: counter}
+	switch typ {
+	case migrationTypeHOTP:
+		key.Type = "hotp"
+	case migrationTypeTOTP, 0:
+		key.Type = "totp"
+		key.Period = 30
+		key.Counter = 0
+	default:
+		return nil, ErrMigrationUnsupported
+	}
+	switch algorithm {
+	case migrationAlgorithmSHA1, 0:
+		key.Algorithm = AlgorithmSHA1.String()
+	case migrationAlgorithmSHA256:
+		key.Algorithm = AlgorithmSHA256.String()
+	case migrationAlgorithmSHA512:
+		key.Algorithm = AlgorithmSHA512.String()
+	default:
+		return nil, ErrMigrationUnsupported
+	}
+	switch digits {
+	case migrationDigitsSix, 0:
+		key.Digits = int(DigitsSix)
+	case migrationDigitsEight:
+		key.Digits = int(DigitsEight)
+	default:
+		return nil, ErrMigrationUnsupported
+	}
+
+	// name 可能是 "issuer:account" 的形式，也可能只有 account
+	account := name
+	if i := strings.Index(name, ":"); i >= 0 {
+		if issuer == "" {
+			issuer = name[:i]
+		}
+		account = strings.TrimLeft(name[i+1:], " ")
+	}
+	key.Label = formatLabel(issuer, account)
+	key.AccountName = account
+	key.Issuer = issuer
+	return key, nil
+}
+
+// MigrationURI 将多个账号编码为 Google Authenticator 可以导入的 otpauth-migration://offline?data=... URI。
+//
+// 导出格式仅支持 SHA1/SHA256/SHA512、6/8 位数字以及 30 秒的 period，其他参数会返回 ErrMigrationUnsupported。
+// 生成的 URI 可以作为二维码的内容供 Google Authenticator 扫码导入，账号过多时二维码会变得难以识别，建议分批导出。
+func MigrationURI(keys ...*KeyURI) (string, error) {
+	var payload []byte
+	for _, key := range keys {
+		entry, err := appendMigrationEntry(nil, key)
+		if err != nil {
+			return "", err
+		}
+		payload = appendProtobufBytes(payload, migrationFieldOtpParameters, entry)
+	}
+	payload = appendProtobufVarint(payload, migrationFieldVersion, 1)
+	payload = appendProtobufVarint(payload, migrationFieldBatchSize, 1)
+	payload = appendProtobufVarint(payload, migrationFieldBatchIndex, 0)
+	query := url.Values{"data": {base64.StdEncoding.EncodeToString(payload)}}
+	return "otpauth-migration://offline?" + query.Encode(), nil
+}
+
+// appendMigrationEntry 将 KeyURI 编码为 OtpParameters 消息。
+func appendMigrationEntry(b []byte, key *KeyURI) ([]byte, error) {
+	secret, err := Base32Decode(key.Secret)
+	if err != nil {
+		return nil, err
+	}
+	algorithm, err := Algorithms.from(AlgorithmSHA1, key.Algorithm)
+	if err != nil {
+		return nil, ErrMigrationUnsupported
+	}
+	var algorithmValue uint64
+	switch algorithm {
+	case AlgorithmSHA1:
+		algorithmValue = migrationAlgorithmSHA1
+	case AlgorithmSHA256:
+		algorithmValue = migrationAlgorithmSHA256
+	case AlgorithmSHA512:
+		algorithmValue = migrationAlgorithmSHA512
+	default:
+		return nil, ErrMigrationUnsupported
+	}
+	var digitsValue uint64
+	switch key.Digits {
+	case 0, int(DigitsSix):
+		digitsValue = migrationDigitsSix
+	case int(DigitsEight):
+		digitsValue = migrationDigitsEight
+	default:
+		return nil, ErrMigrationUnsupported
+	}
+	if key.Encoder != "" || key.Epoch != 0 {
+		return nil, ErrMigrationUnsupported
+	}
+	var typeValue uint64
+	switch key.Type {
+	case "totp":
+		if key.Period != 0 && key.Period != 30 {
+			return nil, ErrMigrationUnsupported
+		}
+		typeValue = migrationTypeTOTP
+	case "hotp":
+		typeValue = migrationTypeHOTP
+	default:
+		return nil, ErrMigrationUnsupported
+	}
+
+	label, issuer := key.label(), key.Issuer
+	name := label
+	if i := strings.Index(label, ":"); i >= 0 {
+		if issuer == "" {
+			issuer = label[:i]
+		}
+		name = strings.TrimLeft(label[i+1:], " ")
+	}
+
+	b = appendProtobufBytes(b, migrationFieldSecret, secret)
+	b = appendProtobufBytes(b, migrationFieldName, []byte(name))
+	b = appendProtobufBytes(b, migrationFieldIssuer, []byte(issuer))
+	b = appendProtobufVarint(b, migrationFieldAlgorithm, algorithmValue)
+	b = appendProtobufVarint(b, migrationFieldDigits, digitsValue)
+	b = appendProtobufVarint(b, migrationFieldType, typeValue)
+	if key.Type == "hotp" {
+		b = appendProtobufVarint(b, migrationFieldCounter, uint64(key.Counter))
+	}
+	return b, nil
+}
+
+// appendProtobufVarint 追加一个 varint 类型的字段。
+func appendProtobufVarint(b []byte, field int, value uint64) []byte {
+	b = appendVarint(b, uint64(field)<<3)
+	return appendVarint(b, value)
+}
+
+// appendProtobufBytes 追加一个 length-delimited 类型的字段。
+func appendProtobufBytes(b []byte, field int, data []byte) []byte {
+	b = appendVarint(b, uint64(field)<<3|2)
+	b = appendVarint(b, uint64(len(data)))
+	return append(b, data...)
+}
+
+func appendVarint(b []byte, value uint64) []byte {
+	for value >= 0x80 {
+		b = append(b, byte(value)|0x80)
+		value >>= 7
+	}
+	return append(b, byte(value))
+}
+
+// consumeVarint 读取一个 varint，返回读取的字节数，格式错误时返回 0。
+func consumeVarint(b []byte) (uint64, int) {
+	var value uint64
+	for i := 0; i < len(b) && i < 10; i++ {
+		value |= uint64(b[i]&0x7f) << (7 * i)
+		if b[i] < 0x80 {
+			return value, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// walkProtobuf 依次遍历消息中的字段，varint 类型的字段通过 value 传递，length-delimited 类型的字段通过 data 传递。
+// 只需要处理 MigrationPayload 用到的类型，fixed32/fixed64 类型的字段会被跳过。
+func walkProtobuf(b []byte, fn func(field int, value uint64, data []byte) error) error {
+	for len(b) > 0 {
+		tag, n := consumeVarint(b)
+		if n == 0 || tag>>3 == 0 {
+			return ErrMigrationFormat
+		}
+		b = b[n:]
+		field := int(tag >> 3)
+		var value uint64
+		var data []byte
+		switch tag & 7 {
+		case 0:
+			if value, n = consumeVarint(b); n == 0 {
+				return ErrMigrationFormat
+			}
+			b = b[n:]
+		case 1, 5:
+			size := 8
+			if tag&7 == 5 {
+				size = 4
+			}
+			if len(b) < size {
+				return ErrMigrationFormat
+			}
+			b = b[size:]
+			continue
+		case 2:
+			length, n := consumeVarint(b)
+			if n == 0 || uint64(len(b)-n) < length {
+				return ErrMigrationFormat
+			}
+			data = b[n : n+int(length)]
+			b = b[n+int(length):]
+		default:
+			return ErrMigrationFormat
+		}
+		if err := fn(field, value, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
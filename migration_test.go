@@ -0,0 +1,70 @@
+package otp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestParseMigrationURI(t *testing.T) {
+	t.Run("google authenticator export", func(t *testing.T) {
+		keys, err := ParseMigrationURI("otpauth-migration://offline?data=CjEKCkhlbGxvId6tvu8SGEV4YW1wbGU6YWxpY2VAZ29vZ2xlLmNvbRoHRXhhbXBsZTAC")
+		assert.Nil(t, err)
+		assert.Equal(t, []*KeyURI{{
+			Type:        "totp",
+			Label:       "Example:alice@google.com",
+			AccountName: "alice@google.com",
+			Algorithm:   "SHA1",
+			Digits:      6,
+			Period:      30,
+			Issuer:      "Example",
+			Secret:      "JBSWY3DPEHPK3PXP",
+		}}, keys)
+	})
+
+	t.Run("unescaped plus sign", func(t *testing.T) {
+		// payload 中秘钥从第 4 个字节开始，0xfbefbe 对齐后的 base64 编码为 "++++"
+		secret := Base32Encode([]byte{0, 0, 0xfb, 0xef, 0xbe, 0xfb, 0xef, 0xbe, 0xfb, 0xef})
+		uri, err := MigrationURI(NewTOTP(secret).KeyURI("alice", "Example"))
+		assert.Nil(t, err)
+		assert.Contains(t, uri, "%2B")
+		keys, err := ParseMigrationURI(strings.ReplaceAll(uri, "%2B", "+"))
+		assert.Nil(t, err)
+		assert.Equal(t, secret, keys[0].Secret)
+	})
+
+	t.Run("invalid uri", func(t *testing.T) {
+		for _, uri := range []string{
+			"otpauth://totp/Example:alice?secret=JBSWY3DPEHPK3PXP",
+			"otpauth-migration://online?data=CjEKCkhlbGxvId6tvu8SGEV4YW1wbGU6YWxpY2VAZ29vZ2xlLmNvbRoHRXhhbXBsZTAC",
+			"otpauth-migration://offline",
+			"otpauth-migration://offline?data=!!!",
+			// 长度字段超出数据范围
+			"otpauth-migration://offline?data=CjEKCkhlbGxv",
+		} {
+			_, err := ParseMigrationURI(uri)
+			assert.Equal(t, ErrMigrationFormat, err, uri)
+		}
+	})
+}
+
+func TestMigrationURI(t *testing.T) {
+	keys := []*KeyURI{
+		NewTOTP(TestSecret20).KeyURI("alice@google.com", "Example"),
+		NewTOTP(TestSecret32, WithAlgorithm(AlgorithmSHA256), WithDigits(DigitsEight)).KeyURI("bob", "Example Co"),
+		NewHOTP(TestSecret64, WithCounter(42), WithAlgorithm(AlgorithmSHA512)).KeyURI("carol", "Example"),
+	}
+	uri, err := MigrationURI(keys...)
+	assert.Nil(t, err)
+
+	parsed, err := ParseMigrationURI(uri)
+	assert.Nil(t, err)
+	assert.Equal(t, keys, parsed)
+
+	t.Run("unsupported parameters", func(t *testing.T) {
+		_, err := MigrationURI(NewTOTP(TestSecret20, WithPeriod(60)).KeyURI("alice", "Example"))
+		assert.Equal(t, ErrMigrationUnsupported, err)
+		_, err = MigrationURI(NewTOTP(TestSecret20, WithAlgorithm(AlgorithmSHA3_256)).KeyURI("alice", "Example"))
+		assert.Equal(t, ErrMigrationUnsupported, err)
+	})
+}
@@ -0,0 +1,327 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// OCRA 的 question 固定填充为 128 字节。
+	ocraQuestionLength = 128
+	// OCRA session 信息的默认长度。
+	ocraDefaultSessionLength = 64
+)
+
+// OCRASuite 解析后的 OCRA suite，格式为：OCRA-1:HOTP-<算法>-<长度>:<输入参数>。
+//
+// 例如 OCRA-1:HOTP-SHA1-6:QN08、OCRA-1:HOTP-SHA256-8:C-QN08-PSHA1、OCRA-1:HOTP-SHA512-8:QN08-T1M。
+//
+// See https://datatracker.ietf.org/doc/html/rfc6287#section-6
+type OCRASuite struct {
+	// 原始的 suite 字符串，会作为计算 HMAC 的输入的一部分。
+	Suite string
+	// HMAC 算法，仅支持 SHA1、SHA256、SHA512。
+	Algorithm Algorithms
+	// response 的长度，取值范围 4 - 10。
+	Digits int
+	// 是否需要计数器（C）。
+	Counter bool
+	// question 的格式：'A' 字母数字，'N' 数字，'H' 十六进制。
+	QuestionFormat byte
+	// question 的最大长度，取值范围 4 - 64。
+	QuestionLength int
+	// PIN 的哈希算法（P），为 0 时表示不需要 PIN。
+	PinAlgorithm Algorithms
+	// session 信息的字节数（S），为 0 时表示不需要 session 信息。
+	SessionLength int
+	// 时间步长（T），为 0 时表示不需要时间戳。
+	TimeStep time.Duration
+}
+
+// OCRAInput 计算 OCRA response 的输入参数，需要提供哪些参数由 suite 决定。
+type OCRAInput struct {
+	// 计数器，suite 包含 C 时使用。
+	Counter int64
+	// 挑战问题，必传，格式需要与 suite 的 Q 参数匹配。
+	Question string
+	// PIN，suite 包含 P 时使用，会使用 suite 指定的算法计算哈希。
+	Pin string
+	// PIN 的哈希值，如果服务端只保存了哈希值可以直接传入，优先于 Pin。
+	PinHash []byte
+	// session 信息，suite 包含 S 时使用，长度需要与 suite 一致。
+	Session []byte
+	// 时间，suite 包含 T 时使用，零值表示当前时间。
+	Time time.Time
+}
+
+// OCRA 基于 RFC-6287 的 OCRA 挑战应答算法。
+type OCRA struct {
+	Suite *OCRASuite
+	// base32 encoded string
+	Secret string
+	// base32 decoded string
+	decodedSecret []byte
+}
+
+// ParseOCRASuite 解析 OCRA suite 字符串，格式错误时返回 ErrOCRASuite。
+func ParseOCRASuite(suite string) (*OCRASuite, error) {
+	parts := strings.Split(suite, ":")
+	if len(parts) != 3 || parts[0] != "OCRA-1" {
+		return nil, ErrOCRASuite
+	}
+	ret := &OCRASuite{Suite: suite}
+
+	crypto := strings.Split(parts[1], "-")
+	if len(crypto) != 3 || crypto[0] != "HOTP" {
+		return nil, ErrOCRASuite
+	}
+	algorithm, err := parseOCRAAlgorithm(crypto[1])
+	if err != nil {
+		return nil, err
+	}
+	ret.Algorithm = algorithm
+	digits, err := strconv.Atoi(crypto[2])
+	if err != nil || digits < 4 || digits > 10 {
+		return nil, ErrOCRASuite
+	}
+	ret.Digits = digits
+
+	inputs := strings.Split(parts[2], "-")
+	if inputs[0] == "C" {
+		ret.Counter = true
+		inputs = inputs[1:]
+	}
+	// question 是必须的
+	if len(inputs) == 0 || len(inputs[0]) != 4 || inputs[0][0] != 'Q' {
+		return nil, ErrOCRASuite
+	}
+	ret.QuestionFormat = inputs[0][1]
+	if ret.QuestionFormat != 'A' && ret.QuestionFormat != 'N' && ret.QuestionFormat != 'H' {
+		return nil, ErrOCRASuite
+	}
+	ret.QuestionLength, err = strconv.Atoi(inputs[0][2:])
+	if err != nil || ret.QuestionLength < 4 || ret.QuestionLength > 64 {
+		return nil, ErrOCRASuite
+	}
+	inputs = inputs[1:]
+
+	if len(inputs) > 0 && strings.HasPrefix(inputs[0], "P") {
+		if ret.PinAlgorithm, err = parseOCRAAlgorithm(inputs[0][1:]); err != nil {
+			return nil, err
+		}
+		inputs = inputs[1:]
+	}
+	if len(inputs) > 0 && strings.HasPrefix(inputs[0], "S") {
+		ret.SessionLength = ocraDefaultSessionLength
+		if len(inputs[0]) > 1 {
+			length, err := strconv.Atoi(inputs[0][1:])
+			if err != nil || len(inputs[0]) != 4 || length <= 0 {
+				return nil, ErrOCRASuite
+			}
+			ret.SessionLength = length
+		}
+		inputs = inputs[1:]
+	}
+	if len(inputs) > 0 && strings.HasPrefix(inputs[0], "T") {
+		if ret.TimeStep, err = parseOCRATimeStep(inputs[0][1:]); err != nil {
+			return nil, err
+		}
+		inputs = inputs[1:]
+	}
+	if len(inputs) != 0 {
+		return nil, ErrOCRASuite
+	}
+	return ret, nil
+}
+
+// parseOCRAAlgorithm 解析 SHA1、SHA256、SHA512。
+func parseOCRAAlgorithm(str string) (Algorithms, error) {
+	switch str {
+	case "SHA1":
+		return AlgorithmSHA1, nil
+	case "SHA256":
+		return AlgorithmSHA256, nil
+	case "SHA512":
+		return AlgorithmSHA512, nil
+	default:
+		return 0, ErrOCRASuite
+	}
+}
+
+// parseOCRATimeStep 解析时间步长：1-59S、1-59M、0-48H。
+func parseOCRATimeStep(str string) (time.Duration, error) {
+	if len(str) < 2 {
+		return 0, ErrOCRASuite
+	}
+	n, err := strconv.Atoi(str[:len(str)-1])
+	if err != nil {
+		return 0, ErrOCRASuite
+	}
+	switch str[len(str)-1] {
+	case 'S':
+		if n >= 1 && n <= 59 {
+			return time.Duration(n) * time.Second, nil
+		}
+	case 'M':
+		if n >= 1 && n <= 59 {
+			return time.Duration(n) * time.Minute, nil
+		}
+	case 'H':
+		if n >= 1 && n <= 48 {
+			return time.Duration(n) * time.Hour, nil
+		}
+	}
+	return 0, ErrOCRASuite
+}
+
+// NewOCRA 创建一个 OCRA 结构体。
+//
+// Params:
+//
+//	suite : 必传，OCRA suite 字符串，例如 OCRA-1:HOTP-SHA1-6:QN08。
+//	secret: 必传，一个 base32 编码后的字符串。
+//
+// Panic:
+//   - suite format error
+//   - secret base32 decode error（*SecretDecodeError）
+//   - secret is an empty string
+//
+// Example:
+//
+//	ocra     := NewOCRA("OCRA-1:HOTP-SHA1-6:QN08", secret)
+//	response, err := ocra.Generate(OCRAInput{Question: "12345678"})
+func NewOCRA(suite, secret string) *OCRA {
+	ocra, err := NewOCRAWithError(suite, secret)
+	if err != nil {
+		panic(err)
+	}
+	return ocra
+}
+
+// NewOCRAWithError 与 NewOCRA 相同，但是在参数错误时返回错误而不是 panic。
+func NewOCRAWithError(suite, secret string) (*OCRA, error) {
+	parsed, err := ParseOCRASuite(suite)
+	if err != nil {
+		return nil, err
+	}
+	if secret == "" {
+		return nil, ErrSecretCannotBeEmpty
+	}
+	decodedSecret, err := Base32Decode(secret)
+	if err != nil {
+		return nil, err
+	}
+	return &OCRA{Suite: parsed, Secret: secret, decodedSecret: decodedSecret}, nil
+}
+
+// Generate 根据输入参数计算 response，输入参数与 suite 不匹配时返回 ErrOCRAInput。
+func (o *OCRA) Generate(input OCRAInput) (string, error) {
+	if len(o.decodedSecret) == 0 {
+		return "", ErrSecretCannotBeEmpty
+	}
+	message, err := o.message(input)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(hasher(o.Suite.Algorithm), o.decodedSecret)
+	mac.Write(message)
+	return truncate(mac.Sum(nil), o.Suite.Digits), nil
+}
+
+// At 与 Generate 相同，但是在输入参数与 suite 不匹配时返回空字符串，用于实现 Generator 接口。
+func (o *OCRA) At(input OCRAInput) string {
+	response, err := o.Generate(input)
+	if err != nil {
+		return ""
+	}
+	return response
+}
+
+// Verify 校验 response 是否有效，输入参数与 suite 不匹配时返回 false。
+func (o *OCRA) Verify(response string, input OCRAInput) bool {
+	if response == "" {
+		return false
+	}
+	expected, err := o.Generate(input)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(expected), []byte(response))
+}
+
+// message 按照 RFC-6287 第 5.1 节拼接 HMAC 的输入：suite | 0x00 | C | Q | P | S | T。
+func (o *OCRA) message(input OCRAInput) ([]byte, error) {
+	suite := o.Suite
+	message := append([]byte(suite.Suite), 0)
+	if suite.Counter {
+		message = append(message, intToByte(input.Counter)...)
+	}
+
+	question, err := suite.question(input.Question)
+	if err != nil {
+		return nil, err
+	}
+	message = append(message, question...)
+
+	if suite.PinAlgorithm != 0 {
+		pinHash := input.PinHash
+		if pinHash == nil {
+			if input.Pin == "" {
+				return nil, ErrOCRAInput
+			}
+			h := hasher(suite.PinAlgorithm)()
+			h.Write([]byte(input.Pin))
+			pinHash = h.Sum(nil)
+		}
+		if len(pinHash) != hasher(suite.PinAlgorithm)().Size() {
+			return nil, ErrOCRAInput
+		}
+		message = append(message, pinHash...)
+	}
+	if suite.SessionLength != 0 {
+		if len(input.Session) != suite.SessionLength {
+			return nil, ErrOCRAInput
+		}
+		message = append(message, input.Session...)
+	}
+	if suite.TimeStep != 0 {
+		t := input.Time
+		if t.IsZero() {
+			t = time.Now()
+		}
+		message = append(message, intToByte(t.Unix()/int64(suite.TimeStep/time.Second))...)
+	}
+	return message, nil
+}
+
+// question 将 question 转换为 128 字节：数字先转换为十六进制，字母数字使用 ASCII 编码，不足的部分在右侧补 0。
+func (s *OCRASuite) question(q string) ([]byte, error) {
+	if q == "" || len(q) > s.QuestionLength {
+		return nil, ErrOCRAInput
+	}
+	var hexStr string
+	switch s.QuestionFormat {
+	case 'N':
+		n, ok := new(big.Int).SetString(q, 10)
+		if !ok || n.Sign() < 0 {
+			return nil, ErrOCRAInput
+		}
+		hexStr = n.Text(16)
+	case 'H':
+		hexStr = q
+	case 'A':
+		hexStr = hex.EncodeToString([]byte(q))
+	}
+	if len(hexStr)%2 == 1 {
+		hexStr += "0"
+	}
+	b, err := hex.DecodeString(hexStr)
+	if err != nil || len(b) > ocraQuestionLength {
+		return nil, ErrOCRAInput
+	}
+	return append(b, make([]byte, ocraQuestionLength-len(b))...), nil
+}
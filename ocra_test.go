@@ -0,0 +1,155 @@
+package otp
+
+import (
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC-6287 附录 C 中使用的秘钥
+var (
+	ocraKey20 = ocraTestKey("3132333435363738393031323334353637383930")
+	ocraKey32 = ocraTestKey("3132333435363738393031323334353637383930313233343536373839303132")
+	ocraKey64 = ocraTestKey(strings.Repeat("31323334353637383930", 6) + "31323334")
+)
+
+func ocraTestKey(h string) string {
+	b, _ := hex.DecodeString(h)
+	return Base32Encode(b)
+}
+
+func TestParseOCRASuite(t *testing.T) {
+	suite, err := ParseOCRASuite("OCRA-1:HOTP-SHA256-8:C-QN08-PSHA1")
+	assert.Nil(t, err)
+	assert.Equal(t, &OCRASuite{
+		Suite:          "OCRA-1:HOTP-SHA256-8:C-QN08-PSHA1",
+		Algorithm:      AlgorithmSHA256,
+		Digits:         8,
+		Counter:        true,
+		QuestionFormat: 'N',
+		QuestionLength: 8,
+		PinAlgorithm:   AlgorithmSHA1,
+	}, suite)
+
+	suite, err = ParseOCRASuite("OCRA-1:HOTP-SHA512-8:QA10-S128-T1M")
+	assert.Nil(t, err)
+	assert.Equal(t, 128, suite.SessionLength)
+	assert.Equal(t, time.Minute, suite.TimeStep)
+
+	for _, str := range []string{
+		"",
+		"OCRA-2:HOTP-SHA1-6:QN08",
+		"OCRA-1:HOTP-MD5-6:QN08",
+		"OCRA-1:HOTP-SHA1-3:QN08",
+		"OCRA-1:HOTP-SHA1-6:C",
+		"OCRA-1:HOTP-SHA1-6:QX08",
+		"OCRA-1:HOTP-SHA1-6:QN99",
+		"OCRA-1:HOTP-SHA1-6:QN08-T60M",
+		"OCRA-1:HOTP-SHA1-6:QN08-PMD5",
+		"OCRA-1:HOTP-SHA1-6:QN08-X",
+	} {
+		_, err := ParseOCRASuite(str)
+		assert.Equal(t, ErrOCRASuite, err, str)
+	}
+}
+
+func TestOCRA_Generate(t *testing.T) {
+	t.Run("OCRA-1:HOTP-SHA1-6:QN08", func(t *testing.T) {
+		ocra := NewOCRA("OCRA-1:HOTP-SHA1-6:QN08", ocraKey20)
+		for question, expected := range map[string]string{
+			"00000000": "237653",
+			"11111111": "243178",
+			"22222222": "653583",
+			"33333333": "740991",
+			"44444444": "608993",
+		} {
+			response, err := ocra.Generate(OCRAInput{Question: question})
+			assert.Nil(t, err)
+			assert.Equal(t, expected, response, question)
+		}
+	})
+
+	t.Run("OCRA-1:HOTP-SHA256-8:C-QN08-PSHA1", func(t *testing.T) {
+		ocra := NewOCRA("OCRA-1:HOTP-SHA256-8:C-QN08-PSHA1", ocraKey32)
+		for counter, expected := range []string{"65347737", "86775851", "78192410", "71565254"} {
+			response, err := ocra.Generate(OCRAInput{Counter: int64(counter), Question: "12345678", Pin: "1234"})
+			assert.Nil(t, err)
+			assert.Equal(t, expected, response)
+		}
+	})
+
+	t.Run("OCRA-1:HOTP-SHA256-8:QN08-PSHA1", func(t *testing.T) {
+		ocra := NewOCRA("OCRA-1:HOTP-SHA256-8:QN08-PSHA1", ocraKey32)
+		for question, expected := range map[string]string{
+			"00000000": "83238735",
+			"11111111": "01501458",
+			"22222222": "17957585",
+		} {
+			response, err := ocra.Generate(OCRAInput{Question: question, Pin: "1234"})
+			assert.Nil(t, err)
+			assert.Equal(t, expected, response, question)
+		}
+	})
+
+	t.Run("OCRA-1:HOTP-SHA512-8:C-QN08", func(t *testing.T) {
+		ocra := NewOCRA("OCRA-1:HOTP-SHA512-8:C-QN08", ocraKey64)
+		response, err := ocra.Generate(OCRAInput{Counter: 0, Question: "00000000"})
+		assert.Nil(t, err)
+		assert.Equal(t, "07016083", response)
+		response, err = ocra.Generate(OCRAInput{Counter: 1, Question: "11111111"})
+		assert.Nil(t, err)
+		assert.Equal(t, "63947962", response)
+	})
+
+	t.Run("OCRA-1:HOTP-SHA512-8:QN08-T1M", func(t *testing.T) {
+		ocra := NewOCRA("OCRA-1:HOTP-SHA512-8:QN08-T1M", ocraKey64)
+		// 0x132d0b6 分钟
+		at := time.Unix(0x132d0b6*60, 0)
+		response, err := ocra.Generate(OCRAInput{Question: "00000000", Time: at})
+		assert.Nil(t, err)
+		assert.Equal(t, "95209754", response)
+		response, err = ocra.Generate(OCRAInput{Question: "11111111", Time: at})
+		assert.Nil(t, err)
+		assert.Equal(t, "55907591", response)
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		ocra := NewOCRA("OCRA-1:HOTP-SHA256-8:QN08-PSHA1-S064", ocraKey32)
+		for _, input := range []OCRAInput{
+			{Pin: "1234", Session: make([]byte, 64)},
+			{Question: "123456789", Pin: "1234", Session: make([]byte, 64)},
+			{Question: "1234abcd", Pin: "1234", Session: make([]byte, 64)},
+			{Question: "12345678", Session: make([]byte, 64)},
+			{Question: "12345678", PinHash: []byte{1, 2, 3}, Session: make([]byte, 64)},
+			{Question: "12345678", Pin: "1234", Session: make([]byte, 10)},
+		} {
+			_, err := ocra.Generate(input)
+			assert.Equal(t, ErrOCRAInput, err)
+		}
+		_, err := ocra.Generate(OCRAInput{Question: "12345678", Pin: "1234", Session: make([]byte, 64)})
+		assert.Nil(t, err)
+	})
+}
+
+func TestOCRA_Verify(t *testing.T) {
+	ocra := NewOCRA("OCRA-1:HOTP-SHA1-6:QH08", ocraKey20)
+	response, err := ocra.Generate(OCRAInput{Question: "DEADBEEF"})
+	assert.Nil(t, err)
+	assert.Equal(t, true, ocra.Verify(response, OCRAInput{Question: "DEADBEEF"}))
+	assert.Equal(t, false, ocra.Verify(response, OCRAInput{Question: "DEADBEEE"}))
+	assert.Equal(t, false, ocra.Verify("", OCRAInput{Question: "DEADBEEF"}))
+	assert.Equal(t, false, ocra.Verify(response, OCRAInput{Question: "NOTHEX"}))
+}
+
+func TestNewOCRA(t *testing.T) {
+	assert.PanicsWithError(t, ErrOCRASuite.Error(), func() {
+		NewOCRA("OCRA-1", ocraKey20)
+	})
+	assert.PanicsWithError(t, ErrSecretCannotBeEmpty.Error(), func() {
+		NewOCRA("OCRA-1:HOTP-SHA1-6:QN08", "")
+	})
+	_, err := NewOCRAWithError("OCRA-1:HOTP-SHA1-6:QN08", "111111")
+	assert.ErrorIs(t, err, ErrSecretDecode)
+}
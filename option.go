@@ -1,5 +1,11 @@
 package otp
 
+import (
+	"crypto/hmac"
+	"fmt"
+	"time"
+)
+
 type Otp struct {
 	// 指定时间窗口，默认 30 秒有效期。
 	// Google Authenticator 可能仅支持默认参数。
@@ -18,23 +24,291 @@ type Otp struct {
 	// 指定 hmac 算法，默认 hmac-sha1
 	// Google Authenticator 可能仅支持默认参数。
 	Algorithm Algorithms
+	// 指定 token 的编码方式，默认为十进制数字。
+	// 仅部分验证器应用支持非默认的编码方式，例如 Steam Guard。
+	Encoder Encoder
+	// 发行商，未编码的原始值，KeyURI 未传入 issuer 时使用。
+	Issuer string
+	// 账户名称，未编码的原始值，KeyURI 未传入 account 时使用。
+	AccountName string
+	// 秘钥的生效时间，零值表示不限制。
+	// 早于此时间的校验都会失败，可用于预先下发但尚未激活的秘钥。
+	NotBefore time.Time
+	// 秘钥的失效时间，零值表示不限制。
+	// 晚于此时间的校验都会失败，可用于临时账号的秘钥自动过期。
+	NotAfter time.Time
+	// RFC-6238 中的 T0，开始计算时间步的时间，零值表示 Unix 纪元，仅支持 TOTP 类型。
+	// 非零值只能被少数硬件令牌和系统识别，Google Authenticator 等验证器应用都会忽略此参数。
+	Epoch time.Time
+	// 是否在 KeyURI 中输出 epoch 参数，通过 WithEpochParameter 配置。
+	epochParameter bool
+	// 获取当前时间的方法，为 nil 时使用 time.Now。
+	clock func() time.Time
+	// 校验通过后用于防止 token 重复使用，为 nil 时不检查。
+	replayGuard ReplayGuard
+	// 通过 WithSkewWindow 配置的非对称窗口，skewWindow 为 false 时使用 Skew。
+	skewWindow   bool
+	skewBackward int
+	skewForward  int
+	// 是否复用 HMAC 的哈希对象，通过 WithReuseHMAC 配置。
+	reuseHMAC bool
+	// 秘钥字符串的编码方式，通过 WithSecretEncoding 配置。
+	secretEncoding SecretEncoding
+	// 是否在创建时校验秘钥长度，通过 WithStrictSecretLength 配置。
+	strictSecretLength bool
+	// 校验过程中的回调，通过 WithHooks 配置。
+	hooks *Hooks
+	// 应用 option 时第一个被修正的参数对应的错误，由 Validate 返回。
+	optionErr error
 }
 
+// Option TOTP 和 HOTP 共用的参数，同时实现了 TOTPOption 和 HOTPOption。
 type Option func(opt *Otp)
 
+// TOTPOption 可以传递给 TOTP 的参数，包括所有的 Option 以及 WithPeriod、WithEpoch 等仅对 TOTP 有意义的参数。
+//
+// 将 WithCounter 等仅对 HOTP 有意义的参数传递给 TOTP 会在编译时报错。
+type TOTPOption interface {
+	applyTOTP(opt *Otp)
+}
+
+// HOTPOption 可以传递给 HOTP 的参数，包括所有的 Option 以及仅对 HOTP 有意义的 WithCounter。
+type HOTPOption interface {
+	applyHOTP(opt *Otp)
+}
+
+func (o Option) applyTOTP(opt *Otp) { o(opt) }
+func (o Option) applyHOTP(opt *Otp) { o(opt) }
+
+// totpOption 仅对 TOTP 有意义的参数。
+type totpOption func(opt *Otp)
+
+func (o totpOption) applyTOTP(opt *Otp) { o(opt) }
+
+// hotpOption 仅对 HOTP 有意义的参数。
+type hotpOption func(opt *Otp)
+
+func (o hotpOption) applyHOTP(opt *Otp) { o(opt) }
+
+// AsTOTPOptions 将共用的 Option 转换为 TOTPOption，便于与 WithPeriod 等参数拼接。
+//
+// Example:
+//
+//	shared  := []Option{WithAlgorithm(AlgorithmSHA256), WithSkew(1)}
+//	totp    := NewTOTP(secret, append(AsTOTPOptions(shared...), WithPeriod(60))...)
+//	hotp    := NewHOTP(secret, append(AsHOTPOptions(shared...), WithCounter(10))...)
+func AsTOTPOptions(options ...Option) []TOTPOption {
+	ret := make([]TOTPOption, len(options))
+	for i, opt := range options {
+		ret[i] = opt
+	}
+	return ret
+}
+
+// AsHOTPOptions 将共用的 Option 转换为 HOTPOption，便于与 WithCounter 拼接。
+func AsHOTPOptions(options ...Option) []HOTPOption {
+	ret := make([]HOTPOption, len(options))
+	for i, opt := range options {
+		ret[i] = opt
+	}
+	return ret
+}
+
+// newTOTPOtp 使用默认参数创建 Otp 并应用 TOTP 的 options。
+func newTOTPOtp(options []TOTPOption) Otp {
+	otp := newOtp()
+	for _, opt := range options {
+		opt.applyTOTP(&otp)
+	}
+	return otp
+}
+
+// newHOTPOtp 使用默认参数创建 Otp 并应用 HOTP 的 options。
+func newHOTPOtp(options []HOTPOption) Otp {
+	otp := newOtp()
+	for _, opt := range options {
+		opt.applyHOTP(&otp)
+	}
+	return otp
+}
+
+// newOtp 使用默认参数创建 Otp 并应用 options，默认参数与 Google Authenticator 兼容。
+func newOtp(options ...Option) Otp {
+	otp := Otp{
+		Skew:      0,
+		Counter:   1,
+		Period:    30,
+		Algorithm: AlgorithmSHA1,
+		Digits:    DigitsSix,
+	}
+	for _, opt := range options {
+		opt(&otp)
+	}
+	return otp
+}
+
+// Validate 校验参数是否合法，返回第一个不合法的参数对应的错误，可以使用 errors.Is(err, ErrInvalidOption) 判断。
+//
+// WithPeriod、WithSkew 等 option 会将超出范围的值修正为最接近的合法值，Validate 会报告这些被修正的参数，
+// 同时也会校验直接修改字段导致的非法值：
+//   - period 不小于 10，digits 在 4 到 10 之间，skew 不小于 0。
+//   - algorithm、encoder、secret encoding 是支持的取值。
+//   - NotBefore 不晚于 NotAfter。
+//
+// NewTOTPWithError、NewHOTPWithError 会调用此方法，NewTOTP、NewHOTP 为了兼容仍然会静默修正参数。
+//
+// Example:
+//
+//	if err := ValidateTOTPOptions(WithPeriod(0)); err != nil {
+//		// invalid option: period 0 is less than 10
+//	}
+func (o Otp) Validate() error {
+	if o.optionErr != nil {
+		return o.optionErr
+	}
+	if o.Period < minPeriodNumber {
+		return fmt.Errorf("%w: period %d is less than %d", ErrInvalidOption, o.Period, minPeriodNumber)
+	}
+	if _, err := Digits.from(DigitsSix, int(o.Digits)); err != nil {
+		return fmt.Errorf("%w: digits %d is out of range [%d, %d]", ErrInvalidOption, o.Digits, minDigitsNumber, maxDigitsNumber)
+	}
+	if o.Skew < minSkewNumber {
+		return fmt.Errorf("%w: skew %d is less than %d", ErrInvalidOption, o.Skew, minSkewNumber)
+	}
+	if o.Algorithm < AlgorithmSHA1 || o.Algorithm > AlgorithmSHA384 {
+		return fmt.Errorf("%w: unknown algorithm %d", ErrInvalidOption, o.Algorithm)
+	}
+	if o.Encoder != EncoderDefault && o.Encoder != EncoderSteam {
+		return fmt.Errorf("%w: unknown encoder %d", ErrInvalidOption, o.Encoder)
+	}
+	if o.secretEncoding < SecretEncodingBase32 || o.secretEncoding > SecretEncodingCrockford {
+		return fmt.Errorf("%w: unknown secret encoding %d", ErrInvalidOption, o.secretEncoding)
+	}
+	if !o.NotBefore.IsZero() && !o.NotAfter.IsZero() && o.NotBefore.After(o.NotAfter) {
+		return fmt.Errorf("%w: not before %s is after not after %s", ErrInvalidOption, o.NotBefore, o.NotAfter)
+	}
+	return nil
+}
+
+// ValidateTOTPOptions 使用默认参数应用 options 并调用 Validate，用于在创建实例之前检查配置，例如启动时检查配置文件。
+func ValidateTOTPOptions(options ...TOTPOption) error {
+	return newTOTPOtp(options).Validate()
+}
+
+// ValidateHOTPOptions 与 ValidateTOTPOptions 相同，用于检查 HOTP 的配置。
+func ValidateHOTPOptions(options ...HOTPOption) error {
+	return newHOTPOtp(options).Validate()
+}
+
+// invalidOption 记录第一个被修正的参数。
+func (o *Otp) invalidOption(format string, args ...interface{}) {
+	if o.optionErr == nil {
+		o.optionErr = fmt.Errorf("%w: "+format, append([]interface{}{ErrInvalidOption}, args...)...)
+	}
+}
+
+// now 返回当前时间，所有隐式使用当前时间的方法都应该通过此方法获取。
+func (o Otp) now() time.Time {
+	if o.clock != nil {
+		return o.clock()
+	}
+	return time.Now()
+}
+
+// generate 使用解码后的秘钥计算计数器（TOTP 为时间步）对应的 token。
+func (o Otp) generate(secret []byte, counter int64) string {
+	if o.reuseHMAC {
+		return o.pooledGenerate(secret, counter)
+	}
+	mac := hmac.New(hasher(o.Algorithm), secret)
+	mac.Write(intToByte(counter))
+	return o.encode(mac.Sum(nil))
+}
+
+// encode 按照 Encoder 将 HMAC 的结果转换为 token。
+func (o Otp) encode(h []byte) string {
+	if o.Encoder == EncoderSteam {
+		return steamEncode(h)
+	}
+	return truncate(h, int(o.Digits))
+}
+
+// step 返回指定时间所在的时间步，即 (t - T0) / period。
+func (o Otp) step(t time.Time) int64 {
+	return (t.Unix() - o.epoch()) / int64(o.Period)
+}
+
+// stepStart 返回时间步开始的时间。
+func (o Otp) stepStart(step int64) time.Time {
+	return time.Unix(o.epoch()+step*int64(o.Period), 0)
+}
+
+// epoch 返回 T0 的 unix 秒数。
+func (o Otp) epoch() int64 {
+	if o.Epoch.IsZero() {
+		return 0
+	}
+	return o.Epoch.Unix()
+}
+
+// window 返回需要校验的向前（过去）和向后（未来）的窗口数。
+func (o Otp) window() (backward, forward int) {
+	if o.skewWindow {
+		return o.skewBackward, o.skewForward
+	}
+	return o.Skew, o.Skew
+}
+
+// validAt 判断秘钥在指定时间是否处于有效期内。
+func (o Otp) validAt(t time.Time) bool {
+	if !o.NotBefore.IsZero() && t.Before(o.NotBefore) {
+		return false
+	}
+	if !o.NotAfter.IsZero() && t.After(o.NotAfter) {
+		return false
+	}
+	return true
+}
+
 // WithSkew 配置同时校验的窗口数，默认为 0 仅校验当前时间窗口。
 //
-// 取值范围是：skew >=0 如果传入的值小于 0 将会设置为 0。
+// 取值范围是：skew >=0 如果传入的值小于 0 将会设置为 0，Validate 会报告该错误。
 func WithSkew(skew int) Option {
 	return func(opt *Otp) {
 		if skew < minSkewNumber {
+			opt.invalidOption("skew %d is less than %d", skew, minSkewNumber)
 			skew = minSkewNumber
 		}
 		opt.Skew = skew
+		opt.skewWindow = false
+	}
+}
+
+// WithSkewWindow 配置非对称的校验窗口，分别指定允许的过去和未来的窗口数，会覆盖 WithSkew 的配置。
+//
+// 客户端的 token 到达服务端时通常已经处于上一个窗口，很少会出现来自未来窗口的 token，
+// 因此 WithSkewWindow(1, 0) 是比 WithSkew(1) 更安全的常见配置。
+//
+// 取值范围是：backward >= 0, forward >= 0 如果传入的值小于 0 将会设置为 0，Validate 会报告该错误。
+func WithSkewWindow(backward, forward int) Option {
+	return func(opt *Otp) {
+		if backward < minSkewNumber {
+			opt.invalidOption("skew window backward %d is less than %d", backward, minSkewNumber)
+			backward = minSkewNumber
+		}
+		if forward < minSkewNumber {
+			opt.invalidOption("skew window forward %d is less than %d", forward, minSkewNumber)
+			forward = minSkewNumber
+		}
+		opt.skewWindow = true
+		opt.skewBackward = backward
+		opt.skewForward = forward
 	}
 }
 
 // WithDigits 配置一次性密码的显示长度，默认为 6, Google Authenticator 可能不支持其他的长度。
+//
+// 取值范围是：4 到 10，超出范围的值会导致 Validate 返回错误。
 func WithDigits(digits Digits) Option {
 	return func(opt *Otp) {
 		opt.Digits = digits
@@ -43,26 +317,170 @@ func WithDigits(digits Digits) Option {
 
 // WithPeriod 配置时间一次性密码的有效期，默认 30 秒，仅支持 TOTP 类型。
 //
-// 取值范围是：period >=10 如果传入的值小于 10 将会设置为 10。
-func WithPeriod(period int) Option {
-	return func(opt *Otp) {
+// 取值范围是：period >=10 如果传入的值小于 10 将会设置为 10，Validate 会报告该错误。
+func WithPeriod(period int) TOTPOption {
+	return totpOption(func(opt *Otp) {
 		if period < minPeriodNumber {
+			opt.invalidOption("period %d is less than %d", period, minPeriodNumber)
 			period = minPeriodNumber
 		}
 		opt.Period = period
+	})
+}
+
+// WithEpoch 配置 RFC-6238 中的 T0，即开始计算时间步的时间，默认为 Unix 纪元，仅支持 TOTP 类型。
+//
+// 用于对接使用非零起始时间的系统和硬件令牌，传入零值时恢复默认值。
+// 默认不会在 KeyURI 中输出该参数，需要时使用 WithEpochParameter 开启。
+//
+// Example:
+//
+//	totp := NewTOTP(secret, WithEpoch(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+func WithEpoch(t time.Time) TOTPOption {
+	return totpOption(func(opt *Otp) {
+		opt.Epoch = t
+	})
+}
+
+// WithEpochParameter 配置是否在 TOTP.KeyURI 中输出非标准的 epoch 参数（T0 的 unix 秒数），默认不输出。
+//
+// epoch 不是 Key Uri Format 中定义的参数，只有明确支持该参数的客户端才应该开启，
+// 其他验证器应用会忽略该参数并使用 Unix 纪元计算 token。FromURI 总是会解析该参数。
+func WithEpochParameter(enabled bool) TOTPOption {
+	return totpOption(func(opt *Otp) {
+		opt.epochParameter = enabled
+	})
+}
+
+// WithStrictSecretLength 创建 TOTP、HOTP 时使用 ValidateSecretStrength 校验秘钥长度，长度不足时
+// NewTOTPWithError 等方法返回 *SecretStrengthError，NewTOTP 等方法 panic。
+//
+// 校验使用最终的 Algorithm，与 option 的顺序无关。通过 SecretStore 或 Signer 创建时无法在创建时获取秘钥，不会校验。
+//
+// Example:
+//
+//	totp, err := NewTOTPWithError(secret, WithAlgorithm(AlgorithmSHA256), WithStrictSecretLength())
+//	if errors.Is(err, ErrSecretTooShort) {
+//		// 秘钥短于 32 字节
+//	}
+func WithStrictSecretLength() Option {
+	return func(opt *Otp) {
+		opt.strictSecretLength = true
 	}
 }
 
-// WithCounter 配置计数器的值，默认为 1 (Google 的默认就是 1)，仅支持 HOTP 类型。
-func WithCounter(counter int64) Option {
+// WithSecretEncoding 配置 NewTOTP、NewHOTP 等方法解析秘钥字符串时使用的编码，默认为标准 base32。
+//
+// 秘钥会被转换为标准 base32 保存在 Secret 字段中，KeyURI 等方法输出的仍然是标准 base32。
+// 仅影响通过字符串创建的方法，NewTOTPFromBytes 等方法不受影响。
+//
+// Example:
+//
+//	totp := NewTOTP("9RMQNFPFT73ONOH1S7IUPBKUCUL1EU2U", WithSecretEncoding(SecretEncodingBase32Hex))
+func WithSecretEncoding(encoding SecretEncoding) Option {
 	return func(opt *Otp) {
-		opt.Counter = counter
+		opt.secretEncoding = encoding
 	}
 }
 
+// WithIssuer 配置发行商，调用 KeyURI 时未传入 issuer 参数则使用该值。
+//
+// Example:
+//
+//	totp := NewTOTP(secret, WithIssuer("Example"), WithAccountName("alice@google.com"))
+//	uri  := totp.KeyURI().URI()
+func WithIssuer(issuer string) Option {
+	return func(opt *Otp) {
+		opt.Issuer = issuer
+	}
+}
+
+// WithAccountName 配置账户名称，调用 KeyURI 时未传入 account 参数则使用该值。
+func WithAccountName(account string) Option {
+	return func(opt *Otp) {
+		opt.AccountName = account
+	}
+}
+
+// labelParts 返回 KeyURI 使用的账户名称和发行商，依次使用 override 中的 account、issuer 覆盖配置的值。
+func (o Otp) labelParts(override []string) (account, issuer string) {
+	account, issuer = o.AccountName, o.Issuer
+	if len(override) > 0 {
+		account = override[0]
+	}
+	if len(override) > 1 {
+		issuer = override[1]
+	}
+	return account, issuer
+}
+
+// WithCounter 配置计数器的值，默认为 1 (Google 的默认就是 1)，仅支持 HOTP 类型。
+func WithCounter(counter int64) HOTPOption {
+	return hotpOption(func(opt *Otp) {
+		opt.Counter = counter
+	})
+}
+
 // WithAlgorithm 配置哈希算法类型。
 func WithAlgorithm(algorithm Algorithms) Option {
 	return func(opt *Otp) {
 		opt.Algorithm = algorithm
 	}
 }
+
+// WithEncoder 配置 token 的编码方式，默认为十进制数字。
+//
+// 使用 EncoderSteam 时会生成 Steam Guard 兼容的 5 位字母数字 token，此时 Digits 参数将被忽略。
+func WithEncoder(encoder Encoder) Option {
+	return func(opt *Otp) {
+		opt.Encoder = encoder
+	}
+}
+
+// WithClock 配置获取当前时间的方法，默认为 time.Now。
+//
+// Now、NowWithExpiration、VerifyNow 以及 HOTP 的有效期校验等隐式使用当前时间的方法都会通过它获取时间，
+// 可用于测试中固定时间，或者在服务端使用经过 NTP 校准的时间源。传入 nil 时恢复默认值。
+//
+// Example:
+//
+//	totp := NewTOTP(secret, WithClock(func() time.Time { return time.Unix(1704075000000, 0) }))
+//	totp.Now() // "076141"
+func WithClock(clock func() time.Time) Option {
+	return func(opt *Otp) {
+		opt.clock = clock
+	}
+}
+
+// WithReplayGuard 配置防重放检查，token 校验通过后还需要 guard.Use 返回 true 才认为有效。
+//
+// 账户使用秘钥的 SHA256 摘要标识，同一个秘钥创建的多个 TOTP 或 HOTP 共享使用记录。
+func WithReplayGuard(guard ReplayGuard) Option {
+	return func(opt *Otp) {
+		opt.replayGuard = guard
+	}
+}
+
+// WithNotBefore 配置秘钥的生效时间，在此之前 Verify 总是返回 false。
+func WithNotBefore(t time.Time) Option {
+	return func(opt *Otp) {
+		opt.NotBefore = t
+	}
+}
+
+// WithNotAfter 配置秘钥的失效时间，在此之后 Verify 总是返回 false。
+func WithNotAfter(t time.Time) Option {
+	return func(opt *Otp) {
+		opt.NotAfter = t
+	}
+}
+
+// WithReuseHMAC 配置是否复用计算 HMAC 使用的哈希对象和缓冲区，默认为 false。
+//
+// 开启后按照算法使用 sync.Pool 复用哈希对象，计算结果与默认实现完全相同，
+// 可以明显减少每秒需要校验大量 token 的服务端的内存分配。
+func WithReuseHMAC(reuse bool) Option {
+	return func(opt *Otp) {
+		opt.reuseHMAC = reuse
+	}
+}
@@ -0,0 +1,72 @@
+package otp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestValidateTOTPOptions(t *testing.T) {
+	assert.Nil(t, ValidateTOTPOptions())
+	assert.Nil(t, ValidateTOTPOptions(WithPeriod(60), WithDigits(DigitsEight), WithSkew(1), WithAlgorithm(AlgorithmSHA512)))
+	assert.Nil(t, ValidateTOTPOptions(WithDigits(4), WithDigits(10), WithSkewWindow(1, 0)))
+
+	now := time.Now()
+	for name, options := range map[string][]TOTPOption{
+		"period":          {WithPeriod(0)},
+		"digits small":    {WithDigits(3)},
+		"digits large":    {WithDigits(11)},
+		"skew":            {WithSkew(-1)},
+		"skew backward":   {WithSkewWindow(-1, 0)},
+		"skew forward":    {WithSkewWindow(0, -1)},
+		"algorithm":       {WithAlgorithm(0)},
+		"encoder":         {WithEncoder(Encoder(9))},
+		"validity window": {WithNotBefore(now), WithNotAfter(now.Add(-time.Hour))},
+		// 被修正的参数即使之后被合法的值覆盖也会报告
+		"overridden": {WithPeriod(5), WithPeriod(30)},
+	} {
+		assert.ErrorIs(t, ValidateTOTPOptions(options...), ErrInvalidOption, name)
+	}
+	assert.EqualError(t, ValidateTOTPOptions(WithPeriod(0)), "invalid option: period 0 is less than 10")
+
+	// 直接修改字段导致的非法值
+	totp := NewTOTP(TestSecret20)
+	totp.Period = 0
+	assert.ErrorIs(t, totp.Validate(), ErrInvalidOption)
+}
+
+func TestNewWithError_InvalidOption(t *testing.T) {
+	totp, err := NewTOTPWithError(TestSecret20, WithPeriod(0))
+	assert.Nil(t, totp)
+	assert.ErrorIs(t, err, ErrInvalidOption)
+
+	hotp, err := NewHOTPWithError(TestSecret20, WithDigits(12))
+	assert.Nil(t, hotp)
+	assert.ErrorIs(t, err, ErrInvalidOption)
+
+	// NewTOTP 为了兼容仍然会修正参数
+	assert.Equal(t, 10, NewTOTP(TestSecret20, WithPeriod(0)).Period)
+}
+
+func TestOptionTypes(t *testing.T) {
+	// 仅对一种类型有意义的参数不能传递给另一种类型
+	_, ok := interface{}(WithCounter(1)).(TOTPOption)
+	assert.False(t, ok)
+	_, ok = interface{}(WithPeriod(60)).(HOTPOption)
+	assert.False(t, ok)
+	_, ok = interface{}(WithEpoch(time.Time{})).(HOTPOption)
+	assert.False(t, ok)
+
+	shared := []Option{WithAlgorithm(AlgorithmSHA256), WithDigits(DigitsEight)}
+	totp := NewTOTP(TestSecret20, append(AsTOTPOptions(shared...), WithPeriod(60))...)
+	assert.Equal(t, AlgorithmSHA256, totp.Algorithm)
+	assert.Equal(t, DigitsEight, totp.Digits)
+	assert.Equal(t, 60, totp.Period)
+
+	hotp := NewHOTP(TestSecret20, append(AsHOTPOptions(shared...), WithCounter(10))...)
+	assert.Equal(t, AlgorithmSHA256, hotp.Algorithm)
+	assert.Equal(t, int64(10), hotp.Counter)
+
+	assert.Nil(t, ValidateHOTPOptions(WithCounter(10), WithSkew(1)))
+	assert.ErrorIs(t, ValidateHOTPOptions(WithSkew(-1)), ErrInvalidOption)
+}
@@ -6,17 +6,21 @@ import (
 	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"golang.org/x/crypto/sha3"
 	"hash"
-	"math"
+	"io"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
 // RandomSecret 获取一个给定长度(字节数)的随机秘钥，如果生成失败将会 panic。
 //
 // 建议存储时将其转换至 base32 或其他的编码，直接转换成字符串可能会存在换行符等奇怪的字符。
 //
-// 内部使用 rand.Read 方法，如果此方法报错将会 panic
+// 内部使用 rand.Read 方法，如果此方法报错将会 panic，长期运行的服务建议使用 GenerateSecret。
 //
 // rfc4266 中建议 secret 最少为 160 位也就是 20 个字节。
 //
@@ -30,19 +34,116 @@ func RandomSecret(length int) []byte {
 	// HMAC-SHA1   建议选择 20 字节长度
 	// HMAC-SHA256 建议选择 32 字节长度
 	// HMAC-SHA512 建议选择 64 字节长度
-	randomBytes := make([]byte, length)
-	_, err := rand.Read(randomBytes)
+	randomBytes, err := randomSecret(length)
 	if err != nil {
 		panic(err)
 	}
 	return randomBytes
 }
 
-// Base32Decode 对一个字符串进行 base32 解码
+// randomSecret 与 RandomSecret 相同，但是在随机数生成器出错时返回错误。
+func randomSecret(length int) ([]byte, error) {
+	randomBytes := make([]byte, length)
+	if _, err := io.ReadFull(randReader, randomBytes); err != nil {
+		return nil, fmt.Errorf("generate secret: %w", err)
+	}
+	return randomBytes, nil
+}
+
+// randReader 生成随机秘钥使用的随机数来源，测试时可以替换。
+var randReader io.Reader = rand.Reader
+
+// GenerateSecret 生成与 HMAC 算法输出长度相同的随机秘钥，并返回 base32 编码后的字符串。
+//
+// SHA1 为 20 字节，SHA256 为 32 字节，SHA512 为 64 字节，生成的秘钥总是满足 ValidateSecretStrength。
+// 与 RandomSecret 不同，系统随机数生成器出错时返回错误而不是 panic。
+//
+// Example:
+//
+//	secret, err := GenerateSecret(AlgorithmSHA256)
+//	if err != nil {
+//		return err
+//	}
+//	totp := NewTOTP(secret, WithAlgorithm(AlgorithmSHA256))
+func GenerateSecret(algorithm Algorithms) (string, error) {
+	if algorithm < AlgorithmSHA1 || algorithm > AlgorithmSHA384 {
+		return "", fmt.Errorf("%w: unknown algorithm %d", ErrInvalidOption, algorithm)
+	}
+	secret, err := randomSecret(hasher(algorithm)().Size())
+	if err != nil {
+		return "", err
+	}
+	defer zero(secret)
+	return Base32Encode(secret), nil
+}
+
+// base32Alphabet RFC 4648 定义的 base32 字母表
+const base32Alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+
+// Base32Decode 对一个字符串进行 base32 解码，忽略大小写。
+//
+// 解码失败时返回 *SecretDecodeError，其中包含出错的字符和位置。
 func Base32Decode(str string) ([]byte, error) {
 	// base32 只包含大小字母
-	upper := strings.ToUpper(str)
-	return base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(upper)
+	upper := strings.Map(upperASCII, str)
+	decoded, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(upper)
+	if err != nil {
+		return nil, newSecretDecodeError(upper, base32Alphabet, base32Hints)
+	}
+	return decoded, nil
+}
+
+// NormalizeSecret 将手动输入或邮件中常见格式的 base32 秘钥规范化：去掉空白字符和连字符，转换为大写，去掉末尾的 = 填充。
+//
+// 规范化之后仍然无法解码时返回 *SecretDecodeError，为空时返回 ErrSecretCannotBeEmpty。
+//
+// Example:
+//
+//	NormalizeSecret("j3w2 xpzp 5hdy xyrb 4hs6 zlu6 m6vb o6c6") // "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6"
+//	NormalizeSecret("JBSWY3DPEE======")                        // "JBSWY3DPEE"
+func NormalizeSecret(secret string) (string, error) {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || unicode.IsSpace(r) {
+			return -1
+		}
+		return upperASCII(r)
+	}, secret)
+	normalized = strings.TrimRight(normalized, "=")
+	if normalized == "" {
+		return "", ErrSecretCannotBeEmpty
+	}
+	if _, err := Base32Decode(normalized); err != nil {
+		return "", err
+	}
+	return normalized, nil
+}
+
+// upperASCII 只将 ASCII 小写字母转换为大写，避免 ı、ſ 等字符被 unicode.ToUpper 转换为合法的 base32 字符。
+func upperASCII(r rune) rune {
+	if 'a' <= r && r <= 'z' {
+		return r - 'a' + 'A'
+	}
+	return r
+}
+
+// HexDecode 对一个十六进制字符串进行解码，忽略大小写、空格以及 0x 前缀。
+//
+// RFC-6238 的测试向量以及 YubiKey 等硬件令牌的种子通常使用十六进制分发，解码后可以传递给 NewTOTPFromBytes、NewHOTPFromBytes。
+//
+// Example:
+//
+//	secret, err := HexDecode("3132333435363738393031323334353637383930")
+//	totp := NewTOTPFromBytes(secret)
+func HexDecode(str string) ([]byte, error) {
+	str = strings.ReplaceAll(str, " ", "")
+	if strings.HasPrefix(str, "0x") || strings.HasPrefix(str, "0X") {
+		str = str[2:]
+	}
+	decoded, err := hex.DecodeString(str)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSecretDecode, err)
+	}
+	return decoded, nil
 }
 
 // Base32Encode 对一个字符串进行 base32 编码
@@ -75,8 +176,17 @@ func truncate(h []byte, digits int) string {
 		uint32(h[offset+1]&0xff)<<16 |
 		uint32(h[offset+2]&0xff)<<8 |
 		uint32(h[offset+3]&0xff)
-	value := bits % uint32(math.Pow10(digits))
-	return padZero(strconv.Itoa(int(value)), digits)
+	value := uint64(bits) % uint64(pow10(digits))
+	return padZero(strconv.FormatUint(value, 10), digits)
+}
+
+// pow10 返回 10 的 n 次方。
+func pow10(n int) int64 {
+	ret := int64(1)
+	for i := 0; i < n; i++ {
+		ret *= 10
+	}
+	return ret
 }
 
 func hasher(algorithm Algorithms) func() hash.Hash {
@@ -87,6 +197,14 @@ func hasher(algorithm Algorithms) func() hash.Hash {
 		return sha256.New
 	case AlgorithmSHA512:
 		return sha512.New
+	case AlgorithmSHA3_256:
+		return sha3.New256
+	case AlgorithmSHA3_512:
+		return sha3.New512
+	case AlgorithmSHA224:
+		return sha256.New224
+	case AlgorithmSHA384:
+		return sha512.New384
 	default:
 		panic("unreachable")
 	}
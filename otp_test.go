@@ -1,8 +1,11 @@
 package otp
 
 import (
+	"crypto/rand"
+	"errors"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"testing/iotest"
 )
 
 func TestBase32Encode(t *testing.T) {
@@ -19,3 +22,62 @@ func TestRandomSecret(t *testing.T) {
 	result := RandomSecret(20)
 	assert.Equal(t, 20, len(result))
 }
+
+func TestGenerateSecret(t *testing.T) {
+	for algorithm, length := range map[Algorithms]int{AlgorithmSHA1: 20, AlgorithmSHA256: 32, AlgorithmSHA512: 64} {
+		secret, err := GenerateSecret(algorithm)
+		assert.Nil(t, err)
+		decoded, err := Base32Decode(secret)
+		assert.Nil(t, err)
+		assert.Equal(t, length, len(decoded))
+		assert.Nil(t, ValidateSecretStrength(decoded, algorithm))
+	}
+
+	_, err := GenerateSecret(0)
+	assert.ErrorIs(t, err, ErrInvalidOption)
+
+	// 随机数生成器出错时返回错误而不是 panic
+	randReader = iotest.ErrReader(errors.New("entropy exhausted"))
+	defer func() { randReader = rand.Reader }()
+	secret, err := GenerateSecret(AlgorithmSHA1)
+	assert.Equal(t, "", secret)
+	assert.EqualError(t, err, "generate secret: entropy exhausted")
+}
+
+func TestHexDecode(t *testing.T) {
+	for _, str := range []string{
+		"3132333435363738393031323334353637383930",
+		"0x3132333435363738393031323334353637383930",
+		"31323334 35363738 39303132 33343536 37383930",
+	} {
+		secret, err := HexDecode(str)
+		assert.Nil(t, err)
+		assert.Equal(t, []byte("12345678901234567890"), secret)
+	}
+	_, err := HexDecode("31323")
+	assert.ErrorIs(t, err, ErrSecretDecode)
+	_, err = HexDecode("zz")
+	assert.ErrorIs(t, err, ErrSecretDecode)
+}
+
+func TestNormalizeSecret(t *testing.T) {
+	for _, secret := range []string{
+		"J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6",
+		"j3w2 xpzp 5hdy xyrb 4hs6 zlu6 m6vb o6c6",
+		"J3W2-XPZP-5HDY-XYRB-4HS6-ZLU6-M6VB-O6C6",
+		" J3W2XPZP5HDYXYRB\n4HS6ZLU6M6VBO6C6\t",
+	} {
+		normalized, err := NormalizeSecret(secret)
+		assert.Nil(t, err)
+		assert.Equal(t, TestSecret20, normalized)
+	}
+
+	normalized, err := NormalizeSecret("jbswy3dpee======")
+	assert.Nil(t, err)
+	assert.Equal(t, "JBSWY3DPEE", normalized)
+
+	_, err = NormalizeSecret(" = ")
+	assert.Equal(t, ErrSecretCannotBeEmpty, err)
+	_, err = NormalizeSecret("J3W2 XPZ1")
+	assert.EqualError(t, err, `secret base32 decode error: illegal character '1' at position 7, did you mean 'I'`)
+}
@@ -0,0 +1,322 @@
+package otpbackup
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/huk10/go-otp"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Aegis 导出加密备份时使用的 scrypt 参数，与 Aegis 默认值一致。
+const (
+	aegisScryptN = 1 << 15
+	aegisScryptR = 8
+	aegisScryptP = 1
+	// Aegis 密码类型的 slot
+	aegisSlotPassword = 1
+)
+
+// 解析备份时允许的 scrypt 参数上限。参数来自不受信任的文件，过大的 N、r 会耗尽内存（约 128*N*r 字节），
+// 过大的 p 会占用大量 CPU，因此在派生秘钥之前拒绝超出上限的参数。上限远大于 Aegis 的默认值。
+const (
+	aegisMaxScryptN      = 1 << 20
+	aegisMaxScryptR      = 32
+	aegisMaxScryptP      = 16
+	aegisMaxScryptMemory = 256 << 20
+)
+
+// aegisVault Aegis 备份文件的顶层结构，加密时 DB 为 base64 编码的密文，否则为 aegisDB。
+type aegisVault struct {
+	Version int             `json:"version"`
+	Header  aegisHeader     `json:"header"`
+	DB      json.RawMessage `json:"db"`
+}
+
+type aegisHeader struct {
+	Slots  []aegisSlot  `json:"slots"`
+	Params *aegisParams `json:"params"`
+}
+
+type aegisSlot struct {
+	Type      int          `json:"type"`
+	UUID      string       `json:"uuid"`
+	Key       string       `json:"key"`
+	KeyParams *aegisParams `json:"key_params"`
+	N         int          `json:"n,omitempty"`
+	R         int          `json:"r,omitempty"`
+	P         int          `json:"p,omitempty"`
+	Salt      string       `json:"salt,omitempty"`
+	Repaired  bool         `json:"repaired,omitempty"`
+}
+
+type aegisParams struct {
+	Nonce string `json:"nonce"`
+	Tag   string `json:"tag"`
+}
+
+type aegisDB struct {
+	Version int          `json:"version"`
+	Entries []aegisEntry `json:"entries"`
+}
+
+type aegisEntry struct {
+	Type     string          `json:"type"`
+	UUID     string          `json:"uuid"`
+	Name     string          `json:"name"`
+	Issuer   string          `json:"issuer"`
+	Note     string          `json:"note"`
+	Favorite bool            `json:"favorite"`
+	Icon     json.RawMessage `json:"icon"`
+	Info     aegisInfo       `json:"info"`
+}
+
+type aegisInfo struct {
+	Secret  string `json:"secret"`
+	Algo    string `json:"algo"`
+	Digits  int    `json:"digits"`
+	Period  int    `json:"period,omitempty"`
+	Counter int64  `json:"counter,omitempty"`
+}
+
+// ParseAegis 解析 Aegis 的备份文件，加密的备份需要提供密码，明文备份会忽略 password。
+//
+// 备份已加密但 password 为空时返回 ErrPasswordRequired，密码错误时返回 ErrDecrypt，
+// scrypt 参数超出上限（N 2^20、r 32、p 16、内存 256 MiB）时返回 ErrFormat。
+// 不支持的条目类型（例如 MOTP、Yandex）和算法（MD5）会返回 ErrFormat。
+func ParseAegis(data []byte, password []byte) ([]*otp.KeyURI, error) {
+	var vault aegisVault
+	if err := json.Unmarshal(data, &vault); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	raw := []byte(vault.DB)
+	if vault.Header.Params != nil {
+		if len(password) == 0 {
+			return nil, ErrPasswordRequired
+		}
+		plain, err := decryptAegis(vault, password)
+		if err != nil {
+			return nil, err
+		}
+		raw = plain
+	}
+	var db aegisDB
+	if err := json.Unmarshal(raw, &db); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	keys := make([]*otp.KeyURI, 0, len(db.Entries))
+	for _, e := range db.Entries {
+		key, err := entry{
+			typ:       e.Type,
+			issuer:    e.Issuer,
+			account:   e.Name,
+			secret:    e.Info.Secret,
+			algorithm: e.Info.Algo,
+			digits:    e.Info.Digits,
+			period:    e.Info.Period,
+			counter:   e.Info.Counter,
+		}.key()
+		if err != nil {
+			return nil, fmt.Errorf("aegis entry %q: %w", e.Name, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// MarshalAegis 将 keys 导出为 Aegis 的明文备份文件。
+//
+// Aegis 只支持 SHA1、SHA256、SHA512 算法，其他算法返回 ErrUnsupported。
+func MarshalAegis(keys []*otp.KeyURI) ([]byte, error) {
+	db, err := marshalAegisDB(keys)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(aegisVault{Version: 1, DB: db}, "", "    ")
+}
+
+// MarshalAegisEncrypted 将 keys 导出为使用密码加密的 Aegis 备份文件，可以直接在 Aegis 中导入。
+func MarshalAegisEncrypted(keys []*otp.KeyURI, password []byte) ([]byte, error) {
+	if len(password) == 0 {
+		return nil, ErrPasswordRequired
+	}
+	db, err := marshalAegisDB(keys)
+	if err != nil {
+		return nil, err
+	}
+	masterKey := otp.RandomSecret(32)
+	salt := otp.RandomSecret(32)
+	derived, err := scrypt.Key(password, salt, aegisScryptN, aegisScryptR, aegisScryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	encryptedKey, keyParams, err := aegisSeal(derived, masterKey)
+	if err != nil {
+		return nil, err
+	}
+	encryptedDB, params, err := aegisSeal(masterKey, db)
+	if err != nil {
+		return nil, err
+	}
+	dbString, err := json.Marshal(base64.StdEncoding.EncodeToString(encryptedDB))
+	if err != nil {
+		return nil, err
+	}
+	vault := aegisVault{
+		Version: 1,
+		Header: aegisHeader{
+			Slots: []aegisSlot{{
+				Type:      aegisSlotPassword,
+				UUID:      newUUID(),
+				Key:       hex.EncodeToString(encryptedKey),
+				KeyParams: keyParams,
+				N:         aegisScryptN,
+				R:         aegisScryptR,
+				P:         aegisScryptP,
+				Salt:      hex.EncodeToString(salt),
+				Repaired:  true,
+			}},
+			Params: params,
+		},
+		DB: dbString,
+	}
+	return json.MarshalIndent(vault, "", "    ")
+}
+
+// marshalAegisDB 将 keys 转换为 Aegis 数据库的 JSON。
+func marshalAegisDB(keys []*otp.KeyURI) ([]byte, error) {
+	db := aegisDB{Version: 2, Entries: make([]aegisEntry, 0, len(keys))}
+	for _, key := range keys {
+		e, err := fromKey(key)
+		if err != nil {
+			return nil, err
+		}
+		switch e.algorithm {
+		case "SHA1", "SHA256", "SHA512":
+		default:
+			return nil, fmt.Errorf("%w: algorithm %s", ErrUnsupported, e.algorithm)
+		}
+		info := aegisInfo{Secret: e.secret, Algo: e.algorithm, Digits: e.digits}
+		if e.typ == "hotp" {
+			info.Counter = e.counter
+		} else {
+			info.Period = e.period
+		}
+		db.Entries = append(db.Entries, aegisEntry{
+			Type:   e.typ,
+			UUID:   newUUID(),
+			Name:   e.account,
+			Issuer: e.issuer,
+			Icon:   json.RawMessage("null"),
+			Info:   info,
+		})
+	}
+	return json.Marshal(db)
+}
+
+// decryptAegis 依次尝试每个密码类型的 slot 解密主秘钥，然后使用主秘钥解密数据库。
+func decryptAegis(vault aegisVault, password []byte) ([]byte, error) {
+	var encoded string
+	if err := json.Unmarshal(vault.DB, &encoded); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	for _, slot := range vault.Header.Slots {
+		if slot.Type != aegisSlotPassword || slot.KeyParams == nil {
+			continue
+		}
+		salt, err := hex.DecodeString(slot.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+		}
+		if err := checkAegisScrypt(slot.N, slot.R, slot.P); err != nil {
+			return nil, err
+		}
+		derived, err := scrypt.Key(password, salt, slot.N, slot.R, slot.P, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+		}
+		encryptedKey, err := hex.DecodeString(slot.Key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+		}
+		masterKey, err := aegisOpen(derived, encryptedKey, slot.KeyParams)
+		if err != nil {
+			continue
+		}
+		return aegisOpen(masterKey, ciphertext, vault.Header.Params)
+	}
+	return nil, ErrDecrypt
+}
+
+// checkAegisScrypt 检查 slot 中的 scrypt 参数是否在上限之内，超出时返回 ErrFormat。
+func checkAegisScrypt(n, r, p int) error {
+	if n <= 0 || n > aegisMaxScryptN || r <= 0 || r > aegisMaxScryptR || p <= 0 || p > aegisMaxScryptP ||
+		128*n*r > aegisMaxScryptMemory {
+		return fmt.Errorf("%w: scrypt parameters n=%d r=%d p=%d exceed limits", ErrFormat, n, r, p)
+	}
+	return nil
+}
+
+// aegisSeal 使用 AES-256-GCM 加密，返回不包含 tag 的密文以及 nonce 和 tag。
+func aegisSeal(key, plaintext []byte) ([]byte, *aegisParams, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	sealed := aead.Seal(nil, nonce, plaintext, nil)
+	ciphertext, tag := sealed[:len(sealed)-aead.Overhead()], sealed[len(sealed)-aead.Overhead():]
+	return ciphertext, &aegisParams{Nonce: hex.EncodeToString(nonce), Tag: hex.EncodeToString(tag)}, nil
+}
+
+// aegisOpen 使用 AES-256-GCM 解密，Aegis 将 tag 与密文分开保存。
+func aegisOpen(key, ciphertext []byte, params *aegisParams) ([]byte, error) {
+	nonce, err := hex.DecodeString(params.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	tag, err := hex.DecodeString(params.Tag)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: nonce size %d", ErrFormat, len(nonce))
+	}
+	plaintext, err := aead.Open(nil, nonce, append(append([]byte(nil), ciphertext...), tag...), nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+// newGCM 创建 AES-GCM 的 AEAD。
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// newUUID 生成一个随机的 UUID（版本 4）。
+func newUUID() string {
+	b := otp.RandomSecret(16)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,105 @@
+package otpbackup
+
+import (
+	"encoding/json"
+	"github.com/huk10/go-otp"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// aegisPlain Aegis 导出的明文备份
+const aegisPlain = `{
+    "version": 1,
+    "header": {"slots": null, "params": null},
+    "db": {
+        "version": 2,
+        "entries": [
+            {"type": "totp", "uuid": "3ae6f1ad-2e65-4ed2-a953-1ec0dff2386d", "name": "alice@google.com", "issuer": "Example",
+             "note": "", "favorite": false, "icon": null,
+             "info": {"secret": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", "algo": "SHA256", "digits": 8, "period": 60}},
+            {"type": "hotp", "uuid": "b5e5c5b4-5b84-4b35-8e5e-1a4d1e3b5d7c", "name": "bob", "issuer": "",
+             "note": "", "favorite": false, "icon": null,
+             "info": {"secret": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", "algo": "SHA1", "digits": 6, "counter": 5}},
+            {"type": "steam", "uuid": "c1a7e4f2-9d3b-4e8a-b6c5-2f1e0d9c8b7a", "name": "carol", "issuer": "Steam",
+             "note": "", "favorite": false, "icon": null,
+             "info": {"secret": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", "algo": "SHA1", "digits": 5, "period": 30}}
+        ]
+    }
+}`
+
+func TestParseAegis(t *testing.T) {
+	keys, err := ParseAegis([]byte(aegisPlain), nil)
+	assert.Nil(t, err)
+	assert.Len(t, keys, 3)
+
+	assert.Equal(t, "otpauth://totp/Example:alice@google.com?secret="+secret+"&issuer=Example&algorithm=SHA256&digits=8&period=60", keys[0].URI().String())
+	assert.Equal(t, "otpauth://hotp/bob?secret="+secret+"&issuer=&counter=5", keys[1].URI().String())
+
+	steam, err := keys[2].TOTP()
+	assert.Nil(t, err)
+	now := time.Unix(1704075000, 0)
+	assert.Equal(t, otp.NewSteamTOTP(secret).At(now), steam.At(now))
+}
+
+func TestAegis_RoundTrip(t *testing.T) {
+	keys, err := ParseAegis([]byte(aegisPlain), nil)
+	assert.Nil(t, err)
+
+	data, err := MarshalAegis(keys)
+	assert.Nil(t, err)
+	parsed, err := ParseAegis(data, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, keys, parsed)
+
+	_, err = MarshalAegis([]*otp.KeyURI{otp.NewTOTP(secret, otp.WithAlgorithm(otp.AlgorithmSHA3_256)).KeyURI("alice", "Example")})
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestAegis_Encrypted(t *testing.T) {
+	keys, err := ParseAegis([]byte(aegisPlain), nil)
+	assert.Nil(t, err)
+
+	data, err := MarshalAegisEncrypted(keys, []byte("correct horse"))
+	assert.Nil(t, err)
+	var vault aegisVault
+	assert.Nil(t, json.Unmarshal(data, &vault))
+	assert.NotContains(t, string(data), secret)
+	assert.Len(t, vault.Header.Slots, 1)
+
+	parsed, err := ParseAegis(data, []byte("correct horse"))
+	assert.Nil(t, err)
+	assert.Equal(t, keys, parsed)
+
+	_, err = ParseAegis(data, nil)
+	assert.ErrorIs(t, err, ErrPasswordRequired)
+	_, err = ParseAegis(data, []byte("wrong"))
+	assert.ErrorIs(t, err, ErrDecrypt)
+	_, err = MarshalAegisEncrypted(keys, nil)
+	assert.ErrorIs(t, err, ErrPasswordRequired)
+
+	// 篡改 scrypt 参数，过大的参数在派生秘钥之前被拒绝
+	for _, params := range [][3]int{{1 << 30, 8, 1}, {1 << 15, 1 << 20, 1}, {1 << 15, 8, 1 << 20}, {1 << 20, 8, 1}, {0, 8, 1}, {1 << 15, -1, 1}} {
+		tampered := vault
+		tampered.Header.Slots = []aegisSlot{vault.Header.Slots[0]}
+		tampered.Header.Slots[0].N, tampered.Header.Slots[0].R, tampered.Header.Slots[0].P = params[0], params[1], params[2]
+		data, _ := json.Marshal(tampered)
+		_, err = ParseAegis(data, []byte("correct horse"))
+		assert.ErrorIs(t, err, ErrFormat, "%v", params)
+	}
+}
+
+func TestParseAegis_Invalid(t *testing.T) {
+	invalid := []string{
+		``,
+		`{"version": 1, "header": {}, "db": {"entries": [{"type": "motp", "name": "a", "info": {"secret": "` + secret + `"}}]}}`,
+		`{"version": 1, "header": {}, "db": {"entries": [{"type": "totp", "name": "a", "info": {"secret": "` + secret + `", "algo": "MD5"}}]}}`,
+		`{"version": 1, "header": {}, "db": {"entries": [{"type": "totp", "name": "a", "info": {"secret": "1"}}]}}`,
+	}
+	for _, data := range invalid {
+		_, err := ParseAegis([]byte(data), nil)
+		assert.ErrorIs(t, err, ErrFormat, data)
+	}
+}
@@ -0,0 +1,87 @@
+package otpbackup
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/huk10/go-otp"
+	"strings"
+)
+
+// andOTPEntry andOTP 明文备份中的一个条目。
+type andOTPEntry struct {
+	Secret        string   `json:"secret"`
+	Issuer        string   `json:"issuer"`
+	Label         string   `json:"label"`
+	Digits        int      `json:"digits"`
+	Type          string   `json:"type"`
+	Algorithm     string   `json:"algorithm"`
+	Thumbnail     string   `json:"thumbnail"`
+	LastUsed      int64    `json:"last_used"`
+	UsedFrequency int      `json:"used_frequency"`
+	Period        int      `json:"period,omitempty"`
+	Counter       int64    `json:"counter,omitempty"`
+	Tags          []string `json:"tags"`
+}
+
+// ParseAndOTP 解析 andOTP 的明文备份文件（otp_accounts.json），不支持加密的备份。
+//
+// 旧版本的 andOTP 没有 issuer 字段，issuer 包含在 label 中（Example:alice），会被自动拆分。
+func ParseAndOTP(data []byte) ([]*otp.KeyURI, error) {
+	var entries []andOTPEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	keys := make([]*otp.KeyURI, 0, len(entries))
+	for _, e := range entries {
+		key, err := entry{
+			typ:       strings.ToLower(e.Type),
+			issuer:    e.Issuer,
+			account:   e.Label,
+			secret:    e.Secret,
+			algorithm: e.Algorithm,
+			digits:    e.Digits,
+			period:    e.Period,
+			counter:   e.Counter,
+		}.key()
+		if err != nil {
+			return nil, fmt.Errorf("andotp entry %q: %w", e.Label, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// MarshalAndOTP 将 keys 导出为 andOTP 的明文备份文件。
+//
+// andOTP 只支持 SHA1、SHA256、SHA512 算法，其他算法返回 ErrUnsupported。
+func MarshalAndOTP(keys []*otp.KeyURI) ([]byte, error) {
+	entries := make([]andOTPEntry, 0, len(keys))
+	for _, key := range keys {
+		e, err := fromKey(key)
+		if err != nil {
+			return nil, err
+		}
+		switch e.algorithm {
+		case "SHA1", "SHA256", "SHA512":
+		default:
+			return nil, fmt.Errorf("%w: algorithm %s", ErrUnsupported, e.algorithm)
+		}
+		item := andOTPEntry{
+			Secret:    e.secret,
+			Issuer:    e.issuer,
+			Label:     e.account,
+			Digits:    e.digits,
+			Type:      strings.ToUpper(e.typ),
+			Algorithm: e.algorithm,
+			Thumbnail: "Default",
+			Tags:      []string{},
+		}
+		if e.typ == "hotp" {
+			item.Counter = e.counter
+		} else {
+			item.Period = e.period
+		}
+		entries = append(entries, item)
+	}
+	return json.MarshalIndent(entries, "", "  ")
+}
@@ -0,0 +1,47 @@
+package otpbackup
+
+import (
+	"github.com/huk10/go-otp"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// andOTPPlain andOTP 导出的明文备份，第二个条目为旧版本没有 issuer 字段的格式
+const andOTPPlain = `[
+  {"secret": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", "issuer": "Example", "label": "alice@google.com", "digits": 6,
+   "type": "TOTP", "algorithm": "SHA1", "thumbnail": "Default", "last_used": 1704075000000, "used_frequency": 3,
+   "period": 30, "tags": ["work"]},
+  {"secret": "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", "label": "Legacy:bob", "digits": 8, "type": "HOTP",
+   "algorithm": "SHA512", "thumbnail": "Default", "last_used": 0, "used_frequency": 0, "counter": 3, "tags": []}
+]`
+
+func TestParseAndOTP(t *testing.T) {
+	keys, err := ParseAndOTP([]byte(andOTPPlain))
+	assert.Nil(t, err)
+	assert.Len(t, keys, 2)
+	assert.Equal(t, "otpauth://totp/Example:alice@google.com?secret="+secret+"&issuer=Example", keys[0].URI().String())
+	assert.Equal(t, "otpauth://hotp/Legacy:bob?secret="+secret+"&issuer=Legacy&algorithm=SHA512&digits=8&counter=3", keys[1].URI().String())
+
+	_, err = ParseAndOTP([]byte(`{"secret": "` + secret + `"}`))
+	assert.ErrorIs(t, err, ErrFormat)
+}
+
+func TestAndOTP_RoundTrip(t *testing.T) {
+	keys, err := ParseAndOTP([]byte(andOTPPlain))
+	assert.Nil(t, err)
+	data, err := MarshalAndOTP(keys)
+	assert.Nil(t, err)
+	parsed, err := ParseAndOTP(data)
+	assert.Nil(t, err)
+	assert.Equal(t, keys, parsed)
+
+	// 与 Aegis 之间互相转换
+	data, err = MarshalAegis(parsed)
+	assert.Nil(t, err)
+	parsed, err = ParseAegis(data, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, keys, parsed)
+
+	_, err = MarshalAndOTP([]*otp.KeyURI{otp.NewTOTP(secret, otp.WithAlgorithm(otp.AlgorithmSHA224)).KeyURI("alice", "Example")})
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
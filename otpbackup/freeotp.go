@@ -0,0 +1,138 @@
+package otpbackup
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/huk10/go-otp"
+	"strings"
+)
+
+// freeOTPBackup FreeOTP+ 导出的 JSON 备份文件。
+type freeOTPBackup struct {
+	TokenOrder []string       `json:"tokenOrder"`
+	Tokens     []freeOTPToken `json:"tokens"`
+}
+
+// freeOTPToken FreeOTP+ 备份中的一个条目，秘钥为 Java 的有符号字节数组。
+type freeOTPToken struct {
+	Algo      string `json:"algo"`
+	Counter   int64  `json:"counter"`
+	Digits    int    `json:"digits"`
+	IssuerExt string `json:"issuerExt"`
+	IssuerInt string `json:"issuerInt,omitempty"`
+	Label     string `json:"label"`
+	Period    int    `json:"period"`
+	Secret    []int8 `json:"secret"`
+	Type      string `json:"type"`
+}
+
+// ParseFreeOTP 解析 FreeOTP+ 导出的 JSON 备份文件，条目按照 tokenOrder 的顺序返回。
+func ParseFreeOTP(data []byte) ([]*otp.KeyURI, error) {
+	var backup freeOTPBackup
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	keys := make([]*otp.KeyURI, 0, len(backup.Tokens))
+	for _, t := range backup.Tokens {
+		secret := make([]byte, len(t.Secret))
+		for i, b := range t.Secret {
+			secret[i] = byte(b)
+		}
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("freeotp token %q: %w", t.Label, otp.ErrMissingSecret)
+		}
+		key, err := entry{
+			typ:       strings.ToLower(t.Type),
+			issuer:    t.IssuerExt,
+			account:   t.Label,
+			secret:    otp.Base32Encode(secret),
+			algorithm: t.Algo,
+			digits:    t.Digits,
+			period:    t.Period,
+			counter:   t.Counter,
+		}.key()
+		if err != nil {
+			return nil, fmt.Errorf("freeotp token %q: %w", t.Label, err)
+		}
+		keys = append(keys, key)
+	}
+	return sortFreeOTP(keys, backup.TokenOrder), nil
+}
+
+// MarshalFreeOTP 将 keys 导出为 FreeOTP+ 的 JSON 备份文件。
+//
+// FreeOTP+ 不支持 SHA3 算法以及 Steam 编码，对应的条目返回 ErrUnsupported。
+func MarshalFreeOTP(keys []*otp.KeyURI) ([]byte, error) {
+	backup := freeOTPBackup{
+		TokenOrder: make([]string, 0, len(keys)),
+		Tokens:     make([]freeOTPToken, 0, len(keys)),
+	}
+	for _, key := range keys {
+		e, err := fromKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if e.typ == "steam" || strings.HasPrefix(e.algorithm, "SHA3") {
+			return nil, fmt.Errorf("%w: %s %s", ErrUnsupported, e.typ, e.algorithm)
+		}
+		decoded, err := otp.Base32Decode(e.secret)
+		if err != nil {
+			return nil, err
+		}
+		secret := make([]int8, len(decoded))
+		for i, b := range decoded {
+			secret[i] = int8(b)
+		}
+		backup.TokenOrder = append(backup.TokenOrder, freeOTPID(e.issuer, e.account))
+		backup.Tokens = append(backup.Tokens, freeOTPToken{
+			Algo:      e.algorithm,
+			Counter:   e.counter,
+			Digits:    e.digits,
+			IssuerExt: e.issuer,
+			IssuerInt: e.issuer,
+			Label:     e.account,
+			Period:    e.period,
+			Secret:    secret,
+			Type:      strings.ToUpper(e.typ),
+		})
+	}
+	return json.Marshal(backup)
+}
+
+// freeOTPID FreeOTP+ 中条目的 id，用于 tokenOrder。
+func freeOTPID(issuer, account string) string {
+	if issuer == "" {
+		return account
+	}
+	return issuer + ":" + account
+}
+
+// sortFreeOTP 按照 tokenOrder 排序，不在 tokenOrder 中的条目保持原有顺序排在最后。
+func sortFreeOTP(keys []*otp.KeyURI, order []string) []*otp.KeyURI {
+	if len(order) == 0 {
+		return keys
+	}
+	index := map[string]int{}
+	for i, id := range order {
+		if _, ok := index[id]; !ok {
+			index[id] = i
+		}
+	}
+	sorted := make([]*otp.KeyURI, 0, len(keys))
+	rest := make([]*otp.KeyURI, 0)
+	placed := make([]*otp.KeyURI, len(order))
+	for _, key := range keys {
+		i, ok := index[freeOTPID(splitLabel(key))]
+		if !ok || placed[i] != nil {
+			rest = append(rest, key)
+			continue
+		}
+		placed[i] = key
+	}
+	for _, key := range placed {
+		if key != nil {
+			sorted = append(sorted, key)
+		}
+	}
+	return append(sorted, rest...)
+}
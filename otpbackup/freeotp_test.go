@@ -0,0 +1,50 @@
+package otpbackup
+
+import (
+	"github.com/huk10/go-otp"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// freeOTPPlain FreeOTP+ 导出的备份，秘钥为 "12345678901234567890" 的有符号字节数组
+const freeOTPPlain = `{
+  "tokenOrder": ["Example:alice@google.com", "bob"],
+  "tokens": [
+    {"algo": "SHA1", "counter": 7, "digits": 6, "issuerExt": "", "label": "bob", "period": 30,
+     "secret": [49,50,51,52,53,54,55,56,57,48,49,50,51,52,53,54,55,56,57,48], "type": "HOTP"},
+    {"algo": "SHA256", "counter": 0, "digits": 8, "issuerExt": "Example", "issuerInt": "Example", "label": "alice@google.com",
+     "period": 60, "secret": [-12,-128,127,0,1], "type": "TOTP"}
+  ]
+}`
+
+func TestParseFreeOTP(t *testing.T) {
+	keys, err := ParseFreeOTP([]byte(freeOTPPlain))
+	assert.Nil(t, err)
+	assert.Len(t, keys, 2)
+	// 按照 tokenOrder 排序
+	assert.Equal(t, "otpauth://totp/Example:alice@google.com?secret="+otp.Base32Encode([]byte{0xf4, 0x80, 0x7f, 0x00, 0x01})+"&issuer=Example&algorithm=SHA256&digits=8&period=60", keys[0].URI().String())
+	assert.Equal(t, "otpauth://hotp/bob?secret="+secret+"&issuer=&counter=7", keys[1].URI().String())
+
+	hotp, err := keys[1].HOTP()
+	assert.Nil(t, err)
+	assert.Equal(t, "287082", hotp.At(1))
+
+	_, err = ParseFreeOTP([]byte(`{"tokens": [{"label": "a", "type": "TOTP"}]}`))
+	assert.ErrorIs(t, err, otp.ErrMissingSecret)
+	_, err = ParseFreeOTP([]byte(`[]`))
+	assert.ErrorIs(t, err, ErrFormat)
+}
+
+func TestFreeOTP_RoundTrip(t *testing.T) {
+	keys, err := ParseFreeOTP([]byte(freeOTPPlain))
+	assert.Nil(t, err)
+	data, err := MarshalFreeOTP(keys)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"tokenOrder":["Example:alice@google.com","bob"]`)
+	parsed, err := ParseFreeOTP(data)
+	assert.Nil(t, err)
+	assert.Equal(t, keys, parsed)
+
+	_, err = MarshalFreeOTP([]*otp.KeyURI{otp.NewSteamTOTP(secret).KeyURI("alice", "Steam")})
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
@@ -0,0 +1,130 @@
+// Package otpbackup 导入和导出开源验证器应用的 JSON 备份文件，在这些应用和本库之间迁移账户。
+//
+// 支持的格式：
+//   - Aegis：明文以及使用密码加密的备份（scrypt + AES-256-GCM）。
+//   - andOTP：明文备份。
+//   - FreeOTP+：JSON 格式的备份。
+//   - PSKC（RFC-6030）：硬件令牌厂商分发种子使用的 XML 文件，仅支持导入，包括使用预共享密钥加密的秘钥（AES-CBC）。
+//
+// 所有格式的条目都转换为 *otp.KeyURI，可以继续使用 KeyURI.TOTP、KeyURI.HOTP 创建对应的结构体，
+// 或者使用 otp.MigrationURI 导出到 Google Authenticator。
+//
+// Example:
+//
+//	keys, err := otpbackup.ParseAegis(data, []byte(password))
+//	for _, key := range keys {
+//		totp, err := key.TOTP()
+//	}
+package otpbackup
+
+import (
+	"errors"
+	"fmt"
+	"github.com/huk10/go-otp"
+	"strings"
+)
+
+var (
+	// ErrFormat 备份文件的格式错误。
+	ErrFormat = errors.New("backup format error")
+	// ErrUnsupported 条目使用了目标格式不支持的参数，例如 andOTP 不支持 SHA3 算法。
+	ErrUnsupported = errors.New("key cannot be represented in backup format")
+	// ErrPasswordRequired 备份文件已加密，但是没有提供密码（PSKC 为预共享密钥）。
+	ErrPasswordRequired = errors.New("backup is encrypted, password required")
+	// ErrDecrypt 密码错误或者备份文件被篡改。
+	ErrDecrypt = errors.New("backup decrypt error")
+)
+
+// entry 各个备份格式共用的条目信息，字段均为未编码的值。
+type entry struct {
+	// totp、hotp 或 steam
+	typ       string
+	issuer    string
+	account   string
+	secret    string
+	algorithm string
+	digits    int
+	period    int
+	counter   int64
+}
+
+// fromKey 将 KeyURI 转换为 entry，不合法的 KeyURI 返回对应的错误。
+func fromKey(key *otp.KeyURI) (entry, error) {
+	if key == nil {
+		return entry{}, fmt.Errorf("%w: nil key", ErrUnsupported)
+	}
+	if err := key.Validate(); err != nil {
+		return entry{}, err
+	}
+	if key.Epoch != 0 {
+		return entry{}, fmt.Errorf("%w: epoch", ErrUnsupported)
+	}
+	issuer, account := splitLabel(key)
+	e := entry{
+		typ:       strings.ToLower(key.Type),
+		issuer:    issuer,
+		account:   account,
+		secret:    key.Secret,
+		algorithm: key.Algorithm,
+		digits:    key.Digits,
+		period:    key.Period,
+		counter:   key.Counter,
+	}
+	if key.Encoder == otp.EncoderSteam.String() {
+		e.typ = "steam"
+		e.digits = 5
+	}
+	return e, nil
+}
+
+// key 将 entry 转换为 KeyURI，label 和 issuer 与 TOTP.KeyURI 一致，都是未编码的值。
+func (e entry) key() (*otp.KeyURI, error) {
+	key := &otp.KeyURI{
+		Type:      e.typ,
+		Label:     e.account,
+		Algorithm: strings.ToUpper(e.algorithm),
+		Digits:    e.digits,
+		Period:    e.period,
+		Counter:   e.counter,
+		Issuer:    e.issuer,
+		Secret:    e.secret,
+	}
+	switch e.typ {
+	case "totp":
+		key.Counter = 0
+	case "hotp":
+		key.Period = 0
+	case "steam":
+		key.Type = "totp"
+		key.Counter = 0
+		key.Encoder = otp.EncoderSteam.String()
+	default:
+		return nil, fmt.Errorf("%w: unsupported type %q", ErrFormat, e.typ)
+	}
+	if key.Type == "totp" && key.Period == 0 {
+		key.Period = 30
+	}
+	if key.Digits == 0 {
+		key.Digits = int(otp.DigitsSix)
+	}
+	key.Normalize()
+	if err := key.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	return key, nil
+}
+
+// splitLabel 返回 KeyURI 中的 issuer 和账户名称。
+func splitLabel(key *otp.KeyURI) (issuer, account string) {
+	issuer, account = key.Issuer, key.Label
+	if key.AccountName != "" {
+		return issuer, key.AccountName
+	}
+	if i := strings.Index(account, ":"); i != -1 {
+		if issuer == "" {
+			issuer = account[:i]
+		}
+		account = strings.TrimLeft(account[i+1:], " ")
+	}
+	return issuer, account
+}
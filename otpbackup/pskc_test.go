@@ -0,0 +1,143 @@
+package otpbackup
+
+import (
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// pskcPlain RFC-6030 图 2 中明文秘钥的示例，额外添加了一个 TOTP 秘钥
+const pskcPlain = `<?xml version="1.0" encoding="UTF-8"?>
+<KeyContainer Version="1.0" Id="exampleID1" xmlns="urn:ietf:params:xml:ns:keyprov:pskc">
+  <KeyPackage>
+    <DeviceInfo>
+      <Manufacturer>Manufacturer</Manufacturer>
+      <SerialNo>987654321</SerialNo>
+    </DeviceInfo>
+    <Key Id="12345678" Algorithm="urn:ietf:params:xml:ns:keyprov:pskc:hotp">
+      <Issuer>Issuer</Issuer>
+      <AlgorithmParameters>
+        <ResponseFormat Length="8" Encoding="DECIMAL"/>
+      </AlgorithmParameters>
+      <Data>
+        <Secret><PlainValue>MTIzNDU2Nzg5MDEyMzQ1Njc4OTA=</PlainValue></Secret>
+        <Counter><PlainValue>0</PlainValue></Counter>
+      </Data>
+    </Key>
+  </KeyPackage>
+  <KeyPackage>
+    <Key Id="TOTP-1" Algorithm="urn:ietf:params:xml:ns:keyprov:pskc#totp">
+      <Issuer>Example</Issuer>
+      <AlgorithmParameters>
+        <Suite>HMAC-SHA256</Suite>
+        <ResponseFormat Length="6" Encoding="DECIMAL"/>
+      </AlgorithmParameters>
+      <Data>
+        <Secret><PlainValue>MTIzNDU2Nzg5MDEyMzQ1Njc4OTA=</PlainValue></Secret>
+        <TimeInterval><PlainValue>60</PlainValue></TimeInterval>
+      </Data>
+      <UserId>alice@google.com</UserId>
+    </Key>
+  </KeyPackage>
+</KeyContainer>`
+
+// pskcEncrypted RFC-6030 图 6 中使用 AES-128-CBC 加密并带有 HMAC-SHA1 的示例
+const pskcEncrypted = `<?xml version="1.0" encoding="UTF-8"?>
+<pskc:KeyContainer xmlns:pskc="urn:ietf:params:xml:ns:keyprov:pskc" xmlns:xenc="http://www.w3.org/2001/04/xmlenc#" xmlns:ds="http://www.w3.org/2000/09/xmldsig#" Version="1.0">
+  <pskc:EncryptionKey><ds:KeyName>Pre-shared-key</ds:KeyName></pskc:EncryptionKey>
+  <pskc:MACMethod Algorithm="http://www.w3.org/2000/09/xmldsig#hmac-sha1">
+    <pskc:MACKey>
+      <xenc:EncryptionMethod Algorithm="http://www.w3.org/2001/04/xmlenc#aes128-cbc"/>
+      <xenc:CipherData>
+        <xenc:CipherValue>ESIzRFVmd4iZABEiM0RVZgKn6WjLaTC1sbeBMSvIhRejN9vJa2BOlSaMrR7I5wSX</xenc:CipherValue>
+      </xenc:CipherData>
+    </pskc:MACKey>
+  </pskc:MACMethod>
+  <pskc:KeyPackage>
+    <pskc:DeviceInfo>
+      <pskc:Manufacturer>Manufacturer</pskc:Manufacturer>
+      <pskc:SerialNo>987654321</pskc:SerialNo>
+    </pskc:DeviceInfo>
+    <pskc:CryptoModuleInfo><pskc:Id>CM_ID_001</pskc:Id></pskc:CryptoModuleInfo>
+    <pskc:Key Id="12345678" Algorithm="urn:ietf:params:xml:ns:keyprov:pskc:hotp">
+      <pskc:Issuer>Issuer</pskc:Issuer>
+      <pskc:AlgorithmParameters>
+        <pskc:ResponseFormat Length="8" Encoding="DECIMAL"/>
+      </pskc:AlgorithmParameters>
+      <pskc:Data>
+        <pskc:Secret>
+          <pskc:EncryptedValue>
+            <xenc:EncryptionMethod Algorithm="http://www.w3.org/2001/04/xmlenc#aes128-cbc"/>
+            <xenc:CipherData>
+              <xenc:CipherValue>AAECAwQFBgcICQoLDA0OD+cIHItlB3Wra1DUpxVvOx2lef1VmNPCMl8jwZqIUqGv</xenc:CipherValue>
+            </xenc:CipherData>
+          </pskc:EncryptedValue>
+          <pskc:ValueMAC>Su+NvtQfmvfJzF6bmQiJqoLRExc=</pskc:ValueMAC>
+        </pskc:Secret>
+        <pskc:Counter><pskc:PlainValue>0</pskc:PlainValue></pskc:Counter>
+      </pskc:Data>
+    </pskc:Key>
+  </pskc:KeyPackage>
+</pskc:KeyContainer>`
+
+// pskcPreSharedKey RFC-6030 图 6 中的预共享密钥
+var pskcPreSharedKey, _ = hex.DecodeString("12345678901234567890123456789012")
+
+func TestParsePSKC(t *testing.T) {
+	keys, err := ParsePSKC([]byte(pskcPlain), nil)
+	assert.Nil(t, err)
+	assert.Len(t, keys, 2)
+	assert.Equal(t, "otpauth://hotp/Issuer:987654321?secret="+secret+"&issuer=Issuer&digits=8&counter=0", keys[0].URI().String())
+	assert.Equal(t, "otpauth://totp/Example:alice@google.com?secret="+secret+"&issuer=Example&algorithm=SHA256&period=60", keys[1].URI().String())
+
+	// RFC-4226 附录 D 的测试向量
+	hotp, err := keys[0].HOTP()
+	assert.Nil(t, err)
+	assert.Equal(t, "84755224", hotp.At(0))
+}
+
+func TestParsePSKC_Encrypted(t *testing.T) {
+	keys, err := ParsePSKC([]byte(pskcEncrypted), pskcPreSharedKey)
+	assert.Nil(t, err)
+	assert.Len(t, keys, 1)
+	assert.Equal(t, secret, keys[0].Secret)
+	assert.Equal(t, "987654321", keys[0].AccountName)
+
+	_, err = ParsePSKC([]byte(pskcEncrypted), nil)
+	assert.ErrorIs(t, err, ErrPasswordRequired)
+
+	wrong := append([]byte(nil), pskcPreSharedKey...)
+	wrong[0] ^= 1
+	_, err = ParsePSKC([]byte(pskcEncrypted), wrong)
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	// 密文被篡改时 MAC 校验失败
+	tampered := strings.Replace(pskcEncrypted, "AAECAwQFBgcICQoLDA0OD+", "AAECAwQFBgcICQoLDA0OE+", 1)
+	_, err = ParsePSKC([]byte(tampered), pskcPreSharedKey)
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	// 不支持的加密算法
+	gcm := strings.ReplaceAll(pskcEncrypted, "aes128-cbc", "aes128-gcm")
+	_, err = ParsePSKC([]byte(gcm), pskcPreSharedKey)
+	assert.ErrorIs(t, err, ErrUnsupported)
+}
+
+func TestParsePSKC_Invalid(t *testing.T) {
+	for _, data := range []string{
+		``,
+		`<KeyContainer><KeyPackage><Key Algorithm="urn:ietf:params:xml:ns:keyprov:pskc:hotp"><Data></Data></Key></KeyPackage></KeyContainer>`,
+		`<KeyContainer><KeyPackage><Key Algorithm="urn:ietf:params:xml:ns:keyprov:pskc:hotp"><Data><Secret><PlainValue>!</PlainValue></Secret></Data></Key></KeyPackage></KeyContainer>`,
+	} {
+		_, err := ParsePSKC([]byte(data), nil)
+		assert.ErrorIs(t, err, ErrFormat, data)
+	}
+
+	for _, data := range []string{
+		`<KeyContainer><KeyPackage><Key Algorithm="urn:ietf:params:xml:ns:keyprov:pskc:ocra-1"></Key></KeyPackage></KeyContainer>`,
+		`<KeyContainer><KeyPackage><Key Algorithm="urn:ietf:params:xml:ns:keyprov:pskc:hotp"><AlgorithmParameters><ResponseFormat Length="8" Encoding="HEXADECIMAL"/></AlgorithmParameters></Key></KeyPackage></KeyContainer>`,
+	} {
+		_, err := ParsePSKC([]byte(data), nil)
+		assert.ErrorIs(t, err, ErrUnsupported, data)
+	}
+}
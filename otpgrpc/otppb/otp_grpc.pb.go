@@ -0,0 +1,267 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: otp.proto
+
+package otppb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	OTPService_GenerateSecret_FullMethodName     = "/otp.v1.OTPService/GenerateSecret"
+	OTPService_GetProvisioningURI_FullMethodName = "/otp.v1.OTPService/GetProvisioningURI"
+	OTPService_VerifyTOTP_FullMethodName         = "/otp.v1.OTPService/VerifyTOTP"
+	OTPService_VerifyHOTP_FullMethodName         = "/otp.v1.OTPService/VerifyHOTP"
+	OTPService_ResyncHOTP_FullMethodName         = "/otp.v1.OTPService/ResyncHOTP"
+)
+
+// OTPServiceClient is the client API for OTPService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type OTPServiceClient interface {
+	// GenerateSecret 为账户生成并保存新的随机秘钥，已存在时覆盖。
+	GenerateSecret(ctx context.Context, in *GenerateSecretRequest, opts ...grpc.CallOption) (*GenerateSecretResponse, error)
+	// GetProvisioningURI 返回账户的 otpauth URI，用于生成二维码。
+	GetProvisioningURI(ctx context.Context, in *GetProvisioningURIRequest, opts ...grpc.CallOption) (*GetProvisioningURIResponse, error)
+	// VerifyTOTP 校验账户当前时间的 TOTP token。
+	VerifyTOTP(ctx context.Context, in *VerifyTOTPRequest, opts ...grpc.CallOption) (*VerifyTOTPResponse, error)
+	// VerifyHOTP 校验账户的 HOTP token，返回下一个期望的计数器。
+	VerifyHOTP(ctx context.Context, in *VerifyHOTPRequest, opts ...grpc.CallOption) (*VerifyHOTPResponse, error)
+	// ResyncHOTP 使用两个连续的 token 重新同步 HOTP 计数器。
+	ResyncHOTP(ctx context.Context, in *ResyncHOTPRequest, opts ...grpc.CallOption) (*ResyncHOTPResponse, error)
+}
+
+type oTPServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewOTPServiceClient(cc grpc.ClientConnInterface) OTPServiceClient {
+	return &oTPServiceClient{cc}
+}
+
+func (c *oTPServiceClient) GenerateSecret(ctx context.Context, in *GenerateSecretRequest, opts ...grpc.CallOption) (*GenerateSecretResponse, error) {
+	out := new(GenerateSecretResponse)
+	err := c.cc.Invoke(ctx, OTPService_GenerateSecret_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oTPServiceClient) GetProvisioningURI(ctx context.Context, in *GetProvisioningURIRequest, opts ...grpc.CallOption) (*GetProvisioningURIResponse, error) {
+	out := new(GetProvisioningURIResponse)
+	err := c.cc.Invoke(ctx, OTPService_GetProvisioningURI_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oTPServiceClient) VerifyTOTP(ctx context.Context, in *VerifyTOTPRequest, opts ...grpc.CallOption) (*VerifyTOTPResponse, error) {
+	out := new(VerifyTOTPResponse)
+	err := c.cc.Invoke(ctx, OTPService_VerifyTOTP_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oTPServiceClient) VerifyHOTP(ctx context.Context, in *VerifyHOTPRequest, opts ...grpc.CallOption) (*VerifyHOTPResponse, error) {
+	out := new(VerifyHOTPResponse)
+	err := c.cc.Invoke(ctx, OTPService_VerifyHOTP_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *oTPServiceClient) ResyncHOTP(ctx context.Context, in *ResyncHOTPRequest, opts ...grpc.CallOption) (*ResyncHOTPResponse, error) {
+	out := new(ResyncHOTPResponse)
+	err := c.cc.Invoke(ctx, OTPService_ResyncHOTP_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// OTPServiceServer is the server API for OTPService service.
+// All implementations must embed UnimplementedOTPServiceServer
+// for forward compatibility
+type OTPServiceServer interface {
+	// GenerateSecret 为账户生成并保存新的随机秘钥，已存在时覆盖。
+	GenerateSecret(context.Context, *GenerateSecretRequest) (*GenerateSecretResponse, error)
+	// GetProvisioningURI 返回账户的 otpauth URI，用于生成二维码。
+	GetProvisioningURI(context.Context, *GetProvisioningURIRequest) (*GetProvisioningURIResponse, error)
+	// VerifyTOTP 校验账户当前时间的 TOTP token。
+	VerifyTOTP(context.Context, *VerifyTOTPRequest) (*VerifyTOTPResponse, error)
+	// VerifyHOTP 校验账户的 HOTP token，返回下一个期望的计数器。
+	VerifyHOTP(context.Context, *VerifyHOTPRequest) (*VerifyHOTPResponse, error)
+	// ResyncHOTP 使用两个连续的 token 重新同步 HOTP 计数器。
+	ResyncHOTP(context.Context, *ResyncHOTPRequest) (*ResyncHOTPResponse, error)
+	mustEmbedUnimplementedOTPServiceServer()
+}
+
+// UnimplementedOTPServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedOTPServiceServer struct {
+}
+
+func (UnimplementedOTPServiceServer) GenerateSecret(context.Context, *GenerateSecretRequest) (*GenerateSecretResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GenerateSecret not implemented")
+}
+func (UnimplementedOTPServiceServer) GetProvisioningURI(context.Context, *GetProvisioningURIRequest) (*GetProvisioningURIResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProvisioningURI not implemented")
+}
+func (UnimplementedOTPServiceServer) VerifyTOTP(context.Context, *VerifyTOTPRequest) (*VerifyTOTPResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyTOTP not implemented")
+}
+func (UnimplementedOTPServiceServer) VerifyHOTP(context.Context, *VerifyHOTPRequest) (*VerifyHOTPResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyHOTP not implemented")
+}
+func (UnimplementedOTPServiceServer) ResyncHOTP(context.Context, *ResyncHOTPRequest) (*ResyncHOTPResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResyncHOTP not implemented")
+}
+func (UnimplementedOTPServiceServer) mustEmbedUnimplementedOTPServiceServer() {}
+
+// UnsafeOTPServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to OTPServiceServer will
+// result in compilation errors.
+type UnsafeOTPServiceServer interface {
+	mustEmbedUnimplementedOTPServiceServer()
+}
+
+func RegisterOTPServiceServer(s grpc.ServiceRegistrar, srv OTPServiceServer) {
+	s.RegisterService(&OTPService_ServiceDesc, srv)
+}
+
+func _OTPService_GenerateSecret_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GenerateSecretRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OTPServiceServer).GenerateSecret(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OTPService_GenerateSecret_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OTPServiceServer).GenerateSecret(ctx, req.(*GenerateSecretRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OTPService_GetProvisioningURI_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetProvisioningURIRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OTPServiceServer).GetProvisioningURI(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OTPService_GetProvisioningURI_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OTPServiceServer).GetProvisioningURI(ctx, req.(*GetProvisioningURIRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OTPService_VerifyTOTP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyTOTPRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OTPServiceServer).VerifyTOTP(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OTPService_VerifyTOTP_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OTPServiceServer).VerifyTOTP(ctx, req.(*VerifyTOTPRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OTPService_VerifyHOTP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyHOTPRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OTPServiceServer).VerifyHOTP(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OTPService_VerifyHOTP_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OTPServiceServer).VerifyHOTP(ctx, req.(*VerifyHOTPRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _OTPService_ResyncHOTP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResyncHOTPRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OTPServiceServer).ResyncHOTP(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OTPService_ResyncHOTP_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OTPServiceServer).ResyncHOTP(ctx, req.(*ResyncHOTPRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// OTPService_ServiceDesc is the grpc.ServiceDesc for OTPService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var OTPService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "otp.v1.OTPService",
+	HandlerType: (*OTPServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GenerateSecret",
+			Handler:    _OTPService_GenerateSecret_Handler,
+		},
+		{
+			MethodName: "GetProvisioningURI",
+			Handler:    _OTPService_GetProvisioningURI_Handler,
+		},
+		{
+			MethodName: "VerifyTOTP",
+			Handler:    _OTPService_VerifyTOTP_Handler,
+		},
+		{
+			MethodName: "VerifyHOTP",
+			Handler:    _OTPService_VerifyHOTP_Handler,
+		},
+		{
+			MethodName: "ResyncHOTP",
+			Handler:    _OTPService_ResyncHOTP_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "otp.proto",
+}
@@ -0,0 +1,226 @@
+// Package otpgrpc 实现了 otp.proto 中定义的 2FA 校验服务，秘钥只保存在服务端。
+//
+// otppb 包含 protoc-gen-go、protoc-gen-go-grpc 生成的代码，修改 otp.proto 之后需要执行 go generate 重新生成。
+// otpgrpc 是一个单独的模块，gRPC 和 protobuf 依赖不会引入到主模块中。
+//
+// Example:
+//
+//	server := grpc.NewServer()
+//	otppb.RegisterOTPServiceServer(server, otpgrpc.NewServer(store, otp.WithReplayGuard(guard)))
+//	server.Serve(listener)
+package otpgrpc
+
+//go:generate protoc --go_out=otppb --go_opt=paths=source_relative --go-grpc_out=otppb --go-grpc_opt=paths=source_relative otp.proto
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/huk10/go-otp"
+	"github.com/huk10/go-otp/otpgrpc/otppb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"math"
+	"time"
+)
+
+const (
+	// DefaultSecretSize GenerateSecret 未指定 size 时生成的秘钥字节数。
+	DefaultSecretSize = 20
+	// MaxSecretSize GenerateSecret 允许的最大秘钥字节数，超出时按最大值生成。
+	MaxSecretSize = 64
+	// MaxLookAhead VerifyHOTP 允许的最大前向窗口，超出时按最大值校验。
+	MaxLookAhead = 20
+	// MaxResyncLookAhead ResyncHOTP 允许的最大前向窗口，超出时按最大值查找。
+	MaxResyncLookAhead = 1000
+)
+
+// ErrInvalidArgument 请求参数错误，对应 gRPC 的 InvalidArgument。
+var ErrInvalidArgument = errors.New("invalid argument")
+
+// randReader 生成随机秘钥使用的随机数来源，测试时可以替换。
+var randReader io.Reader = rand.Reader
+
+// Server 实现 otppb.OTPServiceServer，使用 otppb.RegisterOTPServiceServer 注册。
+//
+// 返回的错误都是 gRPC 的 status 错误，状态码见 Code。
+type Server struct {
+	otppb.UnimplementedOTPServiceServer
+
+	// 保存秘钥的存储，建议使用 otp.EncryptedSecretStore。
+	Store otp.SecretStore
+	// 所有账户共用的 option，例如 otp.WithSkewWindow、otp.WithReplayGuard。
+	Options []otp.Option
+	// 仅用于 TOTP 账户的 option，例如 otp.WithPeriod。
+	TOTPOptions []otp.TOTPOption
+}
+
+// NewServer 创建一个 Server。
+func NewServer(store otp.SecretStore, options ...otp.Option) *Server {
+	return &Server{Store: store, Options: options}
+}
+
+// totpOptions 返回创建 TOTP 时使用的 option，TOTPOptions 在 Options 之后应用。
+func (s *Server) totpOptions() []otp.TOTPOption {
+	return append(otp.AsTOTPOptions(s.Options...), s.TOTPOptions...)
+}
+
+// GenerateSecret 为账户生成并保存新的随机秘钥，已存在时覆盖，size 超过 MaxSecretSize 时按 MaxSecretSize 生成。
+//
+// 系统随机数生成器出错时返回 Internal，而不是 panic。
+func (s *Server) GenerateSecret(_ context.Context, req *otppb.GenerateSecretRequest) (*otppb.GenerateSecretResponse, error) {
+	if req.AccountId == "" || req.Size < 0 {
+		return nil, statusError(ErrInvalidArgument)
+	}
+	size := int(req.Size)
+	if size == 0 {
+		size = DefaultSecretSize
+	}
+	if size > MaxSecretSize {
+		size = MaxSecretSize
+	}
+	secret := make([]byte, size)
+	if _, err := io.ReadFull(randReader, secret); err != nil {
+		return nil, statusError(fmt.Errorf("generate secret: %w", err))
+	}
+	if err := s.Store.Put(req.AccountId, secret); err != nil {
+		return nil, statusError(err)
+	}
+	return &otppb.GenerateSecretResponse{Secret: otp.Base32Encode(secret)}, nil
+}
+
+// GetProvisioningURI 返回账户的 otpauth URI。
+func (s *Server) GetProvisioningURI(_ context.Context, req *otppb.GetProvisioningURIRequest) (*otppb.GetProvisioningURIResponse, error) {
+	if req.AccountId == "" || req.AccountName == "" {
+		return nil, statusError(ErrInvalidArgument)
+	}
+	var key *otp.KeyURI
+	switch req.Type {
+	case "", "totp":
+		totp, err := otp.NewTOTPFromStore(s.Store, req.AccountId, s.totpOptions()...)
+		if err != nil {
+			return nil, statusError(err)
+		}
+		key = totp.KeyURI(req.AccountName, req.Issuer)
+	case "hotp":
+		hotp, err := otp.NewHOTPFromStore(s.Store, req.AccountId, append(otp.AsHOTPOptions(s.Options...), otp.WithCounter(req.Counter))...)
+		if err != nil {
+			return nil, statusError(err)
+		}
+		key = hotp.KeyURI(req.AccountName, req.Issuer)
+	default:
+		return nil, statusError(ErrInvalidArgument)
+	}
+	return &otppb.GetProvisioningURIResponse{Uri: key.URI().String()}, nil
+}
+
+// VerifyTOTP 校验账户当前时间的 TOTP token。
+func (s *Server) VerifyTOTP(ctx context.Context, req *otppb.VerifyTOTPRequest) (*otppb.VerifyTOTPResponse, error) {
+	if req.AccountId == "" {
+		return nil, statusError(ErrInvalidArgument)
+	}
+	totp, err := otp.NewTOTPFromStore(s.Store, req.AccountId, s.totpOptions()...)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	step, ok, err := totp.VerifyWithMatchContext(ctx, req.Token, time.Now())
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &otppb.VerifyTOTPResponse{Valid: ok, Step: step}, nil
+}
+
+// VerifyHOTP 使用 otp.HOTP.ValidateAndSyncContext 校验 token，返回需要保存的新计数器。
+//
+// look_ahead 超过 MaxLookAhead 时按 MaxLookAhead 校验，客户端不能通过扩大窗口增加猜中的概率。
+// counter 小于 0 或者加上 look_ahead 之后超出 int64 范围时返回 InvalidArgument。
+func (s *Server) VerifyHOTP(ctx context.Context, req *otppb.VerifyHOTPRequest) (*otppb.VerifyHOTPResponse, error) {
+	if req.AccountId == "" || req.LookAhead < 0 {
+		return nil, statusError(ErrInvalidArgument)
+	}
+	lookAhead := clamp(req.LookAhead, MaxLookAhead)
+	if !validCounter(req.Counter, lookAhead) {
+		return nil, statusError(ErrInvalidArgument)
+	}
+	hotp, err := otp.NewHOTPFromStore(s.Store, req.AccountId, otp.AsHOTPOptions(s.Options...)...)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	next, ok, err := hotp.ValidateAndSyncContext(ctx, req.Token, req.Counter, lookAhead)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &otppb.VerifyHOTPResponse{Valid: ok, NextCounter: next}, nil
+}
+
+// ResyncHOTP 在 counter 到 counter+look_ahead 之间查找 token1，找到时要求下一个计数器的 token 为 token2，
+// 两个 token 都匹配时返回 token2 的计数器加一。
+//
+// 与 VerifyHOTP 相比要求用户连续输入两个 token，因此可以使用更大的 look_ahead 而不会明显降低安全性，
+// 超过 MaxResyncLookAhead 时按 MaxResyncLookAhead 查找。counter 的检查与 VerifyHOTP 相同。
+func (s *Server) ResyncHOTP(ctx context.Context, req *otppb.ResyncHOTPRequest) (*otppb.ResyncHOTPResponse, error) {
+	if req.AccountId == "" || req.LookAhead < 0 || req.Token1 == "" || req.Token2 == "" {
+		return nil, statusError(ErrInvalidArgument)
+	}
+	lookAhead := clamp(req.LookAhead, MaxResyncLookAhead)
+	if !validCounter(req.Counter, lookAhead) {
+		return nil, statusError(ErrInvalidArgument)
+	}
+	hotp, err := otp.NewHOTPFromStore(s.Store, req.AccountId, otp.AsHOTPOptions(s.Options...)...)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	next, ok, err := hotp.ValidateAndSyncContext(ctx, req.Token1, req.Counter, lookAhead)
+	if err == nil && ok {
+		next, ok, err = hotp.ValidateAndSyncContext(ctx, req.Token2, next, 0)
+	}
+	if err != nil {
+		return nil, statusError(err)
+	}
+	if !ok {
+		return &otppb.ResyncHOTPResponse{Synced: false, NextCounter: req.Counter}, nil
+	}
+	return &otppb.ResyncHOTPResponse{Synced: true, NextCounter: next}, nil
+}
+
+// validCounter 判断客户端传入的 counter 是否有效，counter+lookAhead+1 不能超出 int64 范围。
+func validCounter(counter int64, lookAhead int) bool {
+	return counter >= 0 && counter <= math.MaxInt64-int64(lookAhead)-1
+}
+
+// clamp 返回 lookAhead 与 max 中较小的一个。
+func clamp(lookAhead int32, max int) int {
+	if int(lookAhead) > max {
+		return max
+	}
+	return int(lookAhead)
+}
+
+// Code 返回 err 对应的 gRPC 状态码。
+func Code(err error) codes.Code {
+	switch {
+	case err == nil:
+		return codes.OK
+	case errors.Is(err, ErrInvalidArgument):
+		return codes.InvalidArgument
+	case errors.Is(err, otp.ErrSecretNotFound):
+		return codes.NotFound
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
+
+// statusError 把 err 转换为 gRPC 的 status 错误，Internal 错误不返回原始的错误信息，避免泄露存储的细节。
+func statusError(err error) error {
+	code := Code(err)
+	if code == codes.Internal {
+		return status.Error(code, "internal error")
+	}
+	return status.Error(code, err.Error())
+}
@@ -0,0 +1,213 @@
+package otpgrpc
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"github.com/huk10/go-otp"
+	"github.com/huk10/go-otp/otpgrpc/otppb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"math"
+	"net"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(otp.NewMemorySecretStore())
+
+	_, err := server.GenerateSecret(ctx, &otppb.GenerateSecretRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	generated, err := server.GenerateSecret(ctx, &otppb.GenerateSecretRequest{AccountId: "1"})
+	assert.Nil(t, err)
+	decoded, _ := otp.Base32Decode(generated.Secret)
+	assert.Len(t, decoded, DefaultSecretSize)
+
+	uri, err := server.GetProvisioningURI(ctx, &otppb.GetProvisioningURIRequest{AccountId: "1", AccountName: "alice", Issuer: "Example"})
+	assert.Nil(t, err)
+	key, err := otp.FromURI(uri.Uri)
+	assert.Nil(t, err)
+	assert.Equal(t, "totp", key.Type)
+	assert.Equal(t, generated.Secret, key.Secret)
+
+	uri, err = server.GetProvisioningURI(ctx, &otppb.GetProvisioningURIRequest{AccountId: "1", AccountName: "alice", Type: "hotp", Counter: 5})
+	assert.Nil(t, err)
+	key, _ = otp.FromURI(uri.Uri)
+	assert.Equal(t, int64(5), key.Counter)
+	_, err = server.GetProvisioningURI(ctx, &otppb.GetProvisioningURIRequest{AccountId: "1", AccountName: "alice", Type: "ocra"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = server.GetProvisioningURI(ctx, &otppb.GetProvisioningURIRequest{AccountId: "2", AccountName: "alice"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	now := time.Now()
+	totp := otp.NewTOTP(generated.Secret)
+	verified, err := server.VerifyTOTP(ctx, &otppb.VerifyTOTPRequest{AccountId: "1", Token: totp.At(now)})
+	assert.Nil(t, err)
+	assert.True(t, verified.Valid)
+	assert.Equal(t, now.Unix()/30, verified.Step)
+	verified, _ = server.VerifyTOTP(ctx, &otppb.VerifyTOTPRequest{AccountId: "1"})
+	assert.False(t, verified.Valid)
+}
+
+func TestServer_HOTP(t *testing.T) {
+	ctx := context.Background()
+	store := otp.NewMemorySecretStore()
+	server := NewServer(store)
+	generated, _ := server.GenerateSecret(ctx, &otppb.GenerateSecretRequest{AccountId: "1", Size: 32})
+	hotp := otp.NewHOTP(generated.Secret)
+
+	resp, err := server.VerifyHOTP(ctx, &otppb.VerifyHOTPRequest{AccountId: "1", Token: hotp.At(3), Counter: 1, LookAhead: 5})
+	assert.Nil(t, err)
+	assert.True(t, resp.Valid)
+	assert.Equal(t, int64(4), resp.NextCounter)
+	resp, _ = server.VerifyHOTP(ctx, &otppb.VerifyHOTPRequest{AccountId: "1", Token: hotp.At(3), Counter: 4, LookAhead: 5})
+	assert.False(t, resp.Valid)
+	assert.Equal(t, int64(4), resp.NextCounter)
+
+	synced, err := server.ResyncHOTP(ctx, &otppb.ResyncHOTPRequest{AccountId: "1", Token1: hotp.At(50), Token2: hotp.At(51), Counter: 4, LookAhead: 100})
+	assert.Nil(t, err)
+	assert.True(t, synced.Synced)
+	assert.Equal(t, int64(52), synced.NextCounter)
+	synced, _ = server.ResyncHOTP(ctx, &otppb.ResyncHOTPRequest{AccountId: "1", Token1: hotp.At(50), Token2: hotp.At(52), Counter: 4, LookAhead: 100})
+	assert.False(t, synced.Synced)
+	assert.Equal(t, int64(4), synced.NextCounter)
+	_, err = server.ResyncHOTP(ctx, &otppb.ResyncHOTPRequest{AccountId: "1", Token1: hotp.At(50)})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_Limits(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(otp.NewMemorySecretStore())
+
+	generated, err := server.GenerateSecret(ctx, &otppb.GenerateSecretRequest{AccountId: "1", Size: 1 << 30})
+	assert.Nil(t, err)
+	decoded, _ := otp.Base32Decode(generated.Secret)
+	assert.Len(t, decoded, MaxSecretSize)
+
+	hotp := otp.NewHOTP(generated.Secret)
+	resp, err := server.VerifyHOTP(ctx, &otppb.VerifyHOTPRequest{AccountId: "1", Token: hotp.At(MaxLookAhead), LookAhead: 1 << 30})
+	assert.Nil(t, err)
+	assert.True(t, resp.Valid)
+	resp, _ = server.VerifyHOTP(ctx, &otppb.VerifyHOTPRequest{AccountId: "1", Token: hotp.At(MaxLookAhead + 1), LookAhead: 1 << 30})
+	assert.False(t, resp.Valid)
+
+	synced, err := server.ResyncHOTP(ctx, &otppb.ResyncHOTPRequest{AccountId: "1", Token1: hotp.At(MaxResyncLookAhead), Token2: hotp.At(MaxResyncLookAhead + 1), LookAhead: 1 << 30})
+	assert.Nil(t, err)
+	assert.True(t, synced.Synced)
+	synced, _ = server.ResyncHOTP(ctx, &otppb.ResyncHOTPRequest{AccountId: "1", Token1: hotp.At(MaxResyncLookAhead + 1), Token2: hotp.At(MaxResyncLookAhead + 2), LookAhead: 1 << 30})
+	assert.False(t, synced.Synced)
+}
+
+// failingStore 成功读取 reads 次之后返回错误的 SecretStore，reads 小于 0 时不会出错。
+type failingStore struct {
+	*otp.MemorySecretStore
+	reads int
+}
+
+func (s *failingStore) Get(id string) ([]byte, error) {
+	if s.reads == 0 {
+		return nil, errors.New("store unavailable")
+	}
+	if s.reads > 0 {
+		s.reads--
+	}
+	return s.MemorySecretStore.Get(id)
+}
+
+func TestServer_Counter(t *testing.T) {
+	ctx := context.Background()
+	server := NewServer(otp.NewMemorySecretStore())
+	generated, _ := server.GenerateSecret(ctx, &otppb.GenerateSecretRequest{AccountId: "1"})
+	hotp := otp.NewHOTP(generated.Secret)
+
+	_, err := server.VerifyHOTP(ctx, &otppb.VerifyHOTPRequest{AccountId: "1", Token: hotp.At(0), Counter: -1, LookAhead: 5})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = server.VerifyHOTP(ctx, &otppb.VerifyHOTPRequest{AccountId: "1", Token: hotp.At(0), Counter: math.MaxInt64 - 5, LookAhead: 5})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	// 超出 MaxLookAhead 的部分按 MaxLookAhead 检查
+	_, err = server.VerifyHOTP(ctx, &otppb.VerifyHOTPRequest{AccountId: "1", Token: hotp.At(0), Counter: math.MaxInt64 - MaxLookAhead - 1, LookAhead: 1 << 30})
+	assert.Nil(t, err)
+
+	_, err = server.ResyncHOTP(ctx, &otppb.ResyncHOTPRequest{AccountId: "1", Token1: hotp.At(0), Token2: hotp.At(1), Counter: -1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = server.ResyncHOTP(ctx, &otppb.ResyncHOTPRequest{AccountId: "1", Token1: hotp.At(0), Token2: hotp.At(1), Counter: math.MaxInt64, LookAhead: 100})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_Errors(t *testing.T) {
+	ctx := context.Background()
+	store := &failingStore{MemorySecretStore: otp.NewMemorySecretStore(), reads: -1}
+	server := NewServer(store)
+
+	randReader = iotest.ErrReader(errors.New("no entropy"))
+	_, err := server.GenerateSecret(ctx, &otppb.GenerateSecretRequest{AccountId: "1"})
+	randReader = rand.Reader
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	generated, err := server.GenerateSecret(ctx, &otppb.GenerateSecretRequest{AccountId: "1"})
+	require.Nil(t, err)
+	hotp := otp.NewHOTP(generated.Secret)
+
+	// 创建 TOTP、HOTP 时读取秘钥成功，校验时读取失败，返回错误而不是 valid 为 false
+	store.reads = 1
+	_, err = server.VerifyTOTP(ctx, &otppb.VerifyTOTPRequest{AccountId: "1", Token: "123456"})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	store.reads = 1
+	_, err = server.VerifyHOTP(ctx, &otppb.VerifyHOTPRequest{AccountId: "1", Token: hotp.At(0)})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	store.reads = 1
+	_, err = server.ResyncHOTP(ctx, &otppb.ResyncHOTPRequest{AccountId: "1", Token1: hotp.At(0), Token2: hotp.At(1)})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	store.reads = -1
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = server.VerifyTOTP(canceled, &otppb.VerifyTOTPRequest{AccountId: "1", Token: "123456"})
+	assert.Equal(t, codes.Canceled, status.Code(err))
+	_, err = server.VerifyHOTP(canceled, &otppb.VerifyHOTPRequest{AccountId: "1", Token: hotp.At(0)})
+	assert.Equal(t, codes.Canceled, status.Code(err))
+	_, err = server.ResyncHOTP(canceled, &otppb.ResyncHOTPRequest{AccountId: "1", Token1: hotp.At(0), Token2: hotp.At(1)})
+	assert.Equal(t, codes.Canceled, status.Code(err))
+}
+
+func TestServer_Register(t *testing.T) {
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	otppb.RegisterOTPServiceServer(server, NewServer(otp.NewMemorySecretStore()))
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.Nil(t, err)
+	defer conn.Close()
+	client := otppb.NewOTPServiceClient(conn)
+
+	ctx := context.Background()
+	generated, err := client.GenerateSecret(ctx, &otppb.GenerateSecretRequest{AccountId: "1"})
+	require.Nil(t, err)
+	verified, err := client.VerifyTOTP(ctx, &otppb.VerifyTOTPRequest{AccountId: "1", Token: otp.NewTOTP(generated.Secret).Now()})
+	assert.Nil(t, err)
+	assert.True(t, verified.Valid)
+	_, err = client.VerifyTOTP(ctx, &otppb.VerifyTOTPRequest{AccountId: "2"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestCode(t *testing.T) {
+	assert.Equal(t, codes.OK, Code(nil))
+	assert.Equal(t, codes.InvalidArgument, Code(ErrInvalidArgument))
+	assert.Equal(t, codes.NotFound, Code(otp.ErrSecretNotFound))
+	assert.Equal(t, codes.Internal, Code(otp.ErrSecretDecrypt))
+
+	assert.Equal(t, "rpc error: code = Internal desc = internal error", statusError(otp.ErrSecretDecrypt).Error())
+}
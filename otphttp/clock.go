@@ -0,0 +1,79 @@
+package otphttp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// TimeResponse TimeHandler 的响应。
+type TimeResponse struct {
+	// 服务端当前的 unix 毫秒时间戳
+	UnixMilli int64 `json:"unix_ms"`
+}
+
+// TimeHandler 返回一个返回服务端当前时间的处理器，只接受 GET 和 HEAD 请求，不需要登录。
+//
+// 客户端使用 ClockOffset 或者相同的算法计算本地时钟与服务端的偏差，偏差过大时提示用户校准设备时间，
+// 否则验证器应用生成的 token 会校验失败。
+//
+// Example:
+//
+//	mux.Handle("/otp/time", otphttp.TimeHandler())
+//
+// 浏览器中的计算方法：
+//
+//	const t0 = Date.now()
+//	const { unix_ms } = await (await fetch("/otp/time", { cache: "no-store" })).json()
+//	const t1 = Date.now()
+//	const offset = unix_ms - (t0 + (t1 - t0) / 2)
+func TimeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeJSON(w, http.StatusMethodNotAllowed, VerifyResponse{Error: "method not allowed"})
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusOK, TimeResponse{UnixMilli: time.Now().UnixMilli()})
+	})
+}
+
+// ClockOffset 请求 url 对应的 TimeHandler，返回服务端时钟减去本地时钟的偏差以及请求的往返时间。
+//
+// 假设请求和响应在网络上花费的时间相同，服务端的时间戳对应本地发送请求之后 rtt/2 的时刻，因此偏差的误差不超过 rtt/2。
+// offset 为正数表示本地时钟落后于服务端。client 为 nil 时使用 http.DefaultClient。
+//
+// Example:
+//
+//	offset, rtt, err := otphttp.ClockOffset(ctx, nil, "https://example.com/otp/time")
+//	if err == nil && (offset > 15*time.Second || offset < -15*time.Second) {
+//		warn("设备时间与服务器相差 %s，请校准时间", offset.Round(time.Second))
+//	}
+func ClockOffset(ctx context.Context, client *http.Client, url string) (offset, rtt time.Duration, err error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("otphttp: time endpoint returned %s", resp.Status)
+	}
+	var body TimeResponse
+	if err := json.NewDecoder(http.MaxBytesReader(nil, resp.Body, 1<<12)).Decode(&body); err != nil {
+		return 0, 0, err
+	}
+	rtt = time.Since(start)
+	server := time.UnixMilli(body.UnixMilli)
+	return server.Sub(start.Add(rtt / 2)), rtt, nil
+}
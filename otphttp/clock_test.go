@@ -0,0 +1,51 @@
+package otphttp
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimeHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	TimeHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/time", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	var resp TimeResponse
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.InDelta(t, time.Now().UnixMilli(), resp.UnixMilli, 1000)
+
+	rec = httptest.NewRecorder()
+	TimeHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/time", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestClockOffset(t *testing.T) {
+	server := httptest.NewServer(TimeHandler())
+	defer server.Close()
+	offset, rtt, err := ClockOffset(context.Background(), server.Client(), server.URL)
+	assert.Nil(t, err)
+	assert.Greater(t, rtt, time.Duration(0))
+	assert.InDelta(t, 0, float64(offset), float64(time.Second))
+
+	// 服务端时钟快 30 秒，响应延迟 200 毫秒时偏差仍然接近 30 秒
+	ahead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		time.Sleep(200 * time.Millisecond)
+		writeJSON(w, http.StatusOK, TimeResponse{UnixMilli: now.Add(30 * time.Second).UnixMilli()})
+	}))
+	defer ahead.Close()
+	offset, rtt, err = ClockOffset(context.Background(), ahead.Client(), ahead.URL)
+	assert.Nil(t, err)
+	assert.GreaterOrEqual(t, rtt, 200*time.Millisecond)
+	assert.InDelta(t, float64(30*time.Second), float64(offset), float64(rtt/2+50*time.Millisecond))
+
+	failing := httptest.NewServer(http.NotFoundHandler())
+	defer failing.Close()
+	_, _, err = ClockOffset(context.Background(), failing.Client(), failing.URL)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,416 @@
+// Package otphttp 提供基于 net/http 的开通、校验处理器以及要求完成 OTP 校验的中间件。
+//
+// 账户的识别、秘钥的存储以及会话的管理都通过 Config 中的回调交给调用方实现，处理器只负责协议部分：
+//
+//	cfg := otphttp.Config{
+//		Issuer:      "Example",
+//		Account:     currentUser,
+//		Secret:      loadSecret,
+//		SaveSecret:  saveSecret,
+//		Pending:     loadPending,
+//		SavePending: savePending,
+//		Session:     session,
+//		Throttle:    otp.NewThrottle(5, 15*time.Minute),
+//		IPThrottle:  otp.NewThrottle(50, 15*time.Minute),
+//	}
+//	mux.Handle("/otp/enroll", otphttp.EnrollmentHandler(cfg))
+//	mux.Handle("/otp/enroll/confirm", otphttp.ConfirmEnrollmentHandler(cfg))
+//	mux.Handle("/otp/time", otphttp.TimeHandler())
+//	mux.Handle("/otp/verify", otphttp.VerifyHandler(cfg))
+//	mux.Handle("/account/", otphttp.RequireOTP(session)(accountHandler))
+package otphttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/huk10/go-otp"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Session 记录当前会话是否已经完成 OTP 校验，通常基于调用方已有的会话存储实现。
+type Session interface {
+	// Verified 返回当前请求所属的会话是否已经完成 OTP 校验。
+	Verified(r *http.Request) bool
+	// MarkVerified 在 VerifyHandler 校验通过后调用，标记当前会话已经完成 OTP 校验。
+	MarkVerified(w http.ResponseWriter, r *http.Request) error
+}
+
+// Config 处理器的配置。
+type Config struct {
+	// 发行商，EnrollmentHandler 必传。
+	Issuer string
+	// 创建 TOTP 时使用的 option，开通和校验时需要保持一致。
+	Options []otp.TOTPOption
+	// 返回当前请求的账户名称，同时用作 Throttle 的 key，必传。
+	Account func(r *http.Request) (string, error)
+	// 读取当前账户保存的 base32 秘钥，必传。账户还没有开通时返回空字符串或者 otp.ErrSecretNotFound。
+	Secret func(r *http.Request) (string, error)
+	// 保存确认之后的 base32 秘钥，ConfirmEnrollmentHandler 必传。
+	SaveSecret func(r *http.Request, secret string) error
+	// 读取当前账户待确认的开通信息，ConfirmEnrollmentHandler 必传。没有待确认的开通信息时返回 nil 或者 otp.ErrSecretNotFound。
+	// 只需要恢复 Secret、State 和 ExpiresAt，TOTP 使用 Options 创建。
+	Pending func(r *http.Request) (*otp.Enrollment, error)
+	// 保存待确认的开通信息，至少需要持久化 Secret、State 和 ExpiresAt，EnrollmentHandler 和 ConfirmEnrollmentHandler 必传。
+	// 确认之后以 nil 调用，表示删除待确认的开通信息。
+	SavePending func(r *http.Request, enrollment *otp.Enrollment) error
+	// 可选，待确认的开通信息的有效期，为 0 时使用 DefaultEnrollmentTTL。
+	EnrollmentTTL time.Duration
+	// 可选，校验通过后标记会话。已经开通的账户重新开通时要求会话已经完成 OTP 校验，没有配置时拒绝重新开通。
+	Session Session
+	// 可选，按账户限制校验的失败次数，防止从多个 IP 猜测同一个账户。
+	Throttle *otp.Throttle
+	// 可选，按客户端 IP 限制校验的请求次数（成功的请求同样计入），防止从同一个 IP 轮流猜测多个账户。见 otp.Throttle.Attempt。
+	IPThrottle *otp.Throttle
+	// 可选，返回客户端 IP，作为 IPThrottle 的 key，没有配置时使用 r.RemoteAddr 的主机部分。
+	// 部署在反向代理之后时需要从可信的响应头中读取，IPv6 地址可以返回所在的 /64 前缀。
+	ClientIP func(r *http.Request) string
+	// 可选，拒绝重复使用的 token，多实例部署时需要使用共享的实现，例如 otpredis.ReplayGuard。
+	// 没有配置时 VerifyHandler 使用一个进程内的 otp.MemoryReplayGuard。
+	ReplayGuard otp.ReplayGuard
+	// 可选，Account 以及读取、保存秘钥或者标记会话出错时调用，用于记录日志。响应中只会返回通用的错误信息。
+	OnError func(r *http.Request, err error)
+}
+
+// DefaultEnrollmentTTL 没有配置 EnrollmentTTL 时待确认的开通信息的有效期。
+const DefaultEnrollmentTTL = 10 * time.Minute
+
+// logError 调用 OnError。
+func (cfg Config) logError(r *http.Request, err error) {
+	if cfg.OnError != nil {
+		cfg.OnError(r, err)
+	}
+}
+
+// internalError 调用 OnError 并返回不包含错误细节的 500 响应，避免泄露存储等内部信息。
+func (cfg Config) internalError(w http.ResponseWriter, r *http.Request, err error) {
+	cfg.logError(r, err)
+	writeJSON(w, http.StatusInternalServerError, VerifyResponse{Error: "internal error"})
+}
+
+// existingSecret 返回账户是否已经保存了秘钥。
+func (cfg Config) existingSecret(r *http.Request) (bool, error) {
+	secret, err := cfg.Secret(r)
+	if errors.Is(err, otp.ErrSecretNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return secret != "", nil
+}
+
+// account 返回当前请求的账户名称，无法识别时调用 OnError 并返回不包含错误细节的 401 响应。
+func (cfg Config) account(w http.ResponseWriter, r *http.Request) (string, bool) {
+	account, err := cfg.Account(r)
+	if err != nil {
+		cfg.logError(r, err)
+		writeJSON(w, http.StatusUnauthorized, VerifyResponse{Error: "unauthorized"})
+		return "", false
+	}
+	return account, true
+}
+
+// pending 返回当前账户待确认的开通信息，没有时返回 nil。
+func (cfg Config) pending(r *http.Request) (*otp.Enrollment, error) {
+	enrollment, err := cfg.Pending(r)
+	if errors.Is(err, otp.ErrSecretNotFound) {
+		return nil, nil
+	}
+	return enrollment, err
+}
+
+// clientIP 返回 IPThrottle 使用的客户端 IP。
+func (cfg Config) clientIP(r *http.Request) string {
+	if cfg.ClientIP != nil {
+		return cfg.ClientIP(r)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// verify 校验 token，配置了 IPThrottle 时先以客户端 IP 限制请求次数，配置了 Throttle 时以账户名称作为 key 限制失败次数。
+// 校验通过时返回 true，否则写入 401、429 或 500 响应并返回 false。
+func (cfg Config) verify(w http.ResponseWriter, r *http.Request, account string, verify func(ctx context.Context) (bool, error)) bool {
+	if cfg.IPThrottle != nil {
+		limit := cfg.IPThrottle.Attempt("ip:" + cfg.clientIP(r))
+		if errors.Is(limit.Err, otp.ErrThrottled) {
+			retryAfter := int(math.Ceil(limit.RetryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			writeJSON(w, http.StatusTooManyRequests, VerifyResponse{Error: limit.Err.Error(), RetryAfter: retryAfter})
+			return false
+		}
+		if limit.Err != nil {
+			cfg.internalError(w, r, limit.Err)
+			return false
+		}
+	}
+	var result otp.VerifyResult
+	var remaining *int
+	if cfg.Throttle != nil {
+		result = cfg.Throttle.VerifyContextWithResult(r.Context(), account, verify)
+		remaining = &result.AttemptsRemaining
+	} else {
+		result.Ok, result.Err = verify(r.Context())
+	}
+	retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	}
+	switch {
+	case errors.Is(result.Err, otp.ErrThrottled):
+		writeJSON(w, http.StatusTooManyRequests, VerifyResponse{Error: result.Err.Error(), AttemptsRemaining: remaining, RetryAfter: retryAfter})
+		return false
+	case result.Err != nil:
+		cfg.internalError(w, r, result.Err)
+		return false
+	case !result.Ok:
+		writeJSON(w, http.StatusUnauthorized, VerifyResponse{Error: "invalid token", AttemptsRemaining: remaining, RetryAfter: retryAfter})
+		return false
+	}
+	return true
+}
+
+// EnrollmentResponse EnrollmentHandler 的响应。
+type EnrollmentResponse struct {
+	// 账户名称
+	Account string `json:"account"`
+	// base32 编码的秘钥，用于无法扫码时手动输入
+	Secret string `json:"secret"`
+	// otpauth URI
+	URI string `json:"uri"`
+	// PNG 格式的二维码，JSON 中为 base64 编码
+	QRCode []byte `json:"qr_code"`
+}
+
+// VerifyResponse VerifyHandler 的响应。
+type VerifyResponse struct {
+	Valid bool   `json:"valid"`
+	Error string `json:"error,omitempty"`
+	// 配置了 Throttle 时被锁定之前还可以失败的次数。
+	AttemptsRemaining *int `json:"attempts_remaining,omitempty"`
+	// 被锁定时距离可以再次尝试的秒数，同时设置 Retry-After 响应头。
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+// EnrollmentHandler 返回一个为当前账户生成新秘钥的处理器，只接受 POST 请求。
+//
+// 生成的秘钥作为待确认的开通信息通过 SavePending 保存，然后以 JSON 格式返回 EnrollmentResponse。
+// 此时秘钥还没有启用，已经开通的账户继续使用原来的秘钥，用户通过 ConfirmEnrollmentHandler 提交第一个有效的 token 之后才会替换，
+// 避免没有完成的重新开通把用户锁在账户之外。
+//
+// 账户已经保存了秘钥时，只有已经完成 OTP 校验的会话才能重新开通，否则返回 403，
+// 避免只通过密码登录的会话替换掉用户的第二个因素。
+func EnrollmentHandler(cfg Config) http.Handler {
+	ttl := cfg.EnrollmentTTL
+	if ttl == 0 {
+		ttl = DefaultEnrollmentTTL
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSON(w, http.StatusMethodNotAllowed, VerifyResponse{Error: "method not allowed"})
+			return
+		}
+		account, ok := cfg.account(w, r)
+		if !ok {
+			return
+		}
+		exists, err := cfg.existingSecret(r)
+		if err != nil {
+			cfg.internalError(w, r, err)
+			return
+		}
+		if exists && (cfg.Session == nil || !cfg.Session.Verified(r)) {
+			writeJSON(w, http.StatusForbidden, VerifyResponse{Error: "otp verification required"})
+			return
+		}
+		enrollment, err := otp.NewEnrollment(account, cfg.Issuer, ttl, cfg.Options...)
+		if err != nil {
+			cfg.internalError(w, r, err)
+			return
+		}
+		if err := cfg.SavePending(r, enrollment); err != nil {
+			cfg.internalError(w, r, err)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusOK, EnrollmentResponse{
+			Account: account,
+			Secret:  enrollment.Secret,
+			URI:     enrollment.URI,
+			QRCode:  enrollment.QRCode,
+		})
+	})
+}
+
+// ConfirmEnrollmentHandler 返回一个确认开通的处理器，只接受 POST 请求，token 的传递方式与 VerifyHandler 相同。
+//
+// token 与 Pending 返回的开通信息匹配时，通过 SaveSecret 启用新的秘钥并以 nil 调用 SavePending 删除待确认的开通信息。
+// 配置了 Throttle 时与 VerifyHandler 共用失败次数。响应为 VerifyResponse：
+//
+//	200: 确认成功，新的秘钥已经启用
+//	400: 缺少 token、没有待确认的开通信息或者已经过期
+//	401: 无法识别账户或 token 无效
+//	429: 与 VerifyHandler 相同
+//	500: 读取或保存开通信息出错，错误细节通过 OnError 回调获取
+func ConfirmEnrollmentHandler(cfg Config) http.Handler {
+	options := append([]otp.TOTPOption(nil), cfg.Options...)
+	if cfg.ReplayGuard != nil {
+		options = append(options, otp.WithReplayGuard(cfg.ReplayGuard))
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSON(w, http.StatusMethodNotAllowed, VerifyResponse{Error: "method not allowed"})
+			return
+		}
+		token, err := readToken(r)
+		if err != nil || token == "" {
+			writeJSON(w, http.StatusBadRequest, VerifyResponse{Error: "missing token"})
+			return
+		}
+		account, ok := cfg.account(w, r)
+		if !ok {
+			return
+		}
+		enrollment, err := cfg.pending(r)
+		if err != nil {
+			cfg.internalError(w, r, err)
+			return
+		}
+		now := time.Now()
+		if enrollment == nil || enrollment.State != otp.EnrollmentPending {
+			writeJSON(w, http.StatusBadRequest, VerifyResponse{Error: "no pending enrollment"})
+			return
+		}
+		if enrollment.Expired(now) {
+			writeJSON(w, http.StatusBadRequest, VerifyResponse{Error: "enrollment expired"})
+			return
+		}
+		confirm := func(context.Context) (bool, error) {
+			err := enrollment.Confirm(token, now, options...)
+			if errors.Is(err, otp.ErrTokenInvalid) {
+				return false, nil
+			}
+			return err == nil, err
+		}
+		if !cfg.verify(w, r, account, confirm) {
+			return
+		}
+		if err := cfg.SaveSecret(r, enrollment.Secret); err != nil {
+			cfg.internalError(w, r, err)
+			return
+		}
+		if err := cfg.SavePending(r, nil); err != nil {
+			cfg.internalError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, VerifyResponse{Valid: true})
+	})
+}
+
+// VerifyHandler 返回一个校验当前账户 token 的处理器，只接受 POST 请求。
+//
+// token 可以通过表单字段 token 或者 JSON 请求体 {"token": "..."} 传递，已经使用过的 token 会被拒绝。响应为 VerifyResponse：
+//
+//	200: 校验通过，配置了 Session 时会调用 MarkVerified
+//	400: 缺少 token
+//	401: 无法识别账户、账户没有开通或 token 无效
+//	429: 账户失败次数过多（配置了 Throttle）或者 IP 请求次数过多（配置了 IPThrottle），响应包含 Retry-After 响应头
+//	500: 读取秘钥或会话出错，错误细节通过 OnError 回调获取
+func VerifyHandler(cfg Config) http.Handler {
+	guard := cfg.ReplayGuard
+	if guard == nil {
+		guard = otp.NewMemoryReplayGuard()
+	}
+	options := append(append([]otp.TOTPOption(nil), cfg.Options...), otp.WithReplayGuard(guard))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSON(w, http.StatusMethodNotAllowed, VerifyResponse{Error: "method not allowed"})
+			return
+		}
+		token, err := readToken(r)
+		if err != nil || token == "" {
+			writeJSON(w, http.StatusBadRequest, VerifyResponse{Error: "missing token"})
+			return
+		}
+		account, ok := cfg.account(w, r)
+		if !ok {
+			return
+		}
+		secret, err := cfg.Secret(r)
+		if errors.Is(err, otp.ErrSecretNotFound) || (err == nil && secret == "") {
+			writeJSON(w, http.StatusUnauthorized, VerifyResponse{Error: "otp not enrolled"})
+			return
+		}
+		if err != nil {
+			cfg.internalError(w, r, err)
+			return
+		}
+		totp, err := otp.NewTOTPWithError(secret, options...)
+		if err != nil {
+			cfg.internalError(w, r, err)
+			return
+		}
+		verify := func(context.Context) (bool, error) { return totp.Verify(token, time.Now()), nil }
+		if !cfg.verify(w, r, account, verify) {
+			return
+		}
+		if cfg.Session != nil {
+			if err := cfg.Session.MarkVerified(w, r); err != nil {
+				cfg.internalError(w, r, err)
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, VerifyResponse{Valid: true})
+	})
+}
+
+// RequireOTP 返回一个中间件，会话没有完成 OTP 校验时返回 401，否则交给 next 处理。
+//
+// Example:
+//
+//	mux.Handle("/account/", otphttp.RequireOTP(session)(accountHandler))
+func RequireOTP(session Session) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !session.Verified(r) {
+				writeJSON(w, http.StatusUnauthorized, VerifyResponse{Error: "otp verification required"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// readToken 从表单或 JSON 请求体中读取 token。
+func readToken(r *http.Request) (string, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		var body struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<12)).Decode(&body); err != nil {
+			return "", err
+		}
+		return body.Token, nil
+	}
+	return r.PostFormValue("token"), nil
+}
+
+// writeJSON 以 JSON 格式写入响应。
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,25 @@
+//go:build !noqrcode
+
+package otphttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEnrollmentHandler_QRCode(t *testing.T) {
+	var secret string
+	rec := httptest.NewRecorder()
+	EnrollmentHandler(testConfig(&secret, nil)).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/enroll", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp EnrollmentResponse
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	_, err := png.Decode(bytes.NewReader(resp.QRCode))
+	assert.Nil(t, err)
+}
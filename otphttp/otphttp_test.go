@@ -0,0 +1,353 @@
+package otphttp
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/huk10/go-otp"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testSession struct {
+	verified bool
+}
+
+func (s *testSession) Verified(*http.Request) bool { return s.verified }
+
+func (s *testSession) MarkVerified(http.ResponseWriter, *http.Request) error {
+	s.verified = true
+	return nil
+}
+
+func testConfig(secret *string, session Session) Config {
+	// 只保存需要持久化的字段，模拟从存储中恢复
+	var pending *otp.Enrollment
+	return Config{
+		Issuer:  "Example",
+		Account: func(*http.Request) (string, error) { return "alice@google.com", nil },
+		Secret:  func(*http.Request) (string, error) { return *secret, nil },
+		SaveSecret: func(_ *http.Request, s string) error {
+			*secret = s
+			return nil
+		},
+		Pending: func(*http.Request) (*otp.Enrollment, error) {
+			if pending == nil {
+				return nil, otp.ErrSecretNotFound
+			}
+			restored := *pending
+			return &restored, nil
+		},
+		SavePending: func(_ *http.Request, e *otp.Enrollment) error {
+			pending = nil
+			if e != nil {
+				pending = &otp.Enrollment{Secret: e.Secret, State: e.State, ExpiresAt: e.ExpiresAt}
+			}
+			return nil
+		},
+		Session: session,
+	}
+}
+
+// postToken 以表单的形式提交 token。
+func postToken(handler http.Handler, token string) (*httptest.ResponseRecorder, VerifyResponse) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(url.Values{"token": {token}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	var resp VerifyResponse
+	_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+	return rec, resp
+}
+
+func TestEnrollmentHandler(t *testing.T) {
+	var secret string
+	handler := EnrollmentHandler(testConfig(&secret, nil))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/enroll", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/enroll", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+
+	var resp EnrollmentResponse
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "alice@google.com", resp.Account)
+	key, err := otp.FromURI(resp.URI)
+	assert.Nil(t, err)
+	assert.Equal(t, resp.Secret, key.Secret)
+	assert.Equal(t, "Example", key.Issuer)
+	// 确认之前不会启用
+	assert.Equal(t, "", secret)
+}
+
+func TestConfirmEnrollmentHandler(t *testing.T) {
+	var secret string
+	cfg := testConfig(&secret, nil)
+	cfg.Throttle = otp.NewThrottle(3, time.Minute)
+	confirm := ConfirmEnrollmentHandler(cfg)
+
+	rec, resp := postToken(confirm, "123456")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "no pending enrollment", resp.Error)
+
+	rec = httptest.NewRecorder()
+	EnrollmentHandler(cfg).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/enroll", nil))
+	var enrollment EnrollmentResponse
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &enrollment))
+	token := otp.NewTOTP(enrollment.Secret).Now()
+
+	rec, _ = postToken(confirm, "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec, resp = postToken(confirm, invalidToken(token))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, 2, *resp.AttemptsRemaining)
+	assert.Equal(t, "", secret)
+
+	rec, resp = postToken(confirm, token)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, resp.Valid)
+	assert.Equal(t, enrollment.Secret, secret)
+
+	// 待确认的开通信息已经删除
+	rec, _ = postToken(confirm, token)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestConfirmEnrollmentHandler_Expired(t *testing.T) {
+	var secret string
+	cfg := testConfig(&secret, nil)
+	pending, err := otp.NewEnrollment("alice@google.com", "Example", time.Minute)
+	assert.Nil(t, err)
+	pending.ExpiresAt = time.Now().Add(-time.Second)
+	cfg.Pending = func(*http.Request) (*otp.Enrollment, error) { return pending, nil }
+
+	rec, resp := postToken(ConfirmEnrollmentHandler(cfg), pending.TOTP.Now())
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "enrollment expired", resp.Error)
+	assert.Equal(t, "", secret)
+}
+
+func TestEnrollmentHandler_Existing(t *testing.T) {
+	secret := otp.Base32Encode(otp.RandomSecret(20))
+	original := secret
+	session := &testSession{}
+	handler := EnrollmentHandler(testConfig(&secret, session))
+
+	// 只通过密码登录的会话不能替换已经开通的秘钥
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/enroll", nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, original, secret)
+
+	session.verified = true
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/enroll", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp EnrollmentResponse
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.NotEqual(t, original, resp.Secret)
+	// 没有确认的重新开通不会替换原来的秘钥
+	assert.Equal(t, original, secret)
+
+	// 没有配置 Session 时拒绝重新开通
+	rec = httptest.NewRecorder()
+	EnrollmentHandler(testConfig(&secret, nil)).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/enroll", nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// Secret 返回 ErrSecretNotFound 时视为没有开通
+	cfg := testConfig(&secret, nil)
+	cfg.Secret = func(*http.Request) (string, error) { return "", otp.ErrSecretNotFound }
+	rec = httptest.NewRecorder()
+	EnrollmentHandler(cfg).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/enroll", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestVerifyHandler(t *testing.T) {
+	secret := otp.Base32Encode(otp.RandomSecret(20))
+	session := &testSession{}
+	cfg := testConfig(&secret, session)
+	cfg.Throttle = otp.NewThrottle(2, time.Minute)
+	handler := VerifyHandler(cfg)
+	token := otp.NewTOTP(secret).Now()
+
+	post := func(contentType, body string) (*httptest.ResponseRecorder, VerifyResponse) {
+		req := httptest.NewRequest(http.MethodPost, "/verify", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		var resp VerifyResponse
+		assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return rec, resp
+	}
+
+	rec, _ := post("application/x-www-form-urlencoded", "")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec, resp := post("application/json", `{"token":"`+invalidToken(token)+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.False(t, resp.Valid)
+	assert.Equal(t, 1, *resp.AttemptsRemaining)
+	assert.False(t, session.verified)
+
+	rec, resp = post("application/x-www-form-urlencoded", url.Values{"token": {token}}.Encode())
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, resp.Valid)
+	assert.True(t, session.verified)
+
+	post("application/json", `{"token":"`+invalidToken(token)+`"}`)
+	rec, resp = post("application/json", `{"token":"`+invalidToken(token)+`"}`)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, 0, *resp.AttemptsRemaining)
+	assert.Equal(t, 60, resp.RetryAfter)
+	rec, resp = post("application/json", `{"token":"`+token+`"}`)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+	assert.LessOrEqual(t, resp.RetryAfter, 60)
+	assert.Greater(t, resp.RetryAfter, 0)
+}
+
+func TestVerifyHandler_IPThrottle(t *testing.T) {
+	secret := otp.Base32Encode(otp.RandomSecret(20))
+	cfg := testConfig(&secret, nil)
+	cfg.Throttle = otp.NewThrottle(5, time.Minute)
+	cfg.IPThrottle = otp.NewThrottle(2, time.Minute)
+	// 每个请求尝试不同的账户，按账户计算的失败次数不会超出限制
+	cfg.Account = func(r *http.Request) (string, error) { return r.Header.Get("X-Account"), nil }
+	handler := VerifyHandler(cfg)
+	post := func(account, remoteAddr, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/verify", strings.NewReader("token="+token))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Account", account)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	token := otp.NewTOTP(secret).Now()
+
+	assert.Equal(t, http.StatusUnauthorized, post("alice", "192.0.2.1:1234", invalidToken(token)).Code)
+	assert.Equal(t, http.StatusUnauthorized, post("bob", "192.0.2.1:5678", invalidToken(token)).Code)
+	// 正确的 token 同样受 IP 限制
+	rec := post("carol", "192.0.2.1:1234", token)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "60", rec.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, post("carol", "192.0.2.2:1234", token).Code)
+
+	// 使用 ClientIP 返回的地址，RemoteAddr 192.0.2.1 已经被限制
+	cfg.ClientIP = func(r *http.Request) string { return r.Header.Get("X-Forwarded-For") }
+	handler = VerifyHandler(cfg)
+	req := httptest.NewRequest(http.MethodPost, "/verify", strings.NewReader("token="+invalidToken(token)))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Account", "dave")
+	req.Header.Set("X-Forwarded-For", "198.51.100.7")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestVerifyHandler_Replay(t *testing.T) {
+	secret := otp.Base32Encode(otp.RandomSecret(20))
+	for _, guard := range []otp.ReplayGuard{nil, otp.NewMemoryReplayGuard()} {
+		cfg := testConfig(&secret, nil)
+		cfg.ReplayGuard = guard
+		handler := VerifyHandler(cfg)
+		token := otp.NewTOTP(secret).Now()
+		codes := make([]int, 2)
+		for i := range codes {
+			req := httptest.NewRequest(http.MethodPost, "/verify", strings.NewReader("token="+token))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			codes[i] = rec.Code
+		}
+		assert.Equal(t, []int{http.StatusOK, http.StatusUnauthorized}, codes)
+	}
+}
+
+func TestVerifyHandler_Errors(t *testing.T) {
+	secret := "!!!"
+	cfg := testConfig(&secret, nil)
+	var logged []error
+	cfg.OnError = func(_ *http.Request, err error) { logged = append(logged, err) }
+	req := httptest.NewRequest(http.MethodPost, "/verify", strings.NewReader("token=123456"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	VerifyHandler(cfg).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.JSONEq(t, `{"valid":false,"error":"internal error"}`, rec.Body.String())
+	assert.Len(t, logged, 1)
+
+	// 存储的错误不会出现在响应中
+	storeErr := errors.New("dial tcp 10.0.0.5:5432: connection refused")
+	cfg.Secret = func(*http.Request) (string, error) { return "", storeErr }
+	req = httptest.NewRequest(http.MethodPost, "/verify", strings.NewReader("token=123456"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	VerifyHandler(cfg).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "10.0.0.5")
+	assert.Equal(t, storeErr, logged[1])
+
+	rec = httptest.NewRecorder()
+	EnrollmentHandler(cfg).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/enroll", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "10.0.0.5")
+
+	// 没有开通的账户
+	cfg.Secret = func(*http.Request) (string, error) { return "", nil }
+	req = httptest.NewRequest(http.MethodPost, "/verify", strings.NewReader("token=123456"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	VerifyHandler(cfg).ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Account 的错误只通过 OnError 返回
+	cfg.Account = func(*http.Request) (string, error) { return "", errors.New("session db 10.0.0.6 unavailable") }
+	for _, handler := range []http.Handler{VerifyHandler(cfg), EnrollmentHandler(cfg), ConfirmEnrollmentHandler(cfg)} {
+		rec, resp := postToken(handler, "123456")
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "unauthorized", resp.Error)
+	}
+	assert.Len(t, logged, 6)
+
+	// 保存待确认的开通信息出错
+	cfg = testConfig(&secret, nil)
+	cfg.Secret = func(*http.Request) (string, error) { return "", nil }
+	cfg.SavePending = func(*http.Request, *otp.Enrollment) error { return storeErr }
+	rec = httptest.NewRecorder()
+	EnrollmentHandler(cfg).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/enroll", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.JSONEq(t, `{"valid":false,"error":"internal error"}`, rec.Body.String())
+}
+
+func TestRequireOTP(t *testing.T) {
+	session := &testSession{}
+	handler := RequireOTP(session)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/account", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	session.verified = true
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/account", nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+// invalidToken 返回一个与 token 不同的 token。
+func invalidToken(token string) string {
+	if token == "000000" {
+		return "111111"
+	}
+	return "000000"
+}
@@ -0,0 +1,23 @@
+module github.com/huk10/go-otp/otppgp
+
+go 1.18
+
+require (
+	github.com/ProtonMail/go-crypto v1.0.0
+	github.com/huk10/go-otp v0.0.0
+	github.com/stretchr/testify v1.8.4
+)
+
+require (
+	github.com/cloudflare/circl v1.3.3 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/rogpeppe/go-internal v1.9.0 // indirect
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e // indirect
+	golang.org/x/crypto v0.17.0 // indirect
+	golang.org/x/sys v0.15.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/huk10/go-otp => ../
@@ -0,0 +1,130 @@
+// Package otppgp 使用 OpenPGP 加密并签名 otpbackup 导出的备份文件，用于通过邮件或者共享存储迁移账户。
+//
+// 备份可以加密给一个或多个收件人（例如运维人员和离线保存的托管秘钥），导入时要求消息已加密，
+// 并且带有 keyring 中的秘钥的有效签名，防止导入被替换或篡改的种子。
+// 生成的消息是标准的 ASCII armor 格式，也可以使用 gpg --decrypt 解密。
+//
+// 使用 github.com/ProtonMail/go-crypto/openpgp 实现，golang.org/x/crypto/openpgp 已经废弃。
+//
+// Example:
+//
+//	data, err := otppgp.Export(keys, otpbackup.MarshalFreeOTP, recipients, signer)
+//	keys, err := otppgp.Import(data, otpbackup.ParseFreeOTP, keyring)
+package otppgp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/huk10/go-otp"
+	"io"
+)
+
+// messageType ASCII armor 的块类型。
+const messageType = "PGP MESSAGE"
+
+// maxMessageSize Import 解密之后允许的最大字节数，避免压缩炸弹耗尽内存。
+const maxMessageSize = 16 << 20
+
+var (
+	// ErrNotEncrypted 导入的消息没有加密。
+	ErrNotEncrypted = errors.New("otppgp: message is not encrypted")
+	// ErrUnsigned 导入的消息没有签名，或者签名的秘钥不在 keyring 中。
+	ErrUnsigned = errors.New("otppgp: message is not signed by a trusted key")
+)
+
+// Export 使用 marshal 导出 keys，然后加密给 recipients 并使用 signer 签名，返回 ASCII armor 格式的消息。
+//
+// Params:
+//
+//	keys      : 需要导出的账户。
+//	marshal   : 备份格式，例如 otpbackup.MarshalAegis、otpbackup.MarshalFreeOTP。
+//	recipients: 收件人的公钥，至少需要一个。
+//	signer    : 签名使用的私钥，需要已经解密，为 nil 时不签名，Import 会拒绝这样的消息。
+//
+// Example:
+//
+//	recipients, err := openpgp.ReadArmoredKeyRing(publicKeys)
+//	data, err := otppgp.Export(keys, otpbackup.MarshalAegis, recipients, signer)
+func Export(keys []*otp.KeyURI, marshal func([]*otp.KeyURI) ([]byte, error), recipients openpgp.EntityList, signer *openpgp.Entity) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("%w: at least one recipient is required", otp.ErrInvalidOption)
+	}
+	plaintext, err := marshal(keys)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(plaintext)
+	var buf bytes.Buffer
+	armored, err := armor.Encode(&buf, messageType, nil)
+	if err != nil {
+		return nil, err
+	}
+	w, err := openpgp.Encrypt(armored, recipients, signer, &openpgp.FileHints{IsBinary: true}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("otppgp: encrypt: %w", err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	if err := armored.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Import 使用 keyring 解密 Export 生成的消息并校验签名，然后使用 parse 解析。
+//
+// keyring 需要包含解密使用的私钥（需要已经解密）以及签名者的公钥，可以使用 append 合并多个 EntityList。
+// 消息没有加密时返回 ErrNotEncrypted，没有签名或者签名者不在 keyring 中时返回 ErrUnsigned，
+// 签名无效时返回对应的错误，这些情况下都不会调用 parse。
+//
+// Example:
+//
+//	keys, err := otppgp.Import(data, func(data []byte) ([]*otp.KeyURI, error) {
+//		return otpbackup.ParseAegis(data, nil)
+//	}, append(privateKeys, signerPublicKeys...))
+func Import(data []byte, parse func([]byte) ([]*otp.KeyURI, error), keyring openpgp.KeyRing) ([]*otp.KeyURI, error) {
+	block, err := armor.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("otppgp: decode armor: %w", err)
+	}
+	if block.Type != messageType {
+		return nil, fmt.Errorf("otppgp: unexpected armor type %q", block.Type)
+	}
+	md, err := openpgp.ReadMessage(block.Body, keyring, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("otppgp: decrypt: %w", err)
+	}
+	if !md.IsEncrypted {
+		return nil, ErrNotEncrypted
+	}
+	// 签名和完整性校验只有在读取到 EOF 之后才会完成
+	plaintext, err := io.ReadAll(io.LimitReader(md.UnverifiedBody, maxMessageSize+1))
+	defer zero(plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("otppgp: decrypt: %w", err)
+	}
+	if len(plaintext) > maxMessageSize {
+		return nil, fmt.Errorf("otppgp: message exceeds %d bytes", maxMessageSize)
+	}
+	if !md.IsSigned || md.SignedBy == nil {
+		return nil, ErrUnsigned
+	}
+	if md.SignatureError != nil {
+		return nil, fmt.Errorf("otppgp: verify signature: %w", md.SignatureError)
+	}
+	return parse(plaintext)
+}
+
+// zero 将 b 的内容全部覆盖为 0。
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,100 @@
+package otppgp
+
+import (
+	"bytes"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/huk10/go-otp"
+	"github.com/huk10/go-otp/otpbackup"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newEntity(t *testing.T, name string) *openpgp.Entity {
+	entity, err := openpgp.NewEntity(name, "", name+"@example.com", &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA})
+	if err != nil {
+		t.Fatalf("new entity: %v", err)
+	}
+	return entity
+}
+
+func TestExportImport(t *testing.T) {
+	alice, bob, escrow, mallory := newEntity(t, "alice"), newEntity(t, "bob"), newEntity(t, "escrow"), newEntity(t, "mallory")
+	key, err := otp.FromURI("otpauth://totp/Example:alice@example.com?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&issuer=Example")
+	assert.Nil(t, err)
+	keys := []*otp.KeyURI{key}
+
+	data, err := Export(keys, otpbackup.MarshalFreeOTP, openpgp.EntityList{bob, escrow}, alice)
+	assert.Nil(t, err)
+	assert.True(t, bytes.HasPrefix(data, []byte("-----BEGIN PGP MESSAGE-----")))
+	assert.NotContains(t, string(data), "GEZDGNBVGY3TQOJQ")
+
+	// 每个收件人都可以解密
+	for _, recipient := range []*openpgp.Entity{bob, escrow} {
+		got, err := Import(data, otpbackup.ParseFreeOTP, openpgp.EntityList{recipient, alice})
+		assert.Nil(t, err)
+		assert.Len(t, got, 1)
+		assert.Equal(t, key.Secret, got[0].Secret)
+		assert.Equal(t, key.AccountName, got[0].AccountName)
+	}
+
+	// 不是收件人
+	_, err = Import(data, otpbackup.ParseFreeOTP, openpgp.EntityList{mallory, alice})
+	assert.NotNil(t, err)
+	// 签名者不受信任
+	_, err = Import(data, otpbackup.ParseFreeOTP, openpgp.EntityList{bob})
+	assert.Equal(t, ErrUnsigned, err)
+	// 没有签名
+	unsigned, err := Export(keys, otpbackup.MarshalFreeOTP, openpgp.EntityList{bob}, nil)
+	assert.Nil(t, err)
+	_, err = Import(unsigned, otpbackup.ParseFreeOTP, openpgp.EntityList{bob, alice})
+	assert.Equal(t, ErrUnsigned, err)
+	// 其他人冒充 alice 签名
+	forged, err := Export(keys, otpbackup.MarshalFreeOTP, openpgp.EntityList{bob}, mallory)
+	assert.Nil(t, err)
+	_, err = Import(forged, otpbackup.ParseFreeOTP, openpgp.EntityList{bob, alice})
+	assert.Equal(t, ErrUnsigned, err)
+
+	_, err = Export(keys, otpbackup.MarshalFreeOTP, nil, alice)
+	assert.ErrorIs(t, err, otp.ErrInvalidOption)
+	_, err = Import([]byte("not armored"), otpbackup.ParseFreeOTP, openpgp.EntityList{bob, alice})
+	assert.NotNil(t, err)
+}
+
+func TestImport_Tampered(t *testing.T) {
+	alice, bob := newEntity(t, "alice"), newEntity(t, "bob")
+	key, _ := otp.FromURI("otpauth://totp/alice?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+	data, err := Export([]*otp.KeyURI{key}, otpbackup.MarshalFreeOTP, openpgp.EntityList{bob}, alice)
+	assert.Nil(t, err)
+
+	block, err := armor.Decode(bytes.NewReader(data))
+	assert.Nil(t, err)
+	var raw bytes.Buffer
+	_, err = raw.ReadFrom(block.Body)
+	assert.Nil(t, err)
+	tampered := raw.Bytes()
+	tampered[len(tampered)-10] ^= 1
+	var buf bytes.Buffer
+	w, _ := armor.Encode(&buf, messageType, nil)
+	w.Write(tampered)
+	w.Close()
+	parsed := false
+	_, err = Import(buf.Bytes(), func(data []byte) ([]*otp.KeyURI, error) {
+		parsed = true
+		return otpbackup.ParseFreeOTP(data)
+	}, openpgp.EntityList{bob, alice})
+	assert.NotNil(t, err)
+	assert.False(t, parsed)
+
+	// 只签名没有加密的消息
+	buf.Reset()
+	w, _ = armor.Encode(&buf, messageType, nil)
+	signed, err := openpgp.Sign(w, alice, nil, nil)
+	assert.Nil(t, err)
+	signed.Write([]byte(`{"tokens":[]}`))
+	signed.Close()
+	w.Close()
+	_, err = Import(buf.Bytes(), otpbackup.ParseFreeOTP, openpgp.EntityList{bob, alice})
+	assert.Equal(t, ErrNotEncrypted, err)
+}
@@ -0,0 +1,123 @@
+// Package otpprom 将 otp.Hooks 对接到 Prometheus，导出校验次数、skew 分布以及二维码生成耗时等指标。
+//
+// 导出的指标（默认前缀为 otp）：
+//   - otp_verify_total：校验次数，标签为 type（totp、hotp）、issuer、result（见 otp.VerifyOutcome.String）。
+//   - otp_skew_offset：校验通过时使用的 skew，标签为 type、issuer，负数表示客户端时钟或计数器落后。
+//   - otp_qrcode_generation_seconds：使用 Metrics.QRCode 生成二维码的耗时。
+//
+// 账户名称的基数不可控，不会作为标签导出。
+//
+// otpprom 是一个单独的模块，Prometheus 依赖不会引入到主模块中。
+//
+// Example:
+//
+//	metrics, err := otpprom.New(prometheus.DefaultRegisterer, otpprom.Options{})
+//	totp := otp.NewTOTP(secret, otp.WithIssuer("Example"), metrics.Option())
+//	png, err := metrics.QRCode(totp.KeyURI("alice@google.com"))
+package otpprom
+
+import (
+	"github.com/huk10/go-otp"
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+// Options 创建 Metrics 的参数，零值可以直接使用。
+type Options struct {
+	// 指标名称的前缀，默认为 otp。
+	Namespace string
+	// 指标名称的第二段前缀，默认为空。
+	Subsystem string
+	// 所有指标共用的固定标签，例如服务名称。
+	ConstLabels prometheus.Labels
+	// otp_skew_offset 的桶，默认为 -3 到 3 的整数。
+	SkewBuckets []float64
+	// otp_qrcode_generation_seconds 的桶，默认为 prometheus.DefBuckets。
+	QRCodeBuckets []float64
+}
+
+// Metrics 保存已经注册的指标，可以在多个 TOTP、HOTP 之间共用。
+type Metrics struct {
+	verify *prometheus.CounterVec
+	skew   *prometheus.HistogramVec
+	qrcode prometheus.Histogram
+}
+
+// New 创建 Metrics 并注册到 reg，注册失败（例如重复注册）时返回 reg.Register 的错误。
+//
+// Params:
+//
+//	reg : 指标注册的位置，通常为 prometheus.DefaultRegisterer，测试时可以使用 prometheus.NewRegistry()。
+//	opts: 见 Options。
+func New(reg prometheus.Registerer, opts Options) (*Metrics, error) {
+	if opts.Namespace == "" {
+		opts.Namespace = "otp"
+	}
+	if opts.SkewBuckets == nil {
+		opts.SkewBuckets = prometheus.LinearBuckets(-3, 1, 7)
+	}
+	if opts.QRCodeBuckets == nil {
+		opts.QRCodeBuckets = prometheus.DefBuckets
+	}
+	m := &Metrics{
+		verify: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   opts.Namespace,
+			Subsystem:   opts.Subsystem,
+			Name:        "verify_total",
+			Help:        "Total number of OTP verifications by result.",
+			ConstLabels: opts.ConstLabels,
+		}, []string{"type", "issuer", "result"}),
+		skew: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   opts.Namespace,
+			Subsystem:   opts.Subsystem,
+			Name:        "skew_offset",
+			Help:        "Offset between the matched and the expected time step or counter of successful verifications.",
+			ConstLabels: opts.ConstLabels,
+			Buckets:     opts.SkewBuckets,
+		}, []string{"type", "issuer"}),
+		qrcode: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace:   opts.Namespace,
+			Subsystem:   opts.Subsystem,
+			Name:        "qrcode_generation_seconds",
+			Help:        "Time spent generating provisioning QR codes.",
+			ConstLabels: opts.ConstLabels,
+			Buckets:     opts.QRCodeBuckets,
+		}),
+	}
+	for _, c := range []prometheus.Collector{m.verify, m.skew, m.qrcode} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Hooks 返回记录校验指标的 otp.Hooks。
+func (m *Metrics) Hooks() otp.Hooks {
+	return otp.Hooks{
+		OnSuccess: func(e otp.VerifyEvent) {
+			m.observe(e)
+			m.skew.WithLabelValues(e.Type, e.Issuer).Observe(float64(e.Skew))
+		},
+		OnFailure: m.observe,
+	}
+}
+
+// Option 返回 otp.WithHooks(m.Hooks())，可以直接传给 otp.NewTOTP、otp.NewHOTP。
+func (m *Metrics) Option() otp.Option {
+	return otp.WithHooks(m.Hooks())
+}
+
+// QRCode 调用 key.QRCode 生成二维码并记录耗时，生成失败时同样会记录。
+func (m *Metrics) QRCode(key *otp.KeyURI, options ...otp.QRCodeOption) ([]byte, error) {
+	start := time.Now()
+	defer func() {
+		m.qrcode.Observe(time.Since(start).Seconds())
+	}()
+	return key.QRCode(options...)
+}
+
+// observe 记录一次校验的结果。
+func (m *Metrics) observe(e otp.VerifyEvent) {
+	m.verify.WithLabelValues(e.Type, e.Issuer, e.Outcome.String()).Inc()
+}
@@ -0,0 +1,23 @@
+//go:build !noqrcode
+
+package otpprom
+
+import (
+	"github.com/huk10/go-otp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMetrics_QRCode(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	metrics, err := New(reg, Options{})
+	assert.Nil(t, err)
+
+	key := otp.NewTOTP(secret).KeyURI("alice@google.com", "Example")
+	png, err := metrics.QRCode(key, otp.WithDeterministic())
+	assert.Nil(t, err)
+	expected, _ := key.QRCode(otp.WithDeterministic())
+	assert.Equal(t, expected, png)
+	assert.Equal(t, uint64(1), histograms(t, reg, "otp_qrcode_generation_seconds")[0].GetSampleCount())
+}
@@ -0,0 +1,74 @@
+package otpprom
+
+import (
+	"github.com/huk10/go-otp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+const secret = "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6"
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	metrics, err := New(reg, Options{ConstLabels: prometheus.Labels{"service": "test"}})
+	assert.Nil(t, err)
+
+	now := time.Unix(1704075000, 0)
+	totp := otp.NewTOTP(secret, otp.WithSkew(1), otp.WithIssuer("Example"), metrics.Option(),
+		otp.WithReplayGuard(otp.NewMemoryReplayGuard()))
+	assert.True(t, totp.Verify(totp.At(now.Add(-30*time.Second)), now))
+	assert.False(t, totp.Verify(totp.At(now.Add(-30*time.Second)), now))
+	assert.False(t, totp.Verify("000000", now))
+
+	hotp := otp.NewHOTP(secret, metrics.Option())
+	assert.True(t, hotp.Verify(hotp.At(1), 1))
+
+	expected := `
+# HELP otp_verify_total Total number of OTP verifications by result.
+# TYPE otp_verify_total counter
+otp_verify_total{issuer="",result="success",service="test",type="hotp"} 1
+otp_verify_total{issuer="Example",result="mismatch",service="test",type="totp"} 1
+otp_verify_total{issuer="Example",result="replay",service="test",type="totp"} 1
+otp_verify_total{issuer="Example",result="success",service="test",type="totp"} 1
+`
+	assert.Nil(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "otp_verify_total"))
+
+	// hotp 的一次 skew 为 0 的校验和 totp 的一次 skew 为 -1 的校验
+	skew := histograms(t, reg, "otp_skew_offset")
+	assert.Len(t, skew, 2)
+	assert.Equal(t, uint64(1), skew[0].GetSampleCount())
+	assert.Equal(t, 0.0, skew[0].GetSampleSum())
+	assert.Equal(t, uint64(1), skew[1].GetSampleCount())
+	assert.Equal(t, -1.0, skew[1].GetSampleSum())
+
+	// 使用 noqrcode 构建标签时生成失败，同样记录耗时
+	_, _ = metrics.QRCode(totp.KeyURI("alice@google.com"))
+	assert.Equal(t, uint64(1), histograms(t, reg, "otp_qrcode_generation_seconds")[0].GetSampleCount())
+
+	// 重复注册
+	_, err = New(reg, Options{ConstLabels: prometheus.Labels{"service": "test"}})
+	assert.NotNil(t, err)
+
+	_, err = New(prometheus.NewRegistry(), Options{Namespace: "auth", Subsystem: "mfa"})
+	assert.Nil(t, err)
+}
+
+// histograms 返回 reg 中名称为 name 的直方图，按照标签排序。
+func histograms(t *testing.T, reg *prometheus.Registry, name string) []*dto.Histogram {
+	families, err := reg.Gather()
+	assert.Nil(t, err)
+	var ret []*dto.Histogram
+	for _, family := range families {
+		if family.GetName() == name {
+			for _, metric := range family.GetMetric() {
+				ret = append(ret, metric.GetHistogram())
+			}
+		}
+	}
+	return ret
+}
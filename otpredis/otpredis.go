@@ -0,0 +1,156 @@
+// Package otpredis 基于 Redis 实现 otp.ReplayGuard 和 otp.ThrottleStore，多实例部署时共享防重放和限流状态。
+//
+// 为了不引入具体的 Redis 客户端依赖，这里通过 Doer 接口发送命令，常见客户端只需要一行适配：
+//
+//	// github.com/redis/go-redis/v9
+//	doer := otpredis.DoerFunc(func(ctx context.Context, args ...interface{}) (interface{}, error) {
+//		return rdb.Do(ctx, args...).Result()
+//	})
+//	guard := otpredis.NewReplayGuard(doer, "otp:replay:", 5*time.Minute)
+//	totp  := otp.NewTOTP(secret, otp.WithReplayGuard(guard))
+//	throttle := &otp.Throttle{Store: otpredis.NewThrottleStore(doer, "otp:throttle:"), MaxFailures: 5, Window: 15 * time.Minute}
+package otpredis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Doer 发送一条 Redis 命令并返回结果，结果的类型与 RESP 协议对应（string、int64、nil 等）。
+//
+// 结果为 nil 时可以返回 (nil, nil)，go-redis 返回的 redis.Nil 错误也会按照 nil 处理。
+type Doer interface {
+	Do(ctx context.Context, args ...interface{}) (interface{}, error)
+}
+
+// DoerFunc 将函数适配为 Doer。
+type DoerFunc func(ctx context.Context, args ...interface{}) (interface{}, error)
+
+// Do 实现 Doer 接口。
+func (f DoerFunc) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	return f(ctx, args...)
+}
+
+// isNil 判断错误是否表示 Redis 返回了 nil，兼容 go-redis 的 redis.Nil（错误信息为 "redis: nil"）。
+func isNil(err error) bool {
+	return err != nil && err.Error() == "redis: nil"
+}
+
+// ReplayGuard 基于 Redis 的 otp.ReplayGuard 实现，为每个 (账户, step) 使用 SET NX 写入一个带 TTL 的键。
+type ReplayGuard struct {
+	doer   Doer
+	prefix string
+	ttl    time.Duration
+}
+
+// NewReplayGuard 创建一个 ReplayGuard。
+//
+// Params:
+//
+//	prefix: 键的前缀，完整的键为 prefix + 账户 + ":" + step。
+//	ttl   : 键的有效期，需要大于 token 可能被接受的时长，即 (2*skew+1)*period，建议取几分钟。
+func NewReplayGuard(doer Doer, prefix string, ttl time.Duration) *ReplayGuard {
+	return &ReplayGuard{doer: doer, prefix: prefix, ttl: ttl}
+}
+
+// Use 实现 otp.ReplayGuard 接口，Redis 出错时返回 false。
+func (g *ReplayGuard) Use(key string, step int64) bool {
+	ok, err := g.UseContext(context.Background(), key, step)
+	return err == nil && ok
+}
+
+// UseContext 实现 otp.ReplayGuardContext 接口。
+func (g *ReplayGuard) UseContext(ctx context.Context, key string, step int64) (bool, error) {
+	redisKey := g.prefix + key + ":" + strconv.FormatInt(step, 10)
+	reply, err := g.doer.Do(ctx, "SET", redisKey, "1", "NX", "PX", g.ttl.Milliseconds())
+	if isNil(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return reply != nil, nil
+}
+
+// incrScript 原子地增加失败次数，第一次失败时设置过期时间。
+const incrScript = `local n = redis.call('INCR', KEYS[1])
+if n == 1 then redis.call('PEXPIRE', KEYS[1], ARGV[1]) end
+return n`
+
+// ThrottleStore 基于 Redis 的 otp.ThrottleStore 实现，使用 INCR + PEXPIRE 记录窗口内的失败次数。
+//
+// 窗口的过期时间由 Redis 计算，接口中的 now 参数会被忽略。
+type ThrottleStore struct {
+	doer   Doer
+	prefix string
+}
+
+// NewThrottleStore 创建一个 ThrottleStore，完整的键为 prefix + 账户。
+func NewThrottleStore(doer Doer, prefix string) *ThrottleStore {
+	return &ThrottleStore{doer: doer, prefix: prefix}
+}
+
+// Failures 实现 otp.ThrottleStore 接口。
+func (s *ThrottleStore) Failures(key string, _ time.Time) (int, error) {
+	reply, err := s.doer.Do(context.Background(), "GET", s.prefix+key)
+	if isNil(err) || (err == nil && reply == nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return toInt(reply)
+}
+
+// AddFailure 实现 otp.ThrottleStore 接口。
+func (s *ThrottleStore) AddFailure(key string, _ time.Time, window time.Duration) (int, error) {
+	reply, err := s.doer.Do(context.Background(), "EVAL", incrScript, 1, s.prefix+key, window.Milliseconds())
+	if err != nil {
+		return 0, err
+	}
+	return toInt(reply)
+}
+
+// Expiry 实现 otp.ThrottleExpiry 接口，使用 PTTL 读取窗口的剩余时长并加上 now。
+func (s *ThrottleStore) Expiry(key string, now time.Time) (time.Time, error) {
+	reply, err := s.doer.Do(context.Background(), "PTTL", s.prefix+key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	ttl, err := toInt(reply)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// -2 表示键不存在，-1 表示没有过期时间
+	if ttl < 0 {
+		return time.Time{}, nil
+	}
+	return now.Add(time.Duration(ttl) * time.Millisecond), nil
+}
+
+// Reset 实现 otp.ThrottleStore 接口。
+func (s *ThrottleStore) Reset(key string) error {
+	_, err := s.doer.Do(context.Background(), "DEL", s.prefix+key)
+	if isNil(err) {
+		return nil
+	}
+	return err
+}
+
+// toInt 将 Redis 返回的整数或字符串转换为 int。
+func toInt(reply interface{}) (int, error) {
+	switch v := reply.(type) {
+	case int64:
+		return int(v), nil
+	case int:
+		return v, nil
+	case string:
+		return strconv.Atoi(v)
+	case []byte:
+		return strconv.Atoi(string(v))
+	default:
+		return 0, fmt.Errorf("otpredis: unexpected reply type %T", reply)
+	}
+}
@@ -0,0 +1,189 @@
+package otpredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/huk10/go-otp"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis 只实现了 otpredis 使用的命令，过期时间使用 now 计算。
+type fakeRedis struct {
+	mu      sync.Mutex
+	now     time.Time
+	values  map[string]string
+	expires map[string]time.Time
+	// 为 true 时 nil 结果以 go-redis 风格的错误返回
+	nilErr bool
+}
+
+var errRedisNil = errors.New("redis: nil")
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{now: time.Unix(1704075000, 0), values: map[string]string{}, expires: map[string]time.Time{}}
+}
+
+func (r *fakeRedis) null() (interface{}, error) {
+	if r.nilErr {
+		return nil, errRedisNil
+	}
+	return nil, nil
+}
+
+func (r *fakeRedis) get(key string) (string, bool) {
+	if exp, ok := r.expires[key]; ok && !r.now.Before(exp) {
+		delete(r.values, key)
+		delete(r.expires, key)
+	}
+	v, ok := r.values[key]
+	return v, ok
+}
+
+func (r *fakeRedis) Do(_ context.Context, args ...interface{}) (interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch args[0] {
+	case "SET":
+		key := args[1].(string)
+		if _, ok := r.get(key); ok {
+			return r.null()
+		}
+		r.values[key] = args[2].(string)
+		r.expires[key] = r.now.Add(time.Duration(args[5].(int64)) * time.Millisecond)
+		return "OK", nil
+	case "GET":
+		v, ok := r.get(args[1].(string))
+		if !ok {
+			return r.null()
+		}
+		return v, nil
+	case "DEL":
+		delete(r.values, args[1].(string))
+		delete(r.expires, args[1].(string))
+		return int64(1), nil
+	case "PTTL":
+		key := args[1].(string)
+		if _, ok := r.get(key); !ok {
+			return int64(-2), nil
+		}
+		exp, ok := r.expires[key]
+		if !ok {
+			return int64(-1), nil
+		}
+		return exp.Sub(r.now).Milliseconds(), nil
+	case "EVAL":
+		key := args[3].(string)
+		v, _ := r.get(key)
+		n, _ := strconv.Atoi(v)
+		n++
+		r.values[key] = strconv.Itoa(n)
+		if n == 1 {
+			r.expires[key] = r.now.Add(time.Duration(args[4].(int64)) * time.Millisecond)
+		}
+		return int64(n), nil
+	}
+	return nil, fmt.Errorf("unknown command %v", args[0])
+}
+
+func TestReplayGuard(t *testing.T) {
+	for _, nilErr := range []bool{false, true} {
+		redis := newFakeRedis()
+		redis.nilErr = nilErr
+		guard := NewReplayGuard(redis, "replay:", time.Minute)
+		assert.True(t, guard.Use("a", 10))
+		assert.False(t, guard.Use("a", 10))
+		assert.True(t, guard.Use("a", 11))
+		assert.True(t, guard.Use("b", 10))
+		assert.Equal(t, "1", redis.values["replay:a:10"])
+		// 过期之后可以再次使用
+		redis.now = redis.now.Add(time.Minute)
+		assert.True(t, guard.Use("a", 10))
+	}
+
+	now := time.Unix(1704075000000, 0)
+	totp := otp.NewTOTP("J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6", otp.WithReplayGuard(NewReplayGuard(newFakeRedis(), "", time.Minute)))
+	assert.True(t, totp.Verify("076141", now))
+	assert.False(t, totp.Verify("076141", now))
+}
+
+func TestReplayGuard_Error(t *testing.T) {
+	redisErr := errors.New("connection refused")
+	guard := NewReplayGuard(DoerFunc(func(context.Context, ...interface{}) (interface{}, error) {
+		return nil, redisErr
+	}), "", time.Minute)
+	assert.False(t, guard.Use("a", 1))
+	ok, err := guard.UseContext(context.Background(), "a", 1)
+	assert.False(t, ok)
+	assert.Equal(t, redisErr, err)
+}
+
+func TestThrottleStore(t *testing.T) {
+	redis := newFakeRedis()
+	redis.nilErr = true
+	store := NewThrottleStore(redis, "throttle:")
+	throttle := &otp.Throttle{Store: store, MaxFailures: 2, Window: time.Minute}
+
+	count, err := store.Failures("alice", time.Time{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	ok, err := throttle.Verify("alice", func() bool { return false })
+	assert.False(t, ok)
+	assert.Nil(t, err)
+	throttle.Verify("alice", func() bool { return false })
+	count, _ = store.Failures("alice", time.Time{})
+	assert.Equal(t, 2, count)
+	_, err = throttle.Verify("alice", func() bool { return true })
+	assert.ErrorIs(t, err, otp.ErrThrottled)
+	redis.now = redis.now.Add(20 * time.Second)
+	result := throttle.VerifyWithResult("alice", func() bool { return true })
+	assert.ErrorIs(t, result.Err, otp.ErrThrottled)
+	assert.Equal(t, 40*time.Second, result.RetryAfter)
+	expiry, err := store.Expiry("bob", redis.now)
+	assert.Nil(t, err)
+	assert.True(t, expiry.IsZero())
+
+	// 窗口从第一次失败开始计算
+	redis.now = redis.now.Add(40 * time.Second)
+	ok, err = throttle.Verify("alice", func() bool { return true })
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	assert.Nil(t, store.Reset("alice"))
+}
+
+func TestThrottleStore_Concurrent(t *testing.T) {
+	throttle := &otp.Throttle{Store: NewThrottleStore(newFakeRedis(), "throttle:"), MaxFailures: 3, Window: time.Minute}
+	var mu sync.Mutex
+	calls := 0
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			throttle.Verify("alice", func() bool {
+				mu.Lock()
+				calls++
+				mu.Unlock()
+				time.Sleep(time.Millisecond)
+				return false
+			})
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 3, calls)
+}
+
+func TestToInt(t *testing.T) {
+	for _, reply := range []interface{}{int64(3), 3, "3", []byte("3")} {
+		n, err := toInt(reply)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, n)
+	}
+	_, err := toInt(3.0)
+	assert.NotNil(t, err)
+}
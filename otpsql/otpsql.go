@@ -0,0 +1,233 @@
+// Package otpsql 基于 database/sql 保存秘钥、HOTP 计数器以及恢复码的摘要，支持 Postgres、MySQL 和 SQLite。
+//
+// 使用前需要调用 Migrate 创建数据表，驱动由调用方导入：
+//
+//	db, _ := sql.Open("pgx", dsn)
+//	err := otpsql.Migrate(ctx, db, otpsql.Postgres)
+//	secrets := otpsql.NewSecretStore(db, otpsql.Postgres)
+//	store, _ := otp.NewEncryptedSecretStore(kek, secrets)
+//	totp, err := otp.NewTOTPFromStore(store, userID)
+package otpsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/huk10/go-otp"
+)
+
+// Dialect 数据库方言，决定占位符、upsert 语法和二进制列的类型。
+type Dialect int
+
+const (
+	Postgres Dialect = iota
+	MySQL
+	SQLite
+)
+
+// 数据表名称。
+const (
+	SecretsTable       = "otp_secrets"
+	CountersTable      = "otp_counters"
+	RecoveryCodesTable = "otp_recovery_codes"
+)
+
+// placeholder 返回第 n 个（从 1 开始）参数的占位符。
+func (d Dialect) placeholder(n int) string {
+	if d == Postgres {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+// upsert 返回插入或更新 value 列的语句，参数依次为 id 和 value。
+func (d Dialect) upsert(table, column string) string {
+	insert := fmt.Sprintf("INSERT INTO %s (id, %s) VALUES (%s, %s)", table, column, d.placeholder(1), d.placeholder(2))
+	if d == MySQL {
+		return fmt.Sprintf("%s ON DUPLICATE KEY UPDATE %s = VALUES(%s)", insert, column, column)
+	}
+	return fmt.Sprintf("%s ON CONFLICT (id) DO UPDATE SET %s = excluded.%s", insert, column, column)
+}
+
+// schema 返回创建数据表的语句。
+func (d Dialect) schema() []string {
+	blob := "BLOB"
+	if d == Postgres {
+		blob = "BYTEA"
+	}
+	if d == MySQL {
+		blob = "VARBINARY(1024)"
+	}
+	return []string{
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id VARCHAR(255) PRIMARY KEY, secret %s NOT NULL)", SecretsTable, blob),
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id VARCHAR(255) PRIMARY KEY, counter BIGINT NOT NULL)", CountersTable),
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id VARCHAR(255) NOT NULL, hash VARCHAR(255) NOT NULL, PRIMARY KEY (id, hash))", RecoveryCodesTable),
+	}
+}
+
+// Migrate 创建 otpsql 使用的数据表，数据表已经存在时不做任何修改，可以在每次启动时调用。
+func Migrate(ctx context.Context, db *sql.DB, dialect Dialect) error {
+	for _, stmt := range dialect.schema() {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SecretStore 基于数据库的 otp.SecretStore 实现，同时实现了 otp.SecretStoreContext。
+//
+// 秘钥以明文保存，建议作为 otp.EncryptedSecretStore 的底层存储使用。
+type SecretStore struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+// NewSecretStore 创建一个 SecretStore。
+func NewSecretStore(db *sql.DB, dialect Dialect) *SecretStore {
+	return &SecretStore{db: db, dialect: dialect}
+}
+
+// Get 实现 otp.SecretStore 接口。
+func (s *SecretStore) Get(id string) ([]byte, error) {
+	return s.GetContext(context.Background(), id)
+}
+
+// GetContext 实现 otp.SecretStoreContext 接口，账户不存在时返回 otp.ErrSecretNotFound。
+func (s *SecretStore) GetContext(ctx context.Context, id string) ([]byte, error) {
+	var secret []byte
+	query := fmt.Sprintf("SELECT secret FROM %s WHERE id = %s", SecretsTable, s.dialect.placeholder(1))
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&secret)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, otp.ErrSecretNotFound
+	}
+	return secret, err
+}
+
+// Put 实现 otp.SecretStore 接口。
+func (s *SecretStore) Put(id string, secret []byte) error {
+	_, err := s.db.ExecContext(context.Background(), s.dialect.upsert(SecretsTable, "secret"), id, secret)
+	return err
+}
+
+// Delete 实现 otp.SecretStore 接口。
+func (s *SecretStore) Delete(id string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = %s", SecretsTable, s.dialect.placeholder(1))
+	_, err := s.db.ExecContext(context.Background(), query, id)
+	return err
+}
+
+// CounterStore 保存 HOTP 的计数器。
+type CounterStore struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+// NewCounterStore 创建一个 CounterStore。
+func NewCounterStore(db *sql.DB, dialect Dialect) *CounterStore {
+	return &CounterStore{db: db, dialect: dialect}
+}
+
+// Get 返回账户保存的计数器，账户不存在时返回 def。
+func (s *CounterStore) Get(ctx context.Context, id string, def int64) (int64, error) {
+	var counter int64
+	query := fmt.Sprintf("SELECT counter FROM %s WHERE id = %s", CountersTable, s.dialect.placeholder(1))
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&counter)
+	if errors.Is(err, sql.ErrNoRows) {
+		return def, nil
+	}
+	return counter, err
+}
+
+// Set 保存账户的计数器。
+func (s *CounterStore) Set(ctx context.Context, id string, counter int64) error {
+	_, err := s.db.ExecContext(ctx, s.dialect.upsert(CountersTable, "counter"), id, counter)
+	return err
+}
+
+// Persist 返回可以传递给 otp.NewStatefulHOTP 的持久化回调。
+//
+// Example:
+//
+//	counter, err := counters.Get(ctx, userID, 1)
+//	hotp := otp.NewStatefulHOTP(otp.NewHOTP(secret, otp.WithCounter(counter)), counters.Persist(userID))
+func (s *CounterStore) Persist(id string) func(counter int64) error {
+	return func(counter int64) error {
+		return s.Set(context.Background(), id, counter)
+	}
+}
+
+// RecoveryCodeStore 保存恢复码的摘要（otp.HashRecoveryCode 的结果）。
+type RecoveryCodeStore struct {
+	db      *sql.DB
+	dialect Dialect
+}
+
+// NewRecoveryCodeStore 创建一个 RecoveryCodeStore。
+func NewRecoveryCodeStore(db *sql.DB, dialect Dialect) *RecoveryCodeStore {
+	return &RecoveryCodeStore{db: db, dialect: dialect}
+}
+
+// Replace 在一个事务中删除账户已有的恢复码并保存新的摘要，通常在重新生成恢复码时调用。
+func (s *RecoveryCodeStore) Replace(ctx context.Context, id string, hashes []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	del := fmt.Sprintf("DELETE FROM %s WHERE id = %s", RecoveryCodesTable, s.dialect.placeholder(1))
+	if _, err := tx.ExecContext(ctx, del, id); err != nil {
+		return err
+	}
+	insert := fmt.Sprintf("INSERT INTO %s (id, hash) VALUES (%s, %s)", RecoveryCodesTable, s.dialect.placeholder(1), s.dialect.placeholder(2))
+	for _, hash := range hashes {
+		if _, err := tx.ExecContext(ctx, insert, id, hash); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Hashes 返回账户剩余的恢复码摘要。
+func (s *RecoveryCodeStore) Hashes(ctx context.Context, id string) ([]string, error) {
+	query := fmt.Sprintf("SELECT hash FROM %s WHERE id = %s", RecoveryCodesTable, s.dialect.placeholder(1))
+	rows, err := s.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, rows.Err()
+}
+
+// Use 校验恢复码，校验通过时删除对应的摘要，保证每个恢复码只能使用一次。
+//
+// 删除时检查影响的行数，并发使用同一个恢复码时只有一个请求会返回 true。
+func (s *RecoveryCodeStore) Use(ctx context.Context, id, code string) (bool, error) {
+	hashes, err := s.Hashes(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	i := otp.MatchRecoveryCode(code, hashes)
+	if i < 0 {
+		return false, nil
+	}
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = %s AND hash = %s", RecoveryCodesTable, s.dialect.placeholder(1), s.dialect.placeholder(2))
+	result, err := s.db.ExecContext(ctx, query, id, hashes[i])
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
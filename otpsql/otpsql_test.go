@@ -0,0 +1,238 @@
+package otpsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/huk10/go-otp"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDriver 一个只理解 otpsql 生成的语句的内存数据库，用于在没有真实数据库的情况下测试。
+type fakeDriver struct {
+	mu      sync.Mutex
+	tables  map[string]bool
+	secrets map[string][]byte
+	counter map[string]int64
+	codes   map[string]map[string]bool
+	queries []string
+}
+
+var (
+	drivers   = map[string]*fakeDriver{}
+	driversMu sync.Mutex
+)
+
+func init() {
+	sql.Register("otpsql-fake", &fakeConnector{})
+}
+
+type fakeConnector struct{}
+
+func (*fakeConnector) Open(name string) (driver.Conn, error) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	d, ok := drivers[name]
+	if !ok {
+		d = &fakeDriver{tables: map[string]bool{}, secrets: map[string][]byte{}, counter: map[string]int64{}, codes: map[string]map[string]bool{}}
+		drivers[name] = d
+	}
+	return &fakeConn{d: d}, nil
+}
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{c: c, query: query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *fakeConn) Commit() error             { return nil }
+func (c *fakeConn) Rollback() error           { return nil }
+
+type fakeStmt struct {
+	c     *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	d := s.c.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries = append(d.queries, s.query)
+	q := s.query
+	switch {
+	case strings.HasPrefix(q, "CREATE TABLE IF NOT EXISTS "):
+		d.tables[strings.Fields(q)[5]] = true
+		return driver.RowsAffected(0), nil
+	case strings.HasPrefix(q, "INSERT INTO otp_secrets"):
+		d.secrets[args[0].(string)] = append([]byte(nil), args[1].([]byte)...)
+	case strings.HasPrefix(q, "INSERT INTO otp_counters"):
+		d.counter[args[0].(string)] = args[1].(int64)
+	case strings.HasPrefix(q, "INSERT INTO otp_recovery_codes"):
+		id := args[0].(string)
+		if d.codes[id] == nil {
+			d.codes[id] = map[string]bool{}
+		}
+		d.codes[id][args[1].(string)] = true
+	case strings.HasPrefix(q, "DELETE FROM otp_secrets"):
+		delete(d.secrets, args[0].(string))
+	case strings.HasPrefix(q, "DELETE FROM otp_recovery_codes") && len(args) == 1:
+		delete(d.codes, args[0].(string))
+	case strings.HasPrefix(q, "DELETE FROM otp_recovery_codes"):
+		codes := d.codes[args[0].(string)]
+		if !codes[args[1].(string)] {
+			return driver.RowsAffected(0), nil
+		}
+		delete(codes, args[1].(string))
+	default:
+		return nil, errors.New("unexpected exec: " + q)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	d := s.c.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries = append(d.queries, s.query)
+	var values []driver.Value
+	switch {
+	case strings.HasPrefix(s.query, "SELECT secret FROM otp_secrets"):
+		if secret, ok := d.secrets[args[0].(string)]; ok {
+			values = append(values, secret)
+		}
+	case strings.HasPrefix(s.query, "SELECT counter FROM otp_counters"):
+		if counter, ok := d.counter[args[0].(string)]; ok {
+			values = append(values, counter)
+		}
+	case strings.HasPrefix(s.query, "SELECT hash FROM otp_recovery_codes"):
+		for hash := range d.codes[args[0].(string)] {
+			values = append(values, hash)
+		}
+	default:
+		return nil, errors.New("unexpected query: " + s.query)
+	}
+	return &fakeRows{values: values}, nil
+}
+
+type fakeRows struct {
+	values []driver.Value
+	i      int
+}
+
+func (r *fakeRows) Columns() []string { return []string{"value"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.values) {
+		return io.EOF
+	}
+	dest[0] = r.values[r.i]
+	r.i++
+	return nil
+}
+
+func openFake(t *testing.T, dialect Dialect) (*sql.DB, *fakeDriver) {
+	db, err := sql.Open("otpsql-fake", t.Name())
+	assert.Nil(t, err)
+	assert.Nil(t, Migrate(context.Background(), db, dialect))
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	return db, drivers[t.Name()]
+}
+
+func TestMigrate(t *testing.T) {
+	db, d := openFake(t, Postgres)
+	defer db.Close()
+	assert.Equal(t, map[string]bool{SecretsTable: true, CountersTable: true, RecoveryCodesTable: true}, d.tables)
+	assert.Contains(t, d.queries[0], "BYTEA")
+	assert.Contains(t, MySQL.schema()[0], "VARBINARY")
+	assert.Contains(t, SQLite.schema()[0], "BLOB")
+}
+
+func TestDialect_upsert(t *testing.T) {
+	assert.Equal(t, "INSERT INTO t (id, v) VALUES ($1, $2) ON CONFLICT (id) DO UPDATE SET v = excluded.v", Postgres.upsert("t", "v"))
+	assert.Equal(t, "INSERT INTO t (id, v) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET v = excluded.v", SQLite.upsert("t", "v"))
+	assert.Equal(t, "INSERT INTO t (id, v) VALUES (?, ?) ON DUPLICATE KEY UPDATE v = VALUES(v)", MySQL.upsert("t", "v"))
+}
+
+func TestSecretStore(t *testing.T) {
+	db, _ := openFake(t, SQLite)
+	defer db.Close()
+	store := NewSecretStore(db, SQLite)
+
+	_, err := store.Get("alice")
+	assert.ErrorIs(t, err, otp.ErrSecretNotFound)
+	assert.Nil(t, store.Put("alice", []byte("12345678901234567890")))
+	secret, err := store.Get("alice")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("12345678901234567890"), secret)
+
+	encrypted, _ := otp.NewEncryptedSecretStore(make([]byte, 32), store)
+	assert.Nil(t, encrypted.Put("bob", []byte("12345678901234567890")))
+	totp, err := otp.NewTOTPFromStore(encrypted, "bob", otp.WithDigits(otp.DigitsEight))
+	assert.Nil(t, err)
+	ok, err := totp.VerifyContext(context.Background(), "94287082", time.Unix(59, 0))
+	assert.True(t, ok)
+	assert.Nil(t, err)
+
+	assert.Nil(t, store.Delete("alice"))
+	_, err = store.GetContext(context.Background(), "alice")
+	assert.ErrorIs(t, err, otp.ErrSecretNotFound)
+}
+
+func TestCounterStore(t *testing.T) {
+	db, _ := openFake(t, MySQL)
+	defer db.Close()
+	ctx := context.Background()
+	counters := NewCounterStore(db, MySQL)
+
+	counter, err := counters.Get(ctx, "alice", 1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), counter)
+
+	hotp := otp.NewHOTP("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", otp.WithCounter(counter))
+	stateful := otp.NewStatefulHOTP(hotp, counters.Persist("alice"))
+	assert.True(t, stateful.VerifyAndAdvance("287082"))
+	counter, _ = counters.Get(ctx, "alice", 1)
+	assert.Equal(t, int64(2), counter)
+}
+
+func TestRecoveryCodeStore(t *testing.T) {
+	db, _ := openFake(t, Postgres)
+	defer db.Close()
+	ctx := context.Background()
+	store := NewRecoveryCodeStore(db, Postgres)
+	codes, err := otp.GenerateRecoveryCodes(3, otp.RecoveryFormatAlphanumeric)
+	assert.Nil(t, err)
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashed, err := otp.HashRecoveryCode(code)
+		assert.Nil(t, err)
+		hashes = append(hashes, hashed)
+	}
+	assert.Nil(t, store.Replace(ctx, "alice", hashes[:1]))
+	assert.Nil(t, store.Replace(ctx, "alice", hashes))
+	stored, err := store.Hashes(ctx, "alice")
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, hashes, stored)
+
+	ok, err := store.Use(ctx, "alice", codes[1])
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	ok, _ = store.Use(ctx, "alice", codes[1])
+	assert.False(t, ok)
+	ok, _ = store.Use(ctx, "bob", codes[0])
+	assert.False(t, ok)
+	stored, _ = store.Hashes(ctx, "alice")
+	assert.Len(t, stored, 2)
+}
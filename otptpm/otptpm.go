@@ -0,0 +1,219 @@
+// Package otptpm 使用本机的 TPM 2.0 封装（seal）OTP 秘钥，适用于没有 HSM、KMS 的边缘设备和自助终端。
+//
+// 秘钥作为 sealed data object 创建在 TPM 的存储根秘钥（SRK）之下，底层存储中保存的只是 TPM 加密之后的数据块，
+// 只有同一个 TPM 才能解封，磁盘或数据库被复制到其他机器之后无法还原秘钥。
+// 与 otp.NewTOTPFromStore 一起使用时，只有计算 HMAC 时才会解封，使用之后立即清零，进程内不会长期保存秘钥。
+//
+// Example:
+//
+//	tpm, err := transport.OpenTPM("/dev/tpmrm0")
+//	store := otptpm.NewSealedSecretStore(tpm, backend)
+//	err = store.Put("alice", otp.RandomSecret(20))
+//	totp, err := otp.NewTOTPFromStore(store, "alice")
+package otptpm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/google/go-tpm/tpm2"
+	"github.com/google/go-tpm/tpm2/transport"
+	"github.com/huk10/go-otp"
+	"sync"
+)
+
+// MaxSecretLength TPM 可以封装的秘钥的最大字节数（MAX_SYM_DATA），足够保存 SHA-512 长度的秘钥。
+const MaxSecretLength = 128
+
+// SealedSecretStore 使用 TPM 封装秘钥后保存到底层的 otp.SecretStore 中，实现 otp.SecretStore 和 otp.SecretStoreContext 接口。
+//
+// 保存的数据格式为 TPM2B_PUBLIC | TPM2B_PRIVATE。账户 id 的 SHA-256 作为封装对象的授权值，
+// 数据块被挪用到其他账户时解封会失败。
+//
+// 每次 Get、Put 都会在 TPM 中重新派生 SRK（同一个 TPM 派生的 SRK 总是相同的），使用之后立即释放，
+// 不会占用 TPM 的对象槽位。TPM 同一时间只能执行一个命令，所有操作都是串行的。
+type SealedSecretStore struct {
+	mu      sync.Mutex
+	tpm     transport.TPM
+	backend otp.SecretStore
+}
+
+// NewSealedSecretStore 创建一个 SealedSecretStore。
+//
+// Params:
+//
+//	tpm    : TPM 连接，Linux 上通常使用 transport.OpenTPM("/dev/tpmrm0")，测试时可以使用 go-tpm 的模拟器。
+//	backend: 保存封装之后的数据块的底层存储，例如文件或数据库的实现。
+//
+// Example:
+//
+//	store := otptpm.NewSealedSecretStore(tpm, otp.NewMemorySecretStore())
+//	err := store.Put("alice", otp.RandomSecret(20))
+func NewSealedSecretStore(tpm transport.TPM, backend otp.SecretStore) *SealedSecretStore {
+	return &SealedSecretStore{tpm: tpm, backend: backend}
+}
+
+// Get 实现 otp.SecretStore 接口，从底层存储读取数据块并使用 TPM 解封。
+//
+// 数据块格式错误、由其他 TPM 封装或者属于其他账户时返回 otp.ErrSecretDecrypt，TPM 不可用时返回对应的错误。
+func (s *SealedSecretStore) Get(id string) ([]byte, error) {
+	return s.GetContext(context.Background(), id)
+}
+
+// GetContext 实现 otp.SecretStoreContext 接口，底层存储实现了 otp.SecretStoreContext 时会将 ctx 传递下去。
+//
+// TPM 命令本身无法取消，ctx 只用于读取底层存储以及开始解封之前的检查。
+func (s *SealedSecretStore) GetContext(ctx context.Context, id string) ([]byte, error) {
+	var data []byte
+	var err error
+	if backend, ok := s.backend.(otp.SecretStoreContext); ok {
+		data, err = backend.GetContext(ctx, id)
+	} else {
+		data, err = s.backend.Get(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.unseal(id, data)
+}
+
+// Put 实现 otp.SecretStore 接口，使用 TPM 封装 secret 后保存到底层存储。
+//
+// secret 为空时返回 otp.ErrSecretCannotBeEmpty，超过 MaxSecretLength 时返回 otp.ErrInvalidOption。
+func (s *SealedSecretStore) Put(id string, secret []byte) error {
+	if len(secret) == 0 {
+		return otp.ErrSecretCannotBeEmpty
+	}
+	if len(secret) > MaxSecretLength {
+		return fmt.Errorf("%w: secret has %d bytes, the tpm can seal at most %d", otp.ErrInvalidOption, len(secret), MaxSecretLength)
+	}
+	data, err := s.seal(id, secret)
+	if err != nil {
+		return err
+	}
+	return s.backend.Put(id, data)
+}
+
+// Delete 实现 otp.SecretStore 接口。
+func (s *SealedSecretStore) Delete(id string) error {
+	return s.backend.Delete(id)
+}
+
+// seal 在 SRK 之下创建一个包含 secret 的 sealed data object，返回 TPM2B_PUBLIC | TPM2B_PRIVATE。
+func (s *SealedSecretStore) seal(id string, secret []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	srk, err := s.createSRK()
+	if err != nil {
+		return nil, err
+	}
+	defer s.flush(srk.ObjectHandle)
+	rsp, err := tpm2.Create{
+		ParentHandle: tpm2.NamedHandle{Handle: srk.ObjectHandle, Name: srk.Name},
+		InSensitive: tpm2.TPM2BSensitiveCreate{
+			Sensitive: &tpm2.TPMSSensitiveCreate{
+				UserAuth: tpm2.TPM2BAuth{Buffer: objectAuth(id)},
+				Data:     tpm2.NewTPMUSensitiveCreate(&tpm2.TPM2BSensitiveData{Buffer: secret}),
+			},
+		},
+		InPublic: tpm2.New2B(tpm2.TPMTPublic{
+			Type:    tpm2.TPMAlgKeyedHash,
+			NameAlg: tpm2.TPMAlgSHA256,
+			// NoDA：授权值只用于绑定账户 id，不是口令，不需要参与字典攻击保护
+			ObjectAttributes: tpm2.TPMAObject{FixedTPM: true, FixedParent: true, UserWithAuth: true, NoDA: true},
+		}),
+	}.Execute(s.tpm)
+	if err != nil {
+		return nil, fmt.Errorf("otptpm: seal %q: %w", id, err)
+	}
+	return append(tpm2.Marshal(rsp.OutPublic), tpm2.Marshal(rsp.OutPrivate)...), nil
+}
+
+// unseal 将数据块加载到 SRK 之下并解封。
+func (s *SealedSecretStore) unseal(id string, data []byte) ([]byte, error) {
+	public, private, err := parseSealed(data)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	srk, err := s.createSRK()
+	if err != nil {
+		return nil, err
+	}
+	defer s.flush(srk.ObjectHandle)
+	loaded, err := tpm2.Load{
+		ParentHandle: tpm2.NamedHandle{Handle: srk.ObjectHandle, Name: srk.Name},
+		InPublic:     *public,
+		InPrivate:    *private,
+	}.Execute(s.tpm)
+	if err != nil {
+		return nil, decryptError(id, err)
+	}
+	defer s.flush(loaded.ObjectHandle)
+	rsp, err := tpm2.Unseal{
+		ItemHandle: tpm2.AuthHandle{Handle: loaded.ObjectHandle, Name: loaded.Name, Auth: tpm2.PasswordAuth(objectAuth(id))},
+	}.Execute(s.tpm)
+	if err != nil {
+		return nil, decryptError(id, err)
+	}
+	return rsp.OutData.Buffer, nil
+}
+
+// createSRK 使用 TCG 参考模板派生 ECC P-256 SRK，同一个 TPM 的 owner 层级不变时结果总是相同的。
+func (s *SealedSecretStore) createSRK() (*tpm2.CreatePrimaryResponse, error) {
+	rsp, err := tpm2.CreatePrimary{
+		PrimaryHandle: tpm2.TPMRHOwner,
+		InPublic:      tpm2.New2B(tpm2.ECCSRKTemplate),
+	}.Execute(s.tpm)
+	if err != nil {
+		return nil, fmt.Errorf("otptpm: create srk: %w", err)
+	}
+	return rsp, nil
+}
+
+// flush 释放 TPM 中的临时对象，释放失败时对象会在 TPM 重置时被清除，这里忽略错误。
+func (s *SealedSecretStore) flush(handle tpm2.TPMHandle) {
+	_, _ = tpm2.FlushContext{FlushHandle: handle}.Execute(s.tpm)
+}
+
+// objectAuth 返回账户 id 对应的授权值。
+func objectAuth(id string) []byte {
+	sum := sha256.Sum256([]byte(id))
+	return sum[:]
+}
+
+// parseSealed 解析 TPM2B_PUBLIC | TPM2B_PRIVATE，两者都以 2 字节大端序的长度开头。
+func parseSealed(data []byte) (*tpm2.TPM2BPublic, *tpm2.TPM2BPrivate, error) {
+	if len(data) < 2 {
+		return nil, nil, otp.ErrSecretDecrypt
+	}
+	size := 2 + int(binary.BigEndian.Uint16(data))
+	if len(data) < size {
+		return nil, nil, otp.ErrSecretDecrypt
+	}
+	public, err := tpm2.Unmarshal[tpm2.TPM2BPublic](data[:size])
+	if err != nil {
+		return nil, nil, otp.ErrSecretDecrypt
+	}
+	private, err := tpm2.Unmarshal[tpm2.TPM2BPrivate](data[size:])
+	if err != nil || len(data) != size+2+len(private.Buffer) {
+		return nil, nil, otp.ErrSecretDecrypt
+	}
+	return public, private, nil
+}
+
+// decryptError TPM 拒绝加载或解封（数据块被篡改、由其他 TPM 封装或者授权值不匹配）时返回 otp.ErrSecretDecrypt，
+// 其余错误（例如与 TPM 的通信失败）原样返回。
+func decryptError(id string, err error) error {
+	var rc tpm2.TPMRC
+	if errors.As(err, &rc) && !rc.IsWarning() {
+		return fmt.Errorf("%w: otptpm: unseal %q: %v", otp.ErrSecretDecrypt, id, err)
+	}
+	return fmt.Errorf("otptpm: unseal %q: %w", id, err)
+}
@@ -0,0 +1,67 @@
+package otptpm
+
+import (
+	"context"
+	"github.com/google/go-tpm/tpm2/transport/simulator"
+	"github.com/huk10/go-otp"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSealedSecretStore(t *testing.T) {
+	tpm, err := simulator.OpenSimulator()
+	if err != nil {
+		t.Fatalf("open tpm simulator: %v", err)
+	}
+	defer tpm.Close()
+	backend := otp.NewMemorySecretStore()
+	store := NewSealedSecretStore(tpm, backend)
+
+	secret := []byte("12345678901234567890")
+	assert.Nil(t, store.Put("alice", secret))
+	data, err := backend.Get("alice")
+	assert.Nil(t, err)
+	assert.NotContains(t, string(data), string(secret))
+	got, err := store.Get("alice")
+	assert.Nil(t, err)
+	assert.Equal(t, secret, got)
+
+	// 与 NewTOTPFromStore 一起使用
+	totp, err := otp.NewTOTPFromStore(store, "alice")
+	assert.Nil(t, err)
+	now := time.Unix(1704075000, 0)
+	assert.Equal(t, otp.NewTOTP(otp.Base32Encode(secret)).At(now), totp.At(now))
+	ok, err := totp.VerifyContext(context.Background(), totp.At(now), now)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	// 数据块被挪用到其他账户
+	assert.Nil(t, backend.Put("bob", data))
+	_, err = store.Get("bob")
+	assert.ErrorIs(t, err, otp.ErrSecretDecrypt)
+
+	// 数据块被篡改或者格式错误
+	tampered := append([]byte(nil), data...)
+	tampered[len(tampered)-1] ^= 1
+	for _, data := range [][]byte{tampered, data[:len(data)-1], append(data, 0), {0}, {0, 8, 1}} {
+		assert.Nil(t, backend.Put("carol", data))
+		_, err = store.Get("carol")
+		assert.ErrorIs(t, err, otp.ErrSecretDecrypt)
+	}
+
+	_, err = store.Get("dave")
+	assert.Equal(t, otp.ErrSecretNotFound, err)
+	assert.Nil(t, store.Delete("alice"))
+	_, err = store.Get("alice")
+	assert.Equal(t, otp.ErrSecretNotFound, err)
+
+	assert.Equal(t, otp.ErrSecretCannotBeEmpty, store.Put("alice", nil))
+	assert.ErrorIs(t, store.Put("alice", make([]byte, MaxSecretLength+1)), otp.ErrInvalidOption)
+	assert.Nil(t, store.Put("alice", make([]byte, MaxSecretLength)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = store.GetContext(ctx, "alice")
+	assert.ErrorIs(t, err, context.Canceled)
+}
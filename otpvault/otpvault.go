@@ -0,0 +1,252 @@
+// Package otpvault 使用 HashiCorp Vault 的 TOTP secrets engine 管理秘钥，秘钥只保存在 Vault 中。
+//
+// 只依赖标准库，通过 Vault 的 HTTP API 调用 create key、generate code、validate code 三个接口。
+//
+// See https://developer.hashicorp.com/vault/api-docs/secret/totp
+//
+// Example:
+//
+//	client := otpvault.NewClient("https://vault.example.com:8200", token)
+//	key, err := client.CreateKey(ctx, "alice", otpvault.KeyOptions{Issuer: "Example", AccountName: "alice@google.com"})
+//	// 将 key.URI 或 key.QRCode 下发给用户
+//	ok, err := client.Key("alice").VerifyContext(ctx, token)
+package otpvault
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/huk10/go-otp"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client Vault TOTP secrets engine 的客户端。
+type Client struct {
+	// Vault 的地址，例如 https://vault.example.com:8200
+	Address string
+	// Vault token，通过 X-Vault-Token 请求头传递
+	Token string
+	// TOTP secrets engine 的挂载路径，默认为 totp
+	Mount string
+	// 可选，Vault Enterprise 的命名空间
+	Namespace string
+	// 发送请求使用的 http.Client，为 nil 时使用 http.DefaultClient
+	HTTPClient *http.Client
+}
+
+// NewClient 创建一个使用默认挂载路径 totp 的 Client。
+func NewClient(address, token string) *Client {
+	return &Client{Address: address, Token: token, Mount: "totp"}
+}
+
+// KeyOptions 创建秘钥的参数，零值字段使用 Vault 的默认值。
+type KeyOptions struct {
+	Issuer      string
+	AccountName string
+	Period      int
+	Algorithm   otp.Algorithms
+	Digits      otp.Digits
+	// 秘钥的字节数，Vault 默认为 20
+	KeySize int
+	// 二维码的尺寸，为 0 时 Vault 默认为 200，为负数时不生成二维码
+	QRSize int
+	// 可选，导入已有的 otpauth URI 而不是由 Vault 生成秘钥
+	URL string
+}
+
+// Key CreateKey 返回的开通信息。
+type Key struct {
+	// otpauth URI
+	URI string
+	// PNG 格式的二维码，KeyOptions.QRSize 为负数或者导入已有 URI 时为 nil
+	QRCode []byte
+}
+
+// Error Vault 返回的错误。
+type Error struct {
+	StatusCode int
+	Errors     []string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("vault: status %d: %s", e.StatusCode, strings.Join(e.Errors, "; "))
+}
+
+// CreateKey 在 Vault 中创建名为 name 的秘钥，已存在时 Vault 会覆盖。
+func (c *Client) CreateKey(ctx context.Context, name string, opts KeyOptions) (*Key, error) {
+	body := map[string]interface{}{}
+	if opts.URL != "" {
+		body["url"] = opts.URL
+	} else {
+		body["generate"] = true
+		body["issuer"] = opts.Issuer
+		body["account_name"] = opts.AccountName
+		if opts.KeySize > 0 {
+			body["key_size"] = opts.KeySize
+		}
+		if opts.QRSize != 0 {
+			qrSize := opts.QRSize
+			if qrSize < 0 {
+				qrSize = 0
+			}
+			body["qr_size"] = qrSize
+		}
+	}
+	if opts.Period > 0 {
+		body["period"] = opts.Period
+	}
+	if opts.Algorithm != 0 {
+		body["algorithm"] = opts.Algorithm.String()
+	}
+	if opts.Digits != 0 {
+		body["digits"] = int(opts.Digits)
+	}
+	var resp struct {
+		Data struct {
+			URL     string `json:"url"`
+			Barcode string `json:"barcode"`
+		} `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodPost, "keys/"+url.PathEscape(name), body, &resp); err != nil {
+		return nil, err
+	}
+	key := &Key{URI: resp.Data.URL}
+	if resp.Data.Barcode != "" {
+		qr, err := base64.StdEncoding.DecodeString(resp.Data.Barcode)
+		if err != nil {
+			return nil, err
+		}
+		key.QRCode = qr
+	}
+	return key, nil
+}
+
+// DeleteKey 删除 Vault 中名为 name 的秘钥。
+func (c *Client) DeleteKey(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "keys/"+url.PathEscape(name), nil, nil)
+}
+
+// Generate 由 Vault 生成 name 对应秘钥当前时间的 token。
+func (c *Client) Generate(ctx context.Context, name string) (string, error) {
+	var resp struct {
+		Data struct {
+			Code string `json:"code"`
+		} `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodGet, "code/"+url.PathEscape(name), nil, &resp); err != nil {
+		return "", err
+	}
+	return resp.Data.Code, nil
+}
+
+// Validate 由 Vault 校验 token 在当前时间是否有效，Vault 本身会拒绝重复使用的 token。
+func (c *Client) Validate(ctx context.Context, name, token string) (bool, error) {
+	if token == "" {
+		return false, nil
+	}
+	var resp struct {
+		Data struct {
+			Valid bool `json:"valid"`
+		} `json:"data"`
+	}
+	if err := c.do(ctx, http.MethodPost, "code/"+url.PathEscape(name), map[string]string{"code": token}, &resp); err != nil {
+		// 重复使用的 token Vault 会返回 400 "code already used"，按照无效处理
+		if e, ok := err.(*Error); ok && e.StatusCode == http.StatusBadRequest && strings.Contains(strings.Join(e.Errors, " "), "already used") {
+			return false, nil
+		}
+		return false, err
+	}
+	return resp.Data.Valid, nil
+}
+
+// Key 返回绑定了秘钥名称的 VaultKey，方法签名与 otp.TOTP 对应，便于替换本地秘钥。
+func (c *Client) Key(name string) *VaultKey {
+	return &VaultKey{client: c, name: name}
+}
+
+// VaultKey Vault 中的一个秘钥。
+type VaultKey struct {
+	client *Client
+	name   string
+}
+
+// Now 由 Vault 生成当前时间的 token，出错时返回空字符串。
+func (k *VaultKey) Now() string {
+	token, err := k.client.Generate(context.Background(), k.name)
+	if err != nil {
+		return ""
+	}
+	return token
+}
+
+// NowContext 由 Vault 生成当前时间的 token。
+func (k *VaultKey) NowContext(ctx context.Context) (string, error) {
+	return k.client.Generate(ctx, k.name)
+}
+
+// VerifyNow 由 Vault 校验 token 在当前时间是否有效，出错时返回 false。
+func (k *VaultKey) VerifyNow(token string) bool {
+	ok, err := k.client.Validate(context.Background(), k.name, token)
+	return err == nil && ok
+}
+
+// VerifyContext 由 Vault 校验 token 在当前时间是否有效，与 otp.TOTP.VerifyContext 一样区分无效的 token 和 Vault 不可用。
+func (k *VaultKey) VerifyContext(ctx context.Context, token string) (bool, error) {
+	return k.client.Validate(ctx, k.name, token)
+}
+
+// do 发送请求并将响应中的 JSON 解码到 out。
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	mount := c.Mount
+	if mount == "" {
+		mount = "totp"
+	}
+	endpoint := strings.TrimRight(c.Address, "/") + "/v1/" + strings.Trim(mount, "/") + "/" + path
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", c.Token)
+	if c.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.Namespace)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		e := &Error{StatusCode: resp.StatusCode}
+		var errBody struct {
+			Errors []string `json:"errors"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&errBody) == nil {
+			e.Errors = errBody.Errors
+		}
+		return e
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,137 @@
+package otpvault
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/huk10/go-otp"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeVault 使用本库模拟 Vault TOTP secrets engine 的 HTTP API。
+type fakeVault struct {
+	mu   sync.Mutex
+	keys map[string]*otp.TOTP
+	used map[string]bool
+}
+
+func newFakeVault() *httptest.Server {
+	v := &fakeVault{keys: map[string]*otp.TOTP{}, used: map[string]bool{}}
+	return httptest.NewServer(v)
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if r.Header.Get("X-Vault-Token") != "root" {
+		w.WriteHeader(http.StatusForbidden)
+		_ = json.NewEncoder(w).Encode(map[string][]string{"errors": {"permission denied"}})
+		return
+	}
+	var body map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	name := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/v1/totp/keys/") && r.Method == http.MethodPost:
+		var key *otp.KeyURI
+		if u, ok := body["url"].(string); ok {
+			key, _ = otp.FromURI(u)
+		} else {
+			totp := otp.NewTOTP(otp.Base32Encode(otp.RandomSecret(20)))
+			key = totp.KeyURI(body["account_name"].(string), body["issuer"].(string))
+		}
+		v.keys[name], _ = key.TOTP()
+		// Vault 返回的 PNG 由客户端原样透传，这里不需要真实的二维码
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{
+			"url":     key.URI().String(),
+			"barcode": base64.StdEncoding.EncodeToString([]byte("png:" + key.URI().String())),
+		}})
+	case strings.HasPrefix(r.URL.Path, "/v1/totp/keys/") && r.Method == http.MethodDelete:
+		delete(v.keys, name)
+		w.WriteHeader(http.StatusNoContent)
+	case strings.HasPrefix(r.URL.Path, "/v1/totp/code/"):
+		totp, ok := v.keys[name]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string][]string{"errors": {"unknown key: " + name}})
+			return
+		}
+		if r.Method == http.MethodGet {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"code": totp.Now()}})
+			return
+		}
+		code := body["code"].(string)
+		if v.used[name+code] {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string][]string{"errors": {"code already used; wait until the next time period"}})
+			return
+		}
+		valid := totp.VerifyNow(code)
+		if valid {
+			v.used[name+code] = true
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]bool{"valid": valid}})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestClient(t *testing.T) {
+	server := newFakeVault()
+	defer server.Close()
+	ctx := context.Background()
+	client := NewClient(server.URL, "root")
+
+	key, err := client.CreateKey(ctx, "alice", KeyOptions{Issuer: "Example", AccountName: "alice@google.com"})
+	assert.Nil(t, err)
+	parsed, err := otp.FromURI(key.URI)
+	assert.Nil(t, err)
+	assert.Equal(t, "Example", parsed.Issuer)
+	assert.Equal(t, "png:"+key.URI, string(key.QRCode))
+
+	vaultKey := client.Key("alice")
+	token, err := vaultKey.NowContext(ctx)
+	assert.Nil(t, err)
+	local, _ := parsed.TOTP()
+	assert.Equal(t, local.Now(), token)
+	assert.Equal(t, token, vaultKey.Now())
+
+	ok, err := vaultKey.VerifyContext(ctx, token)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+	// 重复使用的 token 按照无效处理
+	ok, err = vaultKey.VerifyContext(ctx, token)
+	assert.False(t, ok)
+	assert.Nil(t, err)
+	assert.False(t, vaultKey.VerifyNow(""))
+
+	assert.Nil(t, client.DeleteKey(ctx, "alice"))
+	assert.Equal(t, "", vaultKey.Now())
+}
+
+func TestClient_ImportURL(t *testing.T) {
+	server := newFakeVault()
+	defer server.Close()
+	client := NewClient(server.URL+"/", "root")
+	uri := otp.NewTOTP("JBSWY3DPEHPK3PXP").KeyURI("bob", "Example").URI().String()
+	_, err := client.CreateKey(context.Background(), "bob", KeyOptions{URL: uri})
+	assert.Nil(t, err)
+	assert.Equal(t, otp.NewTOTP("JBSWY3DPEHPK3PXP").Now(), client.Key("bob").Now())
+}
+
+func TestClient_Error(t *testing.T) {
+	server := newFakeVault()
+	defer server.Close()
+	client := NewClient(server.URL, "wrong")
+	_, err := client.Generate(context.Background(), "alice")
+	assert.Equal(t, &Error{StatusCode: http.StatusForbidden, Errors: []string{"permission denied"}}, err)
+	assert.Equal(t, "vault: status 403: permission denied", err.Error())
+	ok, err := client.Key("alice").VerifyContext(context.Background(), "123456")
+	assert.False(t, ok)
+	assert.NotNil(t, err)
+}
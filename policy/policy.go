@@ -0,0 +1,265 @@
+// Package policy 从 JSON 或 YAML 配置文件加载 OTP 策略，多个服务共享同一份策略，而不是在各处分散地传递 With* 参数。
+//
+// 配置示例（YAML）：
+//
+//	issuer: Example
+//	algorithm: SHA256
+//	digits: 6
+//	period: 30
+//	skew: 1
+//	rateLimit:
+//	  maxFailures: 5
+//	  window: 15m
+//
+// 使用示例：
+//
+//	p, err := policy.Load("otp-policy.yaml")
+//	throttle := p.Throttle()
+//
+//	totp, err := p.NewTOTP(secret)
+//	uri := totp.KeyURI("alice@google.com").URI()
+//	ok, err := throttle.Verify(userID, func() bool { return totp.VerifyNow(token) })
+//
+// 多租户的服务可以使用 IssuerRegistry 为每个租户配置不同的策略。
+//
+// policy 是一个单独的模块，YAML 依赖不会引入到主模块中。
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/huk10/go-otp"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrFormat 配置文件的格式错误，包括未知的字段。
+	ErrFormat = errors.New("policy format error")
+	// ErrNoPolicy 策略为 nil，例如 IssuerRegistry 中没有对应的租户并且没有默认策略。
+	ErrNoPolicy = errors.New("no policy configured")
+)
+
+// Policy 声明式的 OTP 策略，零值字段使用与 Google Authenticator 兼容的默认值。
+type Policy struct {
+	// 发行商，用于 KeyURI 和 Provisioner。
+	Issuer string `json:"issuer" yaml:"issuer"`
+	// HMAC 算法，与 uri 上的 algorithm 参数一致，默认 SHA1。
+	Algorithm string `json:"algorithm" yaml:"algorithm"`
+	// 一次性密码的长度，默认 6。
+	Digits int `json:"digits" yaml:"digits"`
+	// TOTP 的时间窗口（秒），默认 30。
+	Period int `json:"period" yaml:"period"`
+	// 同时校验的相邻窗口数，默认 0。
+	Skew int `json:"skew" yaml:"skew"`
+	// 是否使用 otp.ValidateSecretStrength 校验秘钥长度。
+	StrictSecretLength bool `json:"strictSecretLength" yaml:"strictSecretLength"`
+	// 失败次数限制，为 nil 时不限制。
+	RateLimit *RateLimit `json:"rateLimit" yaml:"rateLimit"`
+}
+
+// RateLimit 对应 otp.Throttle 的参数。
+type RateLimit struct {
+	// 窗口内允许的最大失败次数。
+	MaxFailures int `json:"maxFailures" yaml:"maxFailures"`
+	// 窗口长度。
+	Window Duration `json:"window" yaml:"window"`
+}
+
+// Duration 使用 time.ParseDuration 的格式（例如 15m）在配置文件中表示时间长度。
+type Duration time.Duration
+
+// MarshalText 实现 encoding.TextMarshaler 接口。
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler 接口。
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// Load 读取配置文件，根据扩展名（.json、.yaml、.yml）选择解析方式，并校验策略。
+func Load(path string) (*Policy, error) {
+	var p Policy
+	if err := load(path, &p); err != nil {
+		return nil, err
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ParseJSON 解析 JSON 格式的策略并校验，未知的字段返回 ErrFormat。
+func ParseJSON(data []byte) (*Policy, error) {
+	var p Policy
+	if err := decodeJSON(data, &p); err != nil {
+		return nil, err
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ParseYAML 解析 YAML 格式的策略并校验，未知的字段返回 ErrFormat。
+func ParseYAML(data []byte) (*Policy, error) {
+	var p Policy
+	if err := decodeYAML(data, &p); err != nil {
+		return nil, err
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// load 读取配置文件并根据扩展名解析到 v。
+func load(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return decodeJSON(data, v)
+	case ".yaml", ".yml":
+		return decodeYAML(data, v)
+	default:
+		return fmt.Errorf("%w: unknown file extension %q", ErrFormat, filepath.Ext(path))
+	}
+}
+
+// decodeJSON 解析 JSON，未知的字段返回 ErrFormat。
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	return nil
+}
+
+// decodeYAML 解析 YAML，未知的字段返回 ErrFormat。
+func decodeYAML(data []byte, v interface{}) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	return nil
+}
+
+// Validate 校验策略，参数错误可以使用 errors.Is(err, otp.ErrInvalidOption) 判断，p 为 nil 时返回 ErrNoPolicy。
+func (p *Policy) Validate() error {
+	if p == nil {
+		return ErrNoPolicy
+	}
+	options, err := p.options()
+	if err != nil {
+		return err
+	}
+	if err := otp.ValidateTOTPOptions(append(otp.AsTOTPOptions(options...), otp.WithPeriod(p.period()))...); err != nil {
+		return err
+	}
+	if p.RateLimit != nil && (p.RateLimit.MaxFailures <= 0 || p.RateLimit.Window <= 0) {
+		return fmt.Errorf("%w: rate limit requires positive maxFailures and window", otp.ErrInvalidOption)
+	}
+	return nil
+}
+
+// period 返回 TOTP 的时间窗口，未配置时为 30 秒。
+func (p *Policy) period() int {
+	if p.Period == 0 {
+		return 30
+	}
+	return p.Period
+}
+
+// options 将策略转换为 TOTP 和 HOTP 共用的 option。
+func (p *Policy) options() ([]otp.Option, error) {
+	var algorithm otp.Algorithms
+	if err := algorithm.UnmarshalText([]byte(p.Algorithm)); err != nil {
+		return nil, fmt.Errorf("%w: algorithm %q", otp.ErrInvalidOption, p.Algorithm)
+	}
+	digits := otp.DigitsSix
+	if p.Digits != 0 {
+		digits = otp.Digits(p.Digits)
+	}
+	options := []otp.Option{
+		otp.WithAlgorithm(algorithm),
+		otp.WithDigits(digits),
+		otp.WithSkew(p.Skew),
+		otp.WithIssuer(p.Issuer),
+	}
+	if p.StrictSecretLength {
+		options = append(options, otp.WithStrictSecretLength())
+	}
+	return options, nil
+}
+
+// TOTPOptions 返回策略对应的 TOTPOption，策略不合法时返回错误。
+func (p *Policy) TOTPOptions() ([]otp.TOTPOption, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	options, _ := p.options()
+	return append(otp.AsTOTPOptions(options...), otp.WithPeriod(p.period())), nil
+}
+
+// HOTPOptions 返回策略对应的 HOTPOption，策略不合法时返回错误。
+func (p *Policy) HOTPOptions() ([]otp.HOTPOption, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	options, _ := p.options()
+	return otp.AsHOTPOptions(options...), nil
+}
+
+// NewTOTP 使用策略创建 TOTP，extra 在策略之后应用，可以覆盖策略中的参数，例如 otp.WithReplayGuard。
+func (p *Policy) NewTOTP(secret string, extra ...otp.TOTPOption) (*otp.TOTP, error) {
+	options, err := p.TOTPOptions()
+	if err != nil {
+		return nil, err
+	}
+	return otp.NewTOTPWithError(secret, append(options, extra...)...)
+}
+
+// NewHOTP 使用策略创建 HOTP，extra 在策略之后应用，例如 otp.WithCounter。
+func (p *Policy) NewHOTP(secret string, extra ...otp.HOTPOption) (*otp.HOTP, error) {
+	options, err := p.HOTPOptions()
+	if err != nil {
+		return nil, err
+	}
+	return otp.NewHOTPWithError(secret, append(options, extra...)...)
+}
+
+// NewProvisioner 使用策略创建 otp.Provisioner。
+func (p *Policy) NewProvisioner() (*otp.Provisioner, error) {
+	options, err := p.TOTPOptions()
+	if err != nil {
+		return nil, err
+	}
+	return otp.NewProvisioner(p.Issuer, options...), nil
+}
+
+// Throttle 返回策略对应的 otp.Throttle，没有配置 RateLimit 或者 p 为 nil 时返回 nil。
+//
+// 每次调用都会创建新的 Throttle 和 MemoryThrottleStore，调用方应该保存返回值，多实例部署时替换 Store。
+func (p *Policy) Throttle() *otp.Throttle {
+	if p == nil || p.RateLimit == nil {
+		return nil
+	}
+	return otp.NewThrottle(p.RateLimit.MaxFailures, time.Duration(p.RateLimit.Window))
+}
@@ -0,0 +1,132 @@
+package policy
+
+import (
+	"github.com/huk10/go-otp"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZA"
+
+const policyYAML = `
+issuer: Example
+algorithm: SHA256
+digits: 8
+period: 60
+skew: 1
+rateLimit:
+  maxFailures: 5
+  window: 15m
+`
+
+const policyJSON = `{
+  "issuer": "Example",
+  "algorithm": "SHA256",
+  "digits": 8,
+  "period": 60,
+  "skew": 1,
+  "rateLimit": {"maxFailures": 5, "window": "15m"}
+}`
+
+func TestParse(t *testing.T) {
+	expected := &Policy{
+		Issuer:    "Example",
+		Algorithm: "SHA256",
+		Digits:    8,
+		Period:    60,
+		Skew:      1,
+		RateLimit: &RateLimit{MaxFailures: 5, Window: Duration(15 * time.Minute)},
+	}
+	p, err := ParseYAML([]byte(policyYAML))
+	assert.Nil(t, err)
+	assert.Equal(t, expected, p)
+
+	p, err = ParseJSON([]byte(policyJSON))
+	assert.Nil(t, err)
+	assert.Equal(t, expected, p)
+
+	throttle := p.Throttle()
+	assert.Equal(t, 5, throttle.MaxFailures)
+	assert.Equal(t, 15*time.Minute, throttle.Window)
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{"policy.yaml": policyYAML, "policy.yml": policyYAML, "policy.json": policyJSON} {
+		path := filepath.Join(dir, name)
+		assert.Nil(t, os.WriteFile(path, []byte(data), 0o600))
+		p, err := Load(path)
+		assert.Nil(t, err, name)
+		assert.Equal(t, "Example", p.Issuer, name)
+	}
+
+	path := filepath.Join(dir, "policy.toml")
+	assert.Nil(t, os.WriteFile(path, []byte(policyYAML), 0o600))
+	_, err := Load(path)
+	assert.ErrorIs(t, err, ErrFormat)
+}
+
+func TestPolicy_Factories(t *testing.T) {
+	p, err := ParseYAML([]byte(policyYAML))
+	assert.Nil(t, err)
+
+	totp, err := p.NewTOTP(secret)
+	assert.Nil(t, err)
+	expected := otp.NewTOTP(secret, otp.WithAlgorithm(otp.AlgorithmSHA256), otp.WithDigits(otp.DigitsEight), otp.WithPeriod(60))
+	now := time.Unix(1704075000, 0)
+	assert.Equal(t, expected.At(now), totp.At(now))
+	assert.Equal(t, 1, totp.Skew)
+	assert.Equal(t, "otpauth://totp/Example:alice?secret="+secret+"&issuer=Example&algorithm=SHA256&digits=8&period=60", totp.KeyURI("alice").URI().String())
+
+	hotp, err := p.NewHOTP(secret, otp.WithCounter(5))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), hotp.Counter)
+	assert.Equal(t, otp.AlgorithmSHA256, hotp.Algorithm)
+
+	provisioner, err := p.NewProvisioner()
+	assert.Nil(t, err)
+	provisioner.SkipQRCode = true
+	enrollments, err := provisioner.Provision("alice")
+	assert.Nil(t, err)
+	assert.Equal(t, 60, enrollments[0].KeyURI.Period)
+
+	// 零值使用默认参数
+	p = &Policy{}
+	totp, err = p.NewTOTP(secret)
+	assert.Nil(t, err)
+	assert.Equal(t, otp.NewTOTP(secret).Otp, totp.Otp)
+	assert.Nil(t, p.Throttle())
+}
+
+func TestPolicy_Invalid(t *testing.T) {
+	for _, data := range []string{
+		"issuer: Example\nunknown: 1\n",
+		"rateLimit:\n  window: soon\n",
+		"digits: [6]\n",
+	} {
+		_, err := ParseYAML([]byte(data))
+		assert.ErrorIs(t, err, ErrFormat, data)
+	}
+	_, err := ParseJSON([]byte(`{"issuer": "Example", "unknown": 1}`))
+	assert.ErrorIs(t, err, ErrFormat)
+
+	for _, data := range []string{
+		"algorithm: MD5\n",
+		"digits: 3\n",
+		"period: 5\n",
+		"skew: -1\n",
+		"rateLimit:\n  maxFailures: 0\n  window: 1m\n",
+	} {
+		_, err := ParseYAML([]byte(data))
+		assert.ErrorIs(t, err, otp.ErrInvalidOption, data)
+	}
+
+	// 秘钥长度在创建时校验
+	p, err := ParseYAML([]byte("strictSecretLength: true\nalgorithm: SHA512\n"))
+	assert.Nil(t, err)
+	_, err = p.NewTOTP(secret)
+	assert.ErrorIs(t, err, otp.ErrSecretTooShort)
+}
@@ -0,0 +1,117 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// IssuerRegistry 保存每个租户的策略，适用于为多个客户品牌提供两步验证的 SaaS 服务。
+//
+// 每个租户可以有不同的发行商名称、算法、长度和时间窗口，没有单独配置的租户使用 Default。
+// 可以被多个 goroutine 同时使用。
+//
+// Example:
+//
+//	registry := policy.NewIssuerRegistry(defaultPolicy)
+//	err := registry.Register("acme", &policy.Policy{Issuer: "ACME", Digits: 8})
+//	totp, err := registry.ForTenant("acme").NewTOTP(secret)
+//	uri := totp.KeyURI("alice@acme.com").URI() // otpauth://totp/ACME:alice@acme.com?...
+type IssuerRegistry struct {
+	// 没有单独配置的租户使用的策略，为 nil 时 ForTenant 返回 nil。
+	Default *Policy
+
+	mu      sync.RWMutex
+	tenants map[string]*Policy
+}
+
+// registryConfig IssuerRegistry 配置文件的结构。
+type registryConfig struct {
+	Default *Policy            `json:"default" yaml:"default"`
+	Tenants map[string]*Policy `json:"tenants" yaml:"tenants"`
+}
+
+// NewIssuerRegistry 创建一个 IssuerRegistry，defaultPolicy 可以为 nil。
+func NewIssuerRegistry(defaultPolicy *Policy) *IssuerRegistry {
+	return &IssuerRegistry{Default: defaultPolicy, tenants: make(map[string]*Policy)}
+}
+
+// LoadRegistry 读取配置文件创建 IssuerRegistry，格式与 Load 相同，所有策略都会被校验。
+//
+// 配置示例（YAML）：
+//
+//	default:
+//	  issuer: Example
+//	tenants:
+//	  acme:
+//	    issuer: ACME
+//	    digits: 8
+func LoadRegistry(path string) (*IssuerRegistry, error) {
+	var config registryConfig
+	if err := load(path, &config); err != nil {
+		return nil, err
+	}
+	if config.Default != nil {
+		if err := config.Default.Validate(); err != nil {
+			return nil, fmt.Errorf("default: %w", err)
+		}
+	}
+	registry := NewIssuerRegistry(config.Default)
+	for id, p := range config.Tenants {
+		if err := registry.Register(id, p); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
+
+// Register 校验并保存租户的策略，已存在时覆盖。
+func (r *IssuerRegistry) Register(id string, p *Policy) error {
+	if err := p.Validate(); err != nil {
+		return fmt.Errorf("tenant %q: %w", id, err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.tenants == nil {
+		r.tenants = make(map[string]*Policy)
+	}
+	r.tenants[id] = p
+	return nil
+}
+
+// Remove 删除租户的策略，之后该租户使用 Default。
+func (r *IssuerRegistry) Remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tenants, id)
+}
+
+// Lookup 返回租户单独配置的策略，不会回退到 Default。
+func (r *IssuerRegistry) Lookup(id string) (*Policy, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.tenants[id]
+	return p, ok
+}
+
+// ForTenant 返回租户的策略，没有单独配置时返回 Default。
+//
+// 两者都不存在时返回 nil，在 nil 上调用 NewTOTP、NewHOTP 等方法会返回 ErrNoPolicy，因此可以直接链式调用。
+func (r *IssuerRegistry) ForTenant(id string) *Policy {
+	if p, ok := r.Lookup(id); ok {
+		return p
+	}
+	return r.Default
+}
+
+// Tenants 返回所有单独配置了策略的租户，按字典序排列。
+func (r *IssuerRegistry) Tenants() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ids := make([]string, 0, len(r.tenants))
+	for id := range r.tenants {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,68 @@
+package policy
+
+import (
+	"github.com/huk10/go-otp"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIssuerRegistry(t *testing.T) {
+	registry := NewIssuerRegistry(&Policy{Issuer: "Example"})
+	assert.Nil(t, registry.Register("acme", &Policy{Issuer: "ACME", Digits: 8}))
+	assert.Nil(t, registry.Register("globex", &Policy{Issuer: "Globex", Algorithm: "SHA256", Period: 60}))
+	assert.Equal(t, []string{"acme", "globex"}, registry.Tenants())
+
+	totp, err := registry.ForTenant("acme").NewTOTP(secret)
+	assert.Nil(t, err)
+	assert.Equal(t, "otpauth://totp/ACME:alice?secret="+secret+"&issuer=ACME&digits=8", totp.KeyURI("alice").URI().String())
+
+	totp, err = registry.ForTenant("globex").NewTOTP(secret)
+	assert.Nil(t, err)
+	assert.Equal(t, otp.AlgorithmSHA256, totp.Algorithm)
+	assert.Equal(t, 60, totp.Period)
+
+	// 没有单独配置的租户使用默认策略
+	totp, err = registry.ForTenant("initech").NewTOTP(secret)
+	assert.Nil(t, err)
+	assert.Equal(t, "Example", totp.Issuer)
+	_, ok := registry.Lookup("initech")
+	assert.False(t, ok)
+
+	registry.Remove("acme")
+	assert.Equal(t, "Example", registry.ForTenant("acme").Issuer)
+
+	// 没有默认策略时返回 ErrNoPolicy
+	registry.Default = nil
+	_, err = registry.ForTenant("acme").NewTOTP(secret)
+	assert.ErrorIs(t, err, ErrNoPolicy)
+	_, err = registry.ForTenant("acme").NewHOTP(secret)
+	assert.ErrorIs(t, err, ErrNoPolicy)
+	assert.Nil(t, registry.ForTenant("acme").Throttle())
+
+	err = registry.Register("invalid", &Policy{Digits: 3})
+	assert.ErrorIs(t, err, otp.ErrInvalidOption)
+	assert.ErrorIs(t, registry.Register("nil", nil), ErrNoPolicy)
+}
+
+func TestLoadRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tenants.yaml")
+	assert.Nil(t, os.WriteFile(path, []byte(`
+default:
+  issuer: Example
+tenants:
+  acme:
+    issuer: ACME
+    digits: 8
+`), 0o600))
+	registry, err := LoadRegistry(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"acme"}, registry.Tenants())
+	assert.Equal(t, 8, registry.ForTenant("acme").Digits)
+	assert.Equal(t, "Example", registry.ForTenant("other").Issuer)
+
+	assert.Nil(t, os.WriteFile(path, []byte("tenants:\n  acme:\n    period: 1\n"), 0o600))
+	_, err = LoadRegistry(path)
+	assert.ErrorIs(t, err, otp.ErrInvalidOption)
+}
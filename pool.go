@@ -0,0 +1,86 @@
+package otp
+
+import (
+	"encoding/binary"
+	"hash"
+	"sync"
+)
+
+// macState 计算 HMAC 时复用的哈希对象和缓冲区。
+type macState struct {
+	h     hash.Hash
+	key   []byte
+	pad   []byte
+	inner []byte
+	outer []byte
+	msg   [8]byte
+}
+
+// macPools 按照算法复用 macState，WithReuseHMAC 开启后使用。
+//
+// crypto/hmac 的结果与秘钥绑定，无法在不同秘钥之间复用，这里直接复用底层的哈希对象按照 RFC-2104 计算 HMAC。
+var macPools = map[Algorithms]*sync.Pool{}
+
+func init() {
+	for algorithm := AlgorithmSHA1; algorithm <= AlgorithmSHA384; algorithm++ {
+		newHash := hasher(algorithm)
+		macPools[algorithm] = &sync.Pool{New: func() interface{} {
+			h := newHash()
+			return &macState{
+				h:     h,
+				key:   make([]byte, 0, h.Size()),
+				pad:   make([]byte, h.BlockSize()),
+				inner: make([]byte, 0, h.Size()),
+				outer: make([]byte, 0, h.Size()),
+			}
+		}}
+	}
+}
+
+// pooledGenerate 与 Otp.generate 的结果相同，但是复用哈希对象和缓冲区以减少内存分配。
+func (o Otp) pooledGenerate(secret []byte, counter int64) string {
+	pool := macPools[o.Algorithm]
+	s := pool.Get().(*macState)
+	defer s.release(pool)
+
+	key := secret
+	if len(key) > len(s.pad) {
+		s.h.Reset()
+		s.h.Write(key)
+		s.key = s.h.Sum(s.key[:0])
+		key = s.key
+	}
+	// ipad
+	copy(s.pad, key)
+	zero(s.pad[len(key):])
+	for i := range s.pad {
+		s.pad[i] ^= 0x36
+	}
+	binary.BigEndian.PutUint64(s.msg[:], uint64(counter))
+	s.h.Reset()
+	s.h.Write(s.pad)
+	s.h.Write(s.msg[:])
+	s.inner = s.h.Sum(s.inner[:0])
+	// opad
+	for i := range s.pad {
+		s.pad[i] ^= 0x36 ^ 0x5c
+	}
+	s.h.Reset()
+	s.h.Write(s.pad)
+	s.h.Write(s.inner)
+	s.outer = s.h.Sum(s.outer[:0])
+
+	return o.encode(s.outer)
+}
+
+// release 清除与秘钥相关的状态之后放回 pool：哈希对象的内部状态由 opad 派生，key、pad 以及 HMAC 的中间结果都不能留在 pool 中。
+func (s *macState) release(pool *sync.Pool) {
+	s.h.Reset()
+	zero(s.key[:cap(s.key)])
+	zero(s.pad)
+	zero(s.inner[:cap(s.inner)])
+	zero(s.outer[:cap(s.outer)])
+	zero(s.msg[:])
+	s.key, s.inner, s.outer = s.key[:0], s.inner[:0], s.outer[:0]
+	pool.Put(s)
+}
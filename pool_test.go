@@ -0,0 +1,71 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithReuseHMAC(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+	assert.Equal(t, "076141", NewTOTP(TestSecret20, WithReuseHMAC(true)).At(now))
+
+	algorithms := []Algorithms{AlgorithmSHA1, AlgorithmSHA256, AlgorithmSHA512, AlgorithmSHA3_256, AlgorithmSHA3_512, AlgorithmSHA224, AlgorithmSHA384}
+	for _, algorithm := range algorithms {
+		// 包含超过哈希分块长度的秘钥
+		for _, size := range []int{1, 20, 64, 128, 200} {
+			secret := RandomSecret(size)
+			for counter := int64(0); counter < 5; counter++ {
+				mac := hmac.New(hasher(algorithm), secret)
+				mac.Write(intToByte(counter))
+				expected := truncate(mac.Sum(nil), 8)
+				otp := newOtp(WithAlgorithm(algorithm), WithDigits(DigitsEight), WithReuseHMAC(true))
+				assert.Equal(t, expected, otp.generate(secret, counter), "%s %d", algorithm, size)
+			}
+		}
+	}
+
+	hotp := NewHOTP(TestSecret64, WithAlgorithm(AlgorithmSHA512), WithReuseHMAC(true), WithSkew(1))
+	assert.Equal(t, NewHOTP(TestSecret64, WithAlgorithm(AlgorithmSHA512)).At(7), hotp.At(7))
+	assert.True(t, hotp.Verify(hotp.At(7), 6))
+}
+
+func BenchmarkTOTP_At(b *testing.B) {
+	now := time.Unix(1704075000000, 0)
+	for _, reuse := range []bool{false, true} {
+		totp := NewTOTP(TestSecret20, WithReuseHMAC(reuse))
+		name := "default"
+		if reuse {
+			name = "reuse"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				totp.At(now)
+			}
+		})
+	}
+}
+
+func TestMacState_Release(t *testing.T) {
+	pool := macPools[AlgorithmSHA1]
+	s := pool.New().(*macState)
+	secret := RandomSecret(100)
+	s.h.Write(secret)
+	s.key = s.h.Sum(s.key[:0])
+	copy(s.pad, secret)
+	s.inner = append(s.inner, secret[:20]...)
+	s.outer = append(s.outer, secret[:20]...)
+	s.msg[0] = 1
+
+	s.release(&sync.Pool{})
+	empty := hasher(AlgorithmSHA1)().Sum(nil)
+	assert.Equal(t, empty, s.h.Sum(nil))
+	assert.Equal(t, make([]byte, cap(s.key)), s.key[:cap(s.key)])
+	assert.Equal(t, make([]byte, len(s.pad)), s.pad)
+	assert.Equal(t, make([]byte, cap(s.inner)), s.inner[:cap(s.inner)])
+	assert.Equal(t, make([]byte, cap(s.outer)), s.outer[:cap(s.outer)])
+	assert.Equal(t, [8]byte{}, s.msg)
+}
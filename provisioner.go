@@ -0,0 +1,119 @@
+package otp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Provisioner 为一批账户生成 TOTP 秘钥、otpauth URI 和二维码，适用于批量开通账户的场景。
+type Provisioner struct {
+	// 发行商，必传
+	Issuer string
+	// 随机秘钥的字节数，默认 20
+	SecretSize int
+	// 创建 TOTP 时使用的 option
+	Options []TOTPOption
+	// 生成二维码时使用的 option
+	QRCodeOptions []QRCodeOption
+	// 是否跳过二维码的生成，只需要 URI 时可以节省大量的时间和内存，使用 noqrcode 构建标签时总是跳过
+	SkipQRCode bool
+	// 未确认的开通信息的有效期，为 0 时不会过期
+	TTL time.Duration
+}
+
+// Enrollment 单个账户的开通信息。
+type Enrollment struct {
+	// 账户名称
+	Account string
+	// base32 编码的秘钥，需要由调用方持久化
+	Secret string
+	TOTP   *TOTP
+	KeyURI *KeyURI
+	// otpauth URI
+	URI string
+	// PNG 格式的二维码，SkipQRCode 为 true 或者使用 noqrcode 构建标签时为 nil
+	QRCode []byte
+	// 开通状态，新生成的开通信息为 EnrollmentPending，用户提交一个有效的 token 之后变为 EnrollmentConfirmed
+	State EnrollmentState
+	// 生成的时间
+	CreatedAt time.Time
+	// 未确认时的过期时间，零值表示不会过期
+	ExpiresAt time.Time
+}
+
+// NewProvisioner 创建一个 Provisioner，options 会传递给每个账户的 TOTP。
+//
+// Example:
+//
+//	enrollments, err := NewProvisioner("Example").Provision("alice@google.com", "bob@google.com")
+//	for _, e := range enrollments {
+//		save(e.Account, e.Secret)
+//		send(e.Account, e.QRCode)
+//	}
+func NewProvisioner(issuer string, options ...TOTPOption) *Provisioner {
+	return &Provisioner{Issuer: issuer, SecretSize: 20, Options: options}
+}
+
+// Provision 为每个账户生成随机秘钥以及对应的开通信息，返回的顺序与 accounts 一致。
+//
+// 任意一个账户失败时（账户名为空或重复、标签不合法、生成二维码失败等）返回 nil 和包含账户名称的错误，
+// 不会返回部分结果，避免只有部分账户被持久化。
+func (p *Provisioner) Provision(accounts ...string) ([]Enrollment, error) {
+	size := p.SecretSize
+	if size <= 0 {
+		size = 20
+	}
+	seen := make(map[string]bool, len(accounts))
+	enrollments := make([]Enrollment, 0, len(accounts))
+	for _, account := range accounts {
+		if seen[account] {
+			return nil, fmt.Errorf("%w: %q", ErrDuplicateAccount, account)
+		}
+		seen[account] = true
+		enrollment, err := p.provision(account, size)
+		if err != nil {
+			return nil, fmt.Errorf("provision %q: %w", account, err)
+		}
+		enrollments = append(enrollments, enrollment)
+	}
+	return enrollments, nil
+}
+
+// provision 生成单个账户的开通信息。
+func (p *Provisioner) provision(account string, size int) (Enrollment, error) {
+	if strings.TrimSpace(account) == "" {
+		return Enrollment{}, ErrLabelEmpty
+	}
+	decoded, err := randomSecret(size)
+	if err != nil {
+		return Enrollment{}, err
+	}
+	secret := Base32Encode(decoded)
+	totp, err := NewTOTPWithError(secret, p.Options...)
+	if err != nil {
+		return Enrollment{}, err
+	}
+	key := totp.KeyURI(account, p.Issuer)
+	if err := key.ValidateStrict(); err != nil {
+		return Enrollment{}, err
+	}
+	enrollment := Enrollment{
+		Account:   account,
+		Secret:    secret,
+		TOTP:      totp,
+		KeyURI:    key,
+		URI:       key.URI().String(),
+		State:     EnrollmentPending,
+		CreatedAt: totp.now(),
+	}
+	if p.TTL > 0 {
+		enrollment.ExpiresAt = enrollment.CreatedAt.Add(p.TTL)
+	}
+	if !p.SkipQRCode && qrCodeAvailable {
+		if enrollment.QRCode, err = key.QRCode(p.QRCodeOptions...); err != nil {
+			return Enrollment{}, err
+		}
+	}
+	return enrollment, nil
+}
@@ -0,0 +1,64 @@
+package otp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestProvisioner_Provision(t *testing.T) {
+	accounts := []string{"alice@google.com", "bob@google.com", "carol@google.com"}
+	enrollments, err := NewProvisioner("Example", WithDigits(DigitsEight)).Provision(accounts...)
+	assert.Nil(t, err)
+	assert.Len(t, enrollments, 3)
+
+	secrets := map[string]bool{}
+	for i, e := range enrollments {
+		assert.Equal(t, accounts[i], e.Account)
+		assert.Len(t, e.Secret, 32)
+		assert.False(t, secrets[e.Secret])
+		secrets[e.Secret] = true
+
+		assert.Equal(t, DigitsEight, e.TOTP.Digits)
+		assert.Equal(t, e.KeyURI.URI().String(), e.URI)
+
+		key, err := FromURI(e.URI)
+		assert.Nil(t, err)
+		assert.Equal(t, e.Secret, key.Secret)
+		now := time.Now()
+		assert.Equal(t, true, e.TOTP.Verify(NewTOTP(e.Secret, WithDigits(DigitsEight)).At(now), now))
+	}
+}
+
+func TestProvisioner_Options(t *testing.T) {
+	p := NewProvisioner("Example")
+	p.SecretSize = 32
+	p.SkipQRCode = true
+	enrollments, err := p.Provision("alice")
+	assert.Nil(t, err)
+	assert.Nil(t, enrollments[0].QRCode)
+	decoded, _ := Base32Decode(enrollments[0].Secret)
+	assert.Len(t, decoded, 32)
+
+	p2 := &Provisioner{Issuer: "Example", SkipQRCode: true}
+	enrollments, err = p2.Provision("alice")
+	assert.Nil(t, err)
+	decoded, _ = Base32Decode(enrollments[0].Secret)
+	assert.Len(t, decoded, 20)
+}
+
+func TestProvisioner_Errors(t *testing.T) {
+	p := NewProvisioner("Example")
+	p.SkipQRCode = true
+
+	enrollments, err := p.Provision("alice", "bob", "alice")
+	assert.Nil(t, enrollments)
+	assert.ErrorIs(t, err, ErrDuplicateAccount)
+
+	_, err = p.Provision("alice", " ")
+	assert.ErrorIs(t, err, ErrLabelEmpty)
+
+	_, err = p.Provision("alice", "bob:smith")
+	assert.ErrorIs(t, err, ErrLabelColon)
+	assert.Contains(t, err.Error(), `provision "bob:smith"`)
+}
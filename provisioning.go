@@ -0,0 +1,149 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProvisioningSigner 为 otpauth URI 添加过期时间和 HMAC 签名。
+//
+// 服务端在展示二维码时签名，在用户提交第一个 token 确认绑定时校验签名和过期时间，
+// 过期之后即使有人拿到了二维码截图也无法再完成绑定。
+//
+// 验证器应用会忽略 URI 上的 exp 和 sig 参数，不影响扫码导入。
+type ProvisioningSigner struct {
+	key []byte
+	ttl time.Duration
+}
+
+// SignedKeyURI 带有过期时间和签名的 KeyURI。
+//
+// KeyURI 是普通字段而不是嵌入字段，SignedKeyURI 自身的 URI、二维码以及序列化方法都输出带签名的 URI，
+// 不会因为方法提升而意外输出未签名的 URI。需要未签名的 URI 时显式使用 signed.KeyURI。
+type SignedKeyURI struct {
+	// 签名的原始 KeyURI
+	KeyURI KeyURI
+	// 过期时间
+	Expires time.Time
+	// base64url 编码的 HMAC-SHA256 签名
+	Signature string
+}
+
+// minProvisioningKeyLength ProvisioningSigner 秘钥的最小字节数，与 HMAC-SHA256 的输出长度相同。
+const minProvisioningKeyLength = 32
+
+// NewProvisioningSigner 创建一个 ProvisioningSigner。
+//
+// Params:
+//
+//	key: 签名使用的秘钥，仅服务端持有，至少 32 字节，不要与 MFATokenSigner 共用。秘钥会被复制，之后修改 key 不影响签名。
+//	ttl: 签名的有效期，例如 10 分钟。
+//
+// key 短于 32 字节时返回 ErrSecretTooShort。
+func NewProvisioningSigner(key []byte, ttl time.Duration) (*ProvisioningSigner, error) {
+	if len(key) < minProvisioningKeyLength {
+		return nil, fmt.Errorf("%w: provisioning key has %d bytes, at least %d are required", ErrSecretTooShort, len(key), minProvisioningKeyLength)
+	}
+	return &ProvisioningSigner{key: append([]byte(nil), key...), ttl: ttl}, nil
+}
+
+// Sign 对 KeyURI 进行签名，过期时间为 now + ttl。
+//
+// Example:
+//
+//	signer, err := NewProvisioningSigner(serverKey, 10*time.Minute)
+//	if err != nil {
+//		return err
+//	}
+//	signed := signer.Sign(totp.KeyURI("alice@google.com", "Example"), time.Now())
+//	png, err := signed.QRCode()
+func (s *ProvisioningSigner) Sign(key *KeyURI, now time.Time) *SignedKeyURI {
+	expires := time.Unix(now.Add(s.ttl).Unix(), 0)
+	payload := signingPayload(key.URI().String(), expires)
+	return &SignedKeyURI{
+		KeyURI:    *key,
+		Expires:   expires,
+		Signature: s.sign(payload),
+	}
+}
+
+// Verify 校验签名后的 URI，签名错误返回 ErrSignatureInvalid，已过期返回 ErrProvisioningExpired。
+//
+// 校验通过后返回解析出来的 KeyURI，此时可以继续校验用户提交的 token 并保存秘钥。
+func (s *ProvisioningSigner) Verify(uri string, now time.Time) (*KeyURI, error) {
+	i := strings.LastIndex(uri, "&sig=")
+	if i == -1 {
+		return nil, ErrSignatureInvalid
+	}
+	payload, signature := uri[:i], uri[i+len("&sig="):]
+	if !hmac.Equal([]byte(s.sign(payload)), []byte(signature)) {
+		return nil, ErrSignatureInvalid
+	}
+	u, err := url.Parse(payload)
+	if err != nil {
+		return nil, ErrSignatureInvalid
+	}
+	exp, err := strconv.ParseInt(u.Query().Get("exp"), 10, 64)
+	if err != nil {
+		return nil, ErrSignatureInvalid
+	}
+	if !now.Before(time.Unix(exp, 0)) {
+		return nil, ErrProvisioningExpired
+	}
+	return FromURI(payload)
+}
+
+func (s *ProvisioningSigner) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// URI 生成带有 exp 和 sig 参数的 otpauth URI，sig 总是最后一个参数。
+func (p SignedKeyURI) URI() *url.URL {
+	u := p.KeyURI.URI()
+	u.RawQuery += "&exp=" + strconv.FormatInt(p.Expires.Unix(), 10) + "&sig=" + p.Signature
+	return u
+}
+
+// QRCode 将签名后的 URI 生成一个二维码，参数与 KeyURI.QRCode 相同。
+func (p SignedKeyURI) QRCode(options ...QRCodeOption) ([]byte, error) {
+	return qrCodePNG(p.URI().String(), options...)
+}
+
+// WriteQRCode 将签名后的 URI 生成的二维码写入 w，参数与 KeyURI.WriteQRCode 相同。
+func (p SignedKeyURI) WriteQRCode(w io.Writer, options ...QRCodeOption) error {
+	return writeQRCode(w, p.URI().String(), options...)
+}
+
+// QRCodeWithOptions 将签名后的 URI 生成一个二维码，参数与 KeyURI.QRCodeWithOptions 相同。
+func (p SignedKeyURI) QRCodeWithOptions(opts QRCodeOptions) ([]byte, error) {
+	return qrCodePNGWithOptions(p.URI().String(), opts)
+}
+
+// QRCodeSVG 将签名后的 URI 生成一个 SVG 格式的二维码。
+func (p SignedKeyURI) QRCodeSVG() (string, error) {
+	return qrCodeSVG(p.URI().String())
+}
+
+// QRCodeTerminal 将签名后的 URI 生成一个可以直接打印在终端中的二维码。
+func (p SignedKeyURI) QRCodeTerminal() string {
+	return qrCodeTerminal(p.URI().String())
+}
+
+// MarshalText 实现 encoding.TextMarshaler 接口，输出签名后的 URI，JSON 中同样序列化为该字符串。
+func (p SignedKeyURI) MarshalText() ([]byte, error) {
+	return []byte(p.URI().String()), nil
+}
+
+// signingPayload 返回需要签名的内容：原始 URI 加上 exp 参数。
+func signingPayload(uri string, expires time.Time) string {
+	return uri + "&exp=" + strconv.FormatInt(expires.Unix(), 10)
+}
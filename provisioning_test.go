@@ -0,0 +1,80 @@
+package otp
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProvisioningSigner(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	signer, err := NewProvisioningSigner([]byte("server-side-signing-key-0123456789"), 10*time.Minute)
+	assert.Nil(t, err)
+	key := NewTOTP(TestSecret20).KeyURI("alice@google.com", "Example")
+	signed := signer.Sign(key, now)
+	uri := signed.URI().String()
+	assert.Equal(t, now.Add(10*time.Minute), signed.Expires)
+	assert.True(t, strings.HasPrefix(uri, key.URI().String()+"&exp=1704075600&sig="))
+
+	t.Run("valid", func(t *testing.T) {
+		parsed, err := signer.Verify(uri, now.Add(5*time.Minute))
+		assert.Nil(t, err)
+		assert.Equal(t, key, parsed)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		_, err := signer.Verify(uri, now.Add(10*time.Minute))
+		assert.Equal(t, ErrProvisioningExpired, err)
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		_, err := signer.Verify(strings.Replace(uri, "exp=1704075600", "exp=1804075600", 1), now)
+		assert.Equal(t, ErrSignatureInvalid, err)
+		_, err = signer.Verify(strings.Replace(uri, TestSecret20, TestSecret32, 1), now)
+		assert.Equal(t, ErrSignatureInvalid, err)
+		_, err = signer.Verify(key.URI().String(), now)
+		assert.Equal(t, ErrSignatureInvalid, err)
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		other, _ := NewProvisioningSigner([]byte("another-signing-key-0123456789abcd"), 10*time.Minute)
+		_, err := other.Verify(uri, now)
+		assert.Equal(t, ErrSignatureInvalid, err)
+	})
+
+	t.Run("marshal", func(t *testing.T) {
+		text, err := signed.MarshalText()
+		assert.Nil(t, err)
+		assert.Equal(t, uri, string(text))
+
+		data, err := json.Marshal(signed)
+		assert.Nil(t, err)
+		var decoded string
+		assert.Nil(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, uri, decoded)
+		assert.Contains(t, decoded, "&sig="+signed.Signature)
+
+		data, err = json.Marshal(struct{ Key *SignedKeyURI }{signed})
+		assert.Nil(t, err)
+		assert.Contains(t, string(data), signed.Signature)
+	})
+}
+
+func TestNewProvisioningSigner_Key(t *testing.T) {
+	_, err := NewProvisioningSigner(nil, time.Minute)
+	assert.ErrorIs(t, err, ErrSecretTooShort)
+	_, err = NewProvisioningSigner([]byte("server-side-signing-key"), time.Minute)
+	assert.ErrorIs(t, err, ErrSecretTooShort)
+
+	// 秘钥被复制，调用方修改或清零原切片不影响签名
+	key := []byte("server-side-signing-key-0123456789")
+	signer, err := NewProvisioningSigner(key, time.Minute)
+	assert.Nil(t, err)
+	now := time.Unix(1704075000, 0)
+	uri := signer.Sign(NewTOTP(TestSecret20).KeyURI("alice@google.com", "Example"), now).URI().String()
+	zero(key)
+	_, err = signer.Verify(uri, now)
+	assert.Nil(t, err)
+}
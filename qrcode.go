@@ -0,0 +1,351 @@
+package otp
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// QRCodeOptions 生成二维码时使用的参数，零值表示使用默认参数。
+type QRCodeOptions struct {
+	// 是否输出字节稳定的二维码，默认为 false。
+	// 开启后使用固定的编码参数（不压缩的 PNG，不写入时间等元数据），相同的输入在任何 Go 版本下都会得到完全相同的字节，
+	// 适用于 golden file 测试以及按内容寻址存储二维码图片，代价是图片体积更大。
+	Deterministic bool
+	// 图片的宽高（像素），默认为 256。如果小于二维码的模块数，会自动放大到模块数。
+	// 每个模块使用相同的整数像素，不能整除时多余的像素作为背景留在四周。
+	Size int
+	// 纠错等级，默认为 QRRecoveryHighest。内容较长时降低纠错等级可以得到更稀疏、更容易扫描的二维码。
+	RecoveryLevel QRRecoveryLevel
+	// 前景色，默认为黑色。
+	Foreground color.Color
+	// 背景色，默认为白色。
+	Background color.Color
+	// 四周静区的宽度（模块数），默认为 4，小于 0 时不输出静区。
+	Border int
+	// 覆盖在二维码中心的 logo，默认为 nil。
+	// 设置后纠错等级固定为 QRRecoveryHighest，logo 会等比缩放到二维码宽度的 1/5 以内，保证被遮挡的模块可以被纠错恢复。
+	Logo image.Image
+	// WithLogoPNG 传入的 PNG 数据，生成二维码时解码
+	logoPNG []byte
+}
+
+// QRRecoveryLevel 二维码的纠错等级，等级越高可以容忍的损坏越多，但二维码越密集。
+type QRRecoveryLevel int
+
+const (
+	// QRRecoveryDefault 默认纠错等级，等同于 QRRecoveryHighest。
+	QRRecoveryDefault QRRecoveryLevel = iota
+	// QRRecoveryLow 7% 纠错能力。
+	QRRecoveryLow
+	// QRRecoveryMedium 15% 纠错能力。
+	QRRecoveryMedium
+	// QRRecoveryHigh 25% 纠错能力。
+	QRRecoveryHigh
+	// QRRecoveryHighest 30% 纠错能力。
+	QRRecoveryHighest
+)
+
+type QRCodeOption func(opts *QRCodeOptions)
+
+// WithDeterministic 配置输出字节稳定的二维码。
+func WithDeterministic() QRCodeOption {
+	return func(opts *QRCodeOptions) {
+		opts.Deterministic = true
+	}
+}
+
+// WithLogo 配置覆盖在二维码中心的 logo。
+func WithLogo(logo image.Image) QRCodeOption {
+	return func(opts *QRCodeOptions) {
+		opts.Logo = logo
+	}
+}
+
+// WithLogoPNG 配置覆盖在二维码中心的 logo，data 为 PNG 格式的图片，解码失败时生成二维码会返回错误。
+func WithLogoPNG(data []byte) QRCodeOption {
+	return func(opts *QRCodeOptions) {
+		opts.logoPNG = data
+	}
+}
+
+// QRCode 将此 URI 信息生成一个二维码，可供 Google Authenticator 扫码导入。
+//
+// 使用 noqrcode 构建标签时（例如 TinyGo、WASM 等不需要 go-qrcode 依赖的环境），所有生成二维码的方法都返回 ErrQRCodeUnavailable，
+// QRCodeTerminal 返回空字符串，此时可以使用不依赖任何第三方库的 URI 方法，由前端生成二维码。
+//
+// Example:
+//
+//	png, err := totp.KeyURI("alice@google.com", "Example").QRCode(WithDeterministic())
+//	png, err := totp.KeyURI("alice@google.com", "Example").QRCode(WithLogoPNG(logo))
+func (p KeyURI) QRCode(options ...QRCodeOption) ([]byte, error) {
+	return qrCodePNG(p.URI().String(), options...)
+}
+
+// WriteQRCode 与 QRCode 相同，将 PNG 直接写入 w 而不是返回完整的字节切片，适用于在 HTTP 响应中直接输出二维码。
+//
+// 生成二维码失败（例如内容过长）时不会向 w 写入任何数据。
+//
+// Example:
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//		w.Header().Set("Content-Type", "image/png")
+//		_ = key.WriteQRCode(w)
+//	}
+func (p KeyURI) WriteQRCode(w io.Writer, options ...QRCodeOption) error {
+	return writeQRCode(w, p.URI().String(), options...)
+}
+
+// QRCodeWithOptions 与 QRCode 相同，使用 QRCodeOptions 指定尺寸、纠错等级、颜色以及静区宽度。
+//
+// Example:
+//
+//	png, err := totp.KeyURI("alice@google.com", "Example").QRCodeWithOptions(QRCodeOptions{
+//		Size:          512,
+//		RecoveryLevel: QRRecoveryMedium,
+//		Foreground:    color.RGBA{R: 0x1a, G: 0x73, B: 0xe8, A: 0xff},
+//		Border:        2,
+//	})
+func (p KeyURI) QRCodeWithOptions(opts QRCodeOptions) ([]byte, error) {
+	return qrCodePNGWithOptions(p.URI().String(), opts)
+}
+
+// QRCodeSVG 将此 URI 信息生成一个 SVG 格式的二维码，适用于网页等需要矢量图的场景。
+//
+// 图片尺寸使用 viewBox 描述，每个模块为一个单位，可以通过 CSS 任意缩放。
+func (p KeyURI) QRCodeSVG() (string, error) {
+	return qrCodeSVG(p.URI().String())
+}
+
+// QRCodeTerminal 将此 URI 信息生成一个可以直接打印在终端中的二维码，每个字符表示上下两个模块。
+//
+// 亮色模块使用 Unicode 方块字符输出，适用于深色背景的终端，生成失败（例如内容过长）时返回空字符串。
+//
+// Example:
+//
+//	fmt.Println(totp.KeyURI("alice@google.com", "Example").QRCodeTerminal())
+func (p KeyURI) QRCodeTerminal() string {
+	return qrCodeTerminal(p.URI().String())
+}
+
+// writeQRCode 应用 options 之后将 content 的 PNG 二维码写入 w。
+func writeQRCode(w io.Writer, content string, options ...QRCodeOption) error {
+	opts := QRCodeOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	return writeQRCodePNG(w, content, opts)
+}
+
+// qrCodeSVG 生成 content 的 SVG 二维码。
+func qrCodeSVG(content string) (string, error) {
+	bitmap, err := qrCodeBitmap(content)
+	if err != nil {
+		return "", err
+	}
+	return bitmapSVG(bitmap), nil
+}
+
+// qrCodeTerminal 生成 content 的终端二维码，失败时返回空字符串。
+func qrCodeTerminal(content string) string {
+	bitmap, err := qrCodeBitmap(content)
+	if err != nil {
+		return ""
+	}
+	return bitmapTerminal(bitmap)
+}
+
+// qrCodeBitmap 生成二维码的模块矩阵（包含四周的静区），true 表示深色模块。
+func qrCodeBitmap(content string) ([][]bool, error) {
+	modules, err := qrCodeModules(content, QRRecoveryHighest)
+	if err != nil {
+		return nil, err
+	}
+	return addBorder(modules, 0), nil
+}
+
+// bitmapSVG 将模块矩阵转换为 SVG，同一行中连续的深色模块合并为一个矩形。
+func bitmapSVG(bitmap [][]bool) string {
+	size := strconv.Itoa(len(bitmap))
+	var b strings.Builder
+	b.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 ` + size + " " + size + `" shape-rendering="crispEdges">`)
+	b.WriteString(`<rect width="` + size + `" height="` + size + `" fill="#fff"/>`)
+	b.WriteString(`<path fill="#000" d="`)
+	for y, row := range bitmap {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			b.WriteString("M" + strconv.Itoa(start) + " " + strconv.Itoa(y) + "h" + strconv.Itoa(x-start) + "v1h-" + strconv.Itoa(x-start) + "z")
+		}
+	}
+	b.WriteString(`"/></svg>`)
+	return b.String()
+}
+
+// bitmapTerminal 将模块矩阵转换为终端字符，亮色模块输出为方块，深色模块输出为空格。
+func bitmapTerminal(bitmap [][]bool) string {
+	var b strings.Builder
+	for y := 0; y < len(bitmap); y += 2 {
+		for x := range bitmap[y] {
+			top := !bitmap[y][x]
+			bottom := y+1 < len(bitmap) && !bitmap[y+1][x]
+			switch {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// qrCodePNG 将任意内容生成 PNG 格式的二维码。
+func qrCodePNG(content string, options ...QRCodeOption) ([]byte, error) {
+	opts := QRCodeOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	return qrCodePNGWithOptions(content, opts)
+}
+
+// qrCodePNGWithOptions 将任意内容按照 opts 生成 PNG 格式的二维码。
+func qrCodePNGWithOptions(content string, opts QRCodeOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeQRCodePNG(&buf, content, opts); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeQRCodePNG 将任意内容按照 opts 生成 PNG 格式的二维码并写入 w。
+func writeQRCodePNG(w io.Writer, content string, opts QRCodeOptions) error {
+	img, err := qrCodeImage(content, opts)
+	if err != nil {
+		return err
+	}
+	// 默认与 go-qrcode 一致使用 BestCompression，其压缩结果依赖 compress/flate 的实现，可能随 Go 版本变化。
+	// Deterministic 时不压缩，zlib 只会输出 stored block，结果完全由图片像素决定。
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if opts.Deterministic {
+		encoder.CompressionLevel = png.NoCompression
+	}
+	return encoder.Encode(w, img)
+}
+
+// qrCodeImage 按照 opts 绘制二维码图片，每个模块占用相同的整数个像素，除不尽的像素作为背景均分在四周。
+//
+// go-qrcode 将每个像素映射到最近的模块，模块数不能整除图片尺寸时模块宽窄不一，
+// 部分二维码（随机秘钥约 2%）的定位图形比例因此失真，无法被解码器识别。
+func qrCodeImage(content string, opts QRCodeOptions) (image.Image, error) {
+	if opts.logoPNG != nil {
+		logo, err := png.Decode(bytes.NewReader(opts.logoPNG))
+		if err != nil {
+			return nil, err
+		}
+		opts.Logo = logo
+	}
+	if opts.Logo != nil {
+		opts.RecoveryLevel = QRRecoveryHighest
+	}
+	modules, err := qrCodeModules(content, opts.RecoveryLevel)
+	if err != nil {
+		return nil, err
+	}
+	bitmap := addBorder(modules, opts.Border)
+
+	size := opts.Size
+	if size <= 0 {
+		size = 256
+	}
+	if size < len(bitmap) {
+		size = len(bitmap)
+	}
+	fg, bg := opts.Foreground, opts.Background
+	if fg == nil {
+		fg = color.Black
+	}
+	if bg == nil {
+		bg = color.White
+	}
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{bg, fg})
+	scale := size / len(bitmap)
+	offset := (size - scale*len(bitmap)) / 2
+	for y := 0; y < scale*len(bitmap); y++ {
+		row := bitmap[y/scale]
+		for x := 0; x < scale*len(bitmap); x++ {
+			if row[x/scale] {
+				img.Pix[img.PixOffset(offset+x, offset+y)] = 1
+			}
+		}
+	}
+	if opts.Logo != nil {
+		return drawLogo(img, opts.Logo, bg), nil
+	}
+	return img, nil
+}
+
+// drawLogo 将 logo 等比缩放（最近邻插值）到图片宽度的 1/5 以内，绘制在图片中心，四周保留一圈背景色。
+func drawLogo(qr image.Image, logo image.Image, bg color.Color) image.Image {
+	size := qr.Bounds().Dx()
+	img := image.NewRGBA(qr.Bounds())
+	draw.Draw(img, img.Bounds(), qr, image.Point{}, draw.Src)
+
+	lb := logo.Bounds()
+	if lb.Dx() == 0 || lb.Dy() == 0 {
+		return img
+	}
+	maxSide := size / 5
+	w, h := maxSide, maxSide*lb.Dy()/lb.Dx()
+	if lb.Dy() > lb.Dx() {
+		w, h = maxSide*lb.Dx()/lb.Dy(), maxSide
+	}
+	if w == 0 || h == 0 {
+		return img
+	}
+	x0, y0 := (size-w)/2, (size-h)/2
+	padding := size / 64
+	draw.Draw(img, image.Rect(x0-padding, y0-padding, x0+w+padding, y0+h+padding), image.NewUniform(bg), image.Point{}, draw.Src)
+	scaled := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			scaled.Set(x, y, logo.At(lb.Min.X+x*lb.Dx()/w, lb.Min.Y+y*lb.Dy()/h))
+		}
+	}
+	draw.Draw(img, image.Rect(x0, y0, x0+w, y0+h), scaled, image.Point{}, draw.Over)
+	return img
+}
+
+// addBorder 在模块矩阵四周添加静区，border 为 0 时使用默认的 4 个模块，小于 0 时不添加。
+func addBorder(bitmap [][]bool, border int) [][]bool {
+	if border == 0 {
+		border = 4
+	}
+	if border < 0 {
+		return bitmap
+	}
+	size := len(bitmap) + border*2
+	ret := make([][]bool, size)
+	for i := range ret {
+		ret[i] = make([]bool, size)
+	}
+	for y, row := range bitmap {
+		copy(ret[y+border][border:], row)
+	}
+	return ret
+}
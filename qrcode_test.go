@@ -0,0 +1,289 @@
+//go:build !noqrcode
+
+package otp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+	"github.com/stretchr/testify/assert"
+	"image"
+	"image/color"
+	"image/png"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pureBarcode 生成的图片中只有一个端正的二维码，使用 PURE_BARCODE 直接按模块采样。
+//
+// 默认的定位算法面向相机拍摄的照片，数据区中偶尔出现的类似定位图形的排列会使其失败（随机秘钥约 0.5%），
+// 而二维码本身是正确的。
+var pureBarcode = map[gozxing.DecodeHintType]interface{}{gozxing.DecodeHintType_PURE_BARCODE: true}
+
+// decodeQRCode 解析 PNG 二维码中的内容
+func decodeQRCode(t *testing.T, png []byte) string {
+	img, _, err := image.Decode(bytes.NewReader(png))
+	assert.Nil(t, err)
+	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	assert.Nil(t, err)
+	result, err := qrcode.NewQRCodeReader().Decode(bmp, pureBarcode)
+	if !assert.Nil(t, err) {
+		return ""
+	}
+	return result.String()
+}
+
+// decodeBitmap 将模块矩阵放大绘制为图片后解析其中的内容
+func decodeBitmap(t *testing.T, bitmap [][]bool) string {
+	const scale = 4
+	img := image.NewGray(image.Rect(0, 0, len(bitmap)*scale, len(bitmap)*scale))
+	for y := range bitmap {
+		for x := range bitmap[y] {
+			c := color.Gray{Y: 0xff}
+			if bitmap[y][x] {
+				c = color.Gray{}
+			}
+			for i := 0; i < scale*scale; i++ {
+				img.SetGray(x*scale+i%scale, y*scale+i/scale, c)
+			}
+		}
+	}
+	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	assert.Nil(t, err)
+	result, err := qrcode.NewQRCodeReader().Decode(bmp, pureBarcode)
+	if !assert.Nil(t, err) {
+		return ""
+	}
+	return result.String()
+}
+
+func TestKeyURI_QRCodeSVG(t *testing.T) {
+	key := NewTOTP(TestSecret20).KeyURI("alice@google.com", "Example")
+	svg, err := key.QRCodeSVG()
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 `))
+
+	// 根据 path 还原模块矩阵
+	size, _ := strconv.Atoi(regexp.MustCompile(`viewBox="0 0 (\d+)`).FindStringSubmatch(svg)[1])
+	bitmap := make([][]bool, size)
+	for i := range bitmap {
+		bitmap[i] = make([]bool, size)
+	}
+	for _, m := range regexp.MustCompile(`M(\d+) (\d+)h(\d+)`).FindAllStringSubmatch(svg, -1) {
+		x, _ := strconv.Atoi(m[1])
+		y, _ := strconv.Atoi(m[2])
+		w, _ := strconv.Atoi(m[3])
+		for i := 0; i < w; i++ {
+			bitmap[y][x+i] = true
+		}
+	}
+	assert.Equal(t, key.URI().String(), decodeBitmap(t, bitmap))
+}
+
+func TestKeyURI_QRCodeTerminal(t *testing.T) {
+	key := NewTOTP(TestSecret20).KeyURI("alice@google.com", "Example")
+	text := key.QRCodeTerminal()
+	assert.NotEqual(t, "", text)
+
+	// 每个字符表示上下两个模块，方块为亮色
+	var bitmap [][]bool
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		var top, bottom []bool
+		for _, r := range line {
+			top = append(top, r != '█' && r != '▀')
+			bottom = append(bottom, r != '█' && r != '▄')
+		}
+		bitmap = append(bitmap, top, bottom)
+	}
+	assert.Equal(t, key.URI().String(), decodeBitmap(t, bitmap[:len(bitmap[0])]))
+
+	// 内容过长无法生成二维码
+	key.Secret = strings.Repeat("A", 4000)
+	assert.Equal(t, "", key.QRCodeTerminal())
+	_, err := key.QRCodeSVG()
+	assert.NotNil(t, err)
+}
+
+func TestKeyURI_QRCodeDeterministic(t *testing.T) {
+	key := KeyURI{
+		Digits:    6,
+		Counter:   1,
+		Type:      "hotp",
+		Algorithm: "SHA1",
+		Issuer:    "Example",
+		Label:     "Example:alice@google.com",
+		Secret:    "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6",
+	}
+	png1, err := key.QRCode(WithDeterministic())
+	assert.Nil(t, err)
+	png2, err := key.QRCode(WithDeterministic())
+	assert.Nil(t, err)
+	assert.Equal(t, png1, png2)
+	assert.Equal(t, key.URI().String(), decodeQRCode(t, png1))
+
+	// golden hash，编码参数或依赖变化导致输出改变时此处会失败
+	sum := sha256.Sum256(png1)
+	assert.Equal(t, "0d20baf53a468b87de498e638ddecb9da2b54a4f57286e222771c8fffdb868bb", hex.EncodeToString(sum[:]))
+}
+
+func TestKeyURI_QRCodeWithOptions(t *testing.T) {
+	key := NewTOTP(TestSecret20).KeyURI("alice@google.com", "Example")
+
+	t.Run("default options", func(t *testing.T) {
+		png1, err := key.QRCodeWithOptions(QRCodeOptions{})
+		assert.Nil(t, err)
+		png2, err := key.QRCode()
+		assert.Nil(t, err)
+		assert.Equal(t, png1, png2)
+	})
+
+	t.Run("custom options", func(t *testing.T) {
+		fg := color.RGBA{R: 0x1a, G: 0x73, B: 0xe8, A: 0xff}
+		bg := color.RGBA{R: 0xff, G: 0xff, B: 0xee, A: 0xff}
+		png, err := key.QRCodeWithOptions(QRCodeOptions{
+			Size:          512,
+			RecoveryLevel: QRRecoveryMedium,
+			Foreground:    fg,
+			Background:    bg,
+			Border:        2,
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, key.URI().String(), decodeQRCode(t, png))
+
+		img, _, err := image.Decode(bytes.NewReader(png))
+		assert.Nil(t, err)
+		assert.Equal(t, 512, img.Bounds().Dx())
+		assert.Equal(t, color.RGBAModel.Convert(bg), color.RGBAModel.Convert(img.At(0, 0)))
+	})
+
+	t.Run("recovery level", func(t *testing.T) {
+		low, _ := qrCodeImage(key.URI().String(), QRCodeOptions{RecoveryLevel: QRRecoveryLow, Size: 1})
+		highest, _ := qrCodeImage(key.URI().String(), QRCodeOptions{Size: 1})
+		// Size 小于模块数时每个模块一个像素
+		assert.Less(t, low.Bounds().Dx(), highest.Bounds().Dx())
+	})
+
+	t.Run("border", func(t *testing.T) {
+		noBorder, _ := qrCodeImage(key.URI().String(), QRCodeOptions{Border: -1, Size: 1})
+		border, _ := qrCodeImage(key.URI().String(), QRCodeOptions{Border: 1, Size: 1})
+		assert.Equal(t, noBorder.Bounds().Dx()+2, border.Bounds().Dx())
+		// 没有静区时左上角是定位图案的深色模块
+		r, _, _, _ := noBorder.At(0, 0).RGBA()
+		assert.Equal(t, uint32(0), r)
+	})
+}
+
+func TestKeyURI_QRCodeLogo(t *testing.T) {
+	key := NewTOTP(TestSecret20).KeyURI("alice@google.com", "Example")
+	logo := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	for i := 0; i < len(logo.Pix); i += 4 {
+		logo.Pix[i], logo.Pix[i+3] = 0xff, 0xff
+	}
+
+	png1, err := key.QRCode(WithLogo(logo))
+	assert.Nil(t, err)
+	assert.Equal(t, key.URI().String(), decodeQRCode(t, png1))
+
+	// 中心像素被 logo 覆盖
+	img, _, _ := image.Decode(bytes.NewReader(png1))
+	center := img.Bounds().Dx() / 2
+	assert.Equal(t, color.RGBAModel.Convert(color.RGBA{R: 0xff, A: 0xff}), color.RGBAModel.Convert(img.At(center, center)))
+
+	// 即使指定了较低的纠错等级，也会使用最高等级
+	png2, err := key.QRCodeWithOptions(QRCodeOptions{Logo: logo, RecoveryLevel: QRRecoveryLow})
+	assert.Nil(t, err)
+	assert.Equal(t, png1, png2)
+
+	t.Run("png logo", func(t *testing.T) {
+		var buf bytes.Buffer
+		assert.Nil(t, png.Encode(&buf, logo))
+		png3, err := key.QRCode(WithLogoPNG(buf.Bytes()))
+		assert.Nil(t, err)
+		assert.Equal(t, png1, png3)
+
+		_, err = key.QRCode(WithLogoPNG([]byte("not a png")))
+		assert.NotNil(t, err)
+	})
+}
+
+func TestKeyURI_WriteQRCode(t *testing.T) {
+	key := NewTOTP(TestSecret20).KeyURI("alice@google.com", "Example")
+	var buf bytes.Buffer
+	assert.Nil(t, key.WriteQRCode(&buf, WithDeterministic()))
+	expected, err := key.QRCode(WithDeterministic())
+	assert.Nil(t, err)
+	assert.Equal(t, expected, buf.Bytes())
+
+	// 生成失败时不写入数据
+	buf.Reset()
+	key.Secret = strings.Repeat("A", 4000)
+	assert.NotNil(t, key.WriteQRCode(&buf))
+	assert.Equal(t, 0, buf.Len())
+}
+
+func TestKeyURI_QRCode(t *testing.T) {
+	expected := "otpauth://hotp/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&issuer=Example&counter=1"
+	key := KeyURI{
+		Digits:    6,
+		Counter:   1,
+		Type:      "hotp",
+		Algorithm: "SHA1",
+		Issuer:    "Example",
+		Label:     "Example:alice@google.com",
+		Secret:    "J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6",
+	}
+	png, err := key.QRCode()
+	assert.Nil(t, err)
+	img, _, err := image.Decode(bytes.NewReader(png))
+	assert.Nil(t, err)
+	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	assert.Nil(t, err)
+	qrReader := qrcode.NewQRCodeReader()
+	result, err := qrReader.Decode(bmp, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, result.String())
+}
+
+func TestSignedKeyURI_QRCode(t *testing.T) {
+	signer, err := NewProvisioningSigner([]byte("server-side-signing-key-0123456789"), 10*time.Minute)
+	assert.Nil(t, err)
+	key := NewTOTP(TestSecret20).KeyURI("alice@google.com", "Example")
+	signed := signer.Sign(key, time.Unix(1704075000, 0))
+	uri := signed.URI().String()
+
+	png, err := signed.QRCode()
+	assert.Nil(t, err)
+	assert.Equal(t, uri, decodeQRCode(t, png))
+
+	var buf bytes.Buffer
+	assert.Nil(t, signed.WriteQRCode(&buf, WithDeterministic()))
+	assert.Equal(t, uri, decodeQRCode(t, buf.Bytes()))
+
+	png, err = signed.QRCodeWithOptions(QRCodeOptions{Size: 512})
+	assert.Nil(t, err)
+	assert.Equal(t, uri, decodeQRCode(t, png))
+
+	svg, err := signed.QRCodeSVG()
+	assert.Nil(t, err)
+	expected, _ := qrCodeSVG(uri)
+	assert.Equal(t, expected, svg)
+	assert.Equal(t, qrCodeTerminal(uri), signed.QRCodeTerminal())
+	assert.NotEqual(t, key.QRCodeTerminal(), signed.QRCodeTerminal())
+}
+
+func TestProvisioner_QRCode(t *testing.T) {
+	enrollments, err := NewProvisioner("Example", WithDigits(DigitsEight)).Provision("alice@google.com", "bob@google.com")
+	assert.Nil(t, err)
+	for _, e := range enrollments {
+		assert.Equal(t, e.URI, decodeQRCode(t, e.QRCode))
+	}
+
+	enrollment, err := NewEnrollment("alice@google.com", "Example", 10*time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, enrollment.URI, decodeQRCode(t, enrollment.QRCode))
+}
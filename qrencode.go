@@ -0,0 +1,34 @@
+//go:build !noqrcode
+
+package otp
+
+import "github.com/skip2/go-qrcode"
+
+// qrCodeAvailable 当前构建是否包含二维码编码器。
+const qrCodeAvailable = true
+
+// qrCodeModules 使用 go-qrcode 对内容进行编码，返回不包含静区的模块矩阵，true 表示深色模块。
+//
+// 这是唯一依赖 go-qrcode 的地方，使用 noqrcode 构建标签时由 qrencode_noqrcode.go 替换。
+func qrCodeModules(content string, level QRRecoveryLevel) ([][]bool, error) {
+	code, err := qrcode.New(content, level.level())
+	if err != nil {
+		return nil, err
+	}
+	code.DisableBorder = true
+	return code.Bitmap(), nil
+}
+
+// level 转换为 go-qrcode 的纠错等级。
+func (l QRRecoveryLevel) level() qrcode.RecoveryLevel {
+	switch l {
+	case QRRecoveryLow:
+		return qrcode.Low
+	case QRRecoveryMedium:
+		return qrcode.Medium
+	case QRRecoveryHigh:
+		return qrcode.High
+	default:
+		return qrcode.Highest
+	}
+}
@@ -0,0 +1,11 @@
+//go:build noqrcode
+
+package otp
+
+// qrCodeAvailable 当前构建是否包含二维码编码器。
+const qrCodeAvailable = false
+
+// qrCodeModules 使用 noqrcode 构建标签时不包含二维码编码器，所有生成二维码的方法都返回 ErrQRCodeUnavailable。
+func qrCodeModules(string, QRRecoveryLevel) ([][]bool, error) {
+	return nil, ErrQRCodeUnavailable
+}
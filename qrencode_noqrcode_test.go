@@ -0,0 +1,32 @@
+//go:build noqrcode
+
+package otp
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNoQRCode(t *testing.T) {
+	key := NewTOTP(TestSecret20).KeyURI("alice@google.com", "Example")
+	assert.Equal(t, "otpauth://totp/Example:alice@google.com?secret=J3W2XPZP5HDYXYRB4HS6ZLU6M6VBO6C6&issuer=Example", key.URI().String())
+
+	_, err := key.QRCode()
+	assert.ErrorIs(t, err, ErrQRCodeUnavailable)
+	_, err = key.QRCodeWithOptions(QRCodeOptions{Size: 512})
+	assert.ErrorIs(t, err, ErrQRCodeUnavailable)
+	_, err = key.QRCodeSVG()
+	assert.ErrorIs(t, err, ErrQRCodeUnavailable)
+	assert.Equal(t, "", key.QRCodeTerminal())
+	var buf bytes.Buffer
+	assert.ErrorIs(t, key.WriteQRCode(&buf), ErrQRCodeUnavailable)
+	assert.Zero(t, buf.Len())
+
+	// 开通时跳过二维码，只返回 URI
+	enrollment, err := NewEnrollment("alice@google.com", "Example", time.Minute)
+	assert.Nil(t, err)
+	assert.Nil(t, enrollment.QRCode)
+	assert.NotEmpty(t, enrollment.URI)
+}
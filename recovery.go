@@ -0,0 +1,170 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"unicode"
+)
+
+// RecoveryFormat 备用恢复码的格式。
+type RecoveryFormat int
+
+const (
+	// RecoveryFormatAlphanumeric 10 个字符的字母数字组合，例如 "7KQ4M-XR92C"，约 50 位熵。
+	// 字母表去掉了容易混淆的 0、1、I、O。
+	RecoveryFormatAlphanumeric RecoveryFormat = iota
+	// RecoveryFormatNumeric 10 位数字，例如 "48213-90577"，约 33 位熵，适合只能输入数字的场景。
+	RecoveryFormatNumeric
+)
+
+// recoveryAlphabet 恢复码使用的字母表，刚好 32 个字符，取随机字节的低 5 位即可均匀分布。
+const recoveryAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// recoveryCodeLength 恢复码的长度（不包含分隔符），每 5 个字符使用 - 分隔。
+const recoveryCodeLength = 10
+
+// GenerateRecoveryCodes 生成 n 个随机的一次性恢复码，用于用户丢失验证器时登录。
+//
+// 恢复码应该只展示给用户一次，服务端使用 HashRecoveryCode 保存摘要，使用之后立刻删除对应的摘要。
+// 系统随机数生成器出错时返回错误。
+//
+// Example:
+//
+//	codes, err := GenerateRecoveryCodes(10, RecoveryFormatAlphanumeric)
+//	if err != nil {
+//		return err
+//	}
+//	for _, code := range codes {
+//		hashed, err := HashRecoveryCode(code)
+//		if err != nil {
+//			return err
+//		}
+//		save(hashed)
+//	}
+func GenerateRecoveryCodes(n int, format RecoveryFormat) ([]string, error) {
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		code, err := generateRecoveryCode(format)
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode 生成一个恢复码。
+func generateRecoveryCode(format RecoveryFormat) (string, error) {
+	code := make([]byte, 0, recoveryCodeLength+1)
+	buf := make([]byte, recoveryCodeLength)
+	for len(code) < recoveryCodeLength+1 {
+		random, err := randomSecret(len(buf))
+		if err != nil {
+			return "", err
+		}
+		for _, b := range random {
+			if len(code) == recoveryCodeLength/2 {
+				code = append(code, '-')
+			}
+			if len(code) == recoveryCodeLength+1 {
+				break
+			}
+			switch format {
+			case RecoveryFormatNumeric:
+				// 拒绝 250 及以上的值，避免取模带来的偏差
+				if b < 250 {
+					code = append(code, '0'+b%10)
+				}
+			default:
+				code = append(code, recoveryAlphabet[b&0x1f])
+			}
+		}
+	}
+	return string(code), nil
+}
+
+// NormalizeRecoveryCode 去掉恢复码中的空白字符和连字符并转换为大写，HashRecoveryCode 和 VerifyRecoveryCode 都会先规范化。
+func NormalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToUpper(r)
+	}, code)
+}
+
+// recoveryHashPrefix HashRecoveryCode 返回的摘要的前缀。
+const recoveryHashPrefix = "$hmac-sha256$"
+
+// recoverySaltLength HashRecoveryCode 使用的随机盐值的长度。
+const recoverySaltLength = 16
+
+// HashRecoveryCode 使用随机盐值计算恢复码的 HMAC-SHA256 摘要，返回值包含盐值，可以直接保存。
+//
+// 恢复码是随机生成的，不需要像密码那样使用 Argon2 等慢哈希，校验的开销与普通的 HMAC 相同，
+// MatchRecoveryCode 遍历所有摘要也不会成为拒绝服务的放大器。摘要泄露之后可以离线穷举，
+// 只有约 33 位熵的 RecoveryFormatNumeric 需要与秘钥一样限制访问。系统随机数生成器出错时返回错误。
+//
+// Example:
+//
+//	$hmac-sha256$c29tZXNhbHRzb21lc2FsdA$jm4W/I2xVTSQ/U2Zfw5Eq7oK024yxmpiF4wRiHBisd8
+func HashRecoveryCode(code string) (string, error) {
+	salt, err := randomSecret(recoverySaltLength)
+	if err != nil {
+		return "", err
+	}
+	return hashRecoveryCode(code, salt), nil
+}
+
+// hashRecoveryCode 使用指定的盐值计算恢复码的摘要。
+func hashRecoveryCode(code string, salt []byte) string {
+	return recoveryHashPrefix + base64.RawStdEncoding.EncodeToString(salt) + "$" +
+		base64.RawStdEncoding.EncodeToString(recoveryMAC(code, salt))
+}
+
+// recoveryMAC 计算规范化之后的恢复码的 HMAC-SHA256，盐值作为秘钥。
+func recoveryMAC(code string, salt []byte) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(NormalizeRecoveryCode(code)))
+	return mac.Sum(nil)
+}
+
+// VerifyRecoveryCode 校验恢复码是否与 HashRecoveryCode 返回的摘要匹配，使用常量时间比较。
+//
+// 摘要格式错误时返回 false。校验通过后调用方需要删除该摘要，保证恢复码只能使用一次。
+func VerifyRecoveryCode(code, hashed string) bool {
+	if code == "" || !strings.HasPrefix(hashed, recoveryHashPrefix) {
+		return false
+	}
+	salt, sum, ok := strings.Cut(hashed[len(recoveryHashPrefix):], "$")
+	if !ok {
+		return false
+	}
+	decodedSalt, err := base64.RawStdEncoding.DecodeString(salt)
+	if err != nil || len(decodedSalt) == 0 {
+		return false
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(sum)
+	if err != nil || len(expected) != sha256.Size {
+		return false
+	}
+	return hmac.Equal(recoveryMAC(code, decodedSalt), expected)
+}
+
+// MatchRecoveryCode 在一组摘要中查找与恢复码匹配的项，返回其下标，未找到时返回 -1。
+//
+// Example:
+//
+//	if i := MatchRecoveryCode(code, hashes); i >= 0 {
+//		hashes = append(hashes[:i], hashes[i+1:]...) // 删除已使用的恢复码
+//	}
+func MatchRecoveryCode(code string, hashes []string) int {
+	for i, hashed := range hashes {
+		if VerifyRecoveryCode(code, hashed) {
+			return i
+		}
+	}
+	return -1
+}
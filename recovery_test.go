@@ -0,0 +1,84 @@
+package otp
+
+import (
+	"crypto/rand"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+	"testing/iotest"
+)
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(20, RecoveryFormatAlphanumeric)
+	assert.Nil(t, err)
+	assert.Len(t, codes, 20)
+	seen := map[string]bool{}
+	for _, code := range codes {
+		assert.Regexp(t, regexp.MustCompile(`^[2-9A-HJ-NP-Z]{5}-[2-9A-HJ-NP-Z]{5}$`), code)
+		assert.False(t, seen[code])
+		seen[code] = true
+	}
+	codes, err = GenerateRecoveryCodes(20, RecoveryFormatNumeric)
+	assert.Nil(t, err)
+	for _, code := range codes {
+		assert.Regexp(t, regexp.MustCompile(`^[0-9]{5}-[0-9]{5}$`), code)
+	}
+	codes, err = GenerateRecoveryCodes(0, RecoveryFormatNumeric)
+	assert.Nil(t, err)
+	assert.Len(t, codes, 0)
+}
+
+func TestGenerateRecoveryCodes_RandError(t *testing.T) {
+	errRand := errors.New("no entropy")
+	randReader = iotest.ErrReader(errRand)
+	defer func() { randReader = rand.Reader }()
+
+	codes, err := GenerateRecoveryCodes(3, RecoveryFormatAlphanumeric)
+	assert.ErrorIs(t, err, errRand)
+	assert.Nil(t, codes)
+	hashed, err := HashRecoveryCode("7KQ4M-XR92C")
+	assert.ErrorIs(t, err, errRand)
+	assert.Equal(t, "", hashed)
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	assert.Equal(t, "7KQ4MXR92C", NormalizeRecoveryCode(" 7kq4m-xr92c "))
+	assert.Equal(t, "4821390577", NormalizeRecoveryCode("48213 90577"))
+}
+
+func TestVerifyRecoveryCode(t *testing.T) {
+	codes, _ := GenerateRecoveryCodes(1, RecoveryFormatAlphanumeric)
+	code := codes[0]
+	hashed, err := HashRecoveryCode(code)
+	assert.Nil(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^\$hmac-sha256\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`), hashed)
+	other, _ := HashRecoveryCode(code)
+	assert.NotEqual(t, hashed, other)
+
+	assert.True(t, VerifyRecoveryCode(code, hashed))
+	assert.True(t, VerifyRecoveryCode(NormalizeRecoveryCode(code), hashed))
+	assert.False(t, VerifyRecoveryCode("22222-22222", hashed))
+	assert.False(t, VerifyRecoveryCode("", hashed))
+	assert.False(t, VerifyRecoveryCode(code, ""))
+	assert.False(t, VerifyRecoveryCode(code, hashed[:len(hashed)-2]))
+	assert.False(t, VerifyRecoveryCode(code, "$hmac-sha256$c29tZXNhbHQ$!!!"))
+	assert.False(t, VerifyRecoveryCode(code, "$argon2id$v=19$m=1024,t=1,p=1,l=20$c29tZXNhbHQ$2cBlHMpMQ4XyzOMNo6MMG0RlnFE"))
+
+	// 固定盐值的摘要
+	assert.Equal(t, "$hmac-sha256$c29tZXNhbHRzb21lc2FsdA$jm4W/I2xVTSQ/U2Zfw5Eq7oK024yxmpiF4wRiHBisd8", hashRecoveryCode("7KQ4M-XR92C", []byte("somesaltsomesalt")))
+	assert.True(t, VerifyRecoveryCode("7kq4m xr92c", "$hmac-sha256$c29tZXNhbHRzb21lc2FsdA$jm4W/I2xVTSQ/U2Zfw5Eq7oK024yxmpiF4wRiHBisd8"))
+}
+
+func TestMatchRecoveryCode(t *testing.T) {
+	codes, _ := GenerateRecoveryCodes(3, RecoveryFormatNumeric)
+	hashes := make([]string, 0, len(codes))
+	for _, code := range codes {
+		hashed, err := HashRecoveryCode(code)
+		assert.Nil(t, err)
+		hashes = append(hashes, hashed)
+	}
+	assert.Equal(t, 2, MatchRecoveryCode(codes[2], hashes))
+	assert.Equal(t, -1, MatchRecoveryCode("abc", hashes))
+	assert.Equal(t, -1, MatchRecoveryCode(codes[0], nil))
+}
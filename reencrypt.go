@@ -0,0 +1,93 @@
+package otp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ReencryptProgress ReencryptAll 的进度，每处理完一个账户更新一次。
+type ReencryptProgress struct {
+	// 需要处理的账户数，即 ids 的长度
+	Total int
+	// 已经处理的账户数，中断之后可以传入 ids[Done:] 继续
+	Done int
+	// 使用新秘钥重新加密的账户数
+	Reencrypted int
+	// 已经使用新秘钥加密或者不存在而跳过的账户数
+	Skipped int
+	// 最后一个处理的账户 id
+	ID string
+}
+
+// ReencryptAll 将 ids 对应的秘钥从 oldKey 加密改为使用 s 的秘钥加密，用于轮换 KEK（秘钥加密秘钥）。
+//
+// s 与 oldKey 使用同一个底层存储。每个账户先尝试使用新秘钥解密，成功时说明已经处理过，直接跳过；
+// 否则使用 oldKey 解密后重新加密保存。因此中断之后重新执行是安全的，已经处理过的账户不会被重复加密，
+// 也可以根据返回的 Done 只传入剩余的 ids 以减少读取。底层存储中不存在的账户同样跳过。
+//
+// progress 不为 nil 时每处理完一个账户调用一次。两个秘钥都无法解密时返回包含账户 id 的 ErrSecretDecrypt，
+// ctx 被取消或底层存储出错时返回对应的错误，返回的 ReencryptProgress 为出错之前的进度。
+//
+// 轮换期间仍在使用旧秘钥的实例无法读取已经处理过的账户，需要先停止写入或者在所有实例切换到新秘钥之后再执行。
+//
+// Example:
+//
+//	store, err := NewEncryptedSecretStore(newKEK, backend)
+//	done, err := store.ReencryptAll(ctx, oldKEK, ids, func(p ReencryptProgress) {
+//		log.Printf("reencrypt %d/%d", p.Done, p.Total)
+//	})
+//	if err != nil {
+//		// 修复之后使用 ids[done.Done:] 继续
+//	}
+func (s *EncryptedSecretStore) ReencryptAll(ctx context.Context, oldKey []byte, ids []string, progress func(ReencryptProgress)) (ReencryptProgress, error) {
+	old, err := NewEncryptedSecretStore(oldKey, s.backend)
+	if err != nil {
+		return ReencryptProgress{Total: len(ids)}, err
+	}
+	result := ReencryptProgress{Total: len(ids)}
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		reencrypted, err := s.reencrypt(ctx, old, id)
+		if err != nil {
+			return result, err
+		}
+		if reencrypted {
+			result.Reencrypted++
+		} else {
+			result.Skipped++
+		}
+		result.Done++
+		result.ID = id
+		if progress != nil {
+			progress(result)
+		}
+	}
+	return result, nil
+}
+
+// reencrypt 使用 old 解密 id 对应的密文后使用 s 重新加密，已经使用 s 加密或者不存在时返回 false。
+func (s *EncryptedSecretStore) reencrypt(ctx context.Context, old *EncryptedSecretStore, id string) (bool, error) {
+	data, err := getSecretContext(ctx, s.backend, id)
+	if errors.Is(err, ErrSecretNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("reencrypt %q: %w", id, err)
+	}
+	if secret, err := s.decrypt(id, data); err == nil {
+		zero(secret)
+		return false, nil
+	}
+	secret, err := old.decrypt(id, data)
+	if err != nil {
+		return false, fmt.Errorf("reencrypt %q: %w", id, err)
+	}
+	defer zero(secret)
+	if err := s.Put(id, secret); err != nil {
+		return false, fmt.Errorf("reencrypt %q: %w", id, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,83 @@
+package otp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// failingPutStore 在第 n 次 Put 时返回错误。
+type failingPutStore struct {
+	*MemorySecretStore
+	puts int
+	n    int
+}
+
+func (s *failingPutStore) Put(id string, secret []byte) error {
+	s.puts++
+	if s.puts == s.n {
+		return errBackend
+	}
+	return s.MemorySecretStore.Put(id, secret)
+}
+
+func TestEncryptedSecretStore_ReencryptAll(t *testing.T) {
+	oldKey, newKey := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+	backend := &failingPutStore{MemorySecretStore: NewMemorySecretStore()}
+	old, _ := NewEncryptedSecretStore(oldKey, backend)
+	ids := []string{"alice", "bob", "carol", "dave"}
+	for _, id := range ids[:3] {
+		assert.Nil(t, old.Put(id, []byte("secret-of-"+id)))
+	}
+	store, err := NewEncryptedSecretStore(newKey, backend)
+	assert.Nil(t, err)
+
+	// 第二个账户保存失败时中断
+	backend.puts, backend.n = 0, 2
+	var reports []ReencryptProgress
+	done, err := store.ReencryptAll(context.Background(), oldKey, ids, func(p ReencryptProgress) { reports = append(reports, p) })
+	assert.ErrorIs(t, err, errBackend)
+	assert.Contains(t, err.Error(), `"bob"`)
+	assert.Equal(t, ReencryptProgress{Total: 4, Done: 1, Reencrypted: 1, ID: "alice"}, done)
+	assert.Equal(t, []ReencryptProgress{done}, reports)
+
+	// 重新执行时跳过已经处理过的账户，不存在的账户同样跳过
+	reports = nil
+	done, err = store.ReencryptAll(context.Background(), oldKey, ids, func(p ReencryptProgress) { reports = append(reports, p) })
+	assert.Nil(t, err)
+	assert.Equal(t, ReencryptProgress{Total: 4, Done: 4, Reencrypted: 2, Skipped: 2, ID: "dave"}, done)
+	assert.Len(t, reports, 4)
+	for _, id := range ids[:3] {
+		secret, err := store.Get(id)
+		assert.Nil(t, err)
+		assert.Equal(t, []byte("secret-of-"+id), secret)
+		_, err = old.Get(id)
+		assert.Equal(t, ErrSecretDecrypt, err)
+	}
+
+	done, err = store.ReencryptAll(context.Background(), oldKey, ids, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, done.Reencrypted)
+	assert.Equal(t, 4, done.Skipped)
+}
+
+func TestEncryptedSecretStore_ReencryptAllErrors(t *testing.T) {
+	backend := NewMemorySecretStore()
+	store, _ := NewEncryptedSecretStore(bytes.Repeat([]byte{2}, 32), backend)
+	_, err := store.ReencryptAll(context.Background(), []byte("short"), []string{"alice"}, nil)
+	assert.NotNil(t, err)
+
+	// 两个秘钥都无法解密
+	other, _ := NewEncryptedSecretStore(bytes.Repeat([]byte{3}, 32), backend)
+	assert.Nil(t, other.Put("alice", []byte("12345678901234567890")))
+	done, err := store.ReencryptAll(context.Background(), bytes.Repeat([]byte{1}, 32), []string{"alice"}, nil)
+	assert.ErrorIs(t, err, ErrSecretDecrypt)
+	assert.Equal(t, 0, done.Done)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = store.ReencryptAll(ctx, bytes.Repeat([]byte{1}, 32), []string{"alice"}, nil)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
@@ -0,0 +1,54 @@
+package otp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+// ReplayGuard 防止同一个 token 被重复使用。
+//
+// 配置了 WithReplayGuard 之后，TOTP 和 HOTP 的 Verify 在 token 校验通过时会调用 Use，
+// 如果返回 false 则认为 token 已经被使用过，校验失败。
+type ReplayGuard interface {
+	// Use 记录 key 对应的账户使用了时间步（TOTP）或计数器（HOTP）step。
+	// 如果 step 已经被使用过（或者早于最后一次使用的 step）应该返回 false。
+	Use(key string, step int64) bool
+}
+
+// MemoryReplayGuard 基于内存的 ReplayGuard 实现，为每个账户记录最后一次使用的 step，并发安全。
+//
+// 只适用于单实例部署，多实例部署时需要基于共享存储实现 ReplayGuard。
+type MemoryReplayGuard struct {
+	mu    sync.Mutex
+	steps map[string]int64
+}
+
+// NewMemoryReplayGuard 创建一个 MemoryReplayGuard。
+//
+// Example:
+//
+//	guard := NewMemoryReplayGuard()
+//	totp  := NewTOTP(secret, WithReplayGuard(guard))
+//	totp.Verify(token, time.Now()) // true
+//	totp.Verify(token, time.Now()) // false
+func NewMemoryReplayGuard() *MemoryReplayGuard {
+	return &MemoryReplayGuard{steps: map[string]int64{}}
+}
+
+// Use 实现 ReplayGuard 接口，step 必须大于最后一次使用的 step。
+func (g *MemoryReplayGuard) Use(key string, step int64) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if last, ok := g.steps[key]; ok && step <= last {
+		return false
+	}
+	g.steps[key] = step
+	return true
+}
+
+// replayKey 使用秘钥的摘要标识账户，避免 ReplayGuard 中保存明文秘钥。
+func replayKey(decodedSecret []byte) string {
+	sum := sha256.Sum256(decodedSecret)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,66 @@
+package otp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryReplayGuard(t *testing.T) {
+	guard := NewMemoryReplayGuard()
+	assert.Equal(t, true, guard.Use("a", 10))
+	assert.Equal(t, false, guard.Use("a", 10))
+	assert.Equal(t, false, guard.Use("a", 9))
+	assert.Equal(t, true, guard.Use("a", 11))
+	assert.Equal(t, true, guard.Use("b", 10))
+
+	t.Run("concurrent use", func(t *testing.T) {
+		guard := NewMemoryReplayGuard()
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		accepted := 0
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if guard.Use("a", 1) {
+					mu.Lock()
+					accepted++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, 1, accepted)
+	})
+}
+
+func TestWithReplayGuard(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+
+	t.Run("totp", func(t *testing.T) {
+		guard := NewMemoryReplayGuard()
+		totp := NewTOTP(TestSecret20, WithSkew(1), WithReplayGuard(guard))
+		assert.Equal(t, true, totp.Verify("076141", now))
+		assert.Equal(t, false, totp.Verify("076141", now))
+		// 相邻窗口中仍然是同一个 token
+		assert.Equal(t, false, totp.Verify("076141", now.Add(time.Second*30)))
+		// 同一个秘钥创建的其他实例共享使用记录
+		assert.Equal(t, false, NewTOTP(TestSecret20, WithReplayGuard(guard)).Verify("076141", now))
+		// 新的时间窗口
+		next := now.Add(time.Second * 30)
+		assert.Equal(t, true, totp.Verify(totp.At(next), next))
+		// 错误的 token 不会消耗使用记录
+		assert.Equal(t, false, NewTOTP(TestSecret32, WithReplayGuard(guard)).Verify("000000", now))
+		assert.Equal(t, true, NewTOTP(TestSecret32, WithReplayGuard(guard)).Verify(NewTOTP(TestSecret32).At(now), now))
+	})
+
+	t.Run("hotp", func(t *testing.T) {
+		hotp := NewHOTP(TestSecret20, WithSkew(2), WithReplayGuard(NewMemoryReplayGuard()))
+		assert.Equal(t, true, hotp.Verify(hotp.At(3), 2))
+		assert.Equal(t, false, hotp.Verify(hotp.At(3), 2))
+		assert.Equal(t, false, hotp.Verify(hotp.At(2), 2))
+		assert.Equal(t, true, hotp.Verify(hotp.At(4), 3))
+	})
+}
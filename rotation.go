@@ -0,0 +1,89 @@
+package otp
+
+import "time"
+
+// RotatingTOTP 服务端轮换秘钥时使用，在宽限期内同时接受新秘钥和旧秘钥生成的 token。
+//
+// 轮换之后将 KeyURI 返回的新 URI 下发给用户，用户在验证器应用中更新之前仍然可以使用旧秘钥登录，
+// 宽限期结束或者用户第一次使用新秘钥登录之后，旧秘钥不再被接受。
+type RotatingTOTP struct {
+	// 新秘钥
+	Current *TOTP
+	// 旧秘钥，为 nil 时表示没有进行中的轮换
+	Previous *TOTP
+	// 轮换的时间
+	RotatedAt time.Time
+	// 宽限期，RotatedAt 之后的这段时间内接受旧秘钥
+	GracePeriod time.Duration
+}
+
+// RotateTOTP 为 previous 生成一个新的随机秘钥，新秘钥与 previous 的字节数和参数（Digits、Period、Skew 等）保持一致。
+//
+// Example:
+//
+//	rotating := RotateTOTP(totp, time.Now(), 7*24*time.Hour)
+//	save(rotating.Current.Secret, rotating.Previous.Secret, rotating.RotatedAt)
+//	send(rotating.KeyURI("alice@google.com", "Example").QRCode())
+func RotateTOTP(previous *TOTP, rotatedAt time.Time, gracePeriod time.Duration) *RotatingTOTP {
+	size := len(previous.decodedSecret)
+	if size < 20 {
+		size = 20
+	}
+	current := NewTOTPFromBytes(RandomSecret(size))
+	current.Otp = previous.Otp
+	return NewRotatingTOTP(current, previous, rotatedAt, gracePeriod)
+}
+
+// NewRotatingTOTP 使用已有的新旧秘钥创建 RotatingTOTP，通常用于从持久化的数据中恢复轮换状态。
+func NewRotatingTOTP(current, previous *TOTP, rotatedAt time.Time, gracePeriod time.Duration) *RotatingTOTP {
+	return &RotatingTOTP{
+		Current:     current,
+		Previous:    previous,
+		RotatedAt:   rotatedAt,
+		GracePeriod: gracePeriod,
+	}
+}
+
+// InGracePeriod 判断 t 是否处于宽限期内，没有旧秘钥时返回 false。
+func (r *RotatingTOTP) InGracePeriod(t time.Time) bool {
+	return r.Previous != nil && t.Before(r.RotatedAt.Add(r.GracePeriod))
+}
+
+// Verify 校验 token 在 t 时是否有效，宽限期内旧秘钥生成的 token 也认为有效。
+func (r *RotatingTOTP) Verify(token string, t time.Time) bool {
+	ok, _ := r.VerifyWithSecret(token, t)
+	return ok
+}
+
+// VerifyWithSecret 与 Verify 相同，额外返回 token 是否由新秘钥生成。
+//
+// token 由新秘钥生成时说明用户已经更新了验证器应用，调用方可以调用 Complete 提前结束轮换。
+//
+// Example:
+//
+//	ok, current := rotating.VerifyWithSecret(token, time.Now())
+//	if ok && current {
+//		rotating.Complete()
+//	}
+func (r *RotatingTOTP) VerifyWithSecret(token string, t time.Time) (ok bool, current bool) {
+	if r.Current.Verify(token, t) {
+		return true, true
+	}
+	if r.InGracePeriod(t) && r.Previous.Verify(token, t) {
+		return true, false
+	}
+	return false, false
+}
+
+// Complete 结束轮换，清除旧秘钥，之后只接受新秘钥生成的 token。
+func (r *RotatingTOTP) Complete() {
+	if r.Previous != nil {
+		r.Previous.Wipe()
+		r.Previous = nil
+	}
+}
+
+// KeyURI 返回新秘钥的 KeyURI，用于重新下发给用户，参数与 TOTP.KeyURI 相同。
+func (r *RotatingTOTP) KeyURI(override ...string) *KeyURI {
+	return r.Current.KeyURI(override...)
+}
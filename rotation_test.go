@@ -0,0 +1,59 @@
+package otp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRotateTOTP(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+	previous := NewTOTP(TestSecret32, WithDigits(DigitsEight), WithSkew(1))
+	rotating := RotateTOTP(previous, now, time.Hour)
+
+	assert.Same(t, previous, rotating.Previous)
+	assert.NotEqual(t, previous.Secret, rotating.Current.Secret)
+	assert.Len(t, rotating.Current.decodedSecret, 32)
+	assert.Equal(t, DigitsEight, rotating.Current.Digits)
+	assert.Equal(t, 1, rotating.Current.Skew)
+	assert.Len(t, RotateTOTP(NewTOTP("JBSWY3DPEE"), now, time.Hour).Current.decodedSecret, 20)
+
+	key := rotating.KeyURI("alice@google.com", "Example")
+	assert.Equal(t, rotating.Current.Secret, key.Secret)
+	assert.Equal(t, 8, key.Digits)
+}
+
+func TestRotatingTOTP_Verify(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+	previous := NewTOTP(TestSecret20)
+	rotating := RotateTOTP(previous, now, time.Hour)
+
+	ok, current := rotating.VerifyWithSecret("076141", now)
+	assert.True(t, ok)
+	assert.False(t, current)
+	ok, current = rotating.VerifyWithSecret(rotating.Current.At(now), now)
+	assert.True(t, ok)
+	assert.True(t, current)
+	assert.False(t, rotating.Verify("", now))
+
+	// 宽限期结束后不再接受旧秘钥
+	later := now.Add(time.Hour)
+	assert.False(t, rotating.InGracePeriod(later))
+	assert.False(t, rotating.Verify(previous.At(later), later))
+	assert.True(t, rotating.Verify(rotating.Current.At(later), later))
+
+	// 提前结束轮换
+	rotating.Complete()
+	assert.Nil(t, rotating.Previous)
+	assert.False(t, rotating.InGracePeriod(now))
+	assert.False(t, rotating.Verify("076141", now))
+	rotating.Complete()
+}
+
+func TestNewRotatingTOTP(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+	rotating := NewRotatingTOTP(NewTOTP(TestSecret32), NewTOTP(TestSecret20), now.Add(-time.Minute), time.Hour)
+	assert.True(t, rotating.Verify("076141", now))
+	assert.True(t, rotating.Verify(NewTOTP(TestSecret32).At(now), now))
+	assert.False(t, NewRotatingTOTP(NewTOTP(TestSecret32), nil, now, time.Hour).Verify("076141", now))
+}
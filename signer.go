@@ -0,0 +1,93 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"fmt"
+)
+
+// Signer 计算 HMAC 的后端，用于将秘钥保存在 HSM、KMS 或 PKCS#11 设备中。
+//
+// Sign 的参数是 8 字节大端序的计数器（TOTP 为时间步），返回 HMAC 的结果，
+// 使用的哈希算法需要与 TOTP、HOTP 配置的 Algorithm 保持一致（决定截断使用的字节）。
+type Signer interface {
+	Sign(counter []byte) ([]byte, error)
+}
+
+// HMACSigner 在进程内计算 HMAC 的默认 Signer 实现。
+type HMACSigner struct {
+	algorithm Algorithms
+	secret    []byte
+}
+
+// NewHMACSigner 创建一个 HMACSigner，秘钥会被复制。
+//
+// Panic:
+//   - secret is empty
+func NewHMACSigner(algorithm Algorithms, secret []byte) *HMACSigner {
+	if len(secret) == 0 {
+		panic(ErrSecretCannotBeEmpty)
+	}
+	return &HMACSigner{algorithm: algorithm, secret: append([]byte(nil), secret...)}
+}
+
+// Sign 实现 Signer 接口。
+func (s *HMACSigner) Sign(counter []byte) ([]byte, error) {
+	mac := hmac.New(hasher(s.algorithm), s.secret)
+	mac.Write(counter)
+	return mac.Sum(nil), nil
+}
+
+// NewTOTPWithSigner 创建一个使用 signer 计算 HMAC 的 TOTP 结构体，其余参数与 NewTOTP 一致。
+//
+// 秘钥不在进程内，Secret 字段为空，KeyURI 返回的 URI 也不包含秘钥，开通时需要由 HSM 或 KMS 导出。
+// signer 出错时 At 返回空字符串，VerifyContext 返回对应的错误。
+//
+// Example:
+//
+//	totp := NewTOTPWithSigner(kmsSigner, WithAlgorithm(AlgorithmSHA256))
+//	ok, err := totp.VerifyContext(ctx, token, time.Now())
+func NewTOTPWithSigner(signer Signer, options ...TOTPOption) *TOTP {
+	return &TOTP{Otp: newTOTPOtp(options), signer: signer}
+}
+
+// NewHOTPWithSigner 创建一个使用 signer 计算 HMAC 的 HOTP 结构体，其余参数与 NewHOTP 一致。
+//
+// 限制与 NewTOTPWithSigner 相同。
+func NewHOTPWithSigner(signer Signer, options ...HOTPOption) *HOTP {
+	return &HOTP{Otp: newHOTPOtp(options), signer: signer}
+}
+
+// signerGenerator 使用 signer 计算 token。
+func signerGenerator(o Otp, signer Signer) func(counter int64) (string, error) {
+	return func(counter int64) (string, error) {
+		h, err := sign(signer, o.Algorithm, counter)
+		if err != nil {
+			return "", err
+		}
+		return o.encode(h), nil
+	}
+}
+
+// sign 使用 signer 对计数器签名，返回值短于 algorithm 的摘要长度时返回 ErrSignerOutput，
+// 避免截断时越界，也避免与配置的算法不一致的 signer 生成错误的 token。
+func sign(signer Signer, algorithm Algorithms, counter int64) ([]byte, error) {
+	h, err := signer.Sign(intToByte(counter))
+	if err != nil {
+		return nil, err
+	}
+	if size := hasher(algorithm)().Size(); len(h) < size {
+		return nil, fmt.Errorf("%w: got %d bytes, %s requires %d", ErrSignerOutput, len(h), algorithm, size)
+	}
+	return h, nil
+}
+
+// signerReplayKey 使用 signer 对一个固定的计数器签名的摘要标识账户，同一个秘钥的不同 Signer 实例共享使用记录。
+//
+// 签名失败时返回错误，不能退化为所有账户共享的 key。
+func signerReplayKey(signer Signer, algorithm Algorithms) (string, error) {
+	h, err := sign(signer, algorithm, -1)
+	if err != nil {
+		return "", err
+	}
+	return "signer:" + replayKey(h), nil
+}
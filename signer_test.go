@@ -0,0 +1,123 @@
+package otp
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// countingSigner 记录调用次数，并可以模拟 HSM 不可用。
+type countingSigner struct {
+	*HMACSigner
+	calls int
+	err   error
+}
+
+func (s *countingSigner) Sign(counter []byte) ([]byte, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.HMACSigner.Sign(counter)
+}
+
+func TestNewTOTPWithSigner(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+	secret, _ := Base32Decode(TestSecret20)
+	signer := &countingSigner{HMACSigner: NewHMACSigner(AlgorithmSHA1, secret)}
+	totp := NewTOTPWithSigner(signer, WithSkew(1))
+
+	assert.Equal(t, "076141", totp.At(now))
+	assert.True(t, totp.Verify("076141", now))
+	assert.True(t, signer.calls > 0)
+	assert.Equal(t, "", totp.KeyURI("alice", "Example").Secret)
+
+	ok, err := totp.VerifyContext(context.Background(), "076141", now.Add(time.Second*30))
+	assert.True(t, ok)
+	assert.Nil(t, err)
+
+	signer.err = errors.New("hsm unavailable")
+	assert.Equal(t, "", totp.At(now))
+	assert.False(t, totp.Verify("076141", now))
+	ok, err = totp.VerifyContext(context.Background(), "076141", now)
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, signer.err)
+}
+
+func TestNewHOTPWithSigner(t *testing.T) {
+	signer := NewHMACSigner(AlgorithmSHA256, []byte("12345678901234567890123456789012"))
+	hotp := NewHOTPWithSigner(signer, WithAlgorithm(AlgorithmSHA256), WithDigits(DigitsEight))
+	expected := NewHOTPFromBytes([]byte("12345678901234567890123456789012"), WithAlgorithm(AlgorithmSHA256), WithDigits(DigitsEight))
+	for i := int64(0); i < 5; i++ {
+		assert.Equal(t, expected.At(i), hotp.At(i))
+	}
+	ok, err := hotp.VerifyContext(context.Background(), expected.At(3), 3)
+	assert.True(t, ok)
+	assert.Nil(t, err)
+}
+
+func TestSigner_ReplayGuard(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+	secret, _ := Base32Decode(TestSecret20)
+	guard := NewMemoryReplayGuard()
+	totp := NewTOTPWithSigner(NewHMACSigner(AlgorithmSHA1, secret), WithReplayGuard(guard))
+	assert.True(t, totp.Verify("076141", now))
+	// 同一个秘钥的其他 Signer 实例共享使用记录
+	other := NewTOTPWithSigner(NewHMACSigner(AlgorithmSHA1, secret), WithReplayGuard(guard))
+	assert.False(t, other.Verify("076141", now))
+	assert.Panics(t, func() { NewHMACSigner(AlgorithmSHA1, nil) })
+}
+
+// funcSigner 使用函数实现 Signer。
+type funcSigner func(counter []byte) ([]byte, error)
+
+func (f funcSigner) Sign(counter []byte) ([]byte, error) { return f(counter) }
+
+func TestSigner_ShortOutput(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+	short := funcSigner(func([]byte) ([]byte, error) { return []byte{0x01}, nil })
+	totp := NewTOTPWithSigner(short)
+	assert.NotPanics(t, func() { assert.Equal(t, "", totp.At(now)) })
+	ok, err := totp.VerifyContext(context.Background(), "000000", now)
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, ErrSignerOutput)
+
+	// SHA1 的输出不能用于 SHA256
+	secret, _ := Base32Decode(TestSecret20)
+	hotp := NewHOTPWithSigner(NewHMACSigner(AlgorithmSHA1, secret), WithAlgorithm(AlgorithmSHA256))
+	_, err = hotp.VerifyContext(context.Background(), "000000", 0)
+	assert.ErrorIs(t, err, ErrSignerOutput)
+}
+
+func TestSigner_ReplayKeyError(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+	secret, _ := Base32Decode(TestSecret20)
+	hsmErr := errors.New("hsm unavailable")
+	signer := NewHMACSigner(AlgorithmSHA1, secret)
+	// 只有计算防重放 key 时签名失败
+	failing := funcSigner(func(counter []byte) ([]byte, error) {
+		if counter[0] == 0xff {
+			return nil, hsmErr
+		}
+		return signer.Sign(counter)
+	})
+	guard := NewMemoryReplayGuard()
+	totp := NewTOTPWithSigner(failing, WithReplayGuard(guard))
+	ok, err := totp.VerifyContext(context.Background(), "076141", now)
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, hsmErr)
+	assert.False(t, totp.Verify("076141", now))
+
+	hotp := NewHOTPWithSigner(failing, WithReplayGuard(guard))
+	token := NewHOTP(TestSecret20).At(0)
+	ok, err = hotp.VerifyContext(context.Background(), token, 0)
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, hsmErr)
+	assert.False(t, hotp.Verify(token, 0))
+
+	// 失败时不会写入共享的 "signer:" key，其他账户不受影响
+	other := NewTOTPWithSigner(NewHMACSigner(AlgorithmSHA1, secret), WithReplayGuard(guard))
+	assert.True(t, other.Verify("076141", now))
+}
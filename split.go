@@ -0,0 +1,81 @@
+package otp
+
+import (
+	"crypto/sha256"
+	"strings"
+)
+
+// splitChecksumLength 手动输入部分末尾的校验字符数（base32 字符，共 10 位）。
+const splitChecksumLength = 2
+
+// SplitSecret 将 base32 编码的秘钥拆分成两部分：二维码中携带的前半部分和需要手动输入（或通过其他渠道下发）的后缀。
+//
+// 后缀的最后两个字符是基于完整秘钥计算出来的校验码，合并时用于发现输入错误以及两部分不匹配的情况。
+// 这样即使二维码被偷拍，没有后缀也无法得到完整的秘钥。建议 suffixBytes 至少为 5（40 位）。
+//
+// Params:
+//
+//	secret     : base32 编码的完整秘钥。
+//	suffixBytes: 后缀包含的秘钥字节数，取值范围是 [1, 秘钥字节数 - 1]，超出范围返回 ErrSplitLength。
+//
+// Example:
+//
+//	head, suffix, err := SplitSecret(secret, 5)
+//	// head 放入二维码，suffix 展示给用户手动输入或通过短信下发
+//	full, err := CombineSecret(head, suffix)
+func SplitSecret(secret string, suffixBytes int) (head string, suffix string, err error) {
+	if secret == "" {
+		return "", "", ErrSecretCannotBeEmpty
+	}
+	decodedSecret, err := Base32Decode(secret)
+	if err != nil {
+		return "", "", err
+	}
+	if suffixBytes < 1 || suffixBytes >= len(decodedSecret) {
+		return "", "", ErrSplitLength
+	}
+	n := len(decodedSecret) - suffixBytes
+	head = Base32Encode(decodedSecret[:n])
+	suffix = Base32Encode(decodedSecret[n:]) + splitChecksum(decodedSecret)
+	return head, suffix, nil
+}
+
+// CombineSecret 将 SplitSecret 拆分出来的两部分合并成完整的 base32 秘钥。
+//
+// suffix 忽略大小写、空格和连字符，校验码不匹配时返回 ErrSplitChecksum。
+func CombineSecret(head, suffix string) (string, error) {
+	suffix = strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(suffix))
+	if head == "" || len(suffix) <= splitChecksumLength {
+		return "", ErrSecretCannotBeEmpty
+	}
+	decodedHead, err := Base32Decode(head)
+	if err != nil {
+		return "", err
+	}
+	checksum := suffix[len(suffix)-splitChecksumLength:]
+	decodedSuffix, err := Base32Decode(suffix[:len(suffix)-splitChecksumLength])
+	if err != nil {
+		return "", ErrSplitChecksum
+	}
+	full := append(decodedHead, decodedSuffix...)
+	if splitChecksum(full) != checksum {
+		return "", ErrSplitChecksum
+	}
+	return Base32Encode(full), nil
+}
+
+// Split 返回一个只携带秘钥前半部分的 KeyURI 以及需要手动输入的后缀，参数含义与 SplitSecret 相同。
+func (p KeyURI) Split(suffixBytes int) (*KeyURI, string, error) {
+	head, suffix, err := SplitSecret(p.Secret, suffixBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	p.Secret = head
+	return &p, suffix, nil
+}
+
+// splitChecksum 计算完整秘钥的校验码：SHA256 的前 10 位，使用 base32 字母表表示。
+func splitChecksum(secret []byte) string {
+	sum := sha256.Sum256(secret)
+	return Base32Encode(sum[:2])[:splitChecksumLength]
+}
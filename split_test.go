@@ -0,0 +1,79 @@
+package otp
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestSplitSecret(t *testing.T) {
+	for _, suffixBytes := range []int{1, 3, 5, 8, 19} {
+		head, suffix, err := SplitSecret(TestSecret20, suffixBytes)
+		assert.Nil(t, err)
+		assert.NotContains(t, TestSecret20, suffix)
+		full, err := CombineSecret(head, suffix)
+		assert.Nil(t, err)
+		assert.Equal(t, TestSecret20, full)
+	}
+
+	_, _, err := SplitSecret(TestSecret20, 0)
+	assert.Equal(t, ErrSplitLength, err)
+	_, _, err = SplitSecret(TestSecret20, 20)
+	assert.Equal(t, ErrSplitLength, err)
+	_, _, err = SplitSecret("", 5)
+	assert.Equal(t, ErrSecretCannotBeEmpty, err)
+	_, _, err = SplitSecret("111111", 5)
+	assert.True(t, errors.Is(err, ErrSecretDecode))
+}
+
+func TestCombineSecret(t *testing.T) {
+	head, suffix, err := SplitSecret(TestSecret20, 5)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, len(suffix))
+
+	t.Run("tolerate formatting", func(t *testing.T) {
+		formatted := strings.ToLower(suffix[:4] + " " + suffix[4:8] + "-" + suffix[8:])
+		full, err := CombineSecret(head, formatted)
+		assert.Nil(t, err)
+		assert.Equal(t, TestSecret20, full)
+	})
+
+	t.Run("typo", func(t *testing.T) {
+		typo := []byte(suffix)
+		if typo[0] == 'A' {
+			typo[0] = 'B'
+		} else {
+			typo[0] = 'A'
+		}
+		_, err := CombineSecret(head, string(typo))
+		assert.Equal(t, ErrSplitChecksum, err)
+	})
+
+	t.Run("mismatched parts", func(t *testing.T) {
+		_, suffix2, err := SplitSecret(TestSecret32, 5)
+		assert.Nil(t, err)
+		_, err = CombineSecret(head, suffix2)
+		assert.Equal(t, ErrSplitChecksum, err)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		_, err := CombineSecret("", suffix)
+		assert.Equal(t, ErrSecretCannotBeEmpty, err)
+		_, err = CombineSecret(head, "AB")
+		assert.Equal(t, ErrSecretCannotBeEmpty, err)
+	})
+}
+
+func TestKeyURI_Split(t *testing.T) {
+	key := NewTOTP(TestSecret20).KeyURI("alice@google.com", "Example")
+	partial, suffix, err := key.Split(5)
+	assert.Nil(t, err)
+	assert.Equal(t, TestSecret20, key.Secret)
+	assert.NotEqual(t, key.Secret, partial.Secret)
+	assert.Equal(t, key.Label, partial.Label)
+
+	full, err := CombineSecret(partial.Secret, suffix)
+	assert.Nil(t, err)
+	assert.Equal(t, key.Secret, full)
+}
@@ -0,0 +1,81 @@
+package otp
+
+import "sync"
+
+// StatefulHOTP 持有计数器的 HOTP，生成或校验 token 之后自动推进计数器，并发安全。
+//
+// HOTP 的 Counter 字段只用于 KeyURI，计数器需要调用方自己维护；StatefulHOTP 在内部维护下一个计数器，
+// 并在计数器推进时调用持久化回调，避免服务重启后计数器回退导致 token 被重复使用。
+type StatefulHOTP struct {
+	hotp *HOTP
+	// 校验时向前查找的计数器数量，默认为 0，只接受下一个计数器的 token。
+	LookAhead int
+	// 计数器推进时调用，返回错误时不会推进计数器，为 nil 时不持久化。
+	persist func(counter int64) error
+	mu      sync.Mutex
+	counter int64
+}
+
+// NewStatefulHOTP 创建一个 StatefulHOTP，初始计数器为 hotp.Counter。
+//
+// Params:
+//
+//	hotp   : 必传，用于计算 token 的 HOTP。
+//	persist: 可选，计数器推进时以新的计数器调用，通常将其保存到数据库中。
+//
+// Example:
+//
+//	hotp := NewHOTP(secret, WithCounter(savedCounter))
+//	s    := NewStatefulHOTP(hotp, func(counter int64) error {
+//		return db.SaveCounter(userID, counter)
+//	})
+//	s.LookAhead = 10
+//	if s.VerifyAndAdvance(token) {
+//		// token 有效，计数器已经推进并保存
+//	}
+func NewStatefulHOTP(hotp *HOTP, persist func(counter int64) error) *StatefulHOTP {
+	return &StatefulHOTP{hotp: hotp, persist: persist, counter: hotp.Counter}
+}
+
+// Counter 返回下一个计数器。
+func (s *StatefulHOTP) Counter() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counter
+}
+
+// Next 使用下一个计数器生成 token 并推进计数器，返回 token 和生成 token 使用的计数器。
+//
+// 持久化失败时计数器不会推进，返回空字符串。
+func (s *StatefulHOTP) Next() (string, int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counter := s.counter
+	if err := s.advance(counter + 1); err != nil {
+		return "", counter
+	}
+	return s.hotp.At(counter), counter
+}
+
+// VerifyAndAdvance 在下一个计数器到其后 LookAhead 个计数器之间校验 token，
+// 校验通过时将计数器推进到匹配的计数器加一。持久化失败时返回 false 且计数器不会推进。
+func (s *StatefulHOTP) VerifyAndAdvance(token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next, ok := s.hotp.ValidateAndSync(token, s.counter, s.LookAhead)
+	if !ok {
+		return false
+	}
+	return s.advance(next) == nil
+}
+
+// advance 持久化并推进计数器，调用方需要持有锁。
+func (s *StatefulHOTP) advance(counter int64) error {
+	if s.persist != nil {
+		if err := s.persist(counter); err != nil {
+			return err
+		}
+	}
+	s.counter = counter
+	return nil
+}
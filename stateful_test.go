@@ -0,0 +1,82 @@
+package otp
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+func TestStatefulHOTP_Next(t *testing.T) {
+	hotp := NewHOTP(TestSecret20, WithCounter(5))
+	var saved []int64
+	s := NewStatefulHOTP(hotp, func(counter int64) error {
+		saved = append(saved, counter)
+		return nil
+	})
+	assert.Equal(t, int64(5), s.Counter())
+	token, counter := s.Next()
+	assert.Equal(t, hotp.At(5), token)
+	assert.Equal(t, int64(5), counter)
+	token, counter = s.Next()
+	assert.Equal(t, hotp.At(6), token)
+	assert.Equal(t, int64(6), counter)
+	assert.Equal(t, []int64{6, 7}, saved)
+	assert.Equal(t, int64(7), s.Counter())
+}
+
+func TestStatefulHOTP_VerifyAndAdvance(t *testing.T) {
+	hotp := NewHOTP(TestSecret20)
+	s := NewStatefulHOTP(hotp, nil)
+	assert.True(t, s.VerifyAndAdvance(hotp.At(1)))
+	assert.Equal(t, int64(2), s.Counter())
+	// 同一个 token 不能重复使用
+	assert.False(t, s.VerifyAndAdvance(hotp.At(1)))
+	// 默认只接受下一个计数器
+	assert.False(t, s.VerifyAndAdvance(hotp.At(4)))
+	s.LookAhead = 3
+	assert.True(t, s.VerifyAndAdvance(hotp.At(4)))
+	assert.Equal(t, int64(5), s.Counter())
+	assert.False(t, s.VerifyAndAdvance(hotp.At(3)))
+	assert.False(t, s.VerifyAndAdvance(""))
+}
+
+func TestStatefulHOTP_PersistError(t *testing.T) {
+	hotp := NewHOTP(TestSecret20)
+	fail := true
+	s := NewStatefulHOTP(hotp, func(counter int64) error {
+		if fail {
+			return errors.New("db unavailable")
+		}
+		return nil
+	})
+	token, counter := s.Next()
+	assert.Equal(t, "", token)
+	assert.Equal(t, int64(1), counter)
+	assert.False(t, s.VerifyAndAdvance(hotp.At(1)))
+	assert.Equal(t, int64(1), s.Counter())
+
+	fail = false
+	assert.True(t, s.VerifyAndAdvance(hotp.At(1)))
+	assert.Equal(t, int64(2), s.Counter())
+}
+
+func TestStatefulHOTP_Concurrent(t *testing.T) {
+	s := NewStatefulHOTP(NewHOTP(TestSecret20), nil)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	counters := map[int64]bool{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, counter := s.Next()
+			mu.Lock()
+			counters[counter] = true
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, counters, 50)
+	assert.Equal(t, int64(51), s.Counter())
+}
@@ -0,0 +1,36 @@
+package otp
+
+// steamAlphabet Steam Guard token 使用的字符集，去掉了容易混淆的字符。
+const steamAlphabet = "23456789BCDFGHJKMNPQRTVWXY"
+
+// steamDigits Steam Guard token 的长度。
+const steamDigits = 5
+
+// NewSteamTOTP 创建一个与 Steam Guard 兼容的 TOTP 结构体（5 位字母数字）。
+//
+// Steam 的 shared_secret 通常是 base64 编码的，需要先转换为 base32 编码。
+// 传入的 options 会覆盖默认参数，Panic 的情况与 NewTOTP 一致。
+//
+// Example:
+//
+//	sharedSecret, _ := base64.StdEncoding.DecodeString(maFile.SharedSecret)
+//	totp  := NewSteamTOTP(Base32Encode(sharedSecret))
+//	token := totp.Now()
+func NewSteamTOTP(secret string, options ...TOTPOption) *TOTP {
+	return NewTOTP(secret, append([]TOTPOption{WithEncoder(EncoderSteam)}, options...)...)
+}
+
+// steamEncode 与 truncate 相同的方式截取 31 位整数，然后转换为 steamAlphabet 中的字符，低位在前。
+func steamEncode(h []byte) string {
+	offset := h[len(h)-1] & 0xf
+	value := uint32(h[offset]&0x7f)<<24 |
+		uint32(h[offset+1]&0xff)<<16 |
+		uint32(h[offset+2]&0xff)<<8 |
+		uint32(h[offset+3]&0xff)
+	code := make([]byte, steamDigits)
+	for i := range code {
+		code[i] = steamAlphabet[value%uint32(len(steamAlphabet))]
+		value /= uint32(len(steamAlphabet))
+	}
+	return string(code)
+}
@@ -0,0 +1,58 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewSteamTOTP(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+	totp := NewSteamTOTP(TestSecret20)
+	assert.Equal(t, EncoderSteam, totp.Encoder)
+
+	// 使用标准库 hmac 手动计算期望值
+	key, _ := Base32Decode(TestSecret20)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(intToByte(now.Unix() / 30))
+	h := mac.Sum(nil)
+	offset := h[len(h)-1] & 0xf
+	value := (int(h[offset])&0x7f)<<24 | int(h[offset+1])<<16 | int(h[offset+2])<<8 | int(h[offset+3])
+	expected := ""
+	for i := 0; i < 5; i++ {
+		expected += string(steamAlphabet[value%26])
+		value /= 26
+	}
+
+	token := totp.At(now)
+	assert.Equal(t, expected, token)
+	assert.Len(t, token, 5)
+	assert.Equal(t, true, totp.Verify(token, now))
+	assert.Equal(t, false, totp.Verify(token, now.Add(time.Second*30)))
+	assert.NotEqual(t, NewTOTP(TestSecret20).At(now), token)
+
+	t.Run("hotp", func(t *testing.T) {
+		hotp := NewHOTP(TestSecret20, WithEncoder(EncoderSteam))
+		assert.Len(t, hotp.At(1), 5)
+		assert.Equal(t, true, hotp.Verify(hotp.At(1), 1))
+	})
+}
+
+func TestSteamKeyURI(t *testing.T) {
+	uri := NewSteamTOTP(TestSecret20).KeyURI("alice", "Steam")
+	expected := fmt.Sprintf("otpauth://totp/Steam:alice?secret=%s&issuer=Steam&encoder=steam", TestSecret20)
+	assert.Equal(t, expected, uri.URI().String())
+
+	key, err := FromURI(expected)
+	assert.Nil(t, err)
+	assert.Equal(t, "steam", key.Encoder)
+
+	_, err = FromURI(fmt.Sprintf("otpauth://totp/Steam:alice?secret=%s&encoder=unknown", TestSecret20))
+	assert.ErrorIs(t, err, ErrUnsupportedEncoder)
+
+	_, err = MigrationURI(uri)
+	assert.Equal(t, ErrMigrationUnsupported, err)
+}
@@ -0,0 +1,170 @@
+package otp
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"sync"
+)
+
+// SecretStore 按照账户 id 保存解码后的秘钥。
+//
+// 配合 NewTOTPFromStore、NewHOTPFromStore 使用时，秘钥只在计算 token 时读取，不会保存在 TOTP、HOTP 结构体中。
+// 找不到账户时 Get 应该返回 ErrSecretNotFound。Get 返回的切片在使用之后会被清零，实现需要返回秘钥的副本。
+type SecretStore interface {
+	Get(id string) ([]byte, error)
+	Put(id string, secret []byte) error
+	Delete(id string) error
+}
+
+// MemorySecretStore 基于内存的 SecretStore 实现，并发安全。
+//
+// 秘钥以明文保存，通常作为 EncryptedSecretStore 的底层存储或者在测试中使用。
+type MemorySecretStore struct {
+	mu      sync.RWMutex
+	secrets map[string][]byte
+}
+
+// NewMemorySecretStore 创建一个 MemorySecretStore。
+func NewMemorySecretStore() *MemorySecretStore {
+	return &MemorySecretStore{secrets: map[string][]byte{}}
+}
+
+// Get 实现 SecretStore 接口，返回秘钥的副本。
+func (s *MemorySecretStore) Get(id string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	secret, ok := s.secrets[id]
+	if !ok {
+		return nil, ErrSecretNotFound
+	}
+	return append([]byte(nil), secret...), nil
+}
+
+// Put 实现 SecretStore 接口，保存秘钥的副本。
+func (s *MemorySecretStore) Put(id string, secret []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secrets[id] = append([]byte(nil), secret...)
+	return nil
+}
+
+// Delete 实现 SecretStore 接口，账户不存在时不会返回错误。
+func (s *MemorySecretStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.secrets, id)
+	return nil
+}
+
+// EncryptedSecretStore 使用 AES-GCM 加密秘钥后保存到底层的 SecretStore 中。
+//
+// 密文格式为 nonce | ciphertext，账户 id 作为附加数据参与认证，密文被挪用到其他账户时解密会失败。
+type EncryptedSecretStore struct {
+	aead    cipher.AEAD
+	backend SecretStore
+}
+
+// NewEncryptedSecretStore 创建一个 EncryptedSecretStore。
+//
+// Params:
+//
+//	key    : AES 秘钥，长度必须是 16、24 或 32 字节，建议使用 32 字节（AES-256）。
+//	backend: 保存密文的底层存储，例如数据库的实现。
+//
+// Example:
+//
+//	store, err := NewEncryptedSecretStore(kek, NewMemorySecretStore())
+//	err = store.Put("alice", RandomSecret(20))
+//	totp, err := NewTOTPFromStore(store, "alice")
+func NewEncryptedSecretStore(key []byte, backend SecretStore) (*EncryptedSecretStore, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedSecretStore{aead: aead, backend: backend}, nil
+}
+
+// Get 实现 SecretStore 接口，从底层存储读取密文并解密，解密失败时返回 ErrSecretDecrypt。
+func (s *EncryptedSecretStore) Get(id string) ([]byte, error) {
+	data, err := s.backend.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.decrypt(id, data)
+}
+
+// GetContext 实现 SecretStoreContext 接口，底层存储实现了 SecretStoreContext 时会将 ctx 传递下去。
+func (s *EncryptedSecretStore) GetContext(ctx context.Context, id string) ([]byte, error) {
+	data, err := getSecretContext(ctx, s.backend, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.decrypt(id, data)
+}
+
+// decrypt 解密 id 对应的密文。
+func (s *EncryptedSecretStore) decrypt(id string, data []byte) ([]byte, error) {
+	size := s.aead.NonceSize()
+	if len(data) < size {
+		return nil, ErrSecretDecrypt
+	}
+	secret, err := s.aead.Open(nil, data[:size], data[size:], []byte(id))
+	if err != nil {
+		return nil, ErrSecretDecrypt
+	}
+	return secret, nil
+}
+
+// Put 实现 SecretStore 接口，使用随机 nonce 加密后保存到底层存储。
+func (s *EncryptedSecretStore) Put(id string, secret []byte) error {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	return s.backend.Put(id, s.aead.Seal(nonce, nonce, secret, []byte(id)))
+}
+
+// Delete 实现 SecretStore 接口。
+func (s *EncryptedSecretStore) Delete(id string) error {
+	return s.backend.Delete(id)
+}
+
+// NewTOTPFromStore 创建一个从 SecretStore 读取秘钥的 TOTP 结构体。
+//
+// 创建时会读取一次秘钥以确认账户存在，读取的秘钥会立即清零，之后每次计算 token 都会重新读取，TOTP 结构体中不会保存秘钥。
+// 其余参数与 NewTOTP 一致，Secret 字段为空。
+func NewTOTPFromStore(store SecretStore, id string, options ...TOTPOption) (*TOTP, error) {
+	secret, err := store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	zero(secret)
+	return &TOTP{
+		Otp:     newTOTPOtp(options),
+		store:   store,
+		storeID: id,
+	}, nil
+}
+
+// NewHOTPFromStore 创建一个从 SecretStore 读取秘钥的 HOTP 结构体。
+//
+// 创建时会读取一次秘钥以确认账户存在，读取的秘钥会立即清零，之后每次计算 token 都会重新读取，HOTP 结构体中不会保存秘钥。
+// 其余参数与 NewHOTP 一致，Secret 字段为空。
+func NewHOTPFromStore(store SecretStore, id string, options ...HOTPOption) (*HOTP, error) {
+	secret, err := store.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	zero(secret)
+	return &HOTP{
+		Otp:     newHOTPOtp(options),
+		store:   store,
+		storeID: id,
+	}, nil
+}
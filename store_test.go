@@ -0,0 +1,139 @@
+package otp
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMemorySecretStore(t *testing.T) {
+	store := NewMemorySecretStore()
+	_, err := store.Get("alice")
+	assert.Equal(t, ErrSecretNotFound, err)
+
+	secret := []byte("12345678901234567890")
+	assert.Nil(t, store.Put("alice", secret))
+	// 保存的是副本
+	secret[0] = 0
+	got, err := store.Get("alice")
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("12345678901234567890"), got)
+
+	assert.Nil(t, store.Delete("alice"))
+	_, err = store.Get("alice")
+	assert.Equal(t, ErrSecretNotFound, err)
+}
+
+func TestEncryptedSecretStore(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	backend := NewMemorySecretStore()
+	store, err := NewEncryptedSecretStore(key, backend)
+	assert.Nil(t, err)
+
+	secret := []byte("12345678901234567890")
+	assert.Nil(t, store.Put("alice", secret))
+	got, err := store.Get("alice")
+	assert.Nil(t, err)
+	assert.Equal(t, secret, got)
+
+	// 底层存储中是密文
+	raw, _ := backend.Get("alice")
+	assert.False(t, bytes.Contains(raw, secret))
+
+	t.Run("ciphertext bound to account id", func(t *testing.T) {
+		_ = backend.Put("bob", raw)
+		_, err := store.Get("bob")
+		assert.Equal(t, ErrSecretDecrypt, err)
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		other, _ := NewEncryptedSecretStore(bytes.Repeat([]byte{2}, 32), backend)
+		_, err := other.Get("alice")
+		assert.Equal(t, ErrSecretDecrypt, err)
+	})
+
+	t.Run("truncated ciphertext", func(t *testing.T) {
+		_ = backend.Put("carol", []byte{1, 2, 3})
+		_, err := store.Get("carol")
+		assert.Equal(t, ErrSecretDecrypt, err)
+	})
+
+	t.Run("invalid key length", func(t *testing.T) {
+		_, err := NewEncryptedSecretStore([]byte("short"), backend)
+		assert.NotNil(t, err)
+	})
+
+	assert.Nil(t, store.Delete("alice"))
+	_, err = store.Get("alice")
+	assert.Equal(t, ErrSecretNotFound, err)
+}
+
+func TestNewTOTPFromStore(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+	store, _ := NewEncryptedSecretStore(bytes.Repeat([]byte{1}, 32), NewMemorySecretStore())
+	decoded, _ := Base32Decode(TestSecret20)
+	_ = store.Put("alice", decoded)
+
+	totp, err := NewTOTPFromStore(store, "alice")
+	assert.Nil(t, err)
+	assert.Equal(t, "", totp.Secret)
+	assert.Nil(t, totp.decodedSecret)
+	assert.Equal(t, "076141", totp.At(now))
+	assert.Equal(t, true, totp.Verify("076141", now))
+	assert.Equal(t, TestSecret20, totp.KeyURI("alice", "Example").Secret)
+
+	// 删除后无法再生成 token
+	_ = store.Delete("alice")
+	assert.Equal(t, "", totp.At(now))
+	assert.Equal(t, false, totp.Verify("076141", now))
+
+	_, err = NewTOTPFromStore(store, "alice")
+	assert.Equal(t, ErrSecretNotFound, err)
+}
+
+func TestNewHOTPFromStore(t *testing.T) {
+	store := NewMemorySecretStore()
+	decoded, _ := Base32Decode(TestSecret20)
+	_ = store.Put("alice", decoded)
+
+	hotp, err := NewHOTPFromStore(store, "alice", WithReplayGuard(NewMemoryReplayGuard()))
+	assert.Nil(t, err)
+	assert.Equal(t, NewHOTP(TestSecret20).At(1), hotp.At(1))
+	assert.Equal(t, true, hotp.Verify(hotp.At(1), 1))
+	assert.Equal(t, false, hotp.Verify(hotp.At(1), 1))
+
+	_, err = NewHOTPFromStore(store, "bob")
+	assert.Equal(t, ErrSecretNotFound, err)
+}
+
+// recordingStore 记录 Get 返回的所有秘钥，用于检查使用后是否已经清零。
+type recordingStore struct {
+	*MemorySecretStore
+	returned [][]byte
+}
+
+func (s *recordingStore) Get(id string) ([]byte, error) {
+	secret, err := s.MemorySecretStore.Get(id)
+	s.returned = append(s.returned, secret)
+	return secret, err
+}
+
+func TestNewFromStore_ZeroSecret(t *testing.T) {
+	store := &recordingStore{MemorySecretStore: NewMemorySecretStore()}
+	decoded, _ := Base32Decode(TestSecret20)
+	_ = store.Put("alice", decoded)
+
+	totp, err := NewTOTPFromStore(store, "alice")
+	assert.Nil(t, err)
+	hotp, err := NewHOTPFromStore(store, "alice")
+	assert.Nil(t, err)
+	assert.Len(t, store.returned, 2)
+	for _, secret := range store.returned {
+		assert.Equal(t, make([]byte, len(decoded)), secret)
+	}
+
+	// 存储中的秘钥不受影响
+	assert.Equal(t, NewTOTP(TestSecret20).At(time.Unix(0, 0)), totp.At(time.Unix(0, 0)))
+	assert.Equal(t, NewHOTP(TestSecret20).At(1), hotp.At(1))
+}
@@ -0,0 +1,109 @@
+package otp
+
+import "fmt"
+
+var (
+	// RFC-4226 R6 要求秘钥至少为 128 位。
+	minSecretLength = 16
+	// RFC-4226 R6 建议秘钥使用 160 位。
+	recommendedSecretLength = 20
+)
+
+// SecretSeverity 秘钥长度不足的严重程度，数值越小越严重。
+type SecretSeverity int
+
+const (
+	// SecretSeverityInsufficient 短于 RFC-4226 要求的最小长度 128 位（16 字节），不应该继续使用。
+	SecretSeverityInsufficient SecretSeverity = iota + 1
+	// SecretSeverityBelowRecommended 短于 RFC-4226 建议的长度 160 位（20 字节）。
+	SecretSeverityBelowRecommended
+	// SecretSeverityAlgorithmMismatch 短于 HMAC 算法的输出长度，例如 SHA256 的 32 字节、SHA512 的 64 字节。
+	SecretSeverityAlgorithmMismatch
+)
+
+// String 枚举值转换为字符串形式。
+func (s SecretSeverity) String() string {
+	switch s {
+	case SecretSeverityInsufficient:
+		return "insufficient"
+	case SecretSeverityBelowRecommended:
+		return "below recommended"
+	case SecretSeverityAlgorithmMismatch:
+		return "algorithm mismatch"
+	default:
+		panic("unreachable")
+	}
+}
+
+// SecretStrengthError 秘钥长度不足的详细信息。
+//
+// 使用 errors.Is(err, ErrSecretTooShort) 判断是否是长度不足，使用 Severity 区分严重程度。
+type SecretStrengthError struct {
+	// 严重程度
+	Severity SecretSeverity
+	// 秘钥的字节数
+	Length int
+	// 当前严重程度对应的最小字节数
+	Required int
+	// 校验时使用的 HMAC 算法
+	Algorithm Algorithms
+}
+
+func (e *SecretStrengthError) Error() string {
+	switch e.Severity {
+	case SecretSeverityInsufficient:
+		return fmt.Sprintf("%s: %d bytes is less than the RFC-4226 minimum of %d bytes", ErrSecretTooShort, e.Length, e.Required)
+	case SecretSeverityBelowRecommended:
+		return fmt.Sprintf("%s: %d bytes is less than the RFC-4226 recommended %d bytes", ErrSecretTooShort, e.Length, e.Required)
+	default:
+		return fmt.Sprintf("%s: %d bytes is less than the %d bytes output of %s", ErrSecretTooShort, e.Length, e.Required, e.Algorithm)
+	}
+}
+
+// Is 使 errors.Is(err, ErrSecretTooShort) 返回 true。
+func (e *SecretStrengthError) Is(target error) bool {
+	return target == ErrSecretTooShort
+}
+
+// ValidateSecretStrength 校验未编码的秘钥长度是否足够，依次检查：
+//   - RFC-4226 要求的最小长度 128 位（16 字节）。
+//   - RFC-4226 建议的长度 160 位（20 字节）。
+//   - 与 HMAC 算法输出相同的长度：SHA1 20 字节、SHA256 32 字节、SHA512 64 字节等。
+//
+// 长度不足时返回 *SecretStrengthError，Severity 为第一个不满足的检查；algorithm 不是支持的取值时返回 ErrInvalidOption。
+// 只想拒绝不满足 RFC-4226 最小长度的秘钥时，可以只判断 SecretSeverityInsufficient。
+//
+// Example:
+//
+//	err := ValidateSecretStrength(secret, AlgorithmSHA256)
+//	var strength *SecretStrengthError
+//	if errors.As(err, &strength) && strength.Severity == SecretSeverityInsufficient {
+//		// 拒绝该秘钥
+//	}
+func ValidateSecretStrength(secret []byte, algorithm Algorithms) error {
+	if algorithm < AlgorithmSHA1 || algorithm > AlgorithmSHA384 {
+		return fmt.Errorf("%w: unknown algorithm %d", ErrInvalidOption, algorithm)
+	}
+	length := len(secret)
+	newErr := func(severity SecretSeverity, required int) error {
+		return &SecretStrengthError{Severity: severity, Length: length, Required: required, Algorithm: algorithm}
+	}
+	if length < minSecretLength {
+		return newErr(SecretSeverityInsufficient, minSecretLength)
+	}
+	if length < recommendedSecretLength {
+		return newErr(SecretSeverityBelowRecommended, recommendedSecretLength)
+	}
+	if size := hasher(algorithm)().Size(); length < size {
+		return newErr(SecretSeverityAlgorithmMismatch, size)
+	}
+	return nil
+}
+
+// checkSecretStrength 配置了 WithStrictSecretLength 时校验秘钥长度。
+func (o Otp) checkSecretStrength(secret []byte) error {
+	if !o.strictSecretLength {
+		return nil
+	}
+	return ValidateSecretStrength(secret, o.Algorithm)
+}
@@ -0,0 +1,55 @@
+package otp
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestValidateSecretStrength(t *testing.T) {
+	assert.Nil(t, ValidateSecretStrength(make([]byte, 20), AlgorithmSHA1))
+	assert.Nil(t, ValidateSecretStrength(make([]byte, 32), AlgorithmSHA256))
+	assert.Nil(t, ValidateSecretStrength(make([]byte, 64), AlgorithmSHA512))
+
+	for _, tt := range []struct {
+		length    int
+		algorithm Algorithms
+		severity  SecretSeverity
+		required  int
+	}{
+		{10, AlgorithmSHA1, SecretSeverityInsufficient, 16},
+		{16, AlgorithmSHA1, SecretSeverityBelowRecommended, 20},
+		{20, AlgorithmSHA256, SecretSeverityAlgorithmMismatch, 32},
+		{32, AlgorithmSHA512, SecretSeverityAlgorithmMismatch, 64},
+		{40, AlgorithmSHA384, SecretSeverityAlgorithmMismatch, 48},
+	} {
+		err := ValidateSecretStrength(make([]byte, tt.length), tt.algorithm)
+		assert.ErrorIs(t, err, ErrSecretTooShort)
+		var strength *SecretStrengthError
+		assert.True(t, errors.As(err, &strength))
+		assert.Equal(t, tt.severity, strength.Severity)
+		assert.Equal(t, tt.required, strength.Required)
+		assert.Equal(t, tt.length, strength.Length)
+	}
+
+	assert.EqualError(t, ValidateSecretStrength(make([]byte, 20), AlgorithmSHA256), "secret too short: 20 bytes is less than the 32 bytes output of SHA256")
+	assert.ErrorIs(t, ValidateSecretStrength(make([]byte, 64), 0), ErrInvalidOption)
+}
+
+func TestWithStrictSecretLength(t *testing.T) {
+	totp, err := NewTOTPWithError(TestSecret20, WithStrictSecretLength())
+	assert.Nil(t, err)
+	assert.NotNil(t, totp)
+
+	// 校验使用最终的算法，与 option 的顺序无关
+	_, err = NewTOTPWithError(TestSecret20, WithStrictSecretLength(), WithAlgorithm(AlgorithmSHA256))
+	assert.ErrorIs(t, err, ErrSecretTooShort)
+	_, err = NewHOTPWithError(TestSecret32, WithAlgorithm(AlgorithmSHA512), WithStrictSecretLength())
+	assert.ErrorIs(t, err, ErrSecretTooShort)
+	assert.NotNil(t, NewHOTP(TestSecret64, WithAlgorithm(AlgorithmSHA512), WithStrictSecretLength()))
+
+	assert.Panics(t, func() { NewTOTP("GEZDGNBVGY3TQOJQ", WithStrictSecretLength()) })
+	assert.Panics(t, func() { NewHOTPFromBytes(make([]byte, 10), WithStrictSecretLength()) })
+	// 默认不校验
+	assert.NotPanics(t, func() { NewTOTPFromBytes(make([]byte, 10)) })
+}
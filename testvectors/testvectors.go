@@ -0,0 +1,163 @@
+// Package testvectors 导出 RFC-4226 附录 D 和 RFC-6238 附录 B 的测试向量，以及基于它们的兼容性测试。
+//
+// 封装本库或者其他实现的代码可以在自己的测试中调用 RunCompliance 确认与 RFC 的结果一致：
+//
+//	func TestCompliance(t *testing.T) {
+//		testvectors.RunCompliance(t, myGenerator{})
+//	}
+//
+// NewRFC4226Reference、NewRFC6238Reference 使用 RFC 的秘钥创建 HOTP、TOTP，可以用已知的 token 检查部署的服务。
+package testvectors
+
+import (
+	"fmt"
+	"github.com/huk10/go-otp"
+	"testing"
+	"time"
+)
+
+// HOTPVector RFC-4226 附录 D 中的一条测试向量。
+type HOTPVector struct {
+	Counter int64
+	Token   string
+}
+
+// TOTPVector RFC-6238 附录 B 中的一条测试向量。
+type TOTPVector struct {
+	Time      time.Time
+	Algorithm otp.Algorithms
+	Token     string
+}
+
+// HOTPSecret RFC-4226 附录 D 使用的秘钥，ASCII 字符串 "12345678901234567890"。
+var HOTPSecret = []byte("12345678901234567890")
+
+// HOTPDigits RFC-4226 附录 D 中 token 的长度。
+const HOTPDigits = 6
+
+// HOTPVectors RFC-4226 附录 D 中计数器 0 到 9 的 token。
+//
+// See https://datatracker.ietf.org/doc/html/rfc4226#appendix-D
+var HOTPVectors = []HOTPVector{
+	{0, "755224"},
+	{1, "287082"},
+	{2, "359152"},
+	{3, "969429"},
+	{4, "338314"},
+	{5, "254676"},
+	{6, "287922"},
+	{7, "162583"},
+	{8, "399871"},
+	{9, "520489"},
+}
+
+// TOTPSecrets RFC-6238 附录 B 中每种算法使用的秘钥，分别为 20、32、64 字节的重复 "1234567890"。
+//
+// RFC 正文只列出了 SHA1 的 20 字节秘钥，其余两个来自勘误和参考实现，也是各实现普遍采用的取值。
+var TOTPSecrets = map[otp.Algorithms][]byte{
+	otp.AlgorithmSHA1:   []byte("12345678901234567890"),
+	otp.AlgorithmSHA256: []byte("12345678901234567890123456789012"),
+	otp.AlgorithmSHA512: []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+}
+
+// TOTPDigits RFC-6238 附录 B 中 token 的长度。
+const TOTPDigits = 8
+
+// TOTPPeriod RFC-6238 附录 B 中的时间步长（秒）。
+const TOTPPeriod = 30
+
+// TOTPVectors RFC-6238 附录 B 中的测试向量。
+//
+// See https://datatracker.ietf.org/doc/html/rfc6238#appendix-B
+var TOTPVectors = []TOTPVector{
+	{time.Unix(59, 0), otp.AlgorithmSHA1, "94287082"},
+	{time.Unix(59, 0), otp.AlgorithmSHA256, "46119246"},
+	{time.Unix(59, 0), otp.AlgorithmSHA512, "90693936"},
+	{time.Unix(1111111109, 0), otp.AlgorithmSHA1, "07081804"},
+	{time.Unix(1111111109, 0), otp.AlgorithmSHA256, "68084774"},
+	{time.Unix(1111111109, 0), otp.AlgorithmSHA512, "25091201"},
+	{time.Unix(1111111111, 0), otp.AlgorithmSHA1, "14050471"},
+	{time.Unix(1111111111, 0), otp.AlgorithmSHA256, "67062674"},
+	{time.Unix(1111111111, 0), otp.AlgorithmSHA512, "99943326"},
+	{time.Unix(1234567890, 0), otp.AlgorithmSHA1, "89005924"},
+	{time.Unix(1234567890, 0), otp.AlgorithmSHA256, "91819424"},
+	{time.Unix(1234567890, 0), otp.AlgorithmSHA512, "93441116"},
+	{time.Unix(2000000000, 0), otp.AlgorithmSHA1, "69279037"},
+	{time.Unix(2000000000, 0), otp.AlgorithmSHA256, "90698825"},
+	{time.Unix(2000000000, 0), otp.AlgorithmSHA512, "38618901"},
+	{time.Unix(20000000000, 0), otp.AlgorithmSHA1, "65353130"},
+	{time.Unix(20000000000, 0), otp.AlgorithmSHA256, "77737706"},
+	{time.Unix(20000000000, 0), otp.AlgorithmSHA512, "47863826"},
+}
+
+// NewRFC4226Reference 使用 RFC-4226 附录 D 的秘钥和参数创建 HOTP，At(v.Counter) 与 HOTPVectors 中的 token 相同。
+//
+// options 在 RFC 的参数之后应用，可以配置 WithReplayGuard、WithHooks 等不影响 token 的参数，用已知的 token 检查部署的校验流程。
+//
+// Example:
+//
+//	hotp := testvectors.NewRFC4226Reference()
+//	ok := hotp.Verify("755224", 0)
+func NewRFC4226Reference(options ...otp.HOTPOption) *otp.HOTP {
+	options = append([]otp.HOTPOption{otp.WithAlgorithm(otp.AlgorithmSHA1), otp.WithDigits(HOTPDigits)}, options...)
+	return otp.NewHOTPFromBytes(HOTPSecret, options...)
+}
+
+// NewRFC6238Reference 使用 RFC-6238 附录 B 中 algorithm 对应的秘钥和参数创建 TOTP，At(v.Time) 与 TOTPVectors 中的 token 相同。
+//
+// options 的用法与 NewRFC4226Reference 相同。KeyURI 返回的 URI 可以导入验证器应用，与部署的服务端对照。
+//
+// Panic:
+//   - algorithm 不是 SHA1、SHA256 或 SHA512，RFC 中没有对应的秘钥（ErrInvalidOption）
+//
+// Example:
+//
+//	totp := testvectors.NewRFC6238Reference(otp.AlgorithmSHA256)
+//	token := totp.At(time.Unix(1111111109, 0)) // "68084774"
+func NewRFC6238Reference(algorithm otp.Algorithms, options ...otp.TOTPOption) *otp.TOTP {
+	secret, ok := TOTPSecrets[algorithm]
+	if !ok {
+		panic(fmt.Errorf("%w: no RFC-6238 secret for algorithm %d", otp.ErrInvalidOption, algorithm))
+	}
+	options = append([]otp.TOTPOption{otp.WithAlgorithm(algorithm), otp.WithDigits(TOTPDigits), otp.WithPeriod(TOTPPeriod)}, options...)
+	return otp.NewTOTPFromBytes(secret, options...)
+}
+
+// Generator 被测试的实现，使用未编码的秘钥计算 token。
+type Generator interface {
+	HOTP(secret []byte, algorithm otp.Algorithms, digits int, counter int64) string
+	TOTP(secret []byte, algorithm otp.Algorithms, digits int, period int, t time.Time) string
+}
+
+// Library 基于 github.com/huk10/go-otp 的 Generator 实现，可以作为其他实现的对照。
+type Library struct{}
+
+// HOTP 实现 Generator 接口。
+func (Library) HOTP(secret []byte, algorithm otp.Algorithms, digits int, counter int64) string {
+	return otp.NewHOTPFromBytes(secret, otp.WithAlgorithm(algorithm), otp.WithDigits(otp.Digits(digits))).At(counter)
+}
+
+// TOTP 实现 Generator 接口。
+func (Library) TOTP(secret []byte, algorithm otp.Algorithms, digits int, period int, t time.Time) string {
+	totp := otp.NewTOTPFromBytes(secret, otp.WithAlgorithm(algorithm), otp.WithDigits(otp.Digits(digits)), otp.WithPeriod(period))
+	return totp.At(t)
+}
+
+// RunCompliance 使用 HOTPVectors 和 TOTPVectors 测试 gen，每条向量作为一个子测试。
+func RunCompliance(t *testing.T, gen Generator) {
+	t.Run("RFC4226", func(t *testing.T) {
+		for _, v := range HOTPVectors {
+			if token := gen.HOTP(HOTPSecret, otp.AlgorithmSHA1, HOTPDigits, v.Counter); token != v.Token {
+				t.Errorf("counter %d: got %q, want %q", v.Counter, token, v.Token)
+			}
+		}
+	})
+	t.Run("RFC6238", func(t *testing.T) {
+		for _, v := range TOTPVectors {
+			token := gen.TOTP(TOTPSecrets[v.Algorithm], v.Algorithm, TOTPDigits, TOTPPeriod, v.Time)
+			if token != v.Token {
+				t.Errorf("%s at %d: got %q, want %q", v.Algorithm, v.Time.Unix(), token, v.Token)
+			}
+		}
+	})
+}
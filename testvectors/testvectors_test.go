@@ -0,0 +1,48 @@
+package testvectors
+
+import (
+	"github.com/huk10/go-otp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestRunCompliance(t *testing.T) {
+	RunCompliance(t, Library{})
+}
+
+func TestVectors(t *testing.T) {
+	require.Len(t, HOTPVectors, 10)
+	require.Len(t, TOTPVectors, 18)
+	lengths := map[otp.Algorithms]int{otp.AlgorithmSHA1: 20, otp.AlgorithmSHA256: 32, otp.AlgorithmSHA512: 64}
+	assert.Len(t, TOTPSecrets, len(lengths))
+	for algorithm, secret := range TOTPSecrets {
+		assert.Len(t, secret, lengths[algorithm], algorithm.String())
+	}
+}
+
+func TestNewRFC4226Reference(t *testing.T) {
+	hotp := NewRFC4226Reference()
+	for _, v := range HOTPVectors {
+		assert.Equal(t, v.Token, hotp.At(v.Counter), "counter %d", v.Counter)
+	}
+	guarded := NewRFC4226Reference(otp.WithReplayGuard(otp.NewMemoryReplayGuard()))
+	assert.True(t, guarded.Verify("755224", 0))
+	assert.False(t, guarded.Verify("755224", 0))
+}
+
+func TestNewRFC6238Reference(t *testing.T) {
+	for _, v := range TOTPVectors {
+		totp := NewRFC6238Reference(v.Algorithm)
+		assert.Equal(t, v.Token, totp.At(v.Time), "%s at %d", v.Algorithm, v.Time.Unix())
+		assert.True(t, totp.Verify(v.Token, v.Time), "%s at %d", v.Algorithm, v.Time.Unix())
+	}
+	assert.Equal(t, 1, NewRFC6238Reference(otp.AlgorithmSHA1, otp.WithSkew(1)).Skew)
+
+	err := func() (err error) {
+		defer func() { err, _ = recover().(error) }()
+		NewRFC6238Reference(otp.AlgorithmSHA3_256)
+		return nil
+	}()
+	assert.ErrorIs(t, err, otp.ErrInvalidOption)
+}
@@ -0,0 +1,261 @@
+package otp
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ThrottleStore 保存每个账户在当前窗口内的失败次数。
+//
+// 语义与 Redis 的 INCR + EXPIRE 相同：第一次失败时开始一个新的窗口，窗口过期后失败次数清零，
+// 因此基于共享存储的实现只需要原子地执行这两个操作即可在多实例之间共享限流状态。
+type ThrottleStore interface {
+	// Failures 返回 key 在当前窗口内的失败次数，窗口已经过期或者没有记录时返回 0。
+	Failures(key string, now time.Time) (int, error)
+	// AddFailure 记录一次失败并返回当前窗口内的失败次数，没有未过期的窗口时以 now 开始一个长度为 window 的新窗口。
+	AddFailure(key string, now time.Time, window time.Duration) (int, error)
+	// Reset 清除 key 的失败记录，账户不存在时不应该返回错误。
+	Reset(key string) error
+}
+
+// ThrottleExpiry 可选接口，ThrottleStore 实现它时 VerifyResult.RetryAfter 为窗口剩余的准确时长，否则为整个窗口的长度。
+type ThrottleExpiry interface {
+	// Expiry 返回 key 当前窗口过期的时间，没有未过期的窗口时返回零值。
+	Expiry(key string, now time.Time) (time.Time, error)
+}
+
+// Throttle 基于 RFC-4226 第 7.3 节的建议限制校验的尝试次数，防止暴力破解。
+//
+// 同一个账户在窗口内失败 MaxFailures 次之后，直到窗口过期之前的所有尝试都会直接返回 ErrThrottled，不会再进行校验。
+// 每次尝试在校验之前先通过 AddFailure 原子地计入失败次数，校验成功时清除失败记录，
+// 因此并发的请求（包括多个实例共享同一个 Store 时）最多只有 MaxFailures 次能够进入校验。
+//
+// See https://datatracker.ietf.org/doc/html/rfc4226#section-7.3
+type Throttle struct {
+	// 保存失败次数的存储，NewThrottle 默认使用 MemoryThrottleStore。
+	Store ThrottleStore
+	// 窗口内允许的最大失败次数。
+	MaxFailures int
+	// 窗口长度，从窗口内第一次失败开始计算。
+	Window time.Duration
+	// 获取当前时间的方法，为 nil 时使用 time.Now。
+	clock func() time.Time
+}
+
+// NewThrottle 创建一个使用 MemoryThrottleStore 的 Throttle。
+//
+// Example:
+//
+//	throttle := NewThrottle(5, 15*time.Minute)
+//	ok, err := throttle.Verify(userID, func() bool {
+//		return totp.Verify(token, time.Now())
+//	})
+//	if errors.Is(err, ErrThrottled) {
+//		// 失败次数过多，稍后再试
+//	}
+func NewThrottle(maxFailures int, window time.Duration) *Throttle {
+	return &Throttle{
+		Store:       NewMemoryThrottleStore(),
+		MaxFailures: maxFailures,
+		Window:      window,
+	}
+}
+
+// now 返回当前时间。
+func (t *Throttle) now() time.Time {
+	if t.clock != nil {
+		return t.clock()
+	}
+	return time.Now()
+}
+
+// Allow 判断 key 当前是否允许尝试校验。
+func (t *Throttle) Allow(key string) (bool, error) {
+	failures, err := t.Store.Failures(key, t.now())
+	if err != nil {
+		return false, err
+	}
+	return failures < t.MaxFailures, nil
+}
+
+// Verify 在允许尝试时调用 verify 进行校验，并根据结果更新失败次数。
+//
+// 已经达到失败次数上限时返回 false 和 ErrThrottled，verify 不会被调用；Store 出错时返回 false 和对应的错误。
+func (t *Throttle) Verify(key string, verify func() bool) (bool, error) {
+	return t.VerifyContext(context.Background(), key, func(context.Context) (bool, error) {
+		return verify(), nil
+	})
+}
+
+// VerifyContext 与 Verify 相同，但是 verify 可以返回错误，返回的错误会原样返回给调用方。
+//
+// 尝试在校验之前已经计入失败次数，verify 返回错误时同样算作一次失败，否则并发的请求可以借助后端错误绕过限制。
+// 因此配合 TOTP.VerifyContext、HOTP.VerifyContext 使用时，后端持续不可用会导致用户被锁定，
+// 后端恢复之后可以使用 Reset 解锁受影响的账户。
+//
+// Example:
+//
+//	ok, err := throttle.VerifyContext(ctx, userID, func(ctx context.Context) (bool, error) {
+//		return totp.VerifyContext(ctx, token, time.Now())
+//	})
+func (t *Throttle) VerifyContext(ctx context.Context, key string, verify func(ctx context.Context) (bool, error)) (bool, error) {
+	result := t.VerifyContextWithResult(ctx, key, verify)
+	return result.Ok, result.Err
+}
+
+// VerifyWithResult 与 Verify 相同，额外返回剩余的尝试次数以及锁定的剩余时长，用于向用户展示锁定信息。
+//
+// Example:
+//
+//	result := throttle.VerifyWithResult(userID, func() bool {
+//		return totp.Verify(token, time.Now())
+//	})
+//	switch {
+//	case errors.Is(result.Err, ErrThrottled):
+//		fmt.Printf("尝试次数过多，请在 %s 后重试", result.RetryAfter.Round(time.Second))
+//	case !result.Ok:
+//		fmt.Printf("验证码错误，还可以尝试 %d 次", result.AttemptsRemaining)
+//	}
+func (t *Throttle) VerifyWithResult(key string, verify func() bool) VerifyResult {
+	return t.VerifyContextWithResult(context.Background(), key, func(context.Context) (bool, error) {
+		return verify(), nil
+	})
+}
+
+// VerifyContextWithResult 与 VerifyContext 相同，返回值与 VerifyWithResult 相同。
+//
+// 本次失败导致账户被锁定时 Err 为 nil，RetryAfter 为锁定的时长，AttemptsRemaining 为 0。
+// 尝试在校验之前已经计入失败次数，verify 返回错误时同样算作一次失败，AttemptsRemaining 为剩余的次数。
+func (t *Throttle) VerifyContextWithResult(ctx context.Context, key string, verify func(ctx context.Context) (bool, error)) VerifyResult {
+	if err := ctx.Err(); err != nil {
+		return VerifyResult{Err: err}
+	}
+	now := t.now()
+	// 先占用一次尝试再校验，读取和写入之间没有间隙，并发的猜测不能绕过限制
+	failures, err := t.Store.AddFailure(key, now, t.Window)
+	if err != nil {
+		return VerifyResult{Err: err}
+	}
+	if failures > t.MaxFailures {
+		return VerifyResult{Err: ErrThrottled, RetryAfter: t.retryAfter(key, now)}
+	}
+	ok, err := verify(ctx)
+	if ok && err == nil {
+		return VerifyResult{Ok: true, Err: t.Store.Reset(key), AttemptsRemaining: t.MaxFailures}
+	}
+	result := VerifyResult{Err: err, AttemptsRemaining: t.MaxFailures - failures}
+	if result.AttemptsRemaining == 0 {
+		result.RetryAfter = t.retryAfter(key, now)
+	}
+	return result
+}
+
+// Attempt 记录 key 的一次请求，不进行校验，窗口内超过 MaxFailures 次时返回 ErrThrottled。
+//
+// 与 Verify 不同，成功的请求同样计入次数，也不会清除记录，用于按客户端 IP 等维度限制请求的频率：
+// 同一个 IP 轮流尝试多个账户时每个账户的失败次数都不高，按账户计算的 Verify 无法发现。
+// 没有超出限制时 Ok 为 true，AttemptsRemaining 为窗口内剩余的请求次数。
+//
+// Example:
+//
+//	if result := ipThrottle.Attempt(clientIP); !result.Ok {
+//		return fmt.Errorf("请在 %s 后重试: %w", result.RetryAfter, result.Err)
+//	}
+func (t *Throttle) Attempt(key string) VerifyResult {
+	now := t.now()
+	attempts, err := t.Store.AddFailure(key, now, t.Window)
+	if err != nil {
+		return VerifyResult{Err: err}
+	}
+	if attempts > t.MaxFailures {
+		return VerifyResult{Err: ErrThrottled, RetryAfter: t.retryAfter(key, now)}
+	}
+	return VerifyResult{Ok: true, AttemptsRemaining: t.MaxFailures - attempts}
+}
+
+// retryAfter 返回 key 当前窗口的剩余时长，Store 没有实现 ThrottleExpiry 或者出错时返回 Window。
+func (t *Throttle) retryAfter(key string, now time.Time) time.Duration {
+	store, ok := t.Store.(ThrottleExpiry)
+	if !ok {
+		return t.Window
+	}
+	expiry, err := store.Expiry(key, now)
+	if err != nil {
+		return t.Window
+	}
+	if !expiry.After(now) {
+		return 0
+	}
+	return expiry.Sub(now)
+}
+
+// Reset 清除 key 的失败记录，例如管理员手动解锁账户。
+func (t *Throttle) Reset(key string) error {
+	return t.Store.Reset(key)
+}
+
+// MemoryThrottleStore 基于内存的 ThrottleStore 实现，并发安全。
+//
+// 只适用于单实例部署，过期的记录只会在下一次访问时清除。
+type MemoryThrottleStore struct {
+	mu       sync.Mutex
+	failures map[string]throttleEntry
+}
+
+type throttleEntry struct {
+	count   int
+	expires time.Time
+}
+
+// NewMemoryThrottleStore 创建一个 MemoryThrottleStore。
+func NewMemoryThrottleStore() *MemoryThrottleStore {
+	return &MemoryThrottleStore{failures: map[string]throttleEntry{}}
+}
+
+// Failures 实现 ThrottleStore 接口。
+func (s *MemoryThrottleStore) Failures(key string, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.failures[key]
+	if !ok {
+		return 0, nil
+	}
+	if !now.Before(entry.expires) {
+		delete(s.failures, key)
+		return 0, nil
+	}
+	return entry.count, nil
+}
+
+// Expiry 实现 ThrottleExpiry 接口。
+func (s *MemoryThrottleStore) Expiry(key string, now time.Time) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.failures[key]
+	if !ok || !now.Before(entry.expires) {
+		return time.Time{}, nil
+	}
+	return entry.expires, nil
+}
+
+// AddFailure 实现 ThrottleStore 接口。
+func (s *MemoryThrottleStore) AddFailure(key string, now time.Time, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.failures[key]
+	if !ok || !now.Before(entry.expires) {
+		entry = throttleEntry{expires: now.Add(window)}
+	}
+	entry.count++
+	s.failures[key] = entry
+	return entry.count, nil
+}
+
+// Reset 实现 ThrottleStore 接口。
+func (s *MemoryThrottleStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.failures, key)
+	return nil
+}
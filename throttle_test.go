@@ -0,0 +1,177 @@
+package otp
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryThrottleStore(t *testing.T) {
+	store := NewMemoryThrottleStore()
+	now := time.Unix(1704075000, 0)
+	count, _ := store.AddFailure("a", now, time.Minute)
+	assert.Equal(t, 1, count)
+	count, _ = store.AddFailure("a", now.Add(time.Second*30), time.Minute)
+	assert.Equal(t, 2, count)
+	count, _ = store.Failures("a", now.Add(time.Second*59))
+	assert.Equal(t, 2, count)
+	count, _ = store.Failures("b", now)
+	assert.Equal(t, 0, count)
+	// 窗口从第一次失败开始计算
+	count, _ = store.Failures("a", now.Add(time.Minute))
+	assert.Equal(t, 0, count)
+	count, _ = store.AddFailure("a", now.Add(time.Minute), time.Minute)
+	assert.Equal(t, 1, count)
+	assert.Nil(t, store.Reset("a"))
+	assert.Nil(t, store.Reset("a"))
+	count, _ = store.Failures("a", now.Add(time.Minute))
+	assert.Equal(t, 0, count)
+}
+
+func TestThrottle_Verify(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+	throttle := NewThrottle(3, time.Minute)
+	throttle.clock = func() time.Time { return now }
+	totp := NewTOTP(TestSecret20)
+	verify := func(token string) func() bool {
+		return func() bool { return totp.Verify(token, now) }
+	}
+
+	for i := 0; i < 3; i++ {
+		ok, err := throttle.Verify("alice", verify("000000"))
+		assert.False(t, ok)
+		assert.Nil(t, err)
+	}
+	// 达到上限后正确的 token 也会被拒绝，且不会调用 verify
+	called := false
+	ok, err := throttle.Verify("alice", func() bool { called = true; return true })
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, ErrThrottled)
+	assert.False(t, called)
+	allowed, _ := throttle.Allow("alice")
+	assert.False(t, allowed)
+
+	// 其他账户不受影响
+	ok, err = throttle.Verify("bob", verify("076141"))
+	assert.True(t, ok)
+	assert.Nil(t, err)
+
+	// 窗口过期后恢复
+	now = now.Add(time.Minute)
+	ok, err = throttle.Verify("alice", func() bool { return true })
+	assert.True(t, ok)
+	assert.Nil(t, err)
+
+	// 成功后清除失败记录
+	throttle.Verify("alice", verify("000000"))
+	throttle.Verify("alice", verify("000000"))
+	throttle.Verify("alice", func() bool { return true })
+	throttle.Verify("alice", verify("000000"))
+	throttle.Verify("alice", verify("000000"))
+	allowed, _ = throttle.Allow("alice")
+	assert.True(t, allowed)
+
+	throttle.Verify("alice", verify("000000"))
+	assert.Nil(t, throttle.Reset("alice"))
+	allowed, _ = throttle.Allow("alice")
+	assert.True(t, allowed)
+}
+
+func TestThrottle_VerifyWithResult(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	throttle := NewThrottle(3, time.Minute)
+	throttle.clock = func() time.Time { return now }
+
+	result := throttle.VerifyWithResult("alice", func() bool { return false })
+	assert.Equal(t, VerifyResult{AttemptsRemaining: 2}, result)
+	now = now.Add(10 * time.Second)
+	result = throttle.VerifyWithResult("alice", func() bool { return false })
+	assert.Equal(t, VerifyResult{AttemptsRemaining: 1}, result)
+
+	// 尝试在校验之前计入失败次数，verify 出错时同样算作一次失败，本次失败导致锁定
+	verifyErr := errors.New("store unavailable")
+	result = throttle.VerifyContextWithResult(context.Background(), "alice", func(context.Context) (bool, error) {
+		return false, verifyErr
+	})
+	assert.Equal(t, VerifyResult{Err: verifyErr, RetryAfter: 50 * time.Second}, result)
+
+	// 窗口从第一次失败开始计算
+	now = now.Add(20 * time.Second)
+	result = throttle.VerifyWithResult("alice", func() bool { return true })
+	assert.Equal(t, VerifyResult{Err: ErrThrottled, RetryAfter: 30 * time.Second}, result)
+
+	result = throttle.VerifyWithResult("bob", func() bool { return true })
+	assert.Equal(t, VerifyResult{Ok: true, AttemptsRemaining: 3}, result)
+
+	// Store 没有实现 ThrottleExpiry 时使用整个窗口的长度
+	throttle.Store = plainThrottleStore{throttle.Store}
+	result = throttle.VerifyWithResult("alice", func() bool { return true })
+	assert.Equal(t, VerifyResult{Err: ErrThrottled, RetryAfter: time.Minute}, result)
+}
+
+func TestThrottle_Attempt(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	throttle := NewThrottle(2, time.Minute)
+	throttle.clock = func() time.Time { return now }
+
+	assert.Equal(t, VerifyResult{Ok: true, AttemptsRemaining: 1}, throttle.Attempt("10.0.0.1"))
+	now = now.Add(15 * time.Second)
+	assert.Equal(t, VerifyResult{Ok: true, AttemptsRemaining: 0}, throttle.Attempt("10.0.0.1"))
+	assert.Equal(t, VerifyResult{Err: ErrThrottled, RetryAfter: 45 * time.Second}, throttle.Attempt("10.0.0.1"))
+	assert.True(t, throttle.Attempt("10.0.0.2").Ok)
+
+	// 窗口过期之后重新计数
+	now = now.Add(45 * time.Second)
+	assert.True(t, throttle.Attempt("10.0.0.1").Ok)
+
+	throttle.Store = errThrottleStore{err: errBackend}
+	assert.Equal(t, VerifyResult{Err: errBackend}, throttle.Attempt("10.0.0.1"))
+}
+
+// plainThrottleStore 只嵌入 ThrottleStore 接口，隐藏内部 Store 的 Expiry 方法。
+type plainThrottleStore struct{ ThrottleStore }
+
+func TestThrottle_VerifyConcurrent(t *testing.T) {
+	for _, store := range []ThrottleStore{NewMemoryThrottleStore(), plainThrottleStore{NewMemoryThrottleStore()}} {
+		throttle := &Throttle{Store: store, MaxFailures: 3, Window: time.Minute}
+		var calls int32
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				<-start
+				throttle.Verify("alice", func() bool {
+					atomic.AddInt32(&calls, 1)
+					// 放大读取和写入之间的间隙
+					time.Sleep(time.Millisecond)
+					return false
+				})
+			}()
+		}
+		close(start)
+		wg.Wait()
+		assert.Equal(t, int32(3), calls)
+	}
+}
+
+type errThrottleStore struct{ err error }
+
+func (s errThrottleStore) Failures(string, time.Time) (int, error) { return 0, s.err }
+func (s errThrottleStore) AddFailure(string, time.Time, time.Duration) (int, error) {
+	return 0, s.err
+}
+func (s errThrottleStore) Reset(string) error { return s.err }
+
+func TestThrottle_StoreError(t *testing.T) {
+	storeErr := errors.New("store unavailable")
+	throttle := &Throttle{Store: errThrottleStore{storeErr}, MaxFailures: 3, Window: time.Minute}
+	ok, err := throttle.Verify("alice", func() bool { return true })
+	assert.False(t, ok)
+	assert.Equal(t, storeErr, err)
+}
@@ -0,0 +1,149 @@
+package otp
+
+import (
+	"context"
+	"time"
+)
+
+// TimedToken 预先计算的 token 以及对应的计数器（时间步）和有效区间。
+type TimedToken struct {
+	Token string
+	// HOTP 的计数器，或 TOTP 的时间步（unix 秒数 / period）
+	Counter int64
+	// token 的有效区间 [From, To)，HOTP 的 token 没有有效区间，均为零值
+	From time.Time
+	To   time.Time
+}
+
+// MaxTokens Tokens 一次最多计算的 token 数量，超过时返回 nil，避免一个过大的区间分配大量内存。
+const MaxTokens = 100000
+
+// Tokens 计算 from 到 to 之间（包含两端所在的时间步）的所有 token，按时间顺序返回。
+//
+// 适用于打印应急 token 表、为无法联网的系统预置 token，以及排查时钟漂移问题。
+// to 早于 from、区间包含的时间步超过 MaxTokens 或读取秘钥失败时返回 nil。
+//
+// Example:
+//
+//	// 未来一小时的所有 token
+//	for _, token := range totp.Tokens(time.Now(), time.Now().Add(time.Hour)) {
+//		fmt.Println(token.From.Format(time.Kitchen), token.Token)
+//	}
+func (o *TOTP) Tokens(from, to time.Time) []TimedToken {
+	if to.Before(from) {
+		return nil
+	}
+	first := o.step(from)
+	n, ok := tokenCount(first, o.step(to))
+	if !ok {
+		return nil
+	}
+	generate, release, err := o.generator(context.Background())
+	if err != nil {
+		return nil
+	}
+	defer release()
+	tokens := make([]TimedToken, 0, n)
+	for i := int64(0); i < n; i++ {
+		step := first + i
+		token, err := generate(step)
+		if err != nil {
+			return nil
+		}
+		tokens = append(tokens, TimedToken{
+			Token:   token,
+			Counter: step,
+			From:    o.stepStart(step),
+			To:      o.stepStart(step + 1),
+		})
+	}
+	return tokens
+}
+
+// Subscribe 返回一个 channel，订阅后立即发送当前的 token，之后在每个时间步开始时发送新的 token。
+//
+// 发送时间与时间步的边界对齐，而不是与订阅的时间对齐。ctx 取消之后 channel 会被关闭；
+// 读取秘钥失败的时间步不会发送任何内容。channel 没有缓冲，接收方在时间步结束之前没有读取时，
+// 等待发送的 token 会被丢弃并重新计算，因此接收到的总是当前时间步的 token，错过的 token 不会补发。
+//
+// Example:
+//
+//	ctx, cancel := context.WithCancel(context.Background())
+//	defer cancel()
+//	for token := range totp.Subscribe(ctx) {
+//		fmt.Printf("\r%s (%s)", token.Token, token.To.Format(time.Kitchen))
+//	}
+func (o *TOTP) Subscribe(ctx context.Context) <-chan TimedToken {
+	ch := make(chan TimedToken)
+	go func() {
+		defer close(ch)
+		for {
+			now := o.now()
+			timer := time.NewTimer(o.RemainingDuration(now))
+			if tokens := o.Tokens(now, now); len(tokens) == 1 {
+				select {
+				case ch <- tokens[0]:
+				case <-timer.C:
+					// 时间步已经结束，丢弃过期的 token
+					continue
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				}
+			}
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// Tokens 计算 fromCounter 到 toCounter 之间（包含两端）的所有 token，按计数器顺序返回。
+//
+// toCounter 小于 fromCounter、区间包含的计数器超过 MaxTokens 或读取秘钥失败时返回 nil。
+//
+// Example:
+//
+//	// 打印 10 个备用 token，用户使用之后服务端的计数器需要更新为匹配值加一
+//	for _, token := range hotp.Tokens(counter, counter+9) {
+//		fmt.Println(token.Counter, token.Token)
+//	}
+func (h *HOTP) Tokens(fromCounter, toCounter int64) []TimedToken {
+	n, ok := tokenCount(fromCounter, toCounter)
+	if !ok {
+		return nil
+	}
+	generate, release, err := h.generator(context.Background())
+	if err != nil {
+		return nil
+	}
+	defer release()
+	tokens := make([]TimedToken, 0, n)
+	for i := int64(0); i < n; i++ {
+		counter := fromCounter + i
+		token, err := generate(counter)
+		if err != nil {
+			return nil
+		}
+		tokens = append(tokens, TimedToken{Token: token, Counter: counter})
+	}
+	return tokens
+}
+
+// tokenCount 返回 [first, last] 包含的计数器数量，last 小于 first 或数量超过 MaxTokens 时 ok 为 false。
+//
+// 使用无符号数计算差值，first、last 接近 int64 的边界时也不会溢出。
+func tokenCount(first, last int64) (n int64, ok bool) {
+	if last < first {
+		return 0, false
+	}
+	diff := uint64(last) - uint64(first)
+	if diff >= MaxTokens {
+		return 0, false
+	}
+	return int64(diff) + 1, true
+}
@@ -0,0 +1,100 @@
+package otp
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTOTP_Tokens(t *testing.T) {
+	totp := NewTOTP(TestSecret20)
+	from := time.Unix(1704075005, 0)
+	tokens := totp.Tokens(from, from.Add(time.Minute))
+	assert.Equal(t, 3, len(tokens))
+	for i, token := range tokens {
+		assert.Equal(t, from.Unix()/30+int64(i), token.Counter)
+		assert.Equal(t, totp.At(token.From), token.Token)
+		assert.Equal(t, 30*time.Second, token.To.Sub(token.From))
+		assert.Equal(t, 0, int(token.From.Unix()%30))
+	}
+	assert.True(t, !tokens[0].From.After(from) && tokens[0].To.After(from))
+
+	assert.Equal(t, 1, len(totp.Tokens(from, from)))
+	assert.Nil(t, totp.Tokens(from, from.Add(-time.Second)))
+
+	totp.Wipe()
+	assert.Nil(t, totp.Tokens(from, from.Add(time.Minute)))
+}
+
+func TestHOTP_Tokens(t *testing.T) {
+	hotp := NewHOTP("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+	tokens := hotp.Tokens(0, 2)
+	assert.Equal(t, []TimedToken{
+		{Token: "755224", Counter: 0},
+		{Token: "287082", Counter: 1},
+		{Token: "359152", Counter: 2},
+	}, tokens)
+	assert.Equal(t, 1, len(hotp.Tokens(5, 5)))
+	assert.Nil(t, hotp.Tokens(5, 4))
+}
+
+func TestTokens_Range(t *testing.T) {
+	hotp := NewHOTP("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+	assert.Len(t, hotp.Tokens(0, MaxTokens-1), MaxTokens)
+	assert.Nil(t, hotp.Tokens(0, MaxTokens))
+	assert.Nil(t, hotp.Tokens(math.MinInt64, math.MaxInt64))
+
+	// 计数器到达 int64 的最大值时正常结束，不会溢出
+	tokens := hotp.Tokens(math.MaxInt64-1, math.MaxInt64)
+	assert.Len(t, tokens, 2)
+	assert.Equal(t, int64(math.MaxInt64), tokens[1].Counter)
+	assert.Len(t, hotp.Tokens(math.MinInt64, math.MinInt64+1), 2)
+
+	totp := NewTOTP(TestSecret20)
+	from := time.Unix(0, 0)
+	assert.Nil(t, totp.Tokens(from, from.Add(MaxTokens*30*time.Second)))
+	assert.Len(t, totp.Tokens(from, from.Add((MaxTokens-1)*30*time.Second)), MaxTokens)
+}
+
+func TestTOTP_Subscribe(t *testing.T) {
+	// 让时钟停在距离时间步边界 100ms 的位置
+	start := time.Now()
+	boundary := time.Unix(1704075030, 0)
+	offset := boundary.Add(-100 * time.Millisecond).Sub(start)
+	totp := NewTOTP(TestSecret20, WithClock(func() time.Time { return time.Now().Add(offset) }))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := totp.Subscribe(ctx)
+
+	first := <-ch
+	assert.Equal(t, totp.At(boundary.Add(-time.Second)), first.Token)
+	assert.Equal(t, boundary, first.To)
+
+	second := <-ch
+	assert.Equal(t, first.Counter+1, second.Counter)
+	assert.Equal(t, boundary, second.From)
+	assert.Equal(t, totp.At(boundary), second.Token)
+
+	cancel()
+	for range ch {
+	}
+}
+
+func TestTOTP_SubscribeSlowReceiver(t *testing.T) {
+	start := time.Now()
+	boundary := time.Unix(1704075030, 0)
+	offset := boundary.Add(-100 * time.Millisecond).Sub(start)
+	totp := NewTOTP(TestSecret20, WithClock(func() time.Time { return time.Now().Add(offset) }))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := totp.Subscribe(ctx)
+
+	// 接收方在时间步结束之后才读取，收到的是新时间步的 token 而不是已经过期的 token
+	time.Sleep(300 * time.Millisecond)
+	token := <-ch
+	assert.Equal(t, boundary, token.From)
+	assert.Equal(t, totp.At(boundary), token.Token)
+}
@@ -1,9 +1,7 @@
 package otp
 
 import (
-	"crypto/hmac"
-	"fmt"
-	"net/url"
+	"context"
 	"time"
 )
 
@@ -14,20 +12,26 @@ type TOTP struct {
 	Secret string
 	// base32 decoded string
 	decodedSecret []byte
+	// 使用 NewTOTPFromStore 创建时，每次计算 token 都从 store 中读取秘钥
+	store   SecretStore
+	storeID string
+	// 使用 NewTOTPWithSigner 创建时，由 signer 计算 HMAC，内存中不保存秘钥
+	signer Signer
 }
 
 // NewTOTP 创建一个 TOTP 结构体，可以使用 option 的模式传递参数。
 //
 // Params:
 //
-//	secret       : 必传，一个 base32 编码后的字符串，建议使用 RandomSecret 方法生成的。
+//	secret       : 必传，一个 base32 编码后的字符串，建议使用 RandomSecret 方法生成的。允许小写、空格分组以及填充，会使用 NormalizeSecret 规范化。
 //	WithPeriod   : 设置 token 有效期长度。
 //	WithSkew     : 是否校验相邻的窗口。
 //	WithAlgorithm: 设置 hmac 算法类型。
 //
 // Panic:
-//   - secret base32 decode error
+//   - secret base32 decode error（*SecretDecodeError）
 //   - secret is an empty string
+//   - secret too short（*SecretStrengthError），仅在配置了 WithStrictSecretLength 时
 //
 // 默认参数才是 Google Authenticator 兼容的，自定义参数的话 Google Authenticator 可能不会识别。
 //
@@ -37,57 +41,164 @@ type TOTP struct {
 //
 //	secret := Base32Encode(RandomSecret(20))
 //	totp   := NewTOTP(secret, WithDigits(DigitsEight))
-func NewTOTP(secret string, options ...Option) *TOTP {
-	if secret == "" {
+func NewTOTP(secret string, options ...TOTPOption) *TOTP {
+	totp, err := newTOTP(secret, false, options...)
+	if err != nil {
+		panic(err)
+	}
+	return totp
+}
+
+// NewTOTPFromBytes 使用未编码的秘钥创建 TOTP 结构体，其余参数与 NewTOTP 一致。
+//
+// 秘钥会被复制，Secret 字段为秘钥的 base32 编码。十六进制的秘钥可以先使用 HexDecode 解码。
+//
+// Panic:
+//   - secret is empty
+//   - secret too short（*SecretStrengthError），仅在配置了 WithStrictSecretLength 时
+func NewTOTPFromBytes(secret []byte, options ...TOTPOption) *TOTP {
+	if len(secret) == 0 {
 		panic(ErrSecretCannotBeEmpty)
 	}
-	decodedSecret, err := Base32Decode(secret)
+	otp := newTOTPOtp(options)
+	if err := otp.checkSecretStrength(secret); err != nil {
+		panic(err)
+	}
+	return &TOTP{
+		Otp:           otp,
+		Secret:        Base32Encode(secret),
+		decodedSecret: append([]byte(nil), secret...),
+	}
+}
+
+// NewTOTPWithError 与 NewTOTP 相同，但是在 secret 为空或无法解码时返回错误而不是 panic。
+//
+// 与 NewTOTP 不同，option 配置了超出范围的参数时（例如 WithPeriod(0)）会返回 ErrInvalidOption 而不是静默修正，见 Otp.Validate。
+//
+// 适用于 secret 来自外部输入的场景，例如服务端根据外部数据批量开通账号。
+//
+// Example:
+//
+//	totp, err := NewTOTPWithError(secret)
+//	if errors.Is(err, ErrSecretDecode) {
+//		// secret 格式错误
+//	}
+func NewTOTPWithError(secret string, options ...TOTPOption) (*TOTP, error) {
+	return newTOTP(secret, true, options...)
+}
+
+// newTOTP 创建 TOTP 结构体，validate 为 true 时校验 option 配置的参数。
+func newTOTP(secret string, validate bool, options ...TOTPOption) (*TOTP, error) {
+	otp := newTOTPOtp(options)
+	secret, decodedSecret, err := otp.decodeSecret(secret)
 	if err != nil {
-		fmt.Println(err, secret)
-		panic(ErrSecretDecode)
+		return nil, err
 	}
-	otp := Otp{
-		Skew:      0,
-		Counter:   1,
-		Period:    30,
-		Algorithm: AlgorithmSHA1,
-		Digits:    DigitsSix,
+	if validate {
+		if err := otp.Validate(); err != nil {
+			return nil, err
+		}
 	}
-	for _, opt := range options {
-		opt(&otp)
+	if err := otp.checkSecretStrength(decodedSecret); err != nil {
+		return nil, err
 	}
 	return &TOTP{
 		Otp:           otp,
 		Secret:        secret,
 		decodedSecret: decodedSecret,
-	}
+	}, nil
 }
 
 // Now 基于当前时间点生成 token。
 func (o *TOTP) Now() string {
-	return o.At(time.Now())
+	return o.At(o.now())
+}
+
+// NowWithExpiration 获取当前时间的 token 和 token 失效的时间。
+func (o *TOTP) NowWithExpiration() (string, time.Time) {
+	return o.WithExpiration(o.now())
 }
 
 // At 生成某个时间点的 token。
+//
+// 使用 NewTOTPFromStore 创建时，如果从 store 中读取秘钥失败将会返回空字符串。
 func (o *TOTP) At(t time.Time) string {
-	key := intToByte(t.Unix() / int64(o.Period))
-	hashFunc := hasher(o.Algorithm)
-	mac := hmac.New(hashFunc, o.decodedSecret)
-	mac.Write(key)
-	h := mac.Sum(nil)
-	return truncate(h, int(o.Digits))
+	return o.AtStep(o.step(t))
+}
+
+// AtStep 生成指定时间步的 token，时间步可以通过 StepFor 或 VerifyWithMatch 获取。
+//
+// 使用 NewTOTPFromStore 创建时，如果从 store 中读取秘钥失败将会返回空字符串。
+//
+// Example:
+//
+//	step := totp.StepFor(time.Now())
+//	prev := totp.AtStep(step - 1) // 上一个时间窗口的 token
+func (o *TOTP) AtStep(step int64) string {
+	generate, release, err := o.generator(context.Background())
+	if err != nil {
+		return ""
+	}
+	defer release()
+	token, err := generate(step)
+	if err != nil {
+		return ""
+	}
+	return token
 }
 
-// WithExpiration 获取指定时间的 token 和对应的剩余有效时间。
-func (o *TOTP) WithExpiration(t time.Time) (string, int) {
-	token := o.At(t)
-	expiration := o.Expiration(t)
-	return token, expiration
+// StepFor 获取指定时间所在的时间步（(unix 秒数 - T0) / period），与 VerifyWithMatch 返回的时间步可以直接比较。
+//
+// Example:
+//
+//	// 服务端保存最后一次接受的时间步，拒绝重复使用
+//	step, ok := totp.VerifyWithMatch(token, time.Now())
+//	if !ok || step <= lastAcceptedStep {
+//		return false
+//	}
+func (o *TOTP) StepFor(t time.Time) int64 {
+	return o.step(t)
 }
 
-// Expiration 获取指定时间窗口的 token 剩余有效时间。
+// WithExpiration 获取指定时间的 token 和 token 失效的时间，即 ValidUntil(t)。
+//
+// Example:
+//
+//	token, validUntil := totp.WithExpiration(time.Now())
+//	fmt.Printf("%s 将在 %s 后失效", token, time.Until(validUntil).Round(time.Second))
+func (o *TOTP) WithExpiration(t time.Time) (string, time.Time) {
+	return o.At(t), o.ValidUntil(t)
+}
+
+// Expiration 获取指定时间窗口的 token 剩余有效时间（秒），不足一秒的部分会被忽略，需要更高精度时使用 RemainingDuration。
 func (o *TOTP) Expiration(t time.Time) int {
-	return int(int64(o.Period) - t.Unix()%int64(o.Period))
+	return int(o.ValidUntil(t).Unix() - t.Unix())
+}
+
+// RemainingDuration 获取指定时间的 token 剩余的有效时长，保留亚秒精度。
+func (o *TOTP) RemainingDuration(t time.Time) time.Duration {
+	return o.ValidUntil(t).Sub(t)
+}
+
+// ValidUntil 获取指定时间的 token 失效的时间，即下一个时间步开始的时间。
+func (o *TOTP) ValidUntil(t time.Time) time.Time {
+	return o.stepStart(o.step(t) + 1)
+}
+
+// CurrentStep 获取指定时间所在的时间步，与 StepFor 相同。
+func (o *TOTP) CurrentStep(t time.Time) int64 {
+	return o.StepFor(t)
+}
+
+// Progress 获取指定时间在当前时间步中已经经过的比例，取值范围为 [0, 1)，用于在界面上绘制倒计时进度。
+//
+// Example:
+//
+//	token := totp.At(now)
+//	drawRing(1 - totp.Progress(now)) // 剩余时间的比例
+func (o *TOTP) Progress(t time.Time) float64 {
+	period := time.Duration(o.Period) * time.Second
+	return float64(period-o.RemainingDuration(t)) / float64(period)
 }
 
 // Verify 校验 token 是否在指定的时间有效。
@@ -96,31 +207,93 @@ func (o *TOTP) Expiration(t time.Time) int {
 //
 //	token: 需要进行校验的参数，一个字符串，如果字符串为空将会返回 false。
 //	t    : 指定的时间，用以校验 token 在这个时间点是否仍有效。
+//
+// 如果配置了 WithNotBefore 或 WithNotAfter，t 不在秘钥有效期内时将会返回 false。
+// 如果配置了 WithReplayGuard，已经使用过的 token 将会返回 false。
 func (o *TOTP) Verify(token string, t time.Time) bool {
-	if token == "" {
-		return false
-	}
-	givenTime := t
-	sec := t.Unix()
-	for i := o.Skew * -1; i <= o.Skew; i++ {
-		givenTime = time.Unix(sec, 0).Add(time.Second * time.Duration(o.Period*i))
-		if o.At(givenTime) == token {
-			return true
-		}
+	_, ok := o.VerifyWithMatch(token, t)
+	return ok
+}
+
+// VerifyWithMatch 与 Verify 相同，额外返回校验通过的时间步（unix 秒数 / period）。
+//
+// 配置了 WithSkew 时匹配的时间步可能与 t 所在的时间步不同，调用方可以持久化最后一次使用的时间步，
+// 拒绝小于等于该值的时间步，防止同一个 token 被重复使用。
+//
+// Example:
+//
+//	step, ok := totp.VerifyWithMatch(token, time.Now())
+//	if !ok || step <= lastUsedStep {
+//		return false
+//	}
+//	lastUsedStep = step
+func (o *TOTP) VerifyWithMatch(token string, t time.Time) (int64, bool) {
+	current := o.step(t)
+	event := o.beginVerify("totp", current, t)
+	step, outcome, err := o.matchContext(context.Background(), token, t, current)
+	o.endVerify(event, step, outcome, err)
+	if outcome != VerifySuccess {
+		return 0, false
 	}
-	return false
+	return step, true
+}
+
+// VerifyNow 校验 token 在当前时间是否有效。
+func (o *TOTP) VerifyNow(token string) bool {
+	return o.Verify(token, o.now())
 }
 
 // KeyURI 返回一个 KeyURI 结构体，其包含转换至 URI 和生成二维码的方法。
-func (o *TOTP) KeyURI(account, issuer string) *KeyURI {
+//
+// 不传参数时使用 WithAccountName 和 WithIssuer 配置的值；按顺序传入 account、issuer 时覆盖配置的值，
+// 只传入 account 时 issuer 仍使用配置的值。多余的参数将被忽略。
+//
+// Example:
+//
+//	totp.KeyURI()                                // 使用配置的账户名称和发行商
+//	totp.KeyURI("alice@google.com", "Example")   // 覆盖配置的值
+func (o *TOTP) KeyURI(override ...string) *KeyURI {
+	account, issuer := o.labelParts(override)
 	ret := &KeyURI{
-		Type:      "totp",
-		Label:     url.PathEscape(fmt.Sprintf("%s:%s", issuer, account)),
-		Algorithm: o.Algorithm.String(),
-		Digits:    int(o.Digits),
-		Period:    o.Period,
-		Issuer:    url.QueryEscape(issuer),
-		Secret:    o.Secret,
+		Type:        "totp",
+		Label:       formatLabel(issuer, account),
+		AccountName: account,
+		Algorithm:   o.Algorithm.String(),
+		Digits:      int(o.Digits),
+		Period:      o.Period,
+		Issuer:      issuer,
+		Secret:      o.secretString(),
+		Encoder:     o.Encoder.String(),
+	}
+	if o.epochParameter {
+		ret.Epoch = o.epoch()
 	}
 	return ret
 }
+
+// secretString 返回 base32 编码的秘钥，读取失败时返回空字符串。
+func (o *TOTP) secretString() string {
+	if o.signer != nil {
+		return ""
+	}
+	if o.store == nil {
+		return o.Secret
+	}
+	secret, err := o.secretContext(context.Background())
+	if err != nil {
+		return ""
+	}
+	defer zero(secret)
+	return Base32Encode(secret)
+}
+
+// replayKey 返回 ReplayGuard 中标识账户的 key，使用 Signer 签名失败时返回错误。
+func (o *TOTP) replayKey() (string, error) {
+	if o.signer != nil {
+		return signerReplayKey(o.signer, o.Algorithm)
+	}
+	if o.store != nil {
+		return "store:" + o.storeID, nil
+	}
+	return replayKey(o.decodedSecret), nil
+}
@@ -1,8 +1,13 @@
 package otp
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/sha3"
+	"hash"
 	"testing"
 	"time"
 )
@@ -38,17 +43,43 @@ func TestNewTOTP(t *testing.T) {
 	assert.PanicsWithError(t, ErrSecretCannotBeEmpty.Error(), func() {
 		NewTOTP("")
 	})
-	assert.PanicsWithError(t, ErrSecretDecode.Error(), func() {
+	assert.PanicsWithError(t, `secret base32 decode error: illegal character '1' at position 0, did you mean 'I'`, func() {
 		NewHOTP("111111")
 	})
 }
 
+func TestNewTOTPWithError(t *testing.T) {
+	totp, err := NewTOTPWithError(TestSecret20, WithDigits(DigitsEight))
+	assert.Nil(t, err)
+	assert.Equal(t, NewTOTP(TestSecret20, WithDigits(DigitsEight)).Otp, totp.Otp)
+	assert.Equal(t, TestSecret20, totp.Secret)
+
+	totp, err = NewTOTPWithError("")
+	assert.Nil(t, totp)
+	assert.Equal(t, ErrSecretCannotBeEmpty, err)
+
+	totp, err = NewTOTPWithError("111111")
+	assert.Nil(t, totp)
+	assert.ErrorIs(t, err, ErrSecretDecode)
+}
+
 func TestTOTP_Now(t *testing.T) {
 	totp := NewTOTP(TestSecret20)
 	token := totp.Now()
 	assert.Equal(t, true, totp.Verify(token, time.Now()))
 }
 
+func TestTOTP_VerifyNow(t *testing.T) {
+	totp := NewTOTP(TestSecret20)
+	assert.Equal(t, true, totp.VerifyNow(totp.Now()))
+	assert.Equal(t, false, totp.VerifyNow(""))
+
+	token, validUntil := totp.NowWithExpiration()
+	assert.Equal(t, true, totp.VerifyNow(token))
+	remaining := time.Until(validUntil)
+	assert.True(t, remaining > 0 && remaining <= 30*time.Second)
+}
+
 func TestTOTP_At(t *testing.T) {
 	totp := NewTOTP(TestSecret20)
 	time1 := time.Unix(1704075000000, 0)
@@ -59,17 +90,29 @@ func TestTOTP_At(t *testing.T) {
 func TestTOTP_WithExpiration(t *testing.T) {
 	totp := NewTOTP(TestSecret20)
 	sec := int64(1704075000000)
-	token, expiration := totp.WithExpiration(time.Unix(sec, 0))
+	token, validUntil := totp.WithExpiration(time.Unix(sec, 0))
 	assert.Equal(t, "076141", token)
-	assert.Equal(t, 30, expiration)
+	assert.Equal(t, time.Unix(sec+30, 0), validUntil)
 
-	token1, expiration1 := totp.WithExpiration(time.Unix(sec, 0).Add(time.Second))
+	token1, validUntil1 := totp.WithExpiration(time.Unix(sec, 0).Add(time.Second))
 	assert.Equal(t, "076141", token1)
-	assert.Equal(t, 29, expiration1)
+	assert.Equal(t, time.Unix(sec+30, 0), validUntil1)
 
-	token2, expiration2 := totp.WithExpiration(time.Unix(sec, 0).Add(time.Second * 28))
+	token2, validUntil2 := totp.WithExpiration(time.Unix(sec, 0).Add(time.Second * 28))
 	assert.Equal(t, "076141", token2)
-	assert.Equal(t, 2, expiration2)
+	assert.Equal(t, time.Unix(sec+30, 0), validUntil2)
+}
+
+func TestTOTP_RemainingDuration(t *testing.T) {
+	totp := NewTOTP(TestSecret20)
+	time1 := time.Unix(1704075000000, 0)
+	assert.Equal(t, 30*time.Second, totp.RemainingDuration(time1))
+	assert.Equal(t, 29500*time.Millisecond, totp.RemainingDuration(time1.Add(500*time.Millisecond)))
+	assert.Equal(t, time.Millisecond, totp.RemainingDuration(time1.Add(29999*time.Millisecond)))
+
+	assert.Equal(t, time1.Add(30*time.Second), totp.ValidUntil(time1))
+	assert.Equal(t, time1.Add(30*time.Second), totp.ValidUntil(time1.Add(29999*time.Millisecond)))
+	assert.Equal(t, time1.Add(60*time.Second), totp.ValidUntil(time1.Add(30*time.Second)))
 }
 
 func TestTOTP_Expiration(t *testing.T) {
@@ -79,6 +122,19 @@ func TestTOTP_Expiration(t *testing.T) {
 	assert.Equal(t, 29, totp.Expiration(time1.Add(time.Second)))
 }
 
+func TestTOTP_Progress(t *testing.T) {
+	totp := NewTOTP(TestSecret20)
+	time1 := time.Unix(1704075000000, 0)
+	assert.Equal(t, int64(1704075000000/30), totp.CurrentStep(time1))
+	assert.Equal(t, int64(1704075000000/30), totp.CurrentStep(time1.Add(29*time.Second)))
+	assert.Equal(t, int64(1704075000000/30+1), totp.CurrentStep(time1.Add(30*time.Second)))
+
+	assert.Equal(t, 0.0, totp.Progress(time1))
+	assert.Equal(t, 0.5, totp.Progress(time1.Add(15*time.Second)))
+	assert.InDelta(t, 0.25, totp.Progress(time1.Add(7500*time.Millisecond)), 1e-9)
+	assert.Equal(t, 0.0, totp.Progress(time1.Add(30*time.Second)))
+}
+
 // online verify : https://www.verifyr.com/en/otp/check
 func TestTOTP_Verify(t *testing.T) {
 	sec := int64(1704075000000)
@@ -111,6 +167,44 @@ func TestTOTP_Verify(t *testing.T) {
 		totp := NewTOTP(TestSecret64, WithAlgorithm(AlgorithmSHA512))
 		assert.Equal(t, totp.Verify("720824", time.Unix(sec, 0)), true)
 	})
+
+	t.Run("test sha224 and sha384 algorithm", func(t *testing.T) {
+		for algorithm, newHash := range map[Algorithms]func() hash.Hash{
+			AlgorithmSHA224: sha256.New224,
+			AlgorithmSHA384: sha512.New384,
+		} {
+			key, _ := Base32Decode(TestSecret32)
+			mac := hmac.New(newHash, key)
+			mac.Write(intToByte(sec / 30))
+			expected := truncate(mac.Sum(nil), 6)
+			totp := NewTOTP(TestSecret32, WithAlgorithm(algorithm))
+			assert.Equal(t, expected, totp.At(time.Unix(sec, 0)))
+			assert.Equal(t, true, totp.Verify(expected, time.Unix(sec, 0)))
+
+			uri := totp.KeyURI("alice@google.com", "Example")
+			assert.Contains(t, uri.URI().String(), "&algorithm="+algorithm.String())
+			parsed, err := FromURI(uri.URI().String())
+			assert.Nil(t, err)
+			assert.Equal(t, algorithm.String(), parsed.Algorithm)
+		}
+	})
+
+	t.Run("test sha3 algorithm", func(t *testing.T) {
+		// 使用标准库 hmac 与 sha3 手动计算期望值
+		for algorithm, newHash := range map[Algorithms]func() hash.Hash{
+			AlgorithmSHA3_256: sha3.New256,
+			AlgorithmSHA3_512: sha3.New512,
+		} {
+			key, _ := Base32Decode(TestSecret32)
+			mac := hmac.New(newHash, key)
+			mac.Write(intToByte(sec / 30))
+			expected := truncate(mac.Sum(nil), 6)
+			totp := NewTOTP(TestSecret32, WithAlgorithm(algorithm))
+			assert.Equal(t, expected, totp.At(time.Unix(sec, 0)))
+			assert.Equal(t, true, totp.Verify(expected, time.Unix(sec, 0)))
+			assert.NotEqual(t, NewTOTP(TestSecret32, WithAlgorithm(AlgorithmSHA256)).At(time.Unix(sec, 0)), expected)
+		}
+	})
 }
 
 func TestTOTP_KeyURI(t *testing.T) {
@@ -119,13 +213,14 @@ func TestTOTP_KeyURI(t *testing.T) {
 		uri := totp.KeyURI("alice@google.com", "Example")
 		expected := fmt.Sprintf("otpauth://totp/Example:alice@google.com?secret=%s&issuer=Example", TestSecret20)
 		expectedKeyUri := &KeyURI{
-			Digits:    6,
-			Period:    30,
-			Type:      "totp",
-			Algorithm: "SHA1",
-			Issuer:    "Example",
-			Label:     "Example:alice@google.com",
-			Secret:    TestSecret20,
+			Digits:      6,
+			Period:      30,
+			Type:        "totp",
+			Algorithm:   "SHA1",
+			Issuer:      "Example",
+			Label:       "Example:alice@google.com",
+			AccountName: "alice@google.com",
+			Secret:      TestSecret20,
 		}
 		assert.Equal(t, expected, uri.URI().String())
 		assert.Equal(t, expectedKeyUri, uri)
@@ -136,15 +231,216 @@ func TestTOTP_KeyURI(t *testing.T) {
 		uri2 := totp2.KeyURI("alice@google.com", "Example")
 		expected2 := fmt.Sprintf("otpauth://totp/Example:alice@google.com?secret=%s&issuer=Example&algorithm=SHA256&digits=8&period=60", TestSecret32)
 		expectedKeyUri2 := &KeyURI{
-			Digits:    8,
-			Period:    60,
-			Type:      "totp",
-			Algorithm: "SHA256",
-			Issuer:    "Example",
-			Label:     "Example:alice@google.com",
-			Secret:    TestSecret32,
+			Digits:      8,
+			Period:      60,
+			Type:        "totp",
+			Algorithm:   "SHA256",
+			Issuer:      "Example",
+			Label:       "Example:alice@google.com",
+			AccountName: "alice@google.com",
+			Secret:      TestSecret32,
 		}
 		assert.Equal(t, expected2, uri2.URI().String())
 		assert.Equal(t, expectedKeyUri2, uri2)
 	})
 }
+
+func TestTOTP_KeyURIConfigured(t *testing.T) {
+	otp := NewTOTP(TestSecret20, WithIssuer("Example Co"), WithAccountName("alice@google.com"))
+	key := otp.KeyURI()
+	assert.Equal(t, "Example Co:alice@google.com", key.Label)
+	assert.Equal(t, "Example Co", key.Issuer)
+	assert.Equal(t, "alice@google.com", key.AccountName)
+
+	// 显式传入的参数覆盖配置的值
+	assert.Equal(t, "Example Co:bob", otp.KeyURI("bob").Label)
+	assert.Equal(t, "Other:bob", otp.KeyURI("bob", "Other").Label)
+	assert.Equal(t, "bob", otp.KeyURI("bob", "").Label)
+	assert.Equal(t, otp.KeyURI("alice@google.com", "Example Co"), key)
+}
+
+func TestTOTP_VerifyValidityWindow(t *testing.T) {
+	sec := int64(1704075000000)
+	now := time.Unix(sec, 0)
+
+	t.Run("not yet active", func(t *testing.T) {
+		totp := NewTOTP(TestSecret20, WithNotBefore(now.Add(time.Hour)))
+		assert.Equal(t, false, totp.Verify("076141", now))
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		totp := NewTOTP(TestSecret20, WithNotAfter(now.Add(-time.Hour)))
+		assert.Equal(t, false, totp.Verify("076141", now))
+	})
+
+	t.Run("inside window", func(t *testing.T) {
+		totp := NewTOTP(TestSecret20, WithNotBefore(now.Add(-time.Hour)), WithNotAfter(now.Add(time.Hour)))
+		assert.Equal(t, true, totp.Verify("076141", now))
+	})
+}
+
+func TestTOTP_WithClock(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+	totp := NewTOTP(TestSecret20, WithClock(func() time.Time { return now }))
+	assert.Equal(t, "076141", totp.Now())
+	assert.Equal(t, true, totp.VerifyNow("076141"))
+
+	token, validUntil := totp.NowWithExpiration()
+	assert.Equal(t, "076141", token)
+	assert.Equal(t, now.Add(30*time.Second), validUntil)
+
+	now = now.Add(time.Second * 30)
+	assert.Equal(t, false, totp.VerifyNow("076141"))
+
+	// nil 恢复为 time.Now
+	totp2 := NewTOTP(TestSecret20, WithClock(nil))
+	assert.Equal(t, true, totp2.Verify(totp2.Now(), time.Now()))
+}
+
+func TestTOTP_VerifyWithMatch(t *testing.T) {
+	sec := int64(1704075000000)
+	totp := NewTOTP(TestSecret20, WithSkew(1))
+
+	step, ok := totp.VerifyWithMatch("076141", time.Unix(sec, 0))
+	assert.Equal(t, true, ok)
+	assert.Equal(t, sec/30, step)
+
+	// 在下一个时间窗口校验通过时，返回的仍是 token 所属的时间步
+	step, ok = totp.VerifyWithMatch("076141", time.Unix(sec, 0).Add(time.Second*30))
+	assert.Equal(t, true, ok)
+	assert.Equal(t, sec/30, step)
+
+	_, ok = totp.VerifyWithMatch("076141", time.Unix(sec, 0).Add(time.Second*60))
+	assert.Equal(t, false, ok)
+	_, ok = totp.VerifyWithMatch("", time.Unix(sec, 0))
+	assert.Equal(t, false, ok)
+}
+
+func TestTOTP_WithSkewWindow(t *testing.T) {
+	sec := int64(1704075000000)
+	totp := NewTOTP(TestSecret20, WithSkewWindow(1, 0))
+	// token 来自上一个窗口
+	assert.Equal(t, true, totp.Verify("076141", time.Unix(sec, 0).Add(time.Second*30)))
+	assert.Equal(t, false, totp.Verify("076141", time.Unix(sec, 0).Add(time.Second*60)))
+	// token 来自下一个窗口
+	assert.Equal(t, false, totp.Verify("076141", time.Unix(sec, 0).Add(time.Second*-30)))
+
+	totp2 := NewTOTP(TestSecret20, WithSkewWindow(0, 2))
+	assert.Equal(t, false, totp2.Verify("076141", time.Unix(sec, 0).Add(time.Second*30)))
+	assert.Equal(t, true, totp2.Verify("076141", time.Unix(sec, 0).Add(time.Second*-60)))
+
+	// 后配置的 option 生效
+	totp3 := NewTOTP(TestSecret20, WithSkewWindow(0, 0), WithSkew(1))
+	assert.Equal(t, true, totp3.Verify("076141", time.Unix(sec, 0).Add(time.Second*-30)))
+	totp4 := NewTOTP(TestSecret20, WithSkew(1), WithSkewWindow(-1, -1))
+	assert.Equal(t, false, totp4.Verify("076141", time.Unix(sec, 0).Add(time.Second*-30)))
+	assert.Equal(t, true, totp4.Verify("076141", time.Unix(sec, 0)))
+}
+
+func TestTOTP_Digits(t *testing.T) {
+	// RFC-6238 附录 B 中 SHA1 的测试向量为 8 位，截断前的 31 位整数为 1094287082
+	secret := Base32Encode([]byte("12345678901234567890"))
+	at := time.Unix(59, 0)
+	for digits, expected := range map[Digits]string{
+		4:  "7082",
+		6:  "287082",
+		7:  "4287082",
+		8:  "94287082",
+		10: "1094287082",
+	} {
+		totp := NewTOTP(secret, WithDigits(digits))
+		assert.Equal(t, expected, totp.At(at))
+		assert.Equal(t, true, totp.Verify(expected, at))
+	}
+
+	key, err := FromURI("otpauth://totp/Example:alice?secret=" + secret + "&digits=7")
+	assert.Nil(t, err)
+	assert.Equal(t, 7, key.Digits)
+	totp, err := key.TOTP()
+	assert.Nil(t, err)
+	assert.Equal(t, "4287082", totp.At(at))
+}
+
+func TestNewTOTPFromBytes(t *testing.T) {
+	// RFC-6238 附录 B
+	secret, _ := HexDecode("3132333435363738393031323334353637383930")
+	totp := NewTOTPFromBytes(secret, WithDigits(DigitsEight))
+	assert.Equal(t, "94287082", totp.At(time.Unix(59, 0)))
+	assert.Equal(t, "07081804", totp.At(time.Unix(1111111109, 0)))
+	assert.Equal(t, Base32Encode(secret), totp.Secret)
+
+	// 秘钥会被复制
+	secret[0] = 0
+	assert.Equal(t, "94287082", totp.At(time.Unix(59, 0)))
+
+	assert.PanicsWithError(t, ErrSecretCannotBeEmpty.Error(), func() {
+		NewTOTPFromBytes(nil)
+	})
+}
+
+func TestNewTOTP_NormalizeSecret(t *testing.T) {
+	totp := NewTOTP("j3w2 xpzp 5hdy xyrb 4hs6 zlu6 m6vb o6c6")
+	assert.Equal(t, TestSecret20, totp.Secret)
+	assert.Equal(t, "076141", totp.At(time.Unix(1704075000000, 0)))
+
+	key, err := FromURI("otpauth://totp/Example:alice?secret=j3w2%20xpzp%205hdy%20xyrb%204hs6%20zlu6%20m6vb%20o6c6")
+	assert.Nil(t, err)
+	assert.Equal(t, TestSecret20, key.Secret)
+
+	_, err = FromURI("otpauth://totp/Example:alice?secret=J3W2XPZ1")
+	assert.ErrorIs(t, err, ErrURIFormat)
+}
+
+func TestTOTP_WithEpoch(t *testing.T) {
+	epoch := time.Unix(1704075000000-300, 0)
+	totp := NewTOTP(TestSecret20, WithEpoch(epoch))
+	now := time.Unix(1704075000000, 0)
+	// T0 之后的第 10 个时间步
+	assert.Equal(t, int64(10), totp.CurrentStep(now))
+	assert.Equal(t, NewTOTP(TestSecret20).At(time.Unix(300, 0)), totp.At(now))
+	assert.Equal(t, true, totp.Verify(totp.At(now), now))
+	assert.Equal(t, false, NewTOTP(TestSecret20).Verify(totp.At(now), now))
+
+	// T0 不是 period 的整数倍时，时间步的边界随之移动
+	totp = NewTOTP(TestSecret20, WithEpoch(time.Unix(1704075000000-10, 0)))
+	assert.Equal(t, now.Add(20*time.Second), totp.ValidUntil(now))
+	assert.Equal(t, 20, totp.Expiration(now))
+	tokens := totp.Tokens(now, now)
+	assert.Equal(t, now.Add(-10*time.Second), tokens[0].From)
+	assert.Equal(t, totp.At(now), tokens[0].Token)
+
+	// 默认不输出 epoch 参数
+	assert.NotContains(t, totp.KeyURI("alice", "Example").URI().String(), "epoch")
+	totp = NewTOTP(TestSecret20, WithEpoch(time.Unix(1000, 0)), WithEpochParameter(true))
+	uri := totp.KeyURI("alice", "Example").URI().String()
+	assert.Contains(t, uri, "&epoch=1000")
+
+	key, err := FromURI(uri)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1000), key.Epoch)
+	parsed, err := key.TOTP()
+	assert.Nil(t, err)
+	assert.Equal(t, totp.At(now), parsed.At(now))
+	assert.Equal(t, uri, parsed.KeyURI("alice", "Example").URI().String())
+
+	_, err = FromURI("otpauth://totp/Example:alice?secret=" + TestSecret20 + "&epoch=abc")
+	assert.ErrorIs(t, err, ErrInvalidEpoch)
+	_, err = MigrationURI(key)
+	assert.ErrorIs(t, err, ErrMigrationUnsupported)
+}
+
+func TestTOTP_AtStep(t *testing.T) {
+	totp := NewTOTP(TestSecret20)
+	now := time.Unix(1704075000000, 0)
+	step := totp.StepFor(now)
+	assert.Equal(t, int64(1704075000000/30), step)
+	assert.Equal(t, "076141", totp.AtStep(step))
+	assert.Equal(t, totp.At(now.Add(-30*time.Second)), totp.AtStep(step-1))
+
+	matched, ok := totp.VerifyWithMatch(totp.AtStep(step), now)
+	assert.Equal(t, true, ok)
+	assert.Equal(t, step, matched)
+
+	totp.Wipe()
+	assert.Equal(t, "", totp.AtStep(step))
+}
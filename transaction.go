@@ -0,0 +1,112 @@
+package otp
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// TransactionSuite 交易确认码默认使用的 OCRA suite：SHA256、8 位、64 个十六进制字符的 question、1 分钟时间步长。
+const TransactionSuite = "OCRA-1:HOTP-SHA256-8:QH64-T1M"
+
+// transactionDomain 计算交易摘要时的前缀，避免与其他用途的 OCRA question 混用。
+const transactionDomain = "go-otp transaction v1"
+
+// Transaction 需要用户确认的交易详情，确认码只对完全相同的详情有效。
+//
+// 用户的设备需要向用户展示金额和收款人并使用相同的详情计算确认码，
+// 钓鱼页面即使拿到了确认码也无法用于金额或收款人不同的另一笔交易。
+type Transaction struct {
+	// 以最小货币单位表示的金额，例如 125000 表示 1250.00，避免 "1250" 与 "1250.00" 计算出不同的确认码
+	Amount int64
+	// 币种，例如 "EUR"
+	Currency string
+	// 收款人，例如 IBAN 或账户 ID，只以哈希的形式参与计算
+	Recipient string
+	// 可选，服务端为每笔交易生成的随机值，确认之后作废，防止同一笔交易的确认码在时间步内被重复使用
+	Nonce string
+}
+
+// Challenge 返回交易详情的 SHA-256 摘要（64 个十六进制字符），作为 OCRA 的 question。
+//
+// 每个字段都带有长度前缀，字段之间的边界不会产生歧义，例如 Currency 为 "EU"、Recipient 为 "R..."
+// 与 Currency 为 "EUR"、Recipient 为 "..." 的摘要不同。
+func (tx Transaction) Challenge() string {
+	recipient := sha256.Sum256([]byte(tx.Recipient))
+	amount := make([]byte, 8)
+	binary.BigEndian.PutUint64(amount, uint64(tx.Amount))
+	h := sha256.New()
+	for _, field := range [][]byte{[]byte(transactionDomain), amount, []byte(tx.Currency), recipient[:], []byte(tx.Nonce)} {
+		length := make([]byte, 4)
+		binary.BigEndian.PutUint32(length, uint32(len(field)))
+		h.Write(length)
+		h.Write(field)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// TransactionConfirmer 基于 OCRA 计算和校验绑定了交易详情的确认码。
+type TransactionConfirmer struct {
+	OCRA *OCRA
+	// suite 包含时间步长时，Verify 额外接受之前 Skew 个时间步的确认码，给用户输入留出时间，默认为 1。
+	Skew int
+}
+
+// NewTransactionConfirmer 创建一个 TransactionConfirmer。
+//
+// Params:
+//
+//	suite : 必传，OCRA suite 字符串，question 必须为 QH64，不能包含 C、P、S，建议包含 T，例如 TransactionSuite。
+//	secret: 必传，一个 base32 编码后的字符串。
+//
+// suite 不满足以上条件时返回 ErrOCRASuite，秘钥错误时返回的错误与 NewOCRAWithError 相同。
+//
+// Example:
+//
+//	confirmer, err := NewTransactionConfirmer(TransactionSuite, secret)
+//	tx := Transaction{Amount: 125000, Currency: "EUR", Recipient: iban, Nonce: nonce}
+//	if !confirmer.Verify(code, tx, time.Now()) {
+//		return ErrTokenInvalid
+//	}
+func NewTransactionConfirmer(suite, secret string) (*TransactionConfirmer, error) {
+	ocra, err := NewOCRAWithError(suite, secret)
+	if err != nil {
+		return nil, err
+	}
+	s := ocra.Suite
+	if s.QuestionFormat != 'H' || s.QuestionLength != 64 || s.Counter || s.PinAlgorithm != 0 || s.SessionLength != 0 {
+		return nil, fmt.Errorf("%w: transaction suite must use QH64 without C, P or S", ErrOCRASuite)
+	}
+	return &TransactionConfirmer{OCRA: ocra, Skew: 1}, nil
+}
+
+// Generate 计算交易在 t 时的确认码，t 为零值时使用当前时间。
+func (c *TransactionConfirmer) Generate(tx Transaction, t time.Time) (string, error) {
+	return c.OCRA.Generate(OCRAInput{Question: tx.Challenge(), Time: t})
+}
+
+// Verify 校验确认码是否与交易详情匹配，t 为零值时使用当前时间。
+//
+// 同一笔交易在时间窗口内的确认码都会校验通过，需要防止重复使用时在 Nonce 中使用一次性的随机值，并在确认之后作废。
+func (c *TransactionConfirmer) Verify(code string, tx Transaction, t time.Time) bool {
+	if code == "" {
+		return false
+	}
+	if t.IsZero() {
+		t = time.Now()
+	}
+	question := tx.Challenge()
+	skew := 0
+	if c.OCRA.Suite.TimeStep != 0 && c.Skew > 0 {
+		skew = c.Skew
+	}
+	for i := 0; i <= skew; i++ {
+		at := t.Add(-time.Duration(i) * c.OCRA.Suite.TimeStep)
+		if c.OCRA.Verify(code, OCRAInput{Question: question, Time: at}) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package otp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestTransaction_Challenge(t *testing.T) {
+	tx := Transaction{Amount: 125000, Currency: "EUR", Recipient: "DE89370400440532013000", Nonce: "n1"}
+	challenge := tx.Challenge()
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{64}$`), challenge)
+	assert.Equal(t, challenge, tx.Challenge())
+
+	// 任意一个字段不同时摘要都不同
+	for _, other := range []Transaction{
+		{Amount: 125001, Currency: "EUR", Recipient: "DE89370400440532013000", Nonce: "n1"},
+		{Amount: 125000, Currency: "USD", Recipient: "DE89370400440532013000", Nonce: "n1"},
+		{Amount: 125000, Currency: "EUR", Recipient: "DE89370400440532013001", Nonce: "n1"},
+		{Amount: 125000, Currency: "EUR", Recipient: "DE89370400440532013000", Nonce: "n2"},
+		{Amount: 125000, Currency: "EU", Recipient: "RDE89370400440532013000", Nonce: "n1"},
+	} {
+		assert.NotEqual(t, challenge, other.Challenge(), other)
+	}
+}
+
+func TestTransactionConfirmer(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	confirmer, err := NewTransactionConfirmer(TransactionSuite, ocraKey32)
+	assert.Nil(t, err)
+	tx := Transaction{Amount: 125000, Currency: "EUR", Recipient: "DE89370400440532013000", Nonce: "n1"}
+	code, err := confirmer.Generate(tx, now)
+	assert.Nil(t, err)
+	assert.Len(t, code, 8)
+
+	// 与直接使用 OCRA 计算的结果一致
+	expected, err := NewOCRA(TransactionSuite, ocraKey32).Generate(OCRAInput{Question: tx.Challenge(), Time: now})
+	assert.Nil(t, err)
+	assert.Equal(t, expected, code)
+
+	assert.True(t, confirmer.Verify(code, tx, now))
+	assert.True(t, confirmer.Verify(code, tx, now.Add(time.Minute)))
+	assert.False(t, confirmer.Verify(code, tx, now.Add(2*time.Minute)))
+	assert.False(t, confirmer.Verify("", tx, now))
+
+	// 确认码不能用于另一笔交易
+	phished := tx
+	phished.Recipient = "GB29NWBK60161331926819"
+	assert.False(t, confirmer.Verify(code, phished, now))
+	phished = tx
+	phished.Amount = 9999900
+	assert.False(t, confirmer.Verify(code, phished, now))
+
+	confirmer.Skew = 0
+	assert.False(t, confirmer.Verify(code, tx, now.Add(time.Minute)))
+}
+
+func TestNewTransactionConfirmer(t *testing.T) {
+	for _, suite := range []string{
+		"OCRA-1:HOTP-SHA256-8:QN08-T1M",
+		"OCRA-1:HOTP-SHA256-8:QH32-T1M",
+		"OCRA-1:HOTP-SHA256-8:C-QH64",
+		"OCRA-1:HOTP-SHA256-8:QH64-PSHA1",
+		"OCRA-1:HOTP-SHA256-8:QH64-S064",
+		"OCRA-1:HOTP-SHA256-8",
+	} {
+		_, err := NewTransactionConfirmer(suite, ocraKey32)
+		assert.ErrorIs(t, err, ErrOCRASuite, suite)
+	}
+	_, err := NewTransactionConfirmer(TransactionSuite, "")
+	assert.ErrorIs(t, err, ErrSecretCannotBeEmpty)
+
+	// 不包含时间步长的 suite 只校验 t 本身
+	confirmer, err := NewTransactionConfirmer("OCRA-1:HOTP-SHA1-6:QH64", ocraKey20)
+	assert.Nil(t, err)
+	tx := Transaction{Amount: 100, Currency: "EUR", Recipient: "alice"}
+	code, err := confirmer.Generate(tx, time.Time{})
+	assert.Nil(t, err)
+	assert.True(t, confirmer.Verify(code, tx, time.Time{}))
+}
@@ -0,0 +1,357 @@
+package otp
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ParseLenient 与 FromURI 相同，但是尽可能地从不符合规范的 URI 中提取参数，部分验证器应用导出的 URI 并不完全符合 Key Uri Format。
+//
+// FromURI 会返回错误的问题在这里作为警告返回，并使用可以确定的值继续解析：
+//   - 非法的百分号编码按原样保留，无效的 UTF-8 字节替换为 U+FFFD，控制字符和首尾空白被去掉。
+//   - 大写的 type 和参数名（例如 TOTP、Secret）转换为小写，HmacSHA256、SHA-256 等算法名称转换为规范的写法。
+//   - 无法解析或超出范围的 digits、period、counter、algorithm、encoder、epoch 使用默认值。
+//   - label 中的 issuer 前缀与 issuer 参数不一致时使用 issuer 参数。
+//   - label 中多余的冒号以及空的账户名称会被保留。
+//   - 重复的参数使用第一个值，与 FromURI 相同，值不一致时给出警告。
+//
+// 每个警告都可以使用 errors.Is 判断具体原因，与 FromURI 返回的错误相同，例如 ErrInvalidDigits、ErrIssuerMismatch。
+// 缺少 secret、secret 无法解码、scheme 不是 otpauth 或者 type 不是 totp、hotp 时仍然返回错误。
+//
+// 没有警告时返回的 KeyURI 与 FromURI 完全一致；有警告时返回的 KeyURI 不一定能通过 Validate，建议在导入之前展示给用户确认。
+//
+// Example:
+//
+//	key, warnings, err := ParseLenient(uri)
+//	if err != nil {
+//		return err
+//	}
+//	for _, w := range warnings {
+//		log.Printf("import %s: %v", key.AccountName, w)
+//	}
+func ParseLenient(uri string) (*KeyURI, []error, error) {
+	p := &uriParser{lenient: true}
+	key, err := p.parse(uri)
+	if err != nil {
+		return nil, p.warnings, err
+	}
+	return key, p.warnings, nil
+}
+
+// uriParser 解析 otpauth URI，FromURI 和 ParseLenient 共用，lenient 为 false 时遇到第一个问题即返回错误。
+//
+// 这里不使用 url.Parse：它会丢弃百分号编码错误的参数以及包含分号的参数，并且拒绝部分应用导出的非规范 URI。
+type uriParser struct {
+	lenient  bool
+	warnings []error
+}
+
+// fail 处理一个可以恢复的问题：严格模式下返回 err，宽松模式下记录为警告并返回 nil。
+func (p *uriParser) fail(err error) error {
+	if !p.lenient {
+		return err
+	}
+	p.warnings = append(p.warnings, err)
+	return nil
+}
+
+// note 记录一个严格模式下同样接受的问题，仅在宽松模式下作为警告返回。
+func (p *uriParser) note(err error) {
+	if p.lenient {
+		p.warnings = append(p.warnings, err)
+	}
+}
+
+func (p *uriParser) parse(uri string) (*KeyURI, error) {
+	if trimmed := strings.TrimSpace(uri); trimmed != uri {
+		if err := p.fail(fmt.Errorf("%w: leading or trailing whitespace", ErrURIFormat)); err != nil {
+			return nil, err
+		}
+		uri = trimmed
+	}
+	if i := strings.IndexFunc(uri, isControl); i != -1 {
+		if err := p.fail(fmt.Errorf("%w: control character %q at position %d", ErrURIFormat, uri[i], i)); err != nil {
+			return nil, err
+		}
+		uri = strings.Map(func(r rune) rune {
+			if isControl(r) {
+				return -1
+			}
+			return r
+		}, uri)
+	}
+
+	scheme, rest := "", uri
+	if i := strings.IndexByte(uri, ':'); i != -1 {
+		scheme, rest = strings.ToLower(uri[:i]), uri[i+1:]
+	}
+	if scheme != "otpauth" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidScheme, scheme)
+	}
+	if !strings.HasPrefix(rest, "//") {
+		return nil, fmt.Errorf("%w: missing type", ErrURIFormat)
+	}
+	rest = rest[2:]
+	if i := strings.IndexByte(rest, '#'); i != -1 {
+		p.note(fmt.Errorf("%w: fragment %q ignored", ErrURIFormat, rest[i:]))
+		rest = rest[:i]
+	}
+	rest, rawQuery, _ := strings.Cut(rest, "?")
+	typ, rawLabel, _ := strings.Cut(rest, "/")
+	if typ != "totp" && typ != "hotp" {
+		lower := strings.ToLower(typ)
+		if lower != "totp" && lower != "hotp" {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidType, typ)
+		}
+		if err := p.fail(fmt.Errorf("%w: %q", ErrInvalidType, typ)); err != nil {
+			return nil, err
+		}
+		typ = lower
+	}
+
+	query, err := p.parseQuery(rawQuery)
+	if err != nil {
+		return nil, err
+	}
+	secret, ok := query["secret"]
+	if !ok || secret == "" {
+		return nil, ErrMissingSecret
+	}
+	if secret, err = NormalizeSecret(secret); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrURIFormat, err)
+	}
+
+	key := &KeyURI{Type: typ, Secret: secret}
+	if err := p.parseParams(key, query); err != nil {
+		return nil, err
+	}
+	if err := p.parseLabel(key, rawLabel, query["issuer"]); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// parseQuery 解析查询参数，参数之间只使用 & 分隔，重复的参数使用第一个值。
+func (p *uriParser) parseQuery(raw string) (map[string]string, error) {
+	query := make(map[string]string)
+	for _, pair := range strings.Split(raw, "&") {
+		if pair == "" {
+			continue
+		}
+		rawKey, rawValue, _ := strings.Cut(pair, "=")
+		key, ok := unescape(rawKey, true)
+		if !ok {
+			if err := p.fail(fmt.Errorf("%w: invalid escape in parameter name %q", ErrURIFormat, rawKey)); err != nil {
+				return nil, err
+			}
+		}
+		value, ok := unescape(rawValue, true)
+		if !ok {
+			if err := p.fail(fmt.Errorf("%w: invalid escape in parameter %q", ErrURIFormat, key)); err != nil {
+				return nil, err
+			}
+		}
+		if lower := strings.ToLower(key); lower != key && isKnownParam(lower) {
+			// 严格模式下与 url.Values 一样区分大小写，Secret 等参数被忽略
+			if !p.lenient {
+				continue
+			}
+			p.note(fmt.Errorf("%w: parameter %q should be lowercase", ErrURIFormat, key))
+			key = lower
+		}
+		if previous, ok := query[key]; ok {
+			if previous != value {
+				p.note(fmt.Errorf("%w: duplicate parameter %q, using %q", ErrURIFormat, key, previous))
+			}
+			continue
+		}
+		query[key] = value
+	}
+	return query, nil
+}
+
+// parseParams 解析 label、issuer、secret 之外的参数，宽松模式下不合法的参数使用默认值。
+func (p *uriParser) parseParams(key *KeyURI, query map[string]string) error {
+	digits, err := atoi(query["digits"], 6)
+	if err != nil {
+		err = fmt.Errorf("%w: %q", ErrInvalidDigits, query["digits"])
+	} else if _, rangeErr := Digits.from(DigitsSix, digits); rangeErr != nil {
+		err = fmt.Errorf("%w: %d", ErrInvalidDigits, digits)
+	}
+	if err != nil {
+		if err := p.fail(err); err != nil {
+			return err
+		}
+		digits = int(DigitsSix)
+	}
+	key.Digits = digits
+
+	algorithm, err := Algorithms.from(AlgorithmSHA1, query["algorithm"])
+	if err != nil {
+		normalized, normalizedErr := Algorithms.from(AlgorithmSHA1, normalizeAlgorithm(query["algorithm"]))
+		if normalizedErr != nil {
+			normalized = AlgorithmSHA1
+		}
+		if err := p.fail(fmt.Errorf("%w: %q, using %s", ErrUnsupportedAlgorithm, query["algorithm"], normalized)); err != nil {
+			return err
+		}
+		algorithm = normalized
+	}
+	key.Algorithm = algorithm.String()
+
+	encoder, err := Encoder.from(EncoderDefault, query["encoder"])
+	if err != nil {
+		if err := p.fail(fmt.Errorf("%w: %q", ErrUnsupportedEncoder, query["encoder"])); err != nil {
+			return err
+		}
+	}
+	key.Encoder = encoder.String()
+
+	counter, err := parseInt(query["counter"], 1, 10, 64)
+	if err != nil {
+		if err := p.fail(fmt.Errorf("%w: %q", ErrInvalidCounter, query["counter"])); err != nil {
+			return err
+		}
+		counter = 1
+	}
+	period, err := atoi(query["period"], 30)
+	if err != nil || period < minPeriodNumber || period > maxPeriodNumber {
+		if err := p.fail(fmt.Errorf("%w: %q", ErrInvalidPeriod, query["period"])); err != nil {
+			return err
+		}
+		period = 30
+	}
+	epoch, err := parseInt(query["epoch"], 0, 10, 64)
+	if err != nil {
+		if err := p.fail(fmt.Errorf("%w: %q", ErrInvalidEpoch, query["epoch"])); err != nil {
+			return err
+		}
+		epoch = 0
+	}
+	if key.Type == "hotp" {
+		key.Counter = counter
+	} else {
+		key.Period = period
+		key.Epoch = epoch
+	}
+	return nil
+}
+
+// parseLabel 解析 label 并与 issuer 参数合并，设置 Label、AccountName 和 Issuer。
+func (p *uriParser) parseLabel(key *KeyURI, rawLabel string, issuer string) error {
+	label, ok := unescape(rawLabel, false)
+	if !ok {
+		if err := p.fail(fmt.Errorf("%w: invalid escape in label %q", ErrURIFormat, rawLabel)); err != nil {
+			return err
+		}
+	}
+	if !utf8.ValidString(label) {
+		if err := p.fail(fmt.Errorf("%w: label is not valid utf-8", ErrURIFormat)); err != nil {
+			return err
+		}
+		label = strings.ToValidUTF8(label, string(utf8.RuneError))
+	}
+	if !utf8.ValidString(issuer) {
+		if err := p.fail(fmt.Errorf("%w: issuer is not valid utf-8", ErrURIFormat)); err != nil {
+			return err
+		}
+		issuer = strings.ToValidUTF8(issuer, string(utf8.RuneError))
+	}
+	if strings.Contains(issuer, ":") {
+		if err := p.fail(fmt.Errorf("%w: issuer %q contains colon", ErrURIFormat, issuer)); err != nil {
+			return err
+		}
+	}
+
+	labelIssuer, account, err := ParseLabel(label)
+	if err != nil {
+		if err := p.fail(fmt.Errorf("%w: %v", ErrURIFormat, err)); err != nil {
+			return err
+		}
+		// 只按照第一个冒号拆分，保留账户名称中多余的冒号
+		labelIssuer, account = "", label
+		if before, after, found := strings.Cut(label, ":"); found {
+			labelIssuer, account = before, strings.TrimLeft(after, " ")
+		}
+	}
+	// label 中的 issuer 前缀与 issuer 参数都存在时必须一致，只存在一个时使用存在的值
+	if issuer != "" && labelIssuer != "" && issuer != labelIssuer {
+		if err := p.fail(fmt.Errorf("%w: label %q, issuer %q", ErrIssuerMismatch, labelIssuer, issuer)); err != nil {
+			return err
+		}
+	}
+	if issuer == "" {
+		issuer = labelIssuer
+	}
+	key.AccountName = account
+	key.Issuer = issuer
+	if account != "" {
+		key.Label = formatLabel(issuer, account)
+	}
+	return nil
+}
+
+// unescape 解码百分号编码，query 为 true 时将 + 解码为空格。非法的百分号编码按原样保留，此时 ok 为 false。
+func unescape(s string, query bool) (ret string, ok bool) {
+	if !strings.ContainsAny(s, "%+") {
+		return s, true
+	}
+	ok = true
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			b.WriteByte(unhex(s[i+1])<<4 | unhex(s[i+2]))
+			i += 2
+		case c == '%':
+			ok = false
+			b.WriteByte(c)
+		case c == '+' && query:
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), ok
+}
+
+// normalizeAlgorithm 将 HmacSHA256、HMAC-SHA-256、sha-256 等常见的非规范写法转换为 SHA256。
+func normalizeAlgorithm(algorithm string) string {
+	algorithm = strings.ToUpper(strings.TrimSpace(algorithm))
+	algorithm = strings.TrimPrefix(strings.TrimPrefix(algorithm, "HMAC"), "-")
+	if strings.HasPrefix(algorithm, "SHA-") {
+		algorithm = "SHA" + algorithm[len("SHA-"):]
+	}
+	return algorithm
+}
+
+// isKnownParam 判断是否为 FromURI 会读取的参数。
+func isKnownParam(name string) bool {
+	switch name {
+	case "secret", "issuer", "algorithm", "digits", "period", "counter", "encoder", "epoch":
+		return true
+	}
+	return false
+}
+
+// isControl 判断是否为 ASCII 控制字符，url.Parse 同样会拒绝这些字符。
+func isControl(r rune) bool {
+	return r < 0x20 || r == 0x7f
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
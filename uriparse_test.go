@@ -0,0 +1,181 @@
+package otp
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// uriSeeds 模糊测试的初始语料，包括常见验证器应用导出的非规范 URI，更多语料见 testdata/fuzz。
+var uriSeeds = []string{
+	"otpauth://totp/Example:alice@google.com?secret=JBSWY3DPEHPK3PXP&issuer=Example",
+	"otpauth://hotp/Example:alice@google.com?secret=JBSWY3DPEHPK3PXP&issuer=Example&counter=7",
+	"otpauth://totp/ACME%20Co:john.doe@email.com?secret=HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ&issuer=ACME%20Co&algorithm=SHA1&digits=6&period=30",
+	"otpauth://totp/Steam:alice?secret=JBSWY3DPEHPK3PXP&encoder=steam",
+	"otpauth://totp/alice?secret=jbsw%20y3dp%20ehpk%203pxp&issuer=Ex%2BCo",
+	"otpauth://TOTP/Example:alice?Secret=JBSWY3DPEHPK3PXP&algorithm=HmacSHA256",
+	"otpauth://totp/Example:alice?secret=JBSWY3DPEHPK3PXP&issuer=%zz&digits=99999999999999999999",
+	"otpauth://totp/%E4%BE%8B%E5%AD%90:%E7%94%A8%E6%88%B7?secret=JBSWY3DPEHPK3PXP",
+	"otpauth://totp/%ff%fe:alice?secret=JBSWY3DPEHPK3PXP&period=9223372036854775807",
+	"otpauth://totp/a:b:c?secret=JBSWY3DPEHPK3PXP&issuer=x;y#fragment",
+	" otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP\n",
+}
+
+func TestFromURI_Hardening(t *testing.T) {
+	var cases = []struct {
+		uri string
+		err error
+	}{
+		{"otpauth://totp/Example:alice?secret=JBSWY3DPEHPK3PXP&issuer=%zz", ErrURIFormat},
+		{"otpauth://totp/Exa%mple:alice?secret=JBSWY3DPEHPK3PXP", ErrURIFormat},
+		{"otpauth://totp/%ff:alice?secret=JBSWY3DPEHPK3PXP", ErrURIFormat},
+		{"otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&issuer=%ff", ErrURIFormat},
+		{"otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&issuer=a:b", ErrURIFormat},
+		{"otpauth://totp/alice\x00?secret=JBSWY3DPEHPK3PXP", ErrURIFormat},
+		{"otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&digits=99999999999999999999", ErrInvalidDigits},
+		{"otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&period=9223372036854775807", ErrInvalidPeriod},
+		{"otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&period=99999999999999999999", ErrInvalidPeriod},
+		{"otpauth://hotp/alice?secret=JBSWY3DPEHPK3PXP&counter=99999999999999999999", ErrInvalidCounter},
+		{"otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&epoch=1e3", ErrInvalidEpoch},
+		{"otpauth://totp/alice?secret=%C4%B1BSWY3DPEHPK3PXP", ErrURIFormat},
+		{"otpauth:totp/alice?secret=JBSWY3DPEHPK3PXP", ErrURIFormat},
+		{"otpauth://user@totp/alice?secret=JBSWY3DPEHPK3PXP", ErrInvalidType},
+		{"otpauth", ErrInvalidScheme},
+	}
+	for _, c := range cases {
+		key, err := FromURI(c.uri)
+		assert.Nil(t, key, c.uri)
+		assert.ErrorIs(t, err, c.err, c.uri)
+		assert.ErrorIs(t, err, ErrURIFormat, c.uri)
+	}
+
+	// 分号不是参数的分隔符，url.Values 会丢弃包含分号的参数
+	key, err := FromURI("otpauth://totp/alice?secret=JBSWY3DPEHPK3PXP&issuer=A;B")
+	assert.Nil(t, err)
+	assert.Equal(t, "A;B", key.Issuer)
+	assert.Equal(t, "A;B:alice", key.Label)
+
+	key, err = FromURI("OTPAUTH://totp/%E4%BE%8B%E5%AD%90:%E7%94%A8%E6%88%B7?secret=JBSWY3DPEHPK3PXP#ignored")
+	assert.Nil(t, err)
+	assert.Equal(t, "用户", key.AccountName)
+	assert.Equal(t, "例子", key.Issuer)
+}
+
+func TestParseLenient(t *testing.T) {
+	t.Run("conforming uri has no warnings", func(t *testing.T) {
+		for _, uri := range uriSeeds[:5] {
+			expected, err := FromURI(uri)
+			assert.Nil(t, err)
+			key, warnings, err := ParseLenient(uri)
+			assert.Nil(t, err)
+			assert.Empty(t, warnings)
+			assert.Equal(t, expected, key)
+		}
+	})
+
+	t.Run("non-conforming uri", func(t *testing.T) {
+		uri := " otpauth://TOTP/Other:alice:smith?Secret=jbsw-y3dp-ehpk-3pxp&issuer=Ex%zz&algorithm=HmacSHA256&digits=99&period=1&epoch=x&issuer=Other\n"
+		key, warnings, err := ParseLenient(uri)
+		assert.Nil(t, err)
+		assert.Equal(t, &KeyURI{
+			Type:        "totp",
+			Label:       "Ex%zz:alice:smith",
+			AccountName: "alice:smith",
+			Algorithm:   "SHA256",
+			Digits:      6,
+			Period:      30,
+			Issuer:      "Ex%zz",
+			Secret:      "JBSWY3DPEHPK3PXP",
+		}, key)
+		for _, target := range []error{ErrInvalidType, ErrUnsupportedAlgorithm, ErrInvalidDigits, ErrInvalidPeriod, ErrInvalidEpoch, ErrIssuerMismatch} {
+			assert.True(t, containsError(warnings, target), target.Error())
+		}
+		for _, w := range warnings {
+			assert.ErrorIs(t, w, ErrURIFormat)
+		}
+		messages := make([]string, 0, len(warnings))
+		for _, w := range warnings {
+			messages = append(messages, w.Error())
+		}
+		assert.Contains(t, strings.Join(messages, "\n"), `duplicate parameter "issuer"`)
+		assert.Contains(t, strings.Join(messages, "\n"), `parameter "Secret" should be lowercase`)
+	})
+
+	t.Run("invalid unicode", func(t *testing.T) {
+		key, warnings, err := ParseLenient("otpauth://totp/%ff%fe:al\x7fice?secret=JBSWY3DPEHPK3PXP")
+		assert.Nil(t, err)
+		assert.Len(t, warnings, 2)
+		assert.Equal(t, "\uFFFD", key.Issuer)
+		assert.Equal(t, "alice", key.AccountName)
+	})
+
+	t.Run("unrecoverable", func(t *testing.T) {
+		_, _, err := ParseLenient("otpauth://totp/alice?issuer=Example")
+		assert.Equal(t, ErrMissingSecret, err)
+		_, _, err = ParseLenient("otpauth://yotp/alice?secret=JBSWY3DPEHPK3PXP")
+		assert.ErrorIs(t, err, ErrInvalidType)
+		_, _, err = ParseLenient("https://totp/alice?secret=JBSWY3DPEHPK3PXP")
+		assert.ErrorIs(t, err, ErrInvalidScheme)
+	})
+
+	t.Run("empty account", func(t *testing.T) {
+		key, warnings, err := ParseLenient("otpauth://totp/Example:?secret=JBSWY3DPEHPK3PXP")
+		assert.Nil(t, err)
+		assert.True(t, containsError(warnings, ErrURIFormat))
+		assert.Equal(t, "", key.Label)
+		assert.Equal(t, "Example", key.Issuer)
+	})
+}
+
+func containsError(errs []error, target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// FuzzFromURI FromURI 不应该 panic，解析成功的 KeyURI 必须能够通过 Validate，并且经过 URI 方法之后可以还原。
+func FuzzFromURI(f *testing.F) {
+	for _, seed := range uriSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, uri string) {
+		key, err := FromURI(uri)
+		if err != nil {
+			if !errors.Is(err, ErrURIFormat) {
+				t.Fatalf("FromURI(%q) error %v does not wrap ErrURIFormat", uri, err)
+			}
+			return
+		}
+		if err := key.Validate(); err != nil {
+			t.Fatalf("FromURI(%q) = %+v, Validate: %v", uri, key, err)
+		}
+		again, err := FromURI(key.URI().String())
+		if err != nil {
+			t.Fatalf("FromURI(%q) round trip %q: %v", uri, key.URI(), err)
+		}
+		if *again != *key {
+			t.Fatalf("FromURI(%q) round trip: %+v != %+v", uri, again, key)
+		}
+	})
+}
+
+// FuzzParseLenient ParseLenient 不应该 panic，严格模式能够解析的 URI 宽松模式必须得到相同的结果，没有警告时严格模式也必须能够解析。
+func FuzzParseLenient(f *testing.F) {
+	for _, seed := range uriSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, uri string) {
+		key, warnings, err := ParseLenient(uri)
+		strict, strictErr := FromURI(uri)
+		if strictErr == nil && (err != nil || *key != *strict) {
+			t.Fatalf("ParseLenient(%q) = %+v, %v; FromURI = %+v", uri, key, err, strict)
+		}
+		if err == nil && len(warnings) == 0 && strictErr != nil {
+			t.Fatalf("ParseLenient(%q) has no warnings, FromURI: %v", uri, strictErr)
+		}
+	})
+}
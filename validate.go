@@ -0,0 +1,84 @@
+package otp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Validate 校验 KeyURI 的各个参数，返回第一个不合法的参数对应的错误。
+//
+// 校验规则：
+//   - type 只能是 totp 或 hotp。
+//   - secret 不能为空，并且可以被 base32 解码。
+//   - label 不能为空。
+//   - algorithm、digits 是支持的取值，totp 的 period 不小于 10。
+//   - 如果 label 带有 issuer 前缀并且设置了 issuer 参数，两者必须一致。
+//
+// 返回的错误都可以使用 errors.Is(err, ErrURIFormat) 判断，也可以使用 ErrInvalidDigits 等判断具体原因。
+// 对 label 更严格的校验可以使用 ValidateStrict。
+func (p KeyURI) Validate() error {
+	if p.Type != "totp" && p.Type != "hotp" {
+		return fmt.Errorf("%w: %q", ErrInvalidType, p.Type)
+	}
+	if p.Secret == "" {
+		return ErrMissingSecret
+	}
+	if _, err := Base32Decode(p.Secret); err != nil {
+		return fmt.Errorf("%w: %v", ErrURIFormat, err)
+	}
+	label, issuer := p.label(), p.Issuer
+	if label == "" {
+		return fmt.Errorf("%w: %v", ErrURIFormat, ErrLabelEmpty)
+	}
+	if _, err := Algorithms.from(AlgorithmSHA1, p.Algorithm); err != nil {
+		return fmt.Errorf("%w: %q", ErrUnsupportedAlgorithm, p.Algorithm)
+	}
+	if _, err := Digits.from(DigitsSix, p.Digits); err != nil {
+		return fmt.Errorf("%w: %d", ErrInvalidDigits, p.Digits)
+	}
+	if p.Type == "totp" && p.Period < minPeriodNumber {
+		return fmt.Errorf("%w: %d is less than %d", ErrInvalidPeriod, p.Period, minPeriodNumber)
+	}
+	if p.Type == "totp" && p.Period > maxPeriodNumber {
+		return fmt.Errorf("%w: %d is greater than %d", ErrInvalidPeriod, p.Period, maxPeriodNumber)
+	}
+	if i := strings.Index(label, ":"); i != -1 && issuer != "" && label[:i] != issuer {
+		return fmt.Errorf("%w: label %q, issuer %q", ErrIssuerMismatch, label[:i], issuer)
+	}
+	return nil
+}
+
+// Normalize 将 KeyURI 转换为规范的形式，与 TOTP.KeyURI、HOTP.KeyURI 生成的结构一致：
+//   - issuer 为空时使用 label 中的 issuer 前缀，label 没有 issuer 前缀时添加 issuer 前缀。
+//   - AccountName 设置为 label 中的账户名称。
+//   - type 转换为小写，algorithm 转换为规范的写法，secret 转换为大写并去掉空格和填充。
+//
+// 不会校验参数是否合法，可以在 Normalize 之后调用 Validate。
+func (p *KeyURI) Normalize() {
+	label, issuer := p.label(), p.Issuer
+	if i := strings.Index(label, ":"); i != -1 {
+		if issuer == "" {
+			issuer = label[:i]
+		}
+	} else if issuer != "" && label != "" {
+		label = issuer + ":" + label
+	}
+	if _, account, err := ParseLabel(label); err == nil {
+		p.AccountName = account
+	}
+	p.Label = label
+	p.Issuer = issuer
+	p.Type = strings.ToLower(p.Type)
+	if algorithm, err := Algorithms.from(AlgorithmSHA1, p.Algorithm); err == nil {
+		p.Algorithm = algorithm.String()
+	}
+	p.Secret = strings.TrimRight(strings.ToUpper(strings.ReplaceAll(p.Secret, " ", "")), "=")
+}
+
+// label 返回 Label，Label 为空时使用 Issuer 和 AccountName 生成。
+func (p KeyURI) label() string {
+	if p.Label == "" && p.AccountName != "" {
+		return formatLabel(p.Issuer, p.AccountName)
+	}
+	return p.Label
+}
@@ -0,0 +1,71 @@
+package otp
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestKeyURI_Validate(t *testing.T) {
+	assert.Nil(t, NewTOTP(TestSecret20).KeyURI("alice@google.com", "Example").Validate())
+	assert.Nil(t, NewHOTP(TestSecret20).KeyURI("alice smith", "Example Co").Validate())
+
+	valid := func() KeyURI {
+		return *NewTOTP(TestSecret20).KeyURI("alice@google.com", "Example")
+	}
+	var cases = []struct {
+		name   string
+		modify func(key *KeyURI)
+		err    error
+	}{
+		{"type", func(key *KeyURI) { key.Type = "motp" }, ErrInvalidType},
+		{"empty secret", func(key *KeyURI) { key.Secret = "" }, ErrMissingSecret},
+		{"secret", func(key *KeyURI) { key.Secret = "111111" }, ErrURIFormat},
+		{"label", func(key *KeyURI) { key.Label, key.AccountName = "", "" }, ErrURIFormat},
+		{"algorithm", func(key *KeyURI) { key.Algorithm = "MD5" }, ErrUnsupportedAlgorithm},
+		{"digits", func(key *KeyURI) { key.Digits = 11 }, ErrInvalidDigits},
+		{"period", func(key *KeyURI) { key.Period = 5 }, ErrInvalidPeriod},
+		{"issuer", func(key *KeyURI) { key.Issuer = "Other" }, ErrIssuerMismatch},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			key := valid()
+			c.modify(&key)
+			err := key.Validate()
+			assert.True(t, errors.Is(err, c.err), err)
+			assert.True(t, errors.Is(err, ErrURIFormat), err)
+		})
+	}
+
+	// hotp 不校验 period
+	key := *NewHOTP(TestSecret20).KeyURI("alice", "Example")
+	key.Period = 0
+	assert.Nil(t, key.Validate())
+}
+
+func TestKeyURI_Normalize(t *testing.T) {
+	expected := *NewTOTP(TestSecret20).KeyURI("alice smith", "Example Co")
+
+	// 未编码的 label 和 issuer
+	key := &KeyURI{
+		Type:      "TOTP",
+		Label:     "alice smith",
+		Algorithm: "sha1",
+		Digits:    6,
+		Period:    30,
+		Issuer:    "Example Co",
+		Secret:    "j3w2 xpzp 5hdy xyrb 4hs6 zlu6 m6vb o6c6",
+	}
+	key.Normalize()
+	assert.Equal(t, expected, *key)
+
+	// 重复调用结果不变
+	key.Normalize()
+	assert.Equal(t, expected, *key)
+
+	// issuer 从 label 前缀中获取
+	key2 := KeyURI{Label: "Example Co:alice smith"}
+	key2.Normalize()
+	assert.Equal(t, "Example Co:alice smith", key2.Label)
+	assert.Equal(t, "Example Co", key2.Issuer)
+}
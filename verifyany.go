@@ -0,0 +1,95 @@
+package otp
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// OtpConfig VerifyAny 尝试的一组参数，零值的字段沿用 TOTP 自身的参数。
+type OtpConfig struct {
+	// hmac 算法，使用 NewTOTPWithSigner 创建时由 signer 决定，修改此字段不会生效。
+	Algorithm Algorithms
+	// token 的长度。
+	Digits Digits
+	// 时间窗口的长度（秒）。
+	Period int
+}
+
+// VerifyAny 使用同一个秘钥按顺序尝试多组参数校验 token，返回第一组匹配的参数在 configs 中的下标。
+//
+// 用于迁移参数的过渡期，例如从 SHA1/6 位迁移到 SHA256/8 位时同时接受两种 token，而不需要在每次请求时创建两个 TOTP。
+// configs 为空时使用 TOTP 自身的参数，匹配时下标为 0；没有匹配时下标为 -1。
+//
+// 每组参数在校验之前都会按照构造函数的规则检查，任意一组不合法（例如未知的算法、超出范围的位数、过小的时间窗口）时
+// 不会进行校验，返回 ErrInvalidOption。读取秘钥、Signer 签名或防重放检查出错时返回对应的错误。
+//
+// 与 Verify 相同，会检查 WithNotBefore、WithNotAfter 以及 WithReplayGuard，所有参数共享同一个使用记录。
+// 配置了 WithHooks 时只会记录一次校验，而不是每组参数一次。
+//
+// Example:
+//
+//	index, ok, err := totp.VerifyAny(token, time.Now(),
+//		OtpConfig{Algorithm: AlgorithmSHA256, Digits: DigitsEight},
+//		OtpConfig{Algorithm: AlgorithmSHA1, Digits: DigitsSix},
+//	)
+//	if err != nil {
+//		return err
+//	}
+//	if ok && index == 1 {
+//		// 用户仍在使用旧参数，提示重新绑定
+//	}
+func (o *TOTP) VerifyAny(token string, t time.Time, configs ...OtpConfig) (int, bool, error) {
+	if len(configs) == 0 {
+		configs = []OtpConfig{{}}
+	}
+	variants := make([]*TOTP, len(configs))
+	for i, config := range configs {
+		variant, err := o.withConfig(config)
+		if err != nil {
+			return -1, false, fmt.Errorf("config %d: %w", i, err)
+		}
+		variants[i] = variant
+	}
+	event := o.beginVerify("totp", o.step(t), t)
+	var step int64
+	var err error
+	outcome := VerifyMismatch
+	index := -1
+	for i, variant := range variants {
+		current := variant.step(t)
+		step, outcome, err = variant.matchContext(context.Background(), token, t, current)
+		if outcome == VerifyMismatch {
+			continue
+		}
+		event.Expected = current
+		index = i
+		break
+	}
+	o.endVerify(event, step, outcome, err)
+	if outcome != VerifySuccess {
+		return -1, false, err
+	}
+	return index, true, nil
+}
+
+// withConfig 返回使用 config 覆盖参数之后的副本，秘钥与 o 共享，覆盖之后的参数不合法时返回 ErrInvalidOption。
+func (o *TOTP) withConfig(config OtpConfig) (*TOTP, error) {
+	variant := *o
+	if config.Algorithm != 0 {
+		variant.Algorithm = config.Algorithm
+	}
+	if config.Digits != 0 {
+		variant.Digits = config.Digits
+	}
+	if config.Period != 0 {
+		variant.Period = config.Period
+	}
+	// 只检查 config 覆盖之后的参数，构造时已经修正过的参数不影响结果
+	check := variant.Otp
+	check.optionErr = nil
+	if err := check.Validate(); err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
@@ -0,0 +1,100 @@
+package otp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestTOTP_VerifyAny(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	totp := NewTOTP(TestSecret20)
+	sha256 := OtpConfig{Algorithm: AlgorithmSHA256, Digits: DigitsEight}
+	legacy := OtpConfig{}
+
+	index, ok, err := totp.VerifyAny(totp.At(now), now, sha256, legacy)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, index)
+	migrated := NewTOTP(TestSecret20, WithAlgorithm(AlgorithmSHA256), WithDigits(DigitsEight))
+	index, ok, err = totp.VerifyAny(migrated.At(now), now, sha256, legacy)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 0, index)
+	index, ok, err = totp.VerifyAny("000000", now, sha256, legacy)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, -1, index)
+
+	// 没有传入参数时使用自身的参数
+	index, ok, err = totp.VerifyAny(totp.At(now), now)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 0, index)
+
+	// 修改时间窗口
+	long := NewTOTP(TestSecret20, WithPeriod(60))
+	index, ok, err = totp.VerifyAny(long.At(now), now, legacy, OtpConfig{Period: 60})
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, index)
+
+	// 自身的参数不受影响
+	assert.Equal(t, AlgorithmSHA1, totp.Algorithm)
+	assert.Equal(t, DigitsSix, totp.Digits)
+	assert.Equal(t, 30, totp.Period)
+}
+
+func TestTOTP_VerifyAny_InvalidConfig(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	var events []VerifyEvent
+	record := func(event VerifyEvent) { events = append(events, event) }
+	totp := NewTOTP(TestSecret20, WithHooks(Hooks{OnSuccess: record, OnFailure: record}))
+	invalid := []OtpConfig{
+		{Algorithm: Algorithms(99)},
+		{Digits: Digits(3)},
+		{Digits: Digits(11)},
+		{Period: -30},
+		{Period: 1},
+	}
+	for _, config := range invalid {
+		assert.NotPanics(t, func() {
+			index, ok, err := totp.VerifyAny(totp.At(now), now, OtpConfig{}, config)
+			assert.ErrorIs(t, err, ErrInvalidOption, "%+v", config)
+			assert.False(t, ok)
+			assert.Equal(t, -1, index)
+		})
+	}
+	// 参数不合法时不进行校验
+	assert.Empty(t, events)
+}
+
+func TestTOTP_VerifyAny_GuardAndHooks(t *testing.T) {
+	now := time.Unix(1704075000, 0)
+	var events []VerifyEvent
+	record := func(event VerifyEvent) { events = append(events, event) }
+	totp := NewTOTP(TestSecret20,
+		WithReplayGuard(NewMemoryReplayGuard()),
+		WithHooks(Hooks{OnSuccess: record, OnFailure: record}),
+		WithNotAfter(now.Add(time.Hour)),
+	)
+	sha256 := OtpConfig{Algorithm: AlgorithmSHA256, Digits: DigitsEight}
+	token := totp.At(now)
+
+	_, ok, err := totp.VerifyAny(token, now, sha256, OtpConfig{})
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	_, ok, err = totp.VerifyAny(token, now, sha256, OtpConfig{})
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	_, ok, err = totp.VerifyAny(token, now.Add(2*time.Hour), sha256, OtpConfig{})
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	// 每次调用只记录一次
+	assert.Len(t, events, 3)
+	assert.Equal(t, VerifySuccess, events[0].Outcome)
+	assert.Equal(t, 0, events[0].Skew)
+	assert.Equal(t, VerifyReplay, events[1].Outcome)
+	assert.Equal(t, VerifyNotValid, events[2].Outcome)
+}
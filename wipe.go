@@ -0,0 +1,38 @@
+package otp
+
+// Wipe 将内存中解码后的秘钥清零并清空 Secret 字段，之后 At 返回空字符串，Verify 总是返回 false。
+//
+// 适用于秘钥轮换之后尽快缩短旧秘钥在内存中的存活时间。需要注意：
+//   - Secret 是字符串，Go 无法安全地覆盖字符串的内存，这里只能移除引用，等待垃圾回收。
+//   - crypto/hmac 内部会复制一份填充后的秘钥，这部分内存在每次计算之后由垃圾回收处理。
+//   - 使用 NewTOTPFromStore 创建时内存中本来就不保存秘钥，每次从 store 读取的副本在使用之后都会被清零。
+func (o *TOTP) Wipe() {
+	zero(o.decodedSecret)
+	o.decodedSecret = nil
+	o.Secret = ""
+}
+
+// Wipe 将内存中解码后的秘钥清零并清空 Secret 字段，之后 At 返回空字符串，Verify 总是返回 false。
+//
+// 限制与 TOTP.Wipe 相同。
+func (h *HOTP) Wipe() {
+	zero(h.decodedSecret)
+	h.decodedSecret = nil
+	h.Secret = ""
+}
+
+// Wipe 将内存中解码后的秘钥清零并清空 Secret 字段，之后 Generate 返回 ErrSecretCannotBeEmpty。
+//
+// 限制与 TOTP.Wipe 相同。
+func (o *OCRA) Wipe() {
+	zero(o.decodedSecret)
+	o.decodedSecret = nil
+	o.Secret = ""
+}
+
+// zero 将 b 的内容全部覆盖为 0。
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,70 @@
+package otp
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestTOTP_Wipe(t *testing.T) {
+	now := time.Unix(1704075000000, 0)
+	totp := NewTOTP(TestSecret20)
+	decoded := totp.decodedSecret
+	assert.Equal(t, "076141", totp.At(now))
+	totp.Wipe()
+	assert.Equal(t, make([]byte, len(decoded)), decoded)
+	assert.Equal(t, "", totp.Secret)
+	assert.Equal(t, "", totp.At(now))
+	assert.False(t, totp.Verify("076141", now))
+	ok, err := totp.VerifyContext(context.Background(), "076141", now)
+	assert.False(t, ok)
+	assert.ErrorIs(t, err, ErrSecretCannotBeEmpty)
+	// 重复调用不会 panic
+	totp.Wipe()
+}
+
+func TestHOTP_Wipe(t *testing.T) {
+	hotp := NewHOTP(TestSecret20)
+	token := hotp.At(1)
+	decoded := hotp.decodedSecret
+	hotp.Wipe()
+	assert.Equal(t, make([]byte, len(decoded)), decoded)
+	assert.Equal(t, "", hotp.Secret)
+	assert.Equal(t, "", hotp.At(1))
+	assert.False(t, hotp.Verify(token, 1))
+}
+
+func TestOCRA_Wipe(t *testing.T) {
+	ocra, err := NewOCRAWithError("OCRA-1:HOTP-SHA1-6:QN08", Base32Encode([]byte("12345678901234567890")))
+	assert.Nil(t, err)
+	ocra.Wipe()
+	_, err = ocra.Generate(OCRAInput{Question: "00000000"})
+	assert.ErrorIs(t, err, ErrSecretCannotBeEmpty)
+}
+
+// recordingSecretStore 记录 Get 返回的切片，用于检查使用之后是否被清零。
+type recordingSecretStore struct {
+	*MemorySecretStore
+	returned [][]byte
+}
+
+func (s *recordingSecretStore) Get(id string) ([]byte, error) {
+	secret, err := s.MemorySecretStore.Get(id)
+	s.returned = append(s.returned, secret)
+	return secret, err
+}
+
+func TestStoreSecretZeroed(t *testing.T) {
+	store := &recordingSecretStore{MemorySecretStore: NewMemorySecretStore()}
+	_ = store.Put("alice", []byte("12345678901234567890"))
+	totp, err := NewTOTPFromStore(store, "alice")
+	assert.Nil(t, err)
+	store.returned = nil
+	totp.At(time.Unix(59, 0))
+	totp.KeyURI("alice", "Example")
+	assert.Len(t, store.returned, 2)
+	for _, secret := range store.returned {
+		assert.Equal(t, make([]byte, 20), secret)
+	}
+}
@@ -0,0 +1,181 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Yandex.Key 秘钥的有效部分为 16 字节，手动输入的完整秘钥为 26 字节（包含校验信息）。
+	yandexSecretLength     = 16
+	yandexSecretFullLength = 26
+	// Yandex.Key 一次性密码的长度，由 26 个小写字母组成。
+	yandexDigits = 8
+)
+
+// YandexTOTP Yandex.Key 使用的 TOTP 变体。
+//
+// 与标准 TOTP 的区别：
+//   - HMAC 的秘钥为 SHA256(PIN + secret)。
+//   - 固定使用 HMAC-SHA256，截断时取 8 个字节。
+//   - 输出 8 个小写字母而不是数字。
+type YandexTOTP struct {
+	Otp
+	// base32 encoded string
+	Secret string
+	// SHA256(pin + secret)
+	key []byte
+}
+
+// YandexKey Yandex.Key 二维码中携带的账户信息。
+type YandexKey struct {
+	// 账户名称
+	Account string
+	// base32 编码的秘钥
+	Secret string
+	// PIN 的长度，二维码中未携带时为 0
+	PinLength int
+}
+
+// NewYandexTOTP 创建一个 YandexTOTP 结构体，可以使用 option 的模式传递参数。
+//
+// Params:
+//
+//	secret    : 必传，base32 编码的秘钥，可以是二维码中的 16 字节秘钥，也可以是手动输入的 26 字节完整秘钥。
+//	pin       : 必传，用户在 Yandex.Key 中设置的 PIN。
+//	WithPeriod: 设置 token 有效期长度，默认 30 秒。
+//	WithSkew  : 是否校验相邻的窗口。
+//
+// Digits 固定为 8，Algorithm 固定为 SHA256，传入的对应 option 将会被忽略。
+//
+// Panic:
+//   - secret base32 decode error
+//   - secret or pin is an empty string
+func NewYandexTOTP(secret, pin string, options ...TOTPOption) *YandexTOTP {
+	if secret == "" || pin == "" {
+		panic(ErrSecretCannotBeEmpty)
+	}
+	decodedSecret, err := Base32Decode(secret)
+	if err != nil {
+		panic(err)
+	}
+	if len(decodedSecret) != yandexSecretLength && len(decodedSecret) != yandexSecretFullLength {
+		panic(ErrSecretDecode)
+	}
+	otp := Otp{
+		Skew:   0,
+		Period: 30,
+	}
+	for _, opt := range options {
+		opt.applyTOTP(&otp)
+	}
+	otp.Digits = yandexDigits
+	otp.Algorithm = AlgorithmSHA256
+
+	sum := sha256.Sum256(append([]byte(pin), decodedSecret[:yandexSecretLength]...))
+	key := sum[:]
+	if key[0] == 0 {
+		key = key[1:]
+	}
+	return &YandexTOTP{
+		Otp:    otp,
+		Secret: secret,
+		key:    key,
+	}
+}
+
+// Now 基于当前时间点生成 token。
+func (y *YandexTOTP) Now() string {
+	return y.At(y.now())
+}
+
+// At 生成某个时间点的 token。
+func (y *YandexTOTP) At(t time.Time) string {
+	mac := hmac.New(sha256.New, y.key)
+	mac.Write(intToByte(t.Unix() / int64(y.Period)))
+	h := mac.Sum(nil)
+	offset := h[len(h)-1] & 0xf
+	h[offset] &= 0x7f
+	code := binary.BigEndian.Uint64(h[offset : offset+8])
+	return yandexEncode(code, yandexDigits)
+}
+
+// Verify 校验 token 是否在指定的时间有效，忽略大小写。
+func (y *YandexTOTP) Verify(token string, t time.Time) bool {
+	if token == "" {
+		return false
+	}
+	if !y.validAt(t) {
+		return false
+	}
+	token = strings.ToLower(token)
+	sec := t.Unix()
+	backward, forward := y.window()
+	for i := backward * -1; i <= forward; i++ {
+		if y.At(time.Unix(sec, 0).Add(time.Second*time.Duration(y.Period*i))) == token {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyNow 校验 token 在当前时间是否有效。
+func (y *YandexTOTP) VerifyNow(token string) bool {
+	return y.Verify(token, y.now())
+}
+
+// ParseYandexURI 解析 Yandex.Key 的二维码内容。
+//
+// Example:
+//
+//	otpauth://yaotp/alice?secret=LA2V6KMCGYMWWVEW64RNP3JA3I&name=alice&pin_length=4
+func ParseYandexURI(uri string) (*YandexKey, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, ErrURIFormat
+	}
+	if u.Scheme != "otpauth" || u.Host != "yaotp" {
+		return nil, ErrURIFormat
+	}
+	query := u.Query()
+	secret := query.Get("secret")
+	if secret == "" {
+		return nil, ErrURIFormat
+	}
+	decodedSecret, err := Base32Decode(secret)
+	if err != nil || (len(decodedSecret) != yandexSecretLength && len(decodedSecret) != yandexSecretFullLength) {
+		return nil, ErrURIFormat
+	}
+	pinLength, err := atoi(query.Get("pin_length"), 0)
+	if err != nil || pinLength < 0 {
+		return nil, ErrURIFormat
+	}
+	account := query.Get("name")
+	if account == "" {
+		account = strings.TrimPrefix(u.Path, "/")
+	}
+	return &YandexKey{
+		Account:   account,
+		Secret:    secret,
+		PinLength: pinLength,
+	}, nil
+}
+
+// yandexEncode 将数值转换为指定长度的小写字母字符串。
+func yandexEncode(code uint64, length int) string {
+	mod := uint64(1)
+	for i := 0; i < length; i++ {
+		mod *= 26
+	}
+	code %= mod
+	chars := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		chars[i] = byte('a' + code%26)
+		code /= 26
+	}
+	return string(chars)
+}
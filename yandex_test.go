@@ -0,0 +1,75 @@
+package otp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// test vectors from https://github.com/beemdevelopment/Aegis
+func TestYandexTOTP_At(t *testing.T) {
+	var cases = []struct {
+		pin    string
+		secret string
+		sec    int64
+		token  string
+	}{
+		{"5239", "6SB2IKNM6OBZPAVBVTOHDKS4FAAAAAAADFUTQMBTRY", 1641559648, "umozdicq"},
+		{"7586", "LA2V6KMCGYMWWVEW64RNP3JA3IAAAAAAHTSG4HRZPI", 1581064020, "oactmacq"},
+		{"7586", "LA2V6KMCGYMWWVEW64RNP3JA3IAAAAAAHTSG4HRZPI", 1581090810, "wemdwrix"},
+		{"5210481216086702", "JBGSAU4G7IEZG6OY4UAXX62JU4AAAAAAHTSG4HXU3M", 1581091469, "dfrpywob"},
+		{"5210481216086702", "JBGSAU4G7IEZG6OY4UAXX62JU4AAAAAAHTSG4HXU3M", 1581093059, "vunyprpd"},
+	}
+	for _, c := range cases {
+		yandex := NewYandexTOTP(c.secret, c.pin)
+		assert.Equal(t, c.token, yandex.At(time.Unix(c.sec, 0)))
+	}
+
+	// 二维码中只包含前 16 字节秘钥
+	full, _ := Base32Decode("LA2V6KMCGYMWWVEW64RNP3JA3IAAAAAAHTSG4HRZPI")
+	yandex := NewYandexTOTP(Base32Encode(full[:16]), "7586")
+	assert.Equal(t, "oactmacq", yandex.At(time.Unix(1581064020, 0)))
+}
+
+func TestYandexTOTP_Verify(t *testing.T) {
+	yandex := NewYandexTOTP("LA2V6KMCGYMWWVEW64RNP3JA3IAAAAAAHTSG4HRZPI", "7586")
+	now := time.Unix(1581064020, 0)
+	assert.Equal(t, true, yandex.Verify("oactmacq", now))
+	assert.Equal(t, true, yandex.Verify("OACTMACQ", now))
+	assert.Equal(t, false, yandex.Verify("oactmacq", now.Add(30*time.Second)))
+	assert.Equal(t, false, yandex.Verify("", now))
+	assert.Equal(t, false, NewYandexTOTP("LA2V6KMCGYMWWVEW64RNP3JA3IAAAAAAHTSG4HRZPI", "0000").Verify("oactmacq", now))
+	assert.Equal(t, true, yandex.VerifyNow(yandex.Now()))
+
+	yandex2 := NewYandexTOTP("LA2V6KMCGYMWWVEW64RNP3JA3IAAAAAAHTSG4HRZPI", "7586", WithSkew(1), WithDigits(DigitsSix))
+	assert.Equal(t, Digits(8), yandex2.Digits)
+	assert.Equal(t, true, yandex2.Verify("oactmacq", now.Add(30*time.Second)))
+
+	assert.PanicsWithError(t, ErrSecretCannotBeEmpty.Error(), func() {
+		NewYandexTOTP("LA2V6KMCGYMWWVEW64RNP3JA3IAAAAAAHTSG4HRZPI", "")
+	})
+	assert.PanicsWithError(t, ErrSecretDecode.Error(), func() {
+		NewYandexTOTP(TestSecret20, "7586")
+	})
+}
+
+func TestParseYandexURI(t *testing.T) {
+	key, err := ParseYandexURI("otpauth://yaotp/alice?secret=LA2V6KMCGYMWWVEW64RNP3JA3I&name=alice%40yandex.ru&pin_length=4")
+	assert.Nil(t, err)
+	assert.Equal(t, &YandexKey{Account: "alice@yandex.ru", Secret: "LA2V6KMCGYMWWVEW64RNP3JA3I", PinLength: 4}, key)
+
+	key2, err := ParseYandexURI("otpauth://yaotp/alice?secret=LA2V6KMCGYMWWVEW64RNP3JA3I")
+	assert.Nil(t, err)
+	assert.Equal(t, &YandexKey{Account: "alice", Secret: "LA2V6KMCGYMWWVEW64RNP3JA3I"}, key2)
+
+	var errorUris = []string{
+		"otpauth://totp/alice?secret=LA2V6KMCGYMWWVEW64RNP3JA3I",
+		"otpauth://yaotp/alice",
+		"otpauth://yaotp/alice?secret=" + TestSecret20,
+		"otpauth://yaotp/alice?secret=LA2V6KMCGYMWWVEW64RNP3JA3I&pin_length=x",
+	}
+	for _, uri := range errorUris {
+		_, err := ParseYandexURI(uri)
+		assert.Equal(t, ErrURIFormat, err)
+	}
+}
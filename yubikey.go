@@ -0,0 +1,191 @@
+package otp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yubiKeyMaxNameLength YubiKey OATH 应用中凭据名称（包含 period 前缀）的最大字节数。
+const yubiKeyMaxNameLength = 64
+
+// YubiKeyCredential YubiKey OATH 应用中的一个凭据，名称格式与 ykman 一致。
+//
+// ykman 使用 [period/][issuer:]account 作为凭据名称，period 为 30 时省略前缀，HOTP 凭据没有 period 前缀。
+// YubiKey 仅支持 SHA1、SHA256、SHA512 算法以及 6 到 8 位数字。
+//
+// Args 生成的命令会将秘钥作为命令行参数传给 ykman，其他本地用户可以看到，多用户环境中请使用 PromptArgs。
+type YubiKeyCredential struct {
+	// totp 或 hotp
+	Type    string
+	Issuer  string
+	Account string
+	// TOTP 的有效期，HOTP 为 0
+	Period int
+	// 生成 token 时是否需要触摸 YubiKey，对应 ykman 的 --touch 参数
+	Touch bool
+	key   KeyURI
+}
+
+// NewYubiKeyCredential 使用 KeyURI 创建一个 YubiKeyCredential，参数无法存储在 YubiKey 上时返回 ErrYubiKeyUnsupported。
+//
+// Example:
+//
+//	cred, err := NewYubiKeyCredential(totp.KeyURI("alice@google.com", "Example"), true)
+//	cmd := exec.Command("ykman", cred.PromptArgs()...)
+func NewYubiKeyCredential(key *KeyURI, touch bool) (*YubiKeyCredential, error) {
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+	algorithm, _ := Algorithms.from(AlgorithmSHA1, key.Algorithm)
+	switch algorithm {
+	case AlgorithmSHA1, AlgorithmSHA256, AlgorithmSHA512:
+	default:
+		return nil, fmt.Errorf("%w: algorithm %s", ErrYubiKeyUnsupported, algorithm)
+	}
+	if key.Digits < 6 || key.Digits > 8 {
+		return nil, fmt.Errorf("%w: digits %d", ErrYubiKeyUnsupported, key.Digits)
+	}
+	if key.Encoder != "" || key.Epoch != 0 {
+		return nil, fmt.Errorf("%w: non-standard parameters", ErrYubiKeyUnsupported)
+	}
+	issuer, account := key.Issuer, key.label()
+	if i := strings.Index(account, ":"); i != -1 {
+		if issuer == "" {
+			issuer = account[:i]
+		}
+		account = strings.TrimLeft(account[i+1:], " ")
+	}
+	cred := &YubiKeyCredential{
+		Type:    strings.ToLower(key.Type),
+		Issuer:  issuer,
+		Account: account,
+		Touch:   touch,
+		key:     *key,
+	}
+	if cred.Type == "totp" {
+		cred.Period = key.Period
+	}
+	if len(cred.Name()) > yubiKeyMaxNameLength {
+		return nil, fmt.Errorf("%w: name longer than %d bytes", ErrYubiKeyName, yubiKeyMaxNameLength)
+	}
+	return cred, nil
+}
+
+// Name 返回 ykman 格式的凭据名称，例如 Example:alice、60/Example:alice。
+func (c YubiKeyCredential) Name() string {
+	return FormatYubiKeyName(c.Type, c.Issuer, c.Account, c.Period)
+}
+
+// KeyURI 返回凭据对应的 KeyURI，可以使用 `ykman oath accounts uri` 导入。
+//
+// 触摸要求无法在 URI 中表示，需要触摸时使用 Args 生成的命令导入。
+func (c YubiKeyCredential) KeyURI() *KeyURI {
+	key := c.key
+	return &key
+}
+
+// Args 返回添加该凭据的 ykman 命令参数，例如：
+//
+//	oath accounts add --oath-type TOTP --digits 6 --algorithm SHA1 --period 30 --issuer Example --touch -- alice@google.com SECRET
+//
+// 注意：秘钥是最后一个命令行参数，执行期间同一台机器上的其他用户可以通过 ps 或 /proc/<pid>/cmdline 看到秘钥，
+// 只应该在受信任的单用户环境中使用，并且不应该写入日志。需要避免暴露时使用 PromptArgs 由 ykman 提示输入秘钥。
+//
+// 位置参数之前会添加 --，以 - 开头的账户名称不会被当作选项解析。
+func (c YubiKeyCredential) Args() []string {
+	return append(c.PromptArgs(), c.key.Secret)
+}
+
+// PromptArgs 与 Args 相同，但是不包含秘钥，ykman 会在终端中提示输入秘钥（输入不回显），秘钥不会出现在进程的命令行中。
+//
+// Example:
+//
+//	cmd := exec.Command("ykman", cred.PromptArgs()...)
+//	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+//	fmt.Println("secret:", cred.KeyURI().Secret)
+//	err := cmd.Run()
+func (c YubiKeyCredential) PromptArgs() []string {
+	args := []string{
+		"oath", "accounts", "add",
+		"--oath-type", strings.ToUpper(c.Type),
+		"--digits", strconv.Itoa(c.key.Digits),
+		"--algorithm", c.key.Algorithm,
+	}
+	if c.Type == "totp" {
+		args = append(args, "--period", strconv.Itoa(c.Period))
+	} else {
+		args = append(args, "--counter", strconv.FormatInt(c.key.Counter, 10))
+	}
+	if c.Issuer != "" {
+		args = append(args, "--issuer", c.Issuer)
+	}
+	if c.Touch {
+		args = append(args, "--touch")
+	}
+	return append(args, "--", c.Account)
+}
+
+// FormatYubiKeyName 按照 ykman 的规则格式化凭据名称：[period/][issuer:]account。
+//
+// 只有 totp 并且 period 不为 30 时才会添加 period 前缀，issuer 为空时省略 issuer 前缀。
+func FormatYubiKeyName(oathType, issuer, account string, period int) string {
+	name := account
+	if issuer != "" {
+		name = issuer + ":" + account
+	}
+	if strings.EqualFold(oathType, "totp") && period != 0 && period != 30 {
+		name = strconv.Itoa(period) + "/" + name
+	}
+	return name
+}
+
+// ParseYubiKeyName 解析 ykman 格式的凭据名称，返回 issuer、account 和 period，totp 没有 period 前缀时 period 为 30，hotp 为 0。
+//
+// 名称为空或者账户名称为空时返回 ErrYubiKeyName。
+//
+// Example:
+//
+//	issuer, account, period, err := ParseYubiKeyName("totp", "60/Example:alice") // "Example", "alice", 60
+func ParseYubiKeyName(oathType, name string) (issuer, account string, period int, err error) {
+	if strings.EqualFold(oathType, "totp") {
+		period = 30
+		if i := strings.Index(name, "/"); i > 0 {
+			if p, err := strconv.Atoi(name[:i]); err == nil && p > 0 {
+				period = p
+				name = name[i+1:]
+			}
+		}
+	}
+	account = name
+	if i := strings.Index(name, ":"); i != -1 {
+		issuer, account = name[:i], name[i+1:]
+	}
+	if account == "" {
+		return "", "", 0, fmt.Errorf("%w: %q", ErrYubiKeyName, name)
+	}
+	return issuer, account, period, nil
+}
+
+// ParseYubiKeyCredential 使用 ykman 格式的凭据名称和 base32 编码的秘钥创建 KeyURI，
+// 适用于将 `ykman oath accounts list` 中的凭据迁移到其他验证器。
+func ParseYubiKeyCredential(oathType, name, secret string, digits Digits, algorithm Algorithms) (*KeyURI, error) {
+	issuer, account, period, err := ParseYubiKeyName(oathType, name)
+	if err != nil {
+		return nil, err
+	}
+	key := &KeyURI{
+		Type:      strings.ToLower(oathType),
+		Label:     account,
+		Algorithm: algorithm.String(),
+		Digits:    int(digits),
+		Period:    period,
+		Issuer:    issuer,
+		Secret:    secret,
+	}
+	key.Normalize()
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
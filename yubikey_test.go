@@ -0,0 +1,95 @@
+package otp
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFormatYubiKeyName(t *testing.T) {
+	assert.Equal(t, "Example:alice", FormatYubiKeyName("totp", "Example", "alice", 30))
+	assert.Equal(t, "60/Example:alice", FormatYubiKeyName("TOTP", "Example", "alice", 60))
+	assert.Equal(t, "alice", FormatYubiKeyName("totp", "", "alice", 0))
+	assert.Equal(t, "Example:alice", FormatYubiKeyName("hotp", "Example", "alice", 60))
+}
+
+func TestParseYubiKeyName(t *testing.T) {
+	cases := []struct {
+		oathType, name, issuer, account string
+		period                          int
+	}{
+		{"totp", "Example:alice", "Example", "alice", 30},
+		{"totp", "60/Example:alice", "Example", "alice", 60},
+		{"totp", "alice", "", "alice", 30},
+		{"totp", "a/b:c", "a/b", "c", 30},
+		{"hotp", "15/Example:alice", "15/Example", "alice", 0},
+	}
+	for _, c := range cases {
+		issuer, account, period, err := ParseYubiKeyName(c.oathType, c.name)
+		assert.Nil(t, err, c.name)
+		assert.Equal(t, c.issuer, issuer, c.name)
+		assert.Equal(t, c.account, account, c.name)
+		assert.Equal(t, c.period, period, c.name)
+	}
+	_, _, _, err := ParseYubiKeyName("totp", "Example:")
+	assert.ErrorIs(t, err, ErrYubiKeyName)
+}
+
+func TestNewYubiKeyCredential(t *testing.T) {
+	totp := NewTOTP(TestSecret20, WithPeriod(60), WithAlgorithm(AlgorithmSHA256))
+	cred, err := NewYubiKeyCredential(totp.KeyURI("alice@google.com", "Example"), true)
+	assert.Nil(t, err)
+	assert.Equal(t, "60/Example:alice@google.com", cred.Name())
+	assert.Equal(t, []string{
+		"oath", "accounts", "add",
+		"--oath-type", "TOTP", "--digits", "6", "--algorithm", "SHA256", "--period", "60",
+		"--issuer", "Example", "--touch", "--", "alice@google.com", TestSecret20,
+	}, cred.Args())
+	args := cred.PromptArgs()
+	assert.Equal(t, cred.Args()[:len(cred.Args())-1], args)
+	assert.NotContains(t, args, TestSecret20)
+	assert.Equal(t, totp.KeyURI("alice@google.com", "Example"), cred.KeyURI())
+
+	hotp := NewHOTP(TestSecret20, WithCounter(5))
+	cred, err = NewYubiKeyCredential(hotp.KeyURI("alice", ""), false)
+	assert.Nil(t, err)
+	assert.Equal(t, "alice", cred.Name())
+	assert.Equal(t, []string{
+		"oath", "accounts", "add",
+		"--oath-type", "HOTP", "--digits", "6", "--algorithm", "SHA1", "--counter", "5", "--", "alice", TestSecret20,
+	}, cred.Args())
+
+	// 以 - 开头的账户名称不会被当作选项
+	cred, err = NewYubiKeyCredential(NewTOTP(TestSecret20).KeyURI("--touch", "Example"), false)
+	assert.Nil(t, err)
+	args = cred.PromptArgs()
+	assert.Equal(t, []string{"--", "--touch"}, args[len(args)-2:])
+	assert.NotContains(t, args[:len(args)-2], "--touch")
+
+	unsupported := []*KeyURI{
+		NewTOTP(TestSecret20, WithAlgorithm(AlgorithmSHA3_256)).KeyURI("alice", "Example"),
+		NewTOTP(TestSecret20, WithDigits(10)).KeyURI("alice", "Example"),
+		NewTOTP(TestSecret20, WithEncoder(EncoderSteam)).KeyURI("alice", "Example"),
+	}
+	for _, key := range unsupported {
+		_, err := NewYubiKeyCredential(key, false)
+		assert.ErrorIs(t, err, ErrYubiKeyUnsupported)
+	}
+	long := NewTOTP(TestSecret20).KeyURI("a-very-long-account-name@a-very-long-subdomain.example.com", "Example")
+	_, err = NewYubiKeyCredential(long, false)
+	assert.ErrorIs(t, err, ErrYubiKeyName)
+}
+
+func TestParseYubiKeyCredential(t *testing.T) {
+	key, err := ParseYubiKeyCredential("totp", "60/Example:alice@google.com", TestSecret20, DigitsEight, AlgorithmSHA256)
+	assert.Nil(t, err)
+	assert.Equal(t, "Example:alice@google.com", key.Label)
+	assert.Equal(t, "Example", key.Issuer)
+	assert.Equal(t, 60, key.Period)
+
+	cred, err := NewYubiKeyCredential(key, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "60/Example:alice@google.com", cred.Name())
+
+	_, err = ParseYubiKeyCredential("totp", "alice", "", DigitsSix, AlgorithmSHA1)
+	assert.ErrorIs(t, err, ErrMissingSecret)
+}